@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// digestWindow parses the [alerts] digest window, zero when alerts are sent right away
+func digestWindow(config AlertsConfig) (time.Duration, error) {
+	if config.Digest == "" {
+		return 0, nil
+	}
+	window, err := time.ParseDuration(config.Digest)
+	if err != nil || window <= 0 {
+		return 0, fmt.Errorf("invalid alerts digest %q, expected a duration such as \"5m\"", config.Digest)
+	}
+	return window, nil
+}
+
+// alertDigest holds alerts back so every destination gets one message summarizing them all
+type alertDigest struct {
+	mu      sync.Mutex
+	pending map[string][]Alert
+	since   time.Time
+}
+
+// add queues an alert for each of its destinations
+func (g *alertDigest) add(alert Alert, now time.Time) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if len(g.pending) == 0 {
+		g.pending = make(map[string][]Alert)
+		g.since = now
+	}
+	for _, destination := range alert.Notify {
+		g.pending[destination] = append(g.pending[destination], alert)
+	}
+}
+
+// take returns and clears the queued alerts once the oldest has waited for window, or right
+// away when window is zero
+func (g *alertDigest) take(window time.Duration, now time.Time) map[string][]Alert {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if len(g.pending) == 0 || now.Sub(g.since) < window {
+		return nil
+	}
+	pending := g.pending
+	g.pending = nil
+	return pending
+}
+
+// digestMessage summarizes alerts in one message, counting them on its first line
+func digestMessage(alerts []Alert) string {
+	var firing int
+	for _, alert := range alerts {
+		if alert.Firing {
+			firing++
+		}
+	}
+	noun := "alerts"
+	if len(alerts) == 1 {
+		noun = "alert"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "[DIGEST] %d %s: %d firing, %d resolved\n", len(alerts), noun, firing, len(alerts)-firing)
+	for _, alert := range alerts {
+		fmt.Fprintf(&b, "\n%s\n", alert.Message)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// sendDigest sends each destination one message with its queued alerts, returning every failure
+func sendDigest(notifiers NotifiersConfig, pending map[string][]Alert) []error {
+	var errs []error
+	for _, destination := range slices.Sorted(maps.Keys(pending)) {
+		message := digestMessage(pending[destination])
+		subject, _, _ := strings.Cut(message, "\n")
+		if err := notify(notifiers, destination, subject, message); err != nil {
+			errs = append(errs, fmt.Errorf("error sending alert digest to %s: %s", destination, err))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDigestWindow(t *testing.T) {
+	tests := []struct {
+		digest  string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"5m", 5 * time.Minute, false},
+		{"soon", 0, true},
+		{"-1m", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := digestWindow(AlertsConfig{Digest: tt.digest})
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("digestWindow(%q) = %s, %v, want %s, error %v", tt.digest, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestAlertDigest(t *testing.T) {
+	var mu sync.Mutex
+	posted := make(map[string]string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var message slackMessage
+		json.NewDecoder(r.Body).Decode(&message)
+		mu.Lock()
+		posted[message.Channel] = message.Text
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	start := time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC)
+	var digest alertDigest
+	digest.add(Alert{Rule: "payments down", Firing: true, Message: "[FIRING] payments down: payments (3 consecutive failing runs)", Notify: []string{"slack:#ops", "slack:#payments"}}, start)
+	digest.add(Alert{Rule: "slow API", Message: "[RESOLVED] slow API: search (p95 0.40s)", Notify: []string{"slack:#ops"}}, start.Add(time.Minute))
+
+	if pending := digest.take(5*time.Minute, start.Add(4*time.Minute)); pending != nil {
+		t.Fatalf("digest sent before its window: %v", pending)
+	}
+	pending := digest.take(5*time.Minute, start.Add(5*time.Minute))
+	if got := slices.Sorted(maps.Keys(pending)); !slices.Equal(got, []string{"slack:#ops", "slack:#payments"}) {
+		t.Fatalf("digest destinations = %v", got)
+	}
+	if again := digest.take(0, start.Add(time.Hour)); again != nil {
+		t.Errorf("digest sent twice: %v", again)
+	}
+
+	notifiers := NotifiersConfig{Slack: &SlackConfig{WebhookURL: server.URL}}
+	if errs := sendDigest(notifiers, pending); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	want := map[string]string{
+		"#ops":      "[DIGEST] 2 alerts: 1 firing, 1 resolved\n\n[FIRING] payments down: payments (3 consecutive failing runs)\n\n[RESOLVED] slow API: search (p95 0.40s)",
+		"#payments": "[DIGEST] 1 alert: 1 firing, 0 resolved\n\n[FIRING] payments down: payments (3 consecutive failing runs)",
+	}
+	for channel, text := range want {
+		if posted[channel] != text {
+			t.Errorf("message to %s = %q, want %q", channel, posted[channel], text)
+		}
+	}
+	if len(posted) != len(want) {
+		t.Errorf("posted %d messages, want %d", len(posted), len(want))
+	}
+}
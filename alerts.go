@@ -0,0 +1,306 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultAlertWindow is how long a p95 rule looks back when `for` is not set
+const defaultAlertWindow = 5 * time.Minute
+
+// AlertsConfig holds the rules serve mode evaluates against live results
+type AlertsConfig struct {
+	Rules []AlertRule `toml:"rules,omitempty"`
+
+	// Digest batches the alerts of this long, e.g. "5m", into one message per destination
+	Digest string `toml:"digest,omitempty"`
+}
+
+// AlertRule notifies when the targets it selects meet its condition, and again when they recover
+type AlertRule struct {
+	Name string `toml:"name,omitempty"`
+
+	// Target (a name or config::name) and Team select targets; a rule without either covers all
+	Target string `toml:"target,omitempty"`
+	Team   string `toml:"team,omitempty"`
+
+	// ConsecutiveFailures fires after that many failing runs in a row; P95Above fires when the
+	// p95 latency over the last For (default 5m) exceeds it. A rule has exactly one condition
+	ConsecutiveFailures int    `toml:"consecutive_failures,omitzero"`
+	P95Above            string `toml:"p95_above,omitempty"`
+	For                 string `toml:"for,omitempty"`
+
+	// Notify lists destinations: "slack", "slack:#channel", "email", or "email:address"
+	Notify []string `toml:"notify,omitempty"`
+}
+
+// alertsConfig returns the first [alerts] section with rules among the configs
+func alertsConfig(configs []ConfigWithSource) AlertsConfig {
+	for _, configWithSource := range configs {
+		if len(configWithSource.Config.Alerts.Rules) > 0 {
+			return configWithSource.Config.Alerts
+		}
+	}
+	return AlertsConfig{}
+}
+
+// alertRule is a validated rule with its durations parsed
+type alertRule struct {
+	AlertRule
+	p95Above time.Duration
+	window   time.Duration
+}
+
+// compileAlertRules validates rules against the configured notifiers
+func compileAlertRules(config AlertsConfig, notifiers NotifiersConfig) ([]alertRule, error) {
+	rules := make([]alertRule, 0, len(config.Rules))
+	for i, rule := range config.Rules {
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rule %d", i+1)
+		}
+		compiled := alertRule{AlertRule: rule}
+
+		switch {
+		case rule.ConsecutiveFailures > 0 && rule.P95Above != "":
+			return nil, fmt.Errorf("alert rule %q: consecutive_failures and p95_above can't be combined", rule.Name)
+		case rule.ConsecutiveFailures > 0:
+			if rule.For != "" {
+				return nil, fmt.Errorf("alert rule %q: for only applies to p95_above", rule.Name)
+			}
+		case rule.P95Above != "":
+			threshold, err := time.ParseDuration(rule.P95Above)
+			if err != nil {
+				return nil, fmt.Errorf("alert rule %q: invalid p95_above %q: %s", rule.Name, rule.P95Above, err)
+			}
+			compiled.p95Above = threshold
+			compiled.window = defaultAlertWindow
+			if rule.For != "" {
+				if compiled.window, err = time.ParseDuration(rule.For); err != nil || compiled.window <= 0 {
+					return nil, fmt.Errorf("alert rule %q: invalid for %q", rule.Name, rule.For)
+				}
+			}
+		default:
+			return nil, fmt.Errorf("alert rule %q: needs consecutive_failures or p95_above", rule.Name)
+		}
+
+		if len(rule.Notify) == 0 {
+			return nil, fmt.Errorf("alert rule %q: notify is empty", rule.Name)
+		}
+		for _, destination := range rule.Notify {
+			kind, _, _ := strings.Cut(destination, ":")
+			switch {
+			case kind == "slack" && notifiers.Slack != nil, kind == "email" && notifiers.Email != nil:
+			case kind == "slack" || kind == "email":
+				return nil, fmt.Errorf("alert rule %q: notify %q needs a [notifiers.%s] section", rule.Name, destination, kind)
+			default:
+				return nil, fmt.Errorf("alert rule %q: unknown notify destination %q, expected slack or email", rule.Name, destination)
+			}
+		}
+		rules = append(rules, compiled)
+	}
+	return rules, nil
+}
+
+// Alert is a rule starting or stopping to fire for a target
+type Alert struct {
+	Rule    string
+	Key     string
+	Firing  bool
+	Message string
+	Notify  []string
+}
+
+// Subject is the first line of the message, used as the email subject
+func (a Alert) Subject() string {
+	subject, _, _ := strings.Cut(a.Message, "\n")
+	return subject
+}
+
+// latencySample is the duration of one completed request
+type latencySample struct {
+	at      time.Time
+	seconds float64
+}
+
+// alertEngine tracks what each rule needs across runs: failure streaks, recent latencies, and
+// which rules are firing for which targets
+type alertEngine struct {
+	mu        sync.Mutex
+	rules     []alertRule
+	failures  map[string]int
+	samples   map[string][]latencySample
+	firstSeen map[string]time.Time
+	firing    map[string]bool
+}
+
+// newAlertEngine prepares an engine with no history
+func newAlertEngine(rules []alertRule) *alertEngine {
+	return &alertEngine{
+		rules:     rules,
+		failures:  make(map[string]int),
+		samples:   make(map[string][]latencySample),
+		firstSeen: make(map[string]time.Time),
+		firing:    make(map[string]bool),
+	}
+}
+
+// evaluate records a run and returns the alerts that started or stopped firing because of it;
+// a nil engine has no rules
+func (e *alertEngine) evaluate(run TargetRun, now time.Time) []Alert {
+	if e == nil {
+		return nil
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.observe(run, now)
+
+	var alerts []Alert
+	for _, rule := range e.rules {
+		if !rule.selects(run) {
+			continue
+		}
+		met, detail := e.check(rule, run.Key, now)
+
+		firingKey := rule.Name + " " + run.Key
+		if met == e.firing[firingKey] {
+			continue
+		}
+		e.firing[firingKey] = met
+		alerts = append(alerts, Alert{
+			Rule:    rule.Name,
+			Key:     run.Key,
+			Firing:  met,
+			Message: alertMessage(rule, run, met, detail),
+			Notify:  rule.Notify,
+		})
+	}
+	return alerts
+}
+
+// observe updates the failure streak and latency window of a run's target
+func (e *alertEngine) observe(run TargetRun, now time.Time) {
+	if _, ok := e.firstSeen[run.Key]; !ok {
+		e.firstSeen[run.Key] = now
+	}
+
+	failing := false
+	samples := e.samples[run.Key]
+	for _, result := range run.Results {
+		if resultFailed(result) {
+			failing = true
+		}
+		if result.Error == nil {
+			samples = append(samples, latencySample{at: now, seconds: result.Duration.Seconds()})
+		}
+	}
+	if failing {
+		e.failures[run.Key]++
+	} else {
+		e.failures[run.Key] = 0
+	}
+
+	// Keep only what the longest window needs
+	var keep time.Duration
+	for _, rule := range e.rules {
+		keep = max(keep, rule.window)
+	}
+	for len(samples) > 0 && now.Sub(samples[0].at) > keep {
+		samples = samples[1:]
+	}
+	e.samples[run.Key] = samples
+}
+
+// check reports whether a rule's condition holds for a target, describing what was seen
+func (e *alertEngine) check(rule alertRule, key string, now time.Time) (bool, string) {
+	if rule.ConsecutiveFailures > 0 {
+		failures := e.failures[key]
+		return failures >= rule.ConsecutiveFailures, fmt.Sprintf("%d consecutive failing runs", failures)
+	}
+
+	var values []float64
+	for _, sample := range e.samples[key] {
+		if now.Sub(sample.at) <= rule.window {
+			values = append(values, sample.seconds)
+		}
+	}
+	p95 := percentile(values, 95)
+	detail := fmt.Sprintf("p95 %.2fs over the last %s (threshold %s)", p95, rule.window, rule.p95Above)
+
+	// Don't judge a window that hasn't been observed in full yet
+	if now.Sub(e.firstSeen[key]) < rule.window || len(values) == 0 {
+		return false, detail
+	}
+	return p95 > rule.p95Above.Seconds(), detail
+}
+
+// selects reports whether a rule covers the run's target
+func (r alertRule) selects(run TargetRun) bool {
+	if r.Team != "" && r.Team != run.Ownership.Team {
+		return false
+	}
+	if r.Target == "" {
+		return true
+	}
+	return runOptions{only: []string{r.Target}}.selectsTarget(run.ConfigName, run.TargetName)
+}
+
+// alertMessage describes an alert with the failing checks, runbook, and owners
+func alertMessage(rule alertRule, run TargetRun, firing bool, detail string) string {
+	state := "RESOLVED"
+	if firing {
+		state = "FIRING"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "[%s] %s: %s (%s)\n", state, rule.Name, run.TargetName, detail)
+	var runbook string
+	for _, result := range run.Results {
+		if resultFailed(result) {
+			fmt.Fprintf(&b, "%s %s: %s\n", result.Method, result.label(), describeRequestFailure(result))
+		}
+		if result.RunbookURL != "" {
+			runbook = result.RunbookURL
+		}
+	}
+	if firing && runbook != "" {
+		fmt.Fprintf(&b, "Runbook: %s\n", runbook)
+	}
+	if owners := run.Ownership.String(); owners != "" {
+		fmt.Fprintf(&b, "%s\n", owners)
+	}
+	if len(run.Labels) > 0 {
+		fmt.Fprintf(&b, "Labels: %s\n", run.Labels)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// sendAlert delivers an alert to each of its destinations, returning every failure
+func sendAlert(notifiers NotifiersConfig, alert Alert) []error {
+	var errs []error
+	for _, destination := range alert.Notify {
+		if err := notify(notifiers, destination, alert.Subject(), alert.Message); err != nil {
+			errs = append(errs, fmt.Errorf("error sending alert %q to %s: %s", alert.Rule, destination, err))
+		}
+	}
+	return errs
+}
+
+// notify sends a message to one destination, using subject for email
+func notify(notifiers NotifiersConfig, destination, subject, message string) error {
+	kind, arg, _ := strings.Cut(destination, ":")
+	switch {
+	case kind == "slack" && notifiers.Slack != nil:
+		return sendSlack(*notifiers.Slack, arg, message)
+	case kind == "email" && notifiers.Email != nil:
+		email := *notifiers.Email
+		if arg != "" {
+			email.To = []string{arg}
+		}
+		return sendEmail(email, subject, "<pre>"+html.EscapeString(message)+"</pre>")
+	}
+	return fmt.Errorf("notify %q is not configured", destination)
+}
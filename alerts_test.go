@@ -0,0 +1,150 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompileAlertRules(t *testing.T) {
+	notifiers := NotifiersConfig{Slack: &SlackConfig{WebhookURL: "https://hooks.example.com/x"}}
+
+	tests := []struct {
+		name    string
+		rule    AlertRule
+		wantErr string
+	}{
+		{"consecutive failures", AlertRule{ConsecutiveFailures: 3, Notify: []string{"slack:#ops"}}, ""},
+		{"p95 with window", AlertRule{P95Above: "2s", For: "10m", Notify: []string{"slack"}}, ""},
+		{"no condition", AlertRule{Notify: []string{"slack"}}, "needs consecutive_failures or p95_above"},
+		{"both conditions", AlertRule{ConsecutiveFailures: 3, P95Above: "2s", Notify: []string{"slack"}}, "can't be combined"},
+		{"bad threshold", AlertRule{P95Above: "fast", Notify: []string{"slack"}}, "invalid p95_above"},
+		{"for without p95", AlertRule{ConsecutiveFailures: 3, For: "10m", Notify: []string{"slack"}}, "for only applies"},
+		{"no destinations", AlertRule{ConsecutiveFailures: 3}, "notify is empty"},
+		{"unconfigured notifier", AlertRule{ConsecutiveFailures: 3, Notify: []string{"email"}}, "needs a [notifiers.email] section"},
+		{"unknown notifier", AlertRule{ConsecutiveFailures: 3, Notify: []string{"pager"}}, "unknown notify destination"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := compileAlertRules(AlertsConfig{Rules: []AlertRule{tt.rule}}, notifiers)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("compileAlertRules() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("compileAlertRules() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAlertEngineConsecutiveFailures(t *testing.T) {
+	rules, err := compileAlertRules(AlertsConfig{Rules: []AlertRule{
+		{Name: "payments down", Target: "payments", ConsecutiveFailures: 3, Notify: []string{"slack"}},
+	}}, NotifiersConfig{Slack: &SlackConfig{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := newAlertEngine(rules)
+
+	run := func(target string, success bool) TargetRun {
+		return TargetRun{
+			Key:        runKey("a.toml", target),
+			TargetName: target,
+			ConfigName: "a.toml",
+			Results:    []EndpointResult{{Method: "GET", URL: "http://x/", Success: success, StatusCode: 503, RunbookURL: "https://wiki/pay"}},
+		}
+	}
+
+	// Firing is reported once at the third failure and resolution once on recovery
+	steps := []struct {
+		run         TargetRun
+		wantFiring  int
+		wantResolve int
+	}{
+		{run("payments", false), 0, 0},
+		{run("payments", false), 0, 0},
+		{run("web", false), 0, 0},
+		{run("payments", false), 1, 0},
+		{run("payments", false), 0, 0},
+		{run("payments", true), 0, 1},
+		{run("payments", false), 0, 0},
+	}
+	now := time.Now()
+	for i, step := range steps {
+		var firing, resolved int
+		for _, alert := range engine.evaluate(step.run, now) {
+			if alert.Firing {
+				firing++
+				if !strings.Contains(alert.Message, "Runbook: https://wiki/pay") {
+					t.Errorf("step %d: message is missing the runbook:\n%s", i, alert.Message)
+				}
+			} else {
+				resolved++
+			}
+		}
+		if firing != step.wantFiring || resolved != step.wantResolve {
+			t.Fatalf("step %d: %d firing, %d resolved, want %d, %d", i, firing, resolved, step.wantFiring, step.wantResolve)
+		}
+	}
+}
+
+func TestAlertEngineP95(t *testing.T) {
+	rules, err := compileAlertRules(AlertsConfig{Rules: []AlertRule{
+		{Name: "slow", P95Above: "2s", For: "10m", Notify: []string{"slack"}},
+	}}, NotifiersConfig{Slack: &SlackConfig{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := newAlertEngine(rules)
+
+	run := func(duration time.Duration) TargetRun {
+		return TargetRun{Key: "a.toml::api", TargetName: "api", Results: []EndpointResult{{Success: true, Duration: duration}}}
+	}
+
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	steps := []struct {
+		offset     time.Duration
+		duration   time.Duration
+		wantAlerts int
+		wantFiring bool
+	}{
+		// Slow from the start, but the window isn't fully observed until 10m in
+		{0, 3 * time.Second, 0, false},
+		{5 * time.Minute, 3 * time.Second, 0, false},
+		{10 * time.Minute, 3 * time.Second, 1, true},
+		{15 * time.Minute, 100 * time.Millisecond, 0, false},
+		// Once the slow samples age out of the window the alert resolves
+		{21 * time.Minute, 100 * time.Millisecond, 1, false},
+	}
+	for i, step := range steps {
+		alerts := engine.evaluate(run(step.duration), start.Add(step.offset))
+		if len(alerts) != step.wantAlerts {
+			t.Fatalf("step %d: got %d alerts, want %d", i, len(alerts), step.wantAlerts)
+		}
+		if len(alerts) == 1 && alerts[0].Firing != step.wantFiring {
+			t.Errorf("step %d: Firing = %v, want %v", i, alerts[0].Firing, step.wantFiring)
+		}
+	}
+}
+
+func TestAlertRuleSelects(t *testing.T) {
+	run := TargetRun{TargetName: "payments", ConfigName: "a.toml", Ownership: Ownership{Team: "pay"}}
+
+	tests := []struct {
+		rule AlertRule
+		want bool
+	}{
+		{AlertRule{}, true},
+		{AlertRule{Target: "payments"}, true},
+		{AlertRule{Target: "a.toml::payments"}, true},
+		{AlertRule{Target: "web"}, false},
+		{AlertRule{Team: "pay"}, true},
+		{AlertRule{Team: "search"}, false},
+	}
+	for _, tt := range tests {
+		if got := (alertRule{AlertRule: tt.rule}).selects(run); got != tt.want {
+			t.Errorf("selects() with %+v = %v, want %v", tt.rule, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// apdexToleratingFactor is how many times the threshold a check may take and still be tolerated
+const apdexToleratingFactor = 4
+
+// Apdex is the Application Performance Index of a target's checks: satisfied checks answered
+// within the threshold, tolerating ones within four times it, and frustrated ones took longer
+// or failed. Skipped checks don't count
+type Apdex struct {
+	Threshold  float64 `json:"threshold_seconds"`
+	Score      float64 `json:"score"`
+	Satisfied  int     `json:"satisfied"`
+	Tolerating int     `json:"tolerating"`
+	Frustrated int     `json:"frustrated"`
+}
+
+// apdexThreshold is the target's Apdex threshold, zero when it doesn't score Apdex
+func (t TargetConfig) apdexThreshold() time.Duration {
+	return time.Duration(t.ApdexThresholdMS) * time.Millisecond
+}
+
+// newApdex scores results against a threshold, returning nil without a threshold or samples
+func newApdex(results []EndpointResult, threshold time.Duration) *Apdex {
+	if threshold <= 0 {
+		return nil
+	}
+	apdex := Apdex{Threshold: threshold.Seconds()}
+	for _, result := range results {
+		switch {
+		case result.Skipped:
+			continue
+		case resultFailed(result) || result.Duration > apdexToleratingFactor*threshold:
+			apdex.Frustrated++
+		case result.Duration > threshold:
+			apdex.Tolerating++
+		default:
+			apdex.Satisfied++
+		}
+	}
+	total := apdex.Satisfied + apdex.Tolerating + apdex.Frustrated
+	if total == 0 {
+		return nil
+	}
+
+	// Apdex scores are conventionally reported to two decimals
+	score := (float64(apdex.Satisfied) + float64(apdex.Tolerating)/2) / float64(total)
+	apdex.Score = math.Round(score*100) / 100
+	return &apdex
+}
+
+// String formats the score with its threshold, e.g. "0.94 [T=500ms]"
+func (a Apdex) String() string {
+	threshold := time.Duration(a.Threshold * float64(time.Second)).Round(time.Millisecond)
+	return fmt.Sprintf("%.2f [T=%s]", a.Score, threshold)
+}
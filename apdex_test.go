@@ -0,0 +1,44 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNewApdex(t *testing.T) {
+	ms := func(n int) time.Duration { return time.Duration(n) * time.Millisecond }
+	results := []EndpointResult{
+		{Success: true, Duration: ms(100)},
+		{Success: true, Duration: ms(500)},
+		{Success: true, Duration: ms(1500)},
+		{Success: true, Duration: ms(2500)},
+		{Success: false, Duration: ms(50)},
+		{Error: errors.New("timeout"), Duration: ms(10)},
+		{Skipped: true},
+	}
+
+	tests := []struct {
+		name      string
+		results   []EndpointResult
+		threshold time.Duration
+		want      *Apdex
+	}{
+		{"buckets", results, ms(500), &Apdex{Threshold: 0.5, Score: 0.42, Satisfied: 2, Tolerating: 1, Frustrated: 3}},
+		{"all satisfied", results[:2], ms(500), &Apdex{Threshold: 0.5, Score: 1, Satisfied: 2}},
+		{"no threshold", results, 0, nil},
+		{"only skipped", results[6:], ms(500), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newApdex(tt.results, tt.threshold)
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Errorf("newApdex() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	if got := (Apdex{Threshold: 0.5, Score: 0.9}).String(); got != "0.90 [T=500ms]" {
+		t.Errorf("String() = %s", got)
+	}
+}
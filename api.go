@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// newAPIHandler serves the serve mode REST API:
+//
+//	GET  /api/results[?target=name]  latest results of every (or the selected) target
+//	POST /api/run[?target=name]      run every (or the selected) target now and return its results
+//	GET  /api/incidents[?all=1]      open (or all recent) incidents
+//	GET  /status                     HTML status page of the latest results and recent incidents
+//
+// target may be repeated and matches a target name or a config::name key
+func newAPIHandler(d *Daemon) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /api/results", func(w http.ResponseWriter, r *http.Request) {
+		selected := runOptions{only: r.URL.Query()["target"]}
+
+		var runs []TargetRun
+		for _, run := range d.Latest() {
+			if selected.selectsTarget(run.ConfigName, run.TargetName) {
+				runs = append(runs, run)
+			}
+		}
+		if len(runs) == 0 && len(selected.only) > 0 {
+			writeAPIError(w, http.StatusNotFound, "no results for target %s", strings.Join(selected.only, ", "))
+			return
+		}
+		writeAPIRuns(w, runs, d.opts.verbose)
+	})
+
+	mux.HandleFunc("POST /api/run", func(w http.ResponseWriter, r *http.Request) {
+		selected := r.URL.Query()["target"]
+		runs := d.RunNow(selected)
+		if len(runs) == 0 {
+			writeAPIError(w, http.StatusNotFound, "no target matches %s", strings.Join(selected, ", "))
+			return
+		}
+		writeAPIRuns(w, runs, d.opts.verbose)
+	})
+
+	mux.HandleFunc("GET /api/incidents", func(w http.ResponseWriter, r *http.Request) {
+		incidents := d.Incidents(r.URL.Query().Get("all") != "")
+		if incidents == nil {
+			incidents = []Incident{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(struct {
+			Incidents []Incident `json:"incidents"`
+		}{incidents})
+	})
+
+	mux.HandleFunc("GET /status", func(w http.ResponseWriter, r *http.Request) {
+		latest := d.Latest()
+		runs := make([]TargetRun, 0, len(latest))
+		for _, run := range latest {
+			runs = append(runs, run)
+		}
+		slices.SortFunc(runs, func(a, b TargetRun) int {
+			return strings.Compare(a.Key, b.Key)
+		})
+
+		page, err := renderStatusPage(runs, d.Incidents(true), time.Now())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, page)
+	})
+
+	return mux
+}
+
+// Incidents returns the incidents tracked in the state file, or none when tracking is off
+func (d *Daemon) Incidents(includeResolved bool) []Incident {
+	state := d.runOptions().state
+	if state == nil {
+		return nil
+	}
+	return state.Incidents(includeResolved)
+}
+
+// RunNow immediately runs the targets selected by name or key (all when empty) outside their
+// schedules, recording and returning the results sorted by key
+func (d *Daemon) RunNow(only []string) []TargetRun {
+	selected := runOptions{only: only}
+
+	d.mu.Lock()
+	var specs []targetSpec
+	for _, scheduled := range d.targets {
+		if selected.selectsTarget(scheduled.spec.configName, scheduled.spec.targetName) {
+			specs = append(specs, scheduled.spec)
+		}
+	}
+	opts := d.opts
+	d.mu.Unlock()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var runs []TargetRun
+	runID := newRunID()
+	for _, spec := range specs {
+		wg.Add(1)
+		go func(spec targetSpec) {
+			defer wg.Done()
+
+			client, ok := opts.tlsClient(spec.config, spec.target)
+			if !ok {
+				client = opts.httpClient(spec.config, spec.target)
+			}
+			run := TargetRun{
+				Key:        runKey(spec.configName, spec.targetName),
+				TargetName: spec.targetName,
+				ConfigName: spec.configName,
+				Ownership:  spec.target.ownership(),
+				Labels:     opts.labels,
+				Notes:      spec.target.Notes,
+				Results:    runTarget(context.Background(), client, spec.config, spec.targetName, spec.target, d.sem, opts),
+
+				ApdexThreshold: spec.target.apdexThreshold(),
+				RunID:          runID,
+			}
+			d.record(run)
+
+			mu.Lock()
+			runs = append(runs, run)
+			mu.Unlock()
+		}(spec)
+	}
+	wg.Wait()
+
+	slices.SortFunc(runs, func(a, b TargetRun) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+	return runs
+}
+
+// writeAPIRuns writes runs in the same JSONOutput format as `vitals --json`
+func writeAPIRuns(w http.ResponseWriter, runs []TargetRun, verbose bool) {
+	output := JSONOutput{Targets: make(map[string]JSONTargetResults)}
+	for _, run := range runs {
+		targetResults, err := printJSONResults(run.Results, run.TargetName, run.ConfigName, run.Ownership, run.ApdexThreshold, verbose)
+		if err != nil {
+			writeAPIError(w, http.StatusInternalServerError, "error processing results: %s", err)
+			return
+		}
+		targetResults.Notes = run.Notes
+		output.Targets[run.Key] = targetResults
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(output)
+}
+
+// writeAPIError writes a JSON error body with the given status
+func writeAPIError(w http.ResponseWriter, status int, format string, args ...any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf(format, args...)})
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAPIHandler(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/down" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer backend.Close()
+
+	d := newDaemon(nil, "", runOptions{})
+	for name, endpoint := range map[string]string{"up": "/", "down": "/down"} {
+		d.targets[runKey("a.toml", name)] = &scheduledTarget{spec: targetSpec{
+			configName: "a.toml",
+			targetName: name,
+			target:     TargetConfig{BaseURLs: []string{backend.URL}, Endpoints: pathEndpoints(endpoint)},
+		}}
+	}
+	api := httptest.NewServer(newAPIHandler(d))
+	defer api.Close()
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+		wantKeys   []string
+	}{
+		{"no results yet", http.MethodGet, "/api/results", http.StatusOK, nil},
+		{"run one target", http.MethodPost, "/api/run?target=up", http.StatusOK, []string{"a.toml::up"}},
+		{"results after run", http.MethodGet, "/api/results", http.StatusOK, []string{"a.toml::up"}},
+		{"run all targets", http.MethodPost, "/api/run", http.StatusOK, []string{"a.toml::down", "a.toml::up"}},
+		{"results by key", http.MethodGet, "/api/results?target=a.toml::down", http.StatusOK, []string{"a.toml::down"}},
+		{"unknown target", http.MethodPost, "/api/run?target=missing", http.StatusNotFound, nil},
+		{"run requires post", http.MethodGet, "/api/run", http.StatusMethodNotAllowed, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, api.URL+tt.path, nil)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var output JSONOutput
+			if err := json.NewDecoder(resp.Body).Decode(&output); err != nil {
+				t.Fatal(err)
+			}
+			if len(output.Targets) != len(tt.wantKeys) {
+				t.Fatalf("got %d targets, want %v", len(output.Targets), tt.wantKeys)
+			}
+			for _, key := range tt.wantKeys {
+				if _, ok := output.Targets[key]; !ok {
+					t.Errorf("missing target %s", key)
+				}
+			}
+		})
+	}
+
+	if down := d.Latest()["a.toml::down"]; len(down.Results) != 1 || down.Results[0].Success {
+		t.Errorf("down target should have one failing result, got %+v", down.Results)
+	}
+}
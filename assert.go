@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+)
+
+// Assertion is a compiled CEL `assert` expression. It sees:
+//
+//	status       response status code (int)
+//	duration_ms  time to the response headers in milliseconds (int)
+//	body         response body (string)
+//	json         body parsed as JSON, or null when it isn't JSON
+//	headers      response headers with lowercase names (map of string to string)
+//	header(name) a response header by case-insensitive name, "" when missing
+type Assertion struct {
+	source string
+	env    *cel.Env
+	ast    *cel.Ast
+}
+
+// assertionEnv declares the variables and functions available to assertions
+func assertionEnv(opts ...cel.EnvOption) (*cel.Env, error) {
+	return cel.NewEnv(append([]cel.EnvOption{
+		cel.Variable("status", cel.IntType),
+		cel.Variable("duration_ms", cel.IntType),
+		cel.Variable("body", cel.StringType),
+		cel.Variable("json", cel.DynType),
+		cel.Variable("headers", cel.MapType(cel.StringType, cel.StringType)),
+	}, opts...)...)
+}
+
+// headerFunction declares header(name), bound to the given headers when evaluating
+func headerFunction(headers http.Header) cel.EnvOption {
+	return cel.Function("header", cel.Overload("header_string", []*cel.Type{cel.StringType}, cel.StringType,
+		cel.UnaryBinding(func(name ref.Val) ref.Val {
+			return types.String(headers.Get(string(name.(types.String))))
+		})))
+}
+
+// compileAssertion parses and type-checks an assert expression, which must be boolean
+func compileAssertion(source string) (*Assertion, error) {
+	env, err := assertionEnv(headerFunction(nil))
+	if err != nil {
+		return nil, err
+	}
+	ast, issues := env.Compile(source)
+	if issues != nil && issues.Err() != nil {
+		return nil, issues.Err()
+	}
+	if ast.OutputType() != cel.BoolType {
+		return nil, fmt.Errorf("expression returns %s, expected bool", ast.OutputType())
+	}
+	return &Assertion{source: source, env: env, ast: ast}, nil
+}
+
+// check evaluates the assertion against a response, returning the failure reason or ""
+func (a *Assertion) check(resp *http.Response, body string, duration time.Duration) string {
+	env, err := a.env.Extend(headerFunction(resp.Header))
+	if err != nil {
+		return fmt.Sprintf("assert error: %s", err)
+	}
+	program, err := env.Program(a.ast)
+	if err != nil {
+		return fmt.Sprintf("assert error: %s", err)
+	}
+
+	var parsed any
+	if err := json.Unmarshal([]byte(body), &parsed); err != nil {
+		parsed = nil
+	}
+	headers := make(map[string]string, len(resp.Header))
+	for name := range resp.Header {
+		headers[strings.ToLower(name)] = resp.Header.Get(name)
+	}
+
+	out, _, err := program.Eval(map[string]any{
+		"status":      resp.StatusCode,
+		"duration_ms": duration.Milliseconds(),
+		"body":        body,
+		"json":        parsed,
+		"headers":     headers,
+	})
+	if err != nil {
+		return fmt.Sprintf("assert error: %s", err)
+	}
+	if out != types.True {
+		return fmt.Sprintf("assert failed: %s", a.source)
+	}
+	return ""
+}
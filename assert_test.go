@@ -0,0 +1,72 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompileAssertion(t *testing.T) {
+	tests := []struct {
+		source  string
+		wantErr bool
+	}{
+		{`status == 200`, false},
+		{`json.status == "ok" && duration_ms < 500 && header("X-Cache") == "HIT"`, false},
+		{`headers["content-type"].startsWith("application/json")`, false},
+		{`status +`, true},
+		{`status`, true},
+		{`missing == 1`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			_, err := compileAssertion(tt.source)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("compileAssertion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAssertionCheck(t *testing.T) {
+	resp := &http.Response{
+		StatusCode: 200,
+		Header: http.Header{
+			"X-Cache":      []string{"HIT"},
+			"Content-Type": []string{"application/json"},
+		},
+	}
+	body := `{"status": "ok", "items": [1, 2, 3], "db": {"latency": 12}}`
+
+	tests := []struct {
+		name       string
+		source     string
+		body       string
+		duration   time.Duration
+		wantReason string
+	}{
+		{"all match", `json.status == "ok" && duration_ms < 500 && header("x-cache") == "HIT"`, body, 120 * time.Millisecond, ""},
+		{"too slow", `duration_ms < 500`, body, 600 * time.Millisecond, "assert failed: duration_ms < 500"},
+		{"json numbers", `size(json.items) == 3 && json.db.latency < 50`, body, 0, ""},
+		{"missing header", `header("X-Missing") == ""`, body, 0, ""},
+		{"headers map", `headers["content-type"] == "application/json"`, body, 0, ""},
+		{"body text", `body.contains("ok")`, body, 0, ""},
+		{"not json", `json == null`, "plain text", 0, ""},
+		{"missing field", `json.nope == "x"`, body, 0, "assert error:"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertion, err := compileAssertion(tt.source)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := assertion.check(resp, tt.body, tt.duration)
+			if tt.wantReason == "" && got != "" || !strings.HasPrefix(got, tt.wantReason) {
+				t.Errorf("check() = %q, want %q", got, tt.wantReason)
+			}
+		})
+	}
+}
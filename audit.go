@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// minHSTSMaxAge is the shortest Strict-Transport-Security max-age (180 days) not flagged as weak
+const minHSTSMaxAge = 15552000
+
+// headerAudit checks a single security header and returns a warning, or an empty string if it is fine
+type headerAudit func(header http.Header, tls bool) string
+
+// headerAudits maps the audit names usable in a target's `audit` list to their checks
+var headerAudits = map[string]headerAudit{
+	"hsts":     auditHSTS,
+	"csp":      auditCSP,
+	"xcto":     auditXCTO,
+	"xfo":      auditXFO,
+	"referrer": auditReferrerPolicy,
+}
+
+// allHeaderAudits returns every known audit name in a stable order
+func allHeaderAudits() []string {
+	names := make([]string, 0, len(headerAudits))
+	for name := range headerAudits {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// auditHeaders runs the named audits against a response's headers and returns any warnings
+func auditHeaders(header http.Header, tls bool, audits []string) []string {
+	var warnings []string
+	for _, name := range audits {
+		if warning := headerAudits[name](header, tls); warning != "" {
+			warnings = append(warnings, warning)
+		}
+	}
+	return warnings
+}
+
+// auditHSTS requires a long-lived Strict-Transport-Security policy on HTTPS responses
+func auditHSTS(header http.Header, tls bool) string {
+	// Browsers ignore HSTS delivered over plain HTTP
+	if !tls {
+		return ""
+	}
+
+	value := header.Get("Strict-Transport-Security")
+	if value == "" {
+		return "missing Strict-Transport-Security"
+	}
+
+	for _, directive := range strings.Split(value, ";") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		if !strings.EqualFold(name, "max-age") {
+			continue
+		}
+		maxAge, err := strconv.Atoi(strings.Trim(arg, `"`))
+		if err != nil {
+			return fmt.Sprintf("Strict-Transport-Security has invalid max-age %q", arg)
+		}
+		if maxAge < minHSTSMaxAge {
+			return fmt.Sprintf("Strict-Transport-Security max-age %d is below %d", maxAge, minHSTSMaxAge)
+		}
+		return ""
+	}
+	return "Strict-Transport-Security is missing max-age"
+}
+
+// auditCSP requires a Content-Security-Policy without unsafe script sources
+func auditCSP(header http.Header, tls bool) string {
+	value := header.Get("Content-Security-Policy")
+	if value == "" {
+		return "missing Content-Security-Policy"
+	}
+	if strings.Contains(value, "'unsafe-inline'") || strings.Contains(value, "'unsafe-eval'") {
+		return "Content-Security-Policy allows unsafe-inline or unsafe-eval"
+	}
+	return ""
+}
+
+// auditXCTO requires X-Content-Type-Options: nosniff
+func auditXCTO(header http.Header, tls bool) string {
+	value := header.Get("X-Content-Type-Options")
+	if value == "" {
+		return "missing X-Content-Type-Options"
+	}
+	if !strings.EqualFold(strings.TrimSpace(value), "nosniff") {
+		return fmt.Sprintf("X-Content-Type-Options is %q, expected nosniff", value)
+	}
+	return ""
+}
+
+// auditXFO requires clickjacking protection via X-Frame-Options or CSP frame-ancestors
+func auditXFO(header http.Header, tls bool) string {
+	if strings.Contains(header.Get("Content-Security-Policy"), "frame-ancestors") {
+		return ""
+	}
+
+	value := strings.ToUpper(strings.TrimSpace(header.Get("X-Frame-Options")))
+	switch value {
+	case "DENY", "SAMEORIGIN":
+		return ""
+	case "":
+		return "missing X-Frame-Options"
+	default:
+		return fmt.Sprintf("X-Frame-Options is %q, expected DENY or SAMEORIGIN", value)
+	}
+}
+
+// auditReferrerPolicy requires a Referrer-Policy that does not leak full URLs cross-origin
+func auditReferrerPolicy(header http.Header, tls bool) string {
+	value := header.Get("Referrer-Policy")
+	if value == "" {
+		return "missing Referrer-Policy"
+	}
+	if strings.EqualFold(value, "unsafe-url") {
+		return "Referrer-Policy is unsafe-url"
+	}
+	return ""
+}
+
+// AuditFinding groups the header warnings for one checked URL in the audit report section
+type AuditFinding struct {
+	Target   string
+	URL      string
+	Warnings []string
+}
+
+// printAuditFindings prints the security header audit section shown after the result tables
+func printAuditFindings(findings []AuditFinding) {
+	if len(findings) == 0 {
+		return
+	}
+
+	_, _, neutral := setupColorOutput()
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	lines := make([]string, 0, len(findings))
+	for _, finding := range findings {
+		for _, warning := range finding.Warnings {
+			lines = append(lines, fmt.Sprintf("[%s] %s: %s", finding.Target, finding.URL, warning))
+		}
+	}
+
+	// Size the box to the longest line, capped at the terminal width
+	width := len("Security Header Audit")
+	for _, line := range lines {
+		width = max(width, len(line))
+	}
+	width = min(width, getTerminalWidth()-7)
+	totalWidth := width + 4
+
+	title := "Security Header Audit"
+	fmt.Println(neutral("┌" + strings.Repeat("─", totalWidth-2) + "┐"))
+	fmt.Println(neutral(fmt.Sprintf("│ %-*s │", width, title)))
+	fmt.Println(neutral("├" + strings.Repeat("─", totalWidth-2) + "┤"))
+	for _, line := range lines {
+		if len(line) > width {
+			line = line[:width-3] + "..."
+		}
+		fmt.Print(neutral("│ "))
+		fmt.Print(yellow(fmt.Sprintf("%-*s", width, line)))
+		fmt.Println(neutral(" │"))
+	}
+	fmt.Println(neutral("└" + strings.Repeat("─", totalWidth-2) + "┘"))
+	fmt.Println()
+}
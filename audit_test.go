@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestAuditHeaders(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		tls     bool
+		audits  []string
+		want    int
+	}{
+		{
+			name: "fully hardened",
+			headers: map[string]string{
+				"Strict-Transport-Security": "max-age=31536000; includeSubDomains",
+				"Content-Security-Policy":   "default-src 'self'; frame-ancestors 'none'",
+				"X-Content-Type-Options":    "nosniff",
+				"Referrer-Policy":           "no-referrer",
+			},
+			tls:    true,
+			audits: allHeaderAudits(),
+			want:   0,
+		},
+		{
+			name:    "nothing set",
+			headers: map[string]string{},
+			tls:     true,
+			audits:  allHeaderAudits(),
+			want:    5,
+		},
+		{
+			name:    "hsts ignored over plain http",
+			headers: map[string]string{},
+			tls:     false,
+			audits:  []string{"hsts"},
+			want:    0,
+		},
+		{
+			name:    "short hsts max-age",
+			headers: map[string]string{"Strict-Transport-Security": "max-age=300"},
+			tls:     true,
+			audits:  []string{"hsts"},
+			want:    1,
+		},
+		{
+			name:    "wrong xcto value",
+			headers: map[string]string{"X-Content-Type-Options": "sniff"},
+			audits:  []string{"xcto"},
+			want:    1,
+		},
+		{
+			name:    "unsafe csp",
+			headers: map[string]string{"Content-Security-Policy": "script-src 'self' 'unsafe-inline'"},
+			audits:  []string{"csp"},
+			want:    1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			for k, v := range tt.headers {
+				header.Set(k, v)
+			}
+			got := auditHeaders(header, tt.tls, tt.audits)
+			if len(got) != tt.want {
+				t.Errorf("auditHeaders() = %v, want %d warnings", got, tt.want)
+			}
+		})
+	}
+}
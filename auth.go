@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// AuthConfig authenticates every request of a target
+type AuthConfig struct {
+	// Type is the authentication scheme: "digest", "form", or "oauth2"
+	Type string `toml:"type"`
+
+	// Username and Password are the digest or form credentials; the password expands
+	// environment variables, e.g. "${CAMERA_PASSWORD}", or reads the keychain, e.g.
+	// "keyring:camera"
+	Username string `toml:"username,omitempty"`
+	Password string `toml:"password,omitempty"`
+
+	// LoginURL is where form auth posts the credentials, as UsernameField and PasswordField
+	// (default "username" and "password") along with any FormFields. The session cookies it
+	// sets are sent with every request
+	LoginURL      string            `toml:"login_url,omitempty"`
+	UsernameField string            `toml:"username_field,omitempty"`
+	PasswordField string            `toml:"password_field,omitempty"`
+	FormFields    map[string]string `toml:"form_fields,omitempty"`
+
+	// TokenURL, ClientID, ClientSecret, and Scopes configure oauth2, which sends a bearer token
+	// renewed with a refresh token: the one last stored in TokenFile, else RefreshToken. Without
+	// either, the first run in a terminal authorizes at DeviceAuthURL (RFC 8628). The secret and
+	// refresh token are resolved like Password
+	TokenURL      string   `toml:"token_url,omitempty"`
+	DeviceAuthURL string   `toml:"device_authorization_url,omitempty"`
+	ClientID      string   `toml:"client_id,omitempty"`
+	ClientSecret  string   `toml:"client_secret,omitempty"`
+	Scopes        []string `toml:"scopes,omitempty"`
+	RefreshToken  string   `toml:"refresh_token,omitempty"`
+
+	// TokenFile persists renewed OAuth2 tokens, readable only by its owner (default
+	// .vitals-tokens.json)
+	TokenFile string `toml:"token_file,omitempty"`
+}
+
+// BasicAuth is the username and password of HTTP Basic auth; the password is resolved like
+// AuthConfig's
+type BasicAuth struct {
+	Username string `toml:"username"`
+	Password string `toml:"password,omitempty"`
+}
+
+// setBasicAuth sends the credentials of basic with a request, in place of any configured
+// Authorization header
+func setBasicAuth(req *http.Request, basic BasicAuth, auth *AuthConfig) error {
+	if auth != nil {
+		return fmt.Errorf("basic_auth and auth can't both be set")
+	}
+	password, err := expandSecret(basic.Password)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(basic.Username, password)
+	return nil
+}
+
+// withAuth returns a copy of client that authenticates its requests as auth configures
+func withAuth(client *http.Client, auth AuthConfig) (*http.Client, error) {
+	transport := client.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	authed := *client
+	switch auth.Type {
+	case "digest":
+		password, err := expandSecret(auth.Password)
+		if err != nil {
+			return nil, err
+		}
+		authed.Transport = &digestTransport{
+			transport: transport,
+			username:  auth.Username,
+			password:  password,
+		}
+	case "form":
+		session := formSessionFor(auth)
+		// The login goes out before the check is timed, on the client's own transport
+		renew := func(stale http.CookieJar) (http.CookieJar, error) {
+			return session.cookies(client, auth, stale)
+		}
+		jar, err := renew(nil)
+		if err != nil {
+			return nil, err
+		}
+		authed.Transport = &formTransport{transport: transport, loginURL: auth.LoginURL, jar: jar, renew: renew}
+	case "oauth2":
+		tokenFile := auth.TokenFile
+		if tokenFile == "" {
+			tokenFile = defaultTokenFile
+		}
+		store, err := openTokenStore(tokenFile)
+		if err != nil {
+			return nil, err
+		}
+		if auth.ClientSecret, err = expandSecret(auth.ClientSecret); err != nil {
+			return nil, err
+		}
+		if auth.RefreshToken, err = expandSecret(auth.RefreshToken); err != nil {
+			return nil, err
+		}
+		// Token requests go out before the check is timed, on the client's own transport
+		renew := func(rejected string) (string, error) {
+			return store.accessToken(client, auth, rejected)
+		}
+		token, err := renew("")
+		if err != nil {
+			return nil, err
+		}
+		authed.Transport = &bearerTransport{transport: transport, token: token, renew: renew}
+	default:
+		return nil, fmt.Errorf("unknown auth type %q, expected \"digest\", \"form\", or \"oauth2\"", auth.Type)
+	}
+	return &authed, nil
+}
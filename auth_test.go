@@ -0,0 +1,41 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCheckEndpointBasicAuth(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if username, password, ok := r.BasicAuth(); !ok || username != "monitor" || password != "s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	t.Setenv("API_PASSWORD", "s3cret")
+	tests := []struct {
+		name        string
+		target      TargetConfig
+		wantSuccess bool
+		wantErr     string
+	}{
+		{"credentials", TargetConfig{BasicAuth: &BasicAuth{Username: "monitor", Password: "${API_PASSWORD}"}}, true, ""},
+		{"wrong password", TargetConfig{BasicAuth: &BasicAuth{Username: "monitor", Password: "guess"}}, false, ""},
+		{"overrides header", TargetConfig{BasicAuth: &BasicAuth{Username: "monitor", Password: "s3cret"}, Headers: map[string]string{"Authorization": "Bearer stale"}}, true, ""},
+		{"with auth", TargetConfig{BasicAuth: &BasicAuth{Username: "monitor"}, Auth: &AuthConfig{Type: "digest"}}, false, "basic_auth and auth can't both be set"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.target.StatusCodes = []int{200}
+			result := checkEndpoint(server.Client(), server.URL, "/", tt.target, buildResponseChecks(tt.target), nil, false)
+			if result.Success != tt.wantSuccess {
+				t.Errorf("success = %v (status %d, error %v), want %v", result.Success, result.StatusCode, result.Error, tt.wantSuccess)
+			}
+			if tt.wantErr != "" && (result.Error == nil || result.Error.Error() != tt.wantErr) {
+				t.Errorf("error = %v, want %s", result.Error, tt.wantErr)
+			}
+		})
+	}
+}
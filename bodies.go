@@ -0,0 +1,100 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// defaultSaveBodiesLimit is how much of each response body --save-bodies keeps
+const defaultSaveBodiesLimit = 1 << 20
+
+// fileNameUnsafe matches characters kept out of saved body file names
+var fileNameUnsafe = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// bodyExtensions name saved bodies after their media type
+var bodyExtensions = map[string]string{
+	"application/json":         ".json",
+	"application/problem+json": ".json",
+	"application/health+json":  ".json",
+	"application/xml":          ".xml",
+	"text/xml":                 ".xml",
+	"text/html":                ".html",
+	"text/plain":               ".txt",
+}
+
+// saveBodies writes the response body of every check to dir/<target>/<endpoint>_<hash>_<time>.<ext>,
+// cut off after limit bytes, so failing payloads can be inspected after the run. The hash of
+// the method and full URL keeps endpoints apart whose shortened names are the same
+func saveBodies(dir string, runs []TargetRun, at time.Time, limit int) error {
+	stamp := at.UTC().Format("20060102T150405Z")
+	for _, run := range runs {
+		targetDir := filepath.Join(dir, safeFileName(run.Key))
+		for _, result := range run.Results {
+			if result.Skipped || result.Error != nil || result.BodySize == 0 {
+				continue
+			}
+			if err := os.MkdirAll(targetDir, 0o755); err != nil {
+				return fmt.Errorf("error saving response bodies to %s: %s", dir, err)
+			}
+
+			body := result.ResponseBody
+			if len(body) > limit {
+				body = body[:limit]
+			}
+			sum := sha256.Sum256([]byte(result.Method + " " + result.URL))
+			name := safeFileName(result.Method+"_"+strings.TrimPrefix(strings.TrimPrefix(result.URL, "https://"), "http://")) +
+				"_" + hex.EncodeToString(sum[:4]) + "_" + stamp
+			if err := writeNewFile(targetDir, name, bodyExtension(result.ContentType), body); err != nil {
+				return fmt.Errorf("error saving response body of %s: %s", result.URL, err)
+			}
+		}
+	}
+	return nil
+}
+
+// writeNewFile writes data to dir/name.ext, numbering the name (name_2.ext, ...) rather than
+// overwriting a file that is already there, e.g. from the same endpoint checked twice
+func writeNewFile(dir, name, ext, data string) error {
+	path := filepath.Join(dir, name+ext)
+	for n := 2; ; n++ {
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if errors.Is(err, os.ErrExist) {
+			path = filepath.Join(dir, fmt.Sprintf("%s_%d%s", name, n, ext))
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := file.WriteString(data); err != nil {
+			file.Close()
+			return err
+		}
+		return file.Close()
+	}
+}
+
+// safeFileName replaces characters that don't belong in a file name, keeping it short
+func safeFileName(s string) string {
+	name := strings.Trim(fileNameUnsafe.ReplaceAllString(s, "_"), "_.")
+	if len(name) > 120 {
+		name = name[:120]
+	}
+	return name
+}
+
+// bodyExtension returns the file extension for a response's Content-Type
+func bodyExtension(contentType string) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if ext, ok := bodyExtensions[mediaType]; ok {
+		return ext
+	}
+	return ".body"
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestSaveBodies(t *testing.T) {
+	dir := t.TempDir()
+	runs := []TargetRun{{
+		Key: "prod/api",
+		Results: []EndpointResult{
+			{Method: "GET", URL: "https://api.example.com/health", ContentType: "application/json; charset=utf-8", ResponseBody: `{"status":"down"}`, BodySize: 17},
+			{Method: "POST", URL: "http://api.example.com/search?q=a b", ContentType: "text/plain", ResponseBody: "0123456789", BodySize: 10},
+			// Shortened to the same name as the search above, and the health check a second time
+			{Method: "POST", URL: "http://api.example.com/search?q=a_b", ContentType: "text/plain", ResponseBody: "abcdef", BodySize: 6},
+			{Method: "GET", URL: "https://api.example.com/health", ContentType: "application/json", ResponseBody: `{"status":"up"}`, BodySize: 15},
+			{Method: "GET", URL: "https://api.example.com/empty"},
+			{Method: "GET", URL: "https://api.example.com/skipped", Skipped: true, ResponseBody: "x", BodySize: 1},
+		},
+	}}
+	at := time.Date(2026, 3, 1, 12, 30, 0, 0, time.FixedZone("CET", 3600))
+	if err := saveBodies(dir, runs, at, 4); err != nil {
+		t.Fatalf("saveBodies() error = %v", err)
+	}
+
+	want := map[string]string{
+		"prod_api/GET_api.example.com_health_57581b45_20260301T113000Z.json":       `{"st`,
+		"prod_api/GET_api.example.com_health_57581b45_20260301T113000Z_2.json":     `{"st`,
+		"prod_api/POST_api.example.com_search_q_a_b_85e4f831_20260301T113000Z.txt": "0123",
+		"prod_api/POST_api.example.com_search_q_a_b_713b720a_20260301T113000Z.txt": "abcd",
+	}
+	var got []string
+	filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			rel, _ := filepath.Rel(dir, path)
+			got = append(got, rel)
+		}
+		return nil
+	})
+	sort.Strings(got)
+	if len(got) != len(want) {
+		t.Fatalf("saved files = %v, want %d", got, len(want))
+	}
+	for name, body := range want {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Errorf("missing %s: %v", name, err)
+			continue
+		}
+		if string(data) != body {
+			t.Errorf("%s = %q, want %q", name, data, body)
+		}
+	}
+}
+
+func TestBodyExtension(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        string
+	}{
+		{"application/json", ".json"},
+		{"text/html; charset=utf-8", ".html"},
+		{"application/octet-stream", ".body"},
+		{"", ".body"},
+	}
+	for _, tt := range tests {
+		if got := bodyExtension(tt.contentType); got != tt.want {
+			t.Errorf("bodyExtension(%q) = %s, want %s", tt.contentType, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// callbackPlaceholder is replaced by the URL a webhook check waits on
+const callbackPlaceholder = "{callback_url}"
+
+// defaultCallbackTimeout is how long a check waits for its webhook when callback sets no timeout
+const defaultCallbackTimeout = 30 * time.Second
+
+// CallbackConfig makes checks wait for a webhook: {callback_url} in the endpoint URL, body, or
+// headers becomes a URL on a temporary listener, and a check passes only when the service
+// calls it back within Timeout
+type CallbackConfig struct {
+	// Listen is the address of the listener (default "127.0.0.1:0", a free local port)
+	Listen string `toml:"listen,omitempty"`
+
+	// PublicURL is how the service reaches the listener, e.g. "https://monitor.example.com/hooks",
+	// for listeners behind a proxy or on another network (default http:// and the listen address)
+	PublicURL string `toml:"public_url,omitempty"`
+
+	// Timeout is how long to wait for the callback, e.g. "10s" (default 30s)
+	Timeout string `toml:"timeout,omitempty"`
+
+	// Method and BodyContains are what the callback must look like, e.g. "POST" and
+	// `"status":"delivered"`
+	Method       string `toml:"method,omitempty"`
+	BodyContains string `toml:"body_contains,omitempty"`
+}
+
+// callbackRequest is what a webhook delivered, and when
+type callbackRequest struct {
+	method string
+	body   string
+	at     time.Time
+}
+
+// CallbackListener serves the callbacks of every check waiting on one listen address, and
+// stops once none is waiting
+type CallbackListener struct {
+	server  *http.Server
+	baseURL string
+
+	mu      sync.Mutex
+	waiting map[string]chan callbackRequest
+}
+
+// callbackListeners holds the running listeners by listen address
+var (
+	callbackListenersMu sync.Mutex
+	callbackListeners   = make(map[string]*CallbackListener)
+)
+
+// pendingCallback is a check's registration with a listener
+type pendingCallback struct {
+	url      string
+	token    string
+	listener *CallbackListener
+	listen   string
+	received chan callbackRequest
+	started  time.Time
+}
+
+// callbackTimeout parses the timeout of config
+func callbackTimeout(config CallbackConfig) (time.Duration, error) {
+	if config.Timeout == "" {
+		return defaultCallbackTimeout, nil
+	}
+	timeout, err := time.ParseDuration(config.Timeout)
+	if err != nil {
+		return 0, fmt.Errorf("error parsing callback timeout '%s': %s", config.Timeout, err)
+	}
+	return timeout, nil
+}
+
+// awaitCallback registers a check with the listener of config, starting it when it isn't
+// running, and returns the URL the service should call
+func awaitCallback(config CallbackConfig) (*pendingCallback, error) {
+	listen := config.Listen
+	if listen == "" {
+		listen = "127.0.0.1:0"
+	}
+
+	callbackListenersMu.Lock()
+	defer callbackListenersMu.Unlock()
+	listener, ok := callbackListeners[listen]
+	if !ok {
+		var err error
+		if listener, err = startCallbackListener(listen); err != nil {
+			return nil, err
+		}
+		callbackListeners[listen] = listener
+	}
+
+	baseURL := listener.baseURL
+	if config.PublicURL != "" {
+		baseURL = config.PublicURL
+	}
+	token := newRunID()
+	pending := &pendingCallback{
+		url:      strings.TrimSuffix(baseURL, "/") + "/" + token,
+		token:    token,
+		listener: listener,
+		listen:   listen,
+		received: make(chan callbackRequest, 1),
+		started:  time.Now(),
+	}
+	listener.mu.Lock()
+	listener.waiting[token] = pending.received
+	listener.mu.Unlock()
+	return pending, nil
+}
+
+// startCallbackListener listens on listen and serves callbacks until it is stopped
+func startCallbackListener(listen string) (*CallbackListener, error) {
+	ln, err := net.Listen("tcp", listen)
+	if err != nil {
+		return nil, fmt.Errorf("error starting callback listener on %s: %s", listen, err)
+	}
+
+	// Unspecified hosts such as ":9099" are called back on localhost
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	if ip := net.ParseIP(host); ip == nil || ip.IsUnspecified() {
+		host = "localhost"
+	}
+	listener := &CallbackListener{
+		baseURL: "http://" + net.JoinHostPort(host, port),
+		waiting: make(map[string]chan callbackRequest),
+	}
+	listener.server = &http.Server{Handler: http.HandlerFunc(listener.serveCallback), ReadHeaderTimeout: 10 * time.Second}
+	go listener.server.Serve(ln)
+	return listener, nil
+}
+
+// serveCallback hands a webhook to the check waiting on its path
+func (l *CallbackListener) serveCallback(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+	l.mu.Lock()
+	received, ok := l.waiting[token]
+	l.mu.Unlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	select {
+	case received <- callbackRequest{method: r.Method, body: string(body), at: time.Now()}:
+	default: // Only the first callback is checked
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// release unregisters the check, stopping the listener when no other check waits on it
+func (p *pendingCallback) release() {
+	callbackListenersMu.Lock()
+	defer callbackListenersMu.Unlock()
+	p.listener.mu.Lock()
+	delete(p.listener.waiting, p.token)
+	idle := len(p.listener.waiting) == 0
+	p.listener.mu.Unlock()
+	if idle {
+		// Shutting down lets a callback that is still being answered finish
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		p.listener.server.Shutdown(ctx)
+		delete(callbackListeners, p.listen)
+	}
+}
+
+// substitute replaces the callback placeholder in s, including its query-escaped form
+func (p *pendingCallback) substitute(s string) string {
+	s = strings.ReplaceAll(s, url.QueryEscape(callbackPlaceholder), url.QueryEscape(p.url))
+	return strings.ReplaceAll(s, callbackPlaceholder, p.url)
+}
+
+// wait waits until timeout after the check registered for its callback, and returns how long
+// the callback took and the reason it failed, or an empty reason when it arrived as expected
+func (p *pendingCallback) wait(config CallbackConfig, timeout time.Duration) (time.Duration, string) {
+	select {
+	case callback := <-p.received:
+		elapsed := callback.at.Sub(p.started)
+		if config.Method != "" && !strings.EqualFold(callback.method, config.Method) {
+			return elapsed, fmt.Sprintf("callback was %s, expected %s", callback.method, strings.ToUpper(config.Method))
+		}
+		if !strings.Contains(callback.body, config.BodyContains) {
+			return elapsed, fmt.Sprintf("callback body doesn't contain %q", config.BodyContains)
+		}
+		return elapsed, ""
+	case <-time.After(time.Until(p.started.Add(timeout))):
+		return 0, fmt.Sprintf("no callback within %s", timeout)
+	}
+}
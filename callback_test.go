@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCheckEndpointCallback(t *testing.T) {
+	// The service calls back the URL it was given, or doesn't for /lost
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var order struct {
+			Callback string `json:"callback"`
+			Status   string `json:"status"`
+		}
+		json.NewDecoder(r.Body).Decode(&order)
+		if order.Callback == "" {
+			order.Callback = r.URL.Query().Get("hook")
+		}
+		w.WriteHeader(http.StatusAccepted)
+		if r.URL.Path == "/lost" {
+			return
+		}
+		go http.Post(order.Callback, "application/json", strings.NewReader(`{"status":"`+order.Status+`"}`))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name       string
+		target     TargetConfig
+		endpoint   string
+		wantReason string
+		wantErr    string
+	}{
+		{"called back", TargetConfig{Method: "POST", Body: `{"callback":"{callback_url}","status":"delivered"}`}, "/orders", "", ""},
+		{"callback URL in query", TargetConfig{QueryParams: map[string]string{"hook": "{callback_url}"}}, "/ping", "", ""},
+		{"unexpected body", TargetConfig{Method: "POST", Body: `{"callback":"{callback_url}","status":"failed"}`}, "/orders", `callback body doesn't contain "\"status\":\"delivered\""`, ""},
+		{"no callback", TargetConfig{Method: "POST", Body: `{"callback":"{callback_url}"}`}, "/lost", "no callback within 200ms", ""},
+		{"invalid timeout", TargetConfig{Callback: &CallbackConfig{Timeout: "soon"}}, "/orders", "", `error parsing callback timeout 'soon': time: invalid duration "soon"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.target.StatusCodes = []int{202}
+			if tt.target.Callback == nil {
+				tt.target.Callback = &CallbackConfig{Timeout: "200ms", Method: "post"}
+				if strings.Contains(tt.target.Body, "status") {
+					tt.target.Callback.BodyContains = `"status":"delivered"`
+				}
+			}
+			result := checkEndpoint(server.Client(), server.URL, tt.endpoint, tt.target, buildResponseChecks(tt.target), nil, false)
+			if tt.wantErr != "" {
+				if result.Error == nil || result.Error.Error() != tt.wantErr {
+					t.Errorf("error = %v, want %s", result.Error, tt.wantErr)
+				}
+				return
+			}
+			if result.Error != nil {
+				t.Fatal(result.Error)
+			}
+			if result.Success != (tt.wantReason == "") || result.FailureReason != tt.wantReason {
+				t.Errorf("success %v, reason %q, want %q", result.Success, result.FailureReason, tt.wantReason)
+			}
+			if result.Success && result.CallbackDuration <= 0 {
+				t.Errorf("callback duration = %s, want it measured", result.CallbackDuration)
+			}
+		})
+	}
+
+	// The listener stops once no check waits on it
+	callbackListenersMu.Lock()
+	defer callbackListenersMu.Unlock()
+	if len(callbackListeners) != 0 {
+		t.Errorf("%d callback listeners still running", len(callbackListeners))
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"time"
+)
+
+// certExpiry returns when the leaf certificate a server presented expires, zero for
+// responses that didn't come over TLS
+func certExpiry(state *tls.ConnectionState) time.Time {
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return time.Time{}
+	}
+	return state.PeerCertificates[0].NotAfter
+}
+
+// certDaysLeft counts the whole days until expiry, negative once it has passed
+func certDaysLeft(expiry, now time.Time) int {
+	left := expiry.Sub(now)
+	days := int(left / (24 * time.Hour))
+	if left < 0 && left%(24*time.Hour) != 0 {
+		days--
+	}
+	return days
+}
+
+// checkCertExpiry returns why a certificate expires too soon for cert_expiry_warn_days, or
+// an empty string when it doesn't, the response wasn't over TLS, or the target sets no limit
+func checkCertExpiry(expiry, now time.Time, warnDays int) string {
+	if warnDays <= 0 || expiry.IsZero() {
+		return ""
+	}
+	if !now.Before(expiry) {
+		return fmt.Sprintf("certificate expired on %s", expiry.UTC().Format(time.DateOnly))
+	}
+	if expiry.Sub(now) < time.Duration(warnDays)*24*time.Hour {
+		return fmt.Sprintf("certificate expires in %s (%s), within cert_expiry_warn_days %d",
+			describeDays(certDaysLeft(expiry, now)), expiry.UTC().Format(time.DateOnly), warnDays)
+	}
+	return ""
+}
+
+// CertDays is the number of whole days left on a certificate
+type CertDays int
+
+// String summarizes the days left, e.g. "42 days left" or "expired"
+func (d CertDays) String() string {
+	if d < 0 {
+		return "expired"
+	}
+	return describeDays(int(d)) + " left"
+}
+
+// minCertDaysLeft is the fewest days left on any certificate among results, nil when none
+// came over TLS
+func minCertDaysLeft(results []EndpointResult, now time.Time) *CertDays {
+	var fewest *CertDays
+	for _, result := range results {
+		if result.CertExpiry.IsZero() {
+			continue
+		}
+		if days := CertDays(certDaysLeft(result.CertExpiry, now)); fewest == nil || days < *fewest {
+			fewest = &days
+		}
+	}
+	return fewest
+}
+
+// describeDays formats a day count, e.g. "1 day" or "42 days"
+func describeDays(days int) string {
+	if days == 1 {
+		return "1 day"
+	}
+	return fmt.Sprintf("%d days", days)
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckCertExpiry(t *testing.T) {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		expiry   time.Time
+		warnDays int
+		want     string
+	}{
+		{"no limit", now.Add(time.Hour), 0, ""},
+		{"not over TLS", time.Time{}, 14, ""},
+		{"outside the window", now.AddDate(0, 0, 30), 14, ""},
+		{"within the window", now.AddDate(0, 0, 5).Add(time.Hour), 14, "certificate expires in 5 days (2026-10-19), within cert_expiry_warn_days 14"},
+		{"last day", now.Add(20 * time.Hour), 14, "certificate expires in 0 days (2026-10-15), within cert_expiry_warn_days 14"},
+		{"expired", now.AddDate(0, 0, -3), 14, "certificate expired on 2026-10-11"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := checkCertExpiry(tt.expiry, now, tt.warnDays); got != tt.want {
+				t.Errorf("checkCertExpiry() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCertDaysLeft(t *testing.T) {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		expiry time.Time
+		want   int
+		text   string
+	}{
+		{now.AddDate(0, 0, 42).Add(time.Hour), 42, "42 days left"},
+		{now.Add(36 * time.Hour), 1, "1 day left"},
+		{now.Add(time.Hour), 0, "0 days left"},
+		{now.Add(-time.Hour), -1, "expired"},
+		{now.AddDate(0, 0, -2), -2, "expired"},
+	}
+	for _, tt := range tests {
+		got := certDaysLeft(tt.expiry, now)
+		if got != tt.want || CertDays(got).String() != tt.text {
+			t.Errorf("certDaysLeft(%s) = %d (%s), want %d (%s)", tt.expiry, got, CertDays(got), tt.want, tt.text)
+		}
+	}
+
+	results := []EndpointResult{{CertExpiry: now.AddDate(0, 0, 42)}, {}, {CertExpiry: now.AddDate(0, 0, 7)}}
+	if fewest := minCertDaysLeft(results, now); fewest == nil || *fewest != 7 {
+		t.Errorf("minCertDaysLeft() = %v, want 7", fewest)
+	}
+	if fewest := minCertDaysLeft([]EndpointResult{{}}, now); fewest != nil {
+		t.Errorf("minCertDaysLeft() without TLS = %v, want nil", *fewest)
+	}
+}
+
+func TestCheckEndpointCertExpiry(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	expiry := server.Certificate().NotAfter
+	days := certDaysLeft(expiry, time.Now())
+
+	tests := []struct {
+		name        string
+		warnDays    int
+		wantSuccess bool
+	}{
+		{"no limit", 0, true},
+		{"far from expiry", 30, true},
+		{"within the window", days + 2, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := TargetConfig{StatusCodes: []int{200}, CertExpiryWarnDays: tt.warnDays}
+			result := checkEndpoint(server.Client(), server.URL, "/", target, buildResponseChecks(target), nil, false)
+			if !result.CertExpiry.Equal(expiry) {
+				t.Errorf("CertExpiry = %s, want %s", result.CertExpiry, expiry)
+			}
+			if result.Success != tt.wantSuccess {
+				t.Errorf("success = %v, want %v (%s)", result.Success, tt.wantSuccess, result.FailureReason)
+			}
+			if !tt.wantSuccess && !strings.HasPrefix(result.FailureReason, "certificate expires in ") {
+				t.Errorf("reason = %q, want the certificate expiry", result.FailureReason)
+			}
+			if jsonResult := newJSONResult(result, false); jsonResult.CertDaysLeft == nil || *jsonResult.CertDaysLeft != days {
+				t.Errorf("cert_days_remaining = %v, want %d", jsonResult.CertDaysLeft, days)
+			}
+		})
+	}
+}
+
+func TestHTMLReportCertDays(t *testing.T) {
+	days := CertDays(9)
+	targets := map[string]JSONTargetResults{
+		"vitals.toml::api": {Target: "api", Summary: JSONSummary{CertDaysLeft: &days}},
+		"vitals.toml::web": {Target: "web"},
+	}
+	html, err := generateHTMLResults(nil, targets, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(html, "Cert: 9 days left"); n != 1 {
+		t.Errorf("report shows the certificate days %d times, want 1:\n%s", n, html)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// runCheckOne implements `vitals check-one`, a config-free single check meant for container
+// HEALTHCHECK commands: it prints nothing and exits 0 on success or 1 on failure
+func runCheckOne(args []string) int {
+	fs := flag.NewFlagSet("check-one", flag.ExitOnError)
+	url := fs.String("url", "", "URL to check")
+	expect := fs.String("expect", "200", "Acceptable status codes or ranges, e.g. 200,204 or 200-299")
+	timeout := fs.String("timeout", "5s", "Request timeout, e.g. 2s or 500ms")
+	verbose := fs.Bool("verbose", false, "Print the result to stderr")
+	fs.BoolVar(verbose, "v", false, "Print the result to stderr (shorthand)")
+	fs.Parse(args)
+
+	if *url == "" {
+		fmt.Fprintln(os.Stderr, "usage: vitals check-one --url <url> [--expect 200] [--timeout 2s]")
+		return 2
+	}
+
+	d, err := parseDurationOrSeconds(*timeout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid timeout '%s': %s\n", *timeout, err)
+		return 2
+	}
+
+	target := TargetConfig{UserAgent: resolveUserAgent("", "")}
+	if target.StatusCodes, target.StatusRanges, err = parseExpectedStatuses(*expect); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 2
+	}
+
+	checks := buildResponseChecks(target)
+	checks.SkipBody = true
+
+	client := &http.Client{Timeout: d}
+	result := checkEndpoint(client, *url, "", target, checks, nil, false)
+
+	if *verbose {
+		switch {
+		case result.Error != nil:
+			fmt.Fprintf(os.Stderr, "%s: error: %s\n", result.URL, result.Error)
+		case !result.Success:
+			fmt.Fprintf(os.Stderr, "%s: failed with status %d\n", result.URL, result.StatusCode)
+		default:
+			fmt.Fprintf(os.Stderr, "%s: ok (%d in %.2fs)\n", result.URL, result.StatusCode, result.Duration.Seconds())
+		}
+	}
+
+	if result.Error != nil || !result.Success {
+		return 1
+	}
+	return 0
+}
+
+// parseExpectedStatuses splits a list like "200,204,300-399" into status codes and ranges
+func parseExpectedStatuses(expect string) ([]int, []string, error) {
+	var codes []int
+	var ranges []string
+	for _, part := range strings.Split(expect, ",") {
+		part = strings.TrimSpace(part)
+		if strings.Contains(part, "-") {
+			if _, err := parseStatusRange(part); err != nil {
+				return nil, nil, fmt.Errorf("invalid status range '%s': %s", part, err)
+			}
+			ranges = append(ranges, part)
+			continue
+		}
+		code, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid status code '%s'", part)
+		}
+		codes = append(codes, code)
+	}
+	return codes, ranges, nil
+}
+
+// parseDurationOrSeconds parses a Go duration such as "2s", or a bare number of seconds
+func parseDurationOrSeconds(s string) (time.Duration, error) {
+	if seconds, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Duration(seconds * float64(time.Second)), nil
+	}
+	return time.ParseDuration(s)
+}
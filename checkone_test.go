@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseExpectedStatuses(t *testing.T) {
+	codes, ranges, err := parseExpectedStatuses("200, 204,300-399")
+	if err != nil {
+		t.Fatalf("parseExpectedStatuses() error = %v", err)
+	}
+	if !slices.Equal(codes, []int{200, 204}) || !slices.Equal(ranges, []string{"300-399"}) {
+		t.Errorf("parseExpectedStatuses() = %v, %v", codes, ranges)
+	}
+
+	if _, _, err := parseExpectedStatuses("ok"); err == nil {
+		t.Error("expected error for non-numeric status")
+	}
+}
+
+func TestParseDurationOrSeconds(t *testing.T) {
+	tests := map[string]time.Duration{
+		"2s":    2 * time.Second,
+		"500ms": 500 * time.Millisecond,
+		"3":     3 * time.Second,
+		"1.5":   1500 * time.Millisecond,
+	}
+	for input, want := range tests {
+		got, err := parseDurationOrSeconds(input)
+		if err != nil || got != want {
+			t.Errorf("parseDurationOrSeconds(%q) = %v, %v, want %v", input, got, err, want)
+		}
+	}
+}
+
+func TestRunCheckOne(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	if code := runCheckOne([]string{"--url", server.URL + "/health", "--expect", "204"}); code != 0 {
+		t.Errorf("check-one healthy exit code = %d, want 0", code)
+	}
+	if code := runCheckOne([]string{"--url", server.URL + "/down", "--timeout", "1s"}); code != 1 {
+		t.Errorf("check-one unhealthy exit code = %d, want 1", code)
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"errors"
+	"net/url"
+	"sync"
+)
+
+// circuitBreaker counts consecutive connection failures per base URL during one run of a
+// target, so once a host is clearly down its remaining endpoints are skipped rather than
+// each waiting out its own timeout. Until a host has answered once its endpoints are checked
+// one at a time, so concurrent workers can't all be in flight before the first failure counts
+type circuitBreaker struct {
+	threshold int
+
+	mu       sync.Mutex
+	failures map[string]int
+	answered map[string]bool
+	probes   map[string]*sync.Mutex
+}
+
+// newCircuitBreaker returns a breaker that opens after threshold consecutive connection
+// failures, or nil when threshold is zero
+func newCircuitBreaker(threshold int) *circuitBreaker {
+	if threshold <= 0 {
+		return nil
+	}
+	return &circuitBreaker{
+		threshold: threshold,
+		failures:  make(map[string]int),
+		answered:  make(map[string]bool),
+		probes:    make(map[string]*sync.Mutex),
+	}
+}
+
+// probe waits for any other check of baseURL to finish while its host has not answered yet,
+// and returns the function that lets the next one through
+func (b *circuitBreaker) probe(baseURL string) (release func()) {
+	if b == nil {
+		return func() {}
+	}
+	b.mu.Lock()
+	if b.answered[baseURL] {
+		b.mu.Unlock()
+		return func() {}
+	}
+	probe, ok := b.probes[baseURL]
+	if !ok {
+		probe = &sync.Mutex{}
+		b.probes[baseURL] = probe
+	}
+	b.mu.Unlock()
+
+	probe.Lock()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.answered[baseURL] {
+		probe.Unlock()
+		return func() {}
+	}
+	return probe.Unlock
+}
+
+// open reports whether checks against baseURL should be skipped
+func (b *circuitBreaker) open(baseURL string) bool {
+	if b == nil {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.failures[baseURL] >= b.threshold
+}
+
+// record counts a connection failure against the result's base URL, or resets the count when
+// the host answered
+func (b *circuitBreaker) record(result EndpointResult) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if isConnectionError(result.Error) {
+		b.failures[result.BaseURL]++
+	} else {
+		b.failures[result.BaseURL] = 0
+		b.answered[result.BaseURL] = true
+	}
+}
+
+// isConnectionError reports whether a check failed before getting any response, as opposed
+// to failing on what the server sent
+func isConnectionError(err error) bool {
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
+
+// skippedResult is reported for an endpoint left unchecked because its host is down
+func skippedResult(job checkJob, target TargetConfig) EndpointResult {
+	return EndpointResult{
+		BaseURL:       job.baseURL,
+		Endpoint:      job.endpoint.Path,
+		URL:           withQueryParams(constructURL(job.baseURL, job.endpoint.Path), target.QueryParams),
+		Method:        requestMethod(target),
+		Skipped:       true,
+		FailureReason: "host down",
+		RunbookURL:    target.RunbookURL,
+	}
+}
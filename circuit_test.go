@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	refused := &url.Error{Op: "Get", URL: "http://down.test/", Err: errors.New("connection refused")}
+	breaker := newCircuitBreaker(2)
+
+	breaker.record(EndpointResult{BaseURL: "http://down.test", Error: refused})
+	if breaker.open("http://down.test") {
+		t.Error("breaker opened before the threshold")
+	}
+	// An answer from the host resets the count
+	breaker.record(EndpointResult{BaseURL: "http://down.test", StatusCode: 500})
+	breaker.record(EndpointResult{BaseURL: "http://down.test", Error: refused})
+	if breaker.open("http://down.test") {
+		t.Error("breaker counted failures across a response")
+	}
+	// Errors after a response was received are not connection failures
+	breaker.record(EndpointResult{BaseURL: "http://down.test", Error: errors.New("error reading response body")})
+	breaker.record(EndpointResult{BaseURL: "http://down.test", Error: refused})
+	breaker.record(EndpointResult{BaseURL: "http://down.test", Error: refused})
+	if !breaker.open("http://down.test") {
+		t.Error("breaker still closed after consecutive connection failures")
+	}
+	if breaker.open("http://up.test") {
+		t.Error("breaker opened for another base URL")
+	}
+
+	disabled := newCircuitBreaker(0)
+	disabled.record(EndpointResult{BaseURL: "http://down.test", Error: refused})
+	if disabled.open("http://down.test") {
+		t.Error("disabled breaker opened")
+	}
+}
+
+func TestProcessTargetCircuitBreaker(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	// Reserve a port and close it so connections to it are refused
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	downURL := "http://" + listener.Addr().String()
+	listener.Close()
+
+	var endpoints []EndpointConfig
+	for i := range 10 {
+		endpoints = append(endpoints, EndpointConfig{Path: fmt.Sprintf("/%d", i)})
+	}
+	target := TargetConfig{
+		BaseURLs:       []string{downURL, server.URL},
+		Endpoints:      endpoints,
+		StatusCodes:    []int{200},
+		CircuitBreaker: 3,
+	}
+
+	// A single worker checks endpoints in order, so exactly the threshold is attempted
+	sem := make(chan struct{}, 1)
+	results := processTarget(context.Background(), server.Client(), target, ResponseChecks{}, nil, sem, false)
+
+	counts := map[string]int{}
+	for _, result := range results {
+		switch {
+		case result.BaseURL == server.URL && result.Success:
+			counts["up"]++
+		case result.Skipped && result.FailureReason == "host down":
+			counts["skipped"]++
+		case isConnectionError(result.Error):
+			counts["refused"]++
+		default:
+			t.Errorf("unexpected result %+v", result)
+		}
+	}
+	if counts["up"] != 10 || counts["refused"] != 3 || counts["skipped"] != 7 {
+		t.Errorf("counts = %v, want 10 up, 3 refused, 7 skipped", counts)
+	}
+}
+
+func TestProcessTargetCircuitBreakerConcurrent(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	downURL := "http://" + listener.Addr().String()
+	listener.Close()
+
+	var endpoints []EndpointConfig
+	for i := range 4 {
+		endpoints = append(endpoints, EndpointConfig{Path: fmt.Sprintf("/%d", i)})
+	}
+	target := TargetConfig{
+		BaseURLs:       []string{downURL},
+		Endpoints:      endpoints,
+		StatusCodes:    []int{200},
+		CircuitBreaker: 2,
+	}
+
+	// Without a semaphore every endpoint gets its own worker, but a host that hasn't answered
+	// is only probed one endpoint at a time
+	results := processTarget(context.Background(), http.DefaultClient, target, ResponseChecks{}, nil, nil, false)
+
+	var refused, skipped int
+	for _, result := range results {
+		switch {
+		case result.Skipped:
+			skipped++
+		case isConnectionError(result.Error):
+			refused++
+		}
+	}
+	if refused != 2 || skipped != 2 {
+		t.Errorf("refused = %d, skipped = %d, want 2 and 2", refused, skipped)
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// clockSkew estimates how far ahead of the local clock the server that wrote a Date header
+// is, negative when it is behind. Date has second resolution, so the server's clock read
+// [date, date+1s) at some point between sent and received; the skew is the smallest that
+// fits, zero when the clocks agree within that window. It reports false without a valid Date
+func clockSkew(date string, sent, received time.Time) (time.Duration, bool) {
+	serverTime, err := http.ParseTime(date)
+	if err != nil {
+		return 0, false
+	}
+	if ahead := serverTime.Sub(received); ahead > 0 {
+		return ahead, true
+	}
+	if behind := serverTime.Add(time.Second).Sub(sent); behind < 0 {
+		return behind, true
+	}
+	return 0, true
+}
+
+// checkClockSkew returns why a server's clock is off by more than max_clock_skew_ms, or an
+// empty string when it is within it or the target sets no limit
+func checkClockSkew(skew time.Duration, dated bool, maxMS int) string {
+	if maxMS <= 0 {
+		return ""
+	}
+	if !dated {
+		return "no valid Date header to check the server clock against"
+	}
+	if skew.Abs() > time.Duration(maxMS)*time.Millisecond {
+		return fmt.Sprintf("server clock is %s, over max_clock_skew_ms %d", describeSkew(skew), maxMS)
+	}
+	return ""
+}
+
+// describeSkew says how far ahead or behind the server clock is, e.g. "3.2s ahead"
+func describeSkew(skew time.Duration) string {
+	if skew < 0 {
+		return skew.Abs().Round(time.Millisecond).String() + " behind"
+	}
+	return skew.Round(time.Millisecond).String() + " ahead"
+}
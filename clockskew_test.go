@@ -0,0 +1,74 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClockSkew(t *testing.T) {
+	sent := time.Date(2026, 10, 14, 12, 0, 0, 300_000_000, time.UTC)
+	received := sent.Add(200 * time.Millisecond)
+	tests := []struct {
+		name      string
+		date      string
+		want      time.Duration
+		wantDated bool
+	}{
+		{"same second", "Wed, 14 Oct 2026 12:00:00 GMT", 0, true},
+		{"ahead", "Wed, 14 Oct 2026 12:00:05 GMT", 4500 * time.Millisecond, true},
+		{"behind", "Wed, 14 Oct 2026 11:59:50 GMT", -9300 * time.Millisecond, true},
+		{"missing", "", 0, false},
+		{"invalid", "yesterday", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, dated := clockSkew(tt.date, sent, received)
+			if got != tt.want || dated != tt.wantDated {
+				t.Errorf("clockSkew() = %s, %v, want %s, %v", got, dated, tt.want, tt.wantDated)
+			}
+		})
+	}
+}
+
+func TestCheckClockSkew(t *testing.T) {
+	tests := []struct {
+		name  string
+		skew  time.Duration
+		dated bool
+		maxMS int
+		want  string
+	}{
+		{"no limit", time.Hour, true, 0, ""},
+		{"within limit", -500 * time.Millisecond, true, 1000, ""},
+		{"ahead", 4500 * time.Millisecond, true, 1000, "server clock is 4.5s ahead, over max_clock_skew_ms 1000"},
+		{"behind", -2 * time.Minute, true, 30000, "server clock is 2m0s behind, over max_clock_skew_ms 30000"},
+		{"no Date header", 0, false, 1000, "no valid Date header to check the server clock against"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := checkClockSkew(tt.skew, tt.dated, tt.maxMS); got != tt.want {
+				t.Errorf("checkClockSkew() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckEndpointClockSkew(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/skewed" {
+			w.Header().Set("Date", time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat))
+		}
+	}))
+	defer server.Close()
+
+	target := TargetConfig{StatusCodes: []int{200}, MaxClockSkewMS: 5000}
+	if result := checkEndpoint(server.Client(), server.URL, "/", target, ResponseChecks{}, nil, false); !result.Success || result.ClockSkew != 0 {
+		t.Errorf("in sync: success %v, skew %s, reason %q", result.Success, result.ClockSkew, result.FailureReason)
+	}
+	result := checkEndpoint(server.Client(), server.URL, "/skewed", target, ResponseChecks{}, nil, false)
+	if result.Success || result.ClockSkew > -59*time.Minute {
+		t.Errorf("skewed: success %v, skew %s, reason %q", result.Success, result.ClockSkew, result.FailureReason)
+	}
+}
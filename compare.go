@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// maxComparisonColumnWidth caps each column of the comparison table
+const maxComparisonColumnWidth = 40
+
+// minComparisonDelta hides latency differences too small to show at the table's precision
+const minComparisonDelta = 10 * time.Millisecond
+
+// Comparison lines up the results of the same endpoints across a target's base URLs
+type Comparison struct {
+	BaseURLs []string
+	Rows     []ComparisonRow
+}
+
+// ComparisonRow holds one endpoint's result per base URL, nil where it wasn't checked
+type ComparisonRow struct {
+	Endpoint string
+	Results  []*EndpointResult
+}
+
+// Fastest returns the shortest duration among the row's successful results
+func (r ComparisonRow) Fastest() (time.Duration, bool) {
+	var fastest time.Duration
+	var found bool
+	for _, result := range r.Results {
+		if result != nil && result.Error == nil && result.Success && (!found || result.Duration < fastest) {
+			fastest = result.Duration
+			found = true
+		}
+	}
+	return fastest, found
+}
+
+// buildComparison groups configured endpoint results by endpoint and base URL, both sorted;
+// crawled links are left out because they aren't shared between hosts
+func buildComparison(results []EndpointResult) Comparison {
+	var comparison Comparison
+	var endpoints []string
+	for _, result := range results {
+		if result.LinkedFrom != "" {
+			continue
+		}
+		if !slices.Contains(comparison.BaseURLs, result.BaseURL) {
+			comparison.BaseURLs = append(comparison.BaseURLs, result.BaseURL)
+		}
+		if !slices.Contains(endpoints, result.Endpoint) {
+			endpoints = append(endpoints, result.Endpoint)
+		}
+	}
+	slices.Sort(comparison.BaseURLs)
+	slices.Sort(endpoints)
+
+	for _, endpoint := range endpoints {
+		row := ComparisonRow{Endpoint: endpoint, Results: make([]*EndpointResult, len(comparison.BaseURLs))}
+		for i := range results {
+			if results[i].LinkedFrom != "" || results[i].Endpoint != endpoint {
+				continue
+			}
+			row.Results[slices.Index(comparison.BaseURLs, results[i].BaseURL)] = &results[i]
+		}
+		comparison.Rows = append(comparison.Rows, row)
+	}
+	return comparison
+}
+
+// comparisonCell describes one result relative to the fastest host for the same endpoint
+func comparisonCell(result *EndpointResult, fastest time.Duration, hasFastest bool) string {
+	switch {
+	case result == nil:
+		return "-"
+	case result.Error != nil:
+		return "ERROR"
+	case !result.Success:
+		return fmt.Sprintf("%d FAIL %.2fs", result.StatusCode, result.Duration.Seconds())
+	case hasFastest && result.Duration-fastest >= minComparisonDelta:
+		return fmt.Sprintf("%d %.2fs (+%.2fs)", result.StatusCode, result.Duration.Seconds(), (result.Duration - fastest).Seconds())
+	default:
+		return fmt.Sprintf("%d %.2fs", result.StatusCode, result.Duration.Seconds())
+	}
+}
+
+// printComparison prints one row per endpoint with a column per base URL, showing each
+// host's latency relative to the fastest; rows where any host failed are red
+func printComparison(comparison Comparison, targetName string, green, red func(a ...interface{}) string) {
+	headers := append([]string{"ENDPOINT"}, comparison.BaseURLs...)
+	rows := make([][]string, 0, len(comparison.Rows))
+	colors := make([]func(a ...interface{}) string, 0, len(comparison.Rows))
+	for _, row := range comparison.Rows {
+		fastest, hasFastest := row.Fastest()
+		endpoint := row.Endpoint
+		if endpoint == "" {
+			endpoint = "/"
+		}
+
+		cells := []string{endpoint}
+		color := green
+		for _, result := range row.Results {
+			cells = append(cells, comparisonCell(result, fastest, hasFastest))
+			if result == nil || result.Error != nil || !result.Success {
+				color = red
+			}
+		}
+		rows = append(rows, cells)
+		colors = append(colors, color)
+	}
+
+	title := fmt.Sprintf("[%s] compared across %d base URLs", targetName, len(comparison.BaseURLs))
+	printBoxTable(title, headers, rows, colors)
+}
+
+// printBoxTable prints a titled table with a color per row, capping each column at
+// maxComparisonColumnWidth
+func printBoxTable(title string, headers []string, rows [][]string, colors []func(a ...interface{}) string) {
+	_, _, neutral := setupColorOutput()
+
+	widths := make([]int, len(headers))
+	for i, header := range headers {
+		widths[i] = len(header)
+	}
+	for _, cells := range rows {
+		for i, cell := range cells {
+			widths[i] = max(widths[i], len(cell))
+		}
+	}
+	totalWidth := 1
+	for i := range widths {
+		widths[i] = min(widths[i], maxComparisonColumnWidth)
+		totalWidth += widths[i] + 3
+	}
+
+	divider := func(left, middle, right string) string {
+		parts := make([]string, len(widths))
+		for i, width := range widths {
+			parts[i] = strings.Repeat("─", width+2)
+		}
+		return left + strings.Join(parts, middle) + right
+	}
+	printCells := func(cells []string, color func(a ...interface{}) string) {
+		line := neutral("│")
+		for i, cell := range cells {
+			if len(cell) > widths[i] {
+				cell = cell[:widths[i]-3] + "..."
+			}
+			line += color(fmt.Sprintf(" %-*s ", widths[i], cell)) + neutral("│")
+		}
+		fmt.Println(line)
+	}
+
+	fmt.Println(neutral("┌" + strings.Repeat("─", totalWidth-2) + "┐"))
+	padding := max((totalWidth-2-len(title))/2, 1)
+	fmt.Println(neutral("│" + strings.Repeat(" ", padding) + title + strings.Repeat(" ", max(totalWidth-2-padding-len(title), 0)) + "│"))
+	fmt.Println(neutral(divider("├", "┬", "┤")))
+	printCells(headers, neutral)
+	fmt.Println(neutral(divider("├", "┼", "┤")))
+	for i, cells := range rows {
+		printCells(cells, colors[i])
+	}
+	fmt.Println(neutral(divider("└", "┴", "┘")))
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBuildComparison(t *testing.T) {
+	results := []EndpointResult{
+		{BaseURL: "https://us.example.com", Endpoint: "/health", Success: true},
+		{BaseURL: "https://eu.example.com", Endpoint: "/health", Success: true},
+		{BaseURL: "https://eu.example.com", Endpoint: "/api", Success: true},
+		{BaseURL: "https://eu.example.com", Endpoint: "/docs", LinkedFrom: "https://eu.example.com/"},
+	}
+
+	comparison := buildComparison(results)
+	if len(comparison.BaseURLs) != 2 || comparison.BaseURLs[0] != "https://eu.example.com" {
+		t.Fatalf("BaseURLs = %v", comparison.BaseURLs)
+	}
+	if len(comparison.Rows) != 2 || comparison.Rows[0].Endpoint != "/api" || comparison.Rows[1].Endpoint != "/health" {
+		t.Fatalf("Rows = %+v", comparison.Rows)
+	}
+	if comparison.Rows[0].Results[1] != nil {
+		t.Errorf("/api was not checked on us.example.com but has a result")
+	}
+	if comparison.Rows[1].Results[1] != &results[0] {
+		t.Errorf("/health on us.example.com should point at its result")
+	}
+}
+
+func TestComparisonCell(t *testing.T) {
+	fastest := 100 * time.Millisecond
+
+	tests := []struct {
+		name   string
+		result *EndpointResult
+		want   string
+	}{
+		{"not checked", nil, "-"},
+		{"error", &EndpointResult{Error: errors.New("refused")}, "ERROR"},
+		{"failed", &EndpointResult{StatusCode: 503, Duration: 50 * time.Millisecond}, "503 FAIL 0.05s"},
+		{"fastest", &EndpointResult{StatusCode: 200, Success: true, Duration: fastest}, "200 0.10s"},
+		{"slower", &EndpointResult{StatusCode: 200, Success: true, Duration: 350 * time.Millisecond}, "200 0.35s (+0.25s)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := comparisonCell(tt.result, fastest, true); got != tt.want {
+				t.Errorf("comparisonCell() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// acceptEncoding is advertised on every request so compression support can be reported
+const acceptEncoding = "gzip, br"
+
+// isCompressed reports whether a Content-Encoding value denotes a compressed body
+func isCompressed(contentEncoding string) bool {
+	switch strings.ToLower(strings.TrimSpace(contentEncoding)) {
+	case "", "identity":
+		return false
+	default:
+		return true
+	}
+}
+
+// decodeBody decompresses a raw response body according to its Content-Encoding
+func decodeBody(contentEncoding string, raw []byte) ([]byte, error) {
+	var reader io.Reader
+	switch strings.ToLower(strings.TrimSpace(contentEncoding)) {
+	case "", "identity":
+		return raw, nil
+	case "gzip", "x-gzip":
+		gz, err := gzip.NewReader(bytes.NewReader(raw))
+		if err != nil {
+			return nil, fmt.Errorf("error decompressing gzip body: %s", err)
+		}
+		defer gz.Close()
+		reader = gz
+	case "br":
+		reader = brotli.NewReader(bytes.NewReader(raw))
+	case "deflate":
+		fl := flate.NewReader(bytes.NewReader(raw))
+		defer fl.Close()
+		reader = fl
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", contentEncoding)
+	}
+
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("error decompressing %s body: %s", contentEncoding, err)
+	}
+	return body, nil
+}
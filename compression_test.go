@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestDecodeBody(t *testing.T) {
+	plain := []byte("hello hello hello hello")
+
+	var gz bytes.Buffer
+	gw := gzip.NewWriter(&gz)
+	gw.Write(plain)
+	gw.Close()
+
+	var br bytes.Buffer
+	bw := brotli.NewWriter(&br)
+	bw.Write(plain)
+	bw.Close()
+
+	tests := []struct {
+		name     string
+		encoding string
+		raw      []byte
+		wantErr  bool
+	}{
+		{name: "identity", encoding: "", raw: plain},
+		{name: "gzip", encoding: "gzip", raw: gz.Bytes()},
+		{name: "brotli", encoding: "br", raw: br.Bytes()},
+		{name: "corrupt gzip", encoding: "gzip", raw: plain, wantErr: true},
+		{name: "unsupported", encoding: "zstd", raw: plain, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeBody(tt.encoding, tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeBody() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !bytes.Equal(got, plain) {
+				t.Errorf("decodeBody() = %q, want %q", got, plain)
+			}
+		})
+	}
+}
@@ -0,0 +1,15 @@
+package main
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+)
+
+// withCookieJar returns a copy of client with an empty cookie jar, so cookies one response
+// sets are sent with the requests that follow
+func withCookieJar(client *http.Client) *http.Client {
+	jar, _ := cookiejar.New(nil)
+	withJar := *client
+	withJar.Jar = jar
+	return &withJar
+}
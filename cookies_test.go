@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestUseCookies(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc", Path: "/"})
+		case "/account":
+			if cookie, err := r.Cookie("session"); err != nil || cookie.Value != "abc" {
+				w.WriteHeader(http.StatusUnauthorized)
+			}
+		}
+	}))
+	defer server.Close()
+
+	for _, useCookies := range []bool{true, false} {
+		target := TargetConfig{
+			BaseURLs:    []string{server.URL},
+			Endpoints:   pathEndpoints("/login", "/account", "/account", "/account"),
+			StatusCodes: []int{200},
+			UseCookies:  useCookies,
+		}
+		client := server.Client()
+		for _, result := range processTarget(context.Background(), client, target, buildResponseChecks(target), nil, nil, false) {
+			if result.Success != (useCookies || result.Endpoint == "/login") {
+				t.Errorf("use_cookies = %v: %s success = %v", useCookies, result.Endpoint, result.Success)
+			}
+		}
+		if client.Jar != nil {
+			t.Errorf("processTarget() gave the shared client a cookie jar")
+		}
+	}
+}
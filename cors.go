@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// CORSConfig turns a target's checks into CORS preflight requests
+type CORSConfig struct {
+	Origin           string   `toml:"origin,omitempty"`
+	Method           string   `toml:"method,omitempty"`
+	Headers          []string `toml:"headers,omitempty"`
+	AllowCredentials bool     `toml:"allow_credentials,omitempty"`
+}
+
+// setPreflightHeaders adds the headers a browser sends with an OPTIONS preflight
+func setPreflightHeaders(req *http.Request, cors CORSConfig) {
+	req.Header.Set("Origin", cors.Origin)
+	req.Header.Set("Access-Control-Request-Method", preflightMethod(cors))
+	if len(cors.Headers) > 0 {
+		req.Header.Set("Access-Control-Request-Headers", strings.ToLower(strings.Join(cors.Headers, ",")))
+	}
+}
+
+// preflightMethod returns the method the preflight asks permission for, defaulting to GET
+func preflightMethod(cors CORSConfig) string {
+	if cors.Method == "" {
+		return "GET"
+	}
+	return strings.ToUpper(cors.Method)
+}
+
+// checkCORS asserts the Access-Control-Allow-* headers of a preflight response grant the
+// configured origin, method, and headers, returning the reason for the first failure
+func checkCORS(header http.Header, cors CORSConfig) string {
+	allowOrigin := header.Get("Access-Control-Allow-Origin")
+	switch {
+	case allowOrigin == "":
+		return "missing Access-Control-Allow-Origin"
+	case allowOrigin == "*" && cors.AllowCredentials:
+		return "Access-Control-Allow-Origin is * but credentials are required"
+	case allowOrigin != "*" && allowOrigin != cors.Origin:
+		return fmt.Sprintf("Access-Control-Allow-Origin is %s, expected %s", allowOrigin, cors.Origin)
+	}
+
+	method := preflightMethod(cors)
+	if !headerListAllows(header.Get("Access-Control-Allow-Methods"), method) {
+		return fmt.Sprintf("Access-Control-Allow-Methods does not allow %s", method)
+	}
+
+	for _, requested := range cors.Headers {
+		if !headerListAllows(header.Get("Access-Control-Allow-Headers"), requested) {
+			return fmt.Sprintf("Access-Control-Allow-Headers does not allow %s", requested)
+		}
+	}
+
+	if cors.AllowCredentials && header.Get("Access-Control-Allow-Credentials") != "true" {
+		return "Access-Control-Allow-Credentials is not true"
+	}
+
+	return ""
+}
+
+// headerListAllows reports whether a comma-separated Access-Control-Allow-* value
+// contains the item (case-insensitively) or the * wildcard
+func headerListAllows(list, item string) bool {
+	for _, allowed := range strings.Split(list, ",") {
+		allowed = strings.TrimSpace(allowed)
+		if allowed == "*" || strings.EqualFold(allowed, item) {
+			return true
+		}
+	}
+	return false
+}
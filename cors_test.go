@@ -0,0 +1,98 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestCheckCORS(t *testing.T) {
+	cors := CORSConfig{
+		Origin:  "https://app.example.com",
+		Method:  "post",
+		Headers: []string{"Content-Type", "Authorization"},
+	}
+
+	tests := []struct {
+		name     string
+		headers  map[string]string
+		cors     CORSConfig
+		wantFail bool
+	}{
+		{
+			name: "allowed",
+			headers: map[string]string{
+				"Access-Control-Allow-Origin":  "https://app.example.com",
+				"Access-Control-Allow-Methods": "GET, POST, OPTIONS",
+				"Access-Control-Allow-Headers": "content-type, authorization",
+			},
+			cors: cors,
+		},
+		{
+			name: "wildcards",
+			headers: map[string]string{
+				"Access-Control-Allow-Origin":  "*",
+				"Access-Control-Allow-Methods": "*",
+				"Access-Control-Allow-Headers": "*",
+			},
+			cors: cors,
+		},
+		{
+			name:     "missing origin",
+			headers:  map[string]string{"Access-Control-Allow-Methods": "POST"},
+			cors:     cors,
+			wantFail: true,
+		},
+		{
+			name: "other origin",
+			headers: map[string]string{
+				"Access-Control-Allow-Origin":  "https://evil.example.com",
+				"Access-Control-Allow-Methods": "POST",
+			},
+			cors:     cors,
+			wantFail: true,
+		},
+		{
+			name: "method not allowed",
+			headers: map[string]string{
+				"Access-Control-Allow-Origin":  "https://app.example.com",
+				"Access-Control-Allow-Methods": "GET",
+				"Access-Control-Allow-Headers": "content-type, authorization",
+			},
+			cors:     cors,
+			wantFail: true,
+		},
+		{
+			name: "header not allowed",
+			headers: map[string]string{
+				"Access-Control-Allow-Origin":  "https://app.example.com",
+				"Access-Control-Allow-Methods": "POST",
+				"Access-Control-Allow-Headers": "content-type",
+			},
+			cors:     cors,
+			wantFail: true,
+		},
+		{
+			name: "wildcard origin with credentials",
+			headers: map[string]string{
+				"Access-Control-Allow-Origin":      "*",
+				"Access-Control-Allow-Methods":     "GET",
+				"Access-Control-Allow-Credentials": "true",
+			},
+			cors:     CORSConfig{Origin: "https://app.example.com", AllowCredentials: true},
+			wantFail: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			for k, v := range tt.headers {
+				header.Set(k, v)
+			}
+			got := checkCORS(header, tt.cors)
+			if (got != "") != tt.wantFail {
+				t.Errorf("checkCORS() = %q, wantFail %v", got, tt.wantFail)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// parseCrawlSpec parses the --crawl-links value, either "depth=N" or a bare N
+func parseCrawlSpec(spec string) (int, error) {
+	if spec == "" {
+		return 0, nil
+	}
+
+	value := spec
+	if key, v, ok := strings.Cut(spec, "="); ok {
+		if key != "depth" {
+			return 0, fmt.Errorf("invalid --crawl-links value %q, expected depth=N", spec)
+		}
+		value = v
+	}
+
+	depth, err := strconv.Atoi(value)
+	if err != nil || depth < 0 {
+		return 0, fmt.Errorf("invalid --crawl-links value %q, expected depth=N", spec)
+	}
+	return depth, nil
+}
+
+// isHTML reports whether a Content-Type header denotes an HTML document
+func isHTML(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == "text/html" || mediaType == "application/xhtml+xml")
+}
+
+// extractLinks returns the absolute URLs of same-origin links found in an HTML page,
+// without fragments and in document order
+func extractLinks(pageURL, body string) []string {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil
+	}
+
+	var links []string
+	seen := make(map[string]bool)
+	tokenizer := html.NewTokenizer(strings.NewReader(body))
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			return links
+		}
+		if tokenType != html.StartTagToken && tokenType != html.SelfClosingTagToken {
+			continue
+		}
+
+		token := tokenizer.Token()
+		if token.Data != "a" && token.Data != "link" {
+			continue
+		}
+		for _, attr := range token.Attr {
+			if attr.Key != "href" {
+				continue
+			}
+			ref, err := url.Parse(strings.TrimSpace(attr.Val))
+			if err != nil {
+				continue
+			}
+			link := base.ResolveReference(ref)
+			link.Fragment = ""
+			if link.Scheme != base.Scheme || link.Host != base.Host {
+				continue
+			}
+			if s := link.String(); !seen[s] {
+				seen[s] = true
+				links = append(links, s)
+			}
+		}
+	}
+}
+
+// linkTarget derives the target used to check crawled links: same headers and User-Agent,
+// but only a 2xx status is required since the page's own assertions don't apply
+func linkTarget(target TargetConfig, links []string) TargetConfig {
+	return TargetConfig{
+		Name:      target.Name,
+		BaseURLs:  links,
+		Endpoints: pathEndpoints(""),
+		Headers:   target.Headers,
+		UserAgent: target.UserAgent,
+	}
+}
+
+// crawlLinks checks same-origin links found on the HTML pages among results, following
+// new pages up to depth levels, and returns the results for the linked pages
+func crawlLinks(ctx context.Context, client *http.Client, target TargetConfig, results []EndpointResult, depth int, sem chan struct{}, verbose bool) []EndpointResult {
+	checked := make(map[string]bool)
+	for _, result := range results {
+		checked[result.URL] = true
+	}
+
+	linkChecks := ResponseChecks{StatusRanges: []StatusRange{{Min: 200, Max: 299}}}
+
+	var crawled []EndpointResult
+	pages := results
+	for level := 0; level < depth && len(pages) > 0; level++ {
+		// Gather the unchecked links of this level, remembering which page referenced them
+		var links []string
+		linkedFrom := make(map[string]string)
+		for _, page := range pages {
+			if page.Error != nil || !page.Success || !isHTML(page.ContentType) {
+				continue
+			}
+			for _, link := range extractLinks(page.URL, page.ResponseBody) {
+				if !checked[link] {
+					checked[link] = true
+					links = append(links, link)
+					linkedFrom[link] = page.URL
+				}
+			}
+		}
+		if len(links) == 0 {
+			break
+		}
+
+		pages = processTarget(ctx, client, linkTarget(target, links), linkChecks, nil, sem, verbose)
+		for i := range pages {
+			pages[i].LinkedFrom = linkedFrom[pages[i].URL]
+		}
+		crawled = append(crawled, pages...)
+	}
+
+	return crawled
+}
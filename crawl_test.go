@@ -0,0 +1,52 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseCrawlSpec(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    int
+		wantErr bool
+	}{
+		{spec: "", want: 0},
+		{spec: "depth=1", want: 1},
+		{spec: "2", want: 2},
+		{spec: "depth=-1", wantErr: true},
+		{spec: "width=1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := parseCrawlSpec(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseCrawlSpec() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseCrawlSpec() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractLinks(t *testing.T) {
+	body := `<html><head><link rel="stylesheet" href="/style.css"></head><body>
+<a href="/docs">Docs</a>
+<a href="status#today">Status</a>
+<a href="status">Status again</a>
+<a href="https://other.example.com/">Elsewhere</a>
+<a href="mailto:ops@example.com">Mail</a>
+</body></html>`
+
+	got := extractLinks("https://example.com/index.html", body)
+	want := []string{
+		"https://example.com/style.css",
+		"https://example.com/docs",
+		"https://example.com/status",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("extractLinks() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// dedupeCache collapses identical requests made during one run, so an endpoint that several
+// config files check with the same method, URL, and headers is only requested once and every
+// target evaluates its own assertions against the shared response
+type dedupeCache struct {
+	mu     sync.Mutex
+	calls  map[string]*dedupeCall
+	shared int
+}
+
+// dedupeCall is one request in flight or completed, shared by everyone asking for it
+type dedupeCall struct {
+	done chan struct{}
+
+	status     string
+	statusCode int
+	proto      string
+	header     http.Header
+	body       []byte
+	tls        *tls.ConnectionState
+	err        error
+}
+
+// dedupeTransport answers requests from the cache, making the first of each on transport
+type dedupeTransport struct {
+	cache     *dedupeCache
+	transport http.RoundTripper
+}
+
+// newDedupeCache returns a cache for --dedupe, nil when it is disabled
+func newDedupeCache(enabled bool) *dedupeCache {
+	if !enabled {
+		return nil
+	}
+	return &dedupeCache{calls: make(map[string]*dedupeCall)}
+}
+
+// wrap routes a client's requests through the cache; a nil cache leaves it alone
+func (c *dedupeCache) wrap(client *http.Client) *http.Client {
+	if c != nil {
+		transport := client.Transport
+		if transport == nil {
+			transport = http.DefaultTransport
+		}
+		client.Transport = &dedupeTransport{cache: c, transport: transport}
+	}
+	return client
+}
+
+// freshRequestKey marks the context of a request the cache must not answer, such as the retry
+// of a failed check, which would otherwise get the failure it is retrying back
+type freshRequestKey struct{}
+
+// withoutDedupe returns a copy of client whose requests skip the dedupe cache
+func withoutDedupe(client *http.Client) *http.Client {
+	transport := client.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	fresh := *client
+	fresh.Transport = freshTransport{transport: transport}
+	return &fresh
+}
+
+// freshTransport marks every request as one the dedupe cache must not answer
+type freshTransport struct {
+	transport http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t freshTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.transport.RoundTrip(req.WithContext(context.WithValue(req.Context(), freshRequestKey{}, true)))
+}
+
+// Shared returns how many requests were answered from another check's response
+func (c *dedupeCache) Shared() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.shared
+}
+
+// dedupeKey identifies a request by method, URL, and headers; requests with a body are never
+// shared since the body could differ
+func dedupeKey(req *http.Request) (string, bool) {
+	if req.Body != nil && req.Body != http.NoBody {
+		return "", false
+	}
+
+	names := make([]string, 0, len(req.Header))
+	for name := range req.Header {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var b strings.Builder
+	b.WriteString(req.Method + " " + req.URL.String())
+	for _, name := range names {
+		b.WriteString("\n" + name + ": " + strings.Join(req.Header[name], ", "))
+	}
+	return b.String(), true
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *dedupeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	key, ok := dedupeKey(req)
+	if !ok || req.Context().Value(freshRequestKey{}) != nil {
+		return t.transport.RoundTrip(req)
+	}
+
+	t.cache.mu.Lock()
+	if call, ok := t.cache.calls[key]; ok {
+		t.cache.shared++
+		t.cache.mu.Unlock()
+		select {
+		case <-call.done:
+			return call.response(req)
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+	call := &dedupeCall{done: make(chan struct{})}
+	t.cache.calls[key] = call
+	t.cache.mu.Unlock()
+
+	call.do(t.transport, req)
+	return call.response(req)
+}
+
+// do performs the request and keeps the whole response so it can be replayed
+func (c *dedupeCall) do(transport http.RoundTripper, req *http.Request) {
+	defer close(c.done)
+
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		c.err = err
+		return
+	}
+	defer resp.Body.Close()
+
+	if c.body, err = io.ReadAll(resp.Body); err != nil {
+		c.err = err
+		return
+	}
+	c.status, c.statusCode, c.proto = resp.Status, resp.StatusCode, resp.Proto
+	c.header = resp.Header
+	c.tls = resp.TLS
+}
+
+// response builds a fresh copy of the shared response for req
+func (c *dedupeCall) response(req *http.Request) (*http.Response, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &http.Response{
+		Status:        c.status,
+		StatusCode:    c.statusCode,
+		Proto:         c.proto,
+		Header:        c.header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(c.body)),
+		ContentLength: int64(len(c.body)),
+		TLS:           c.tls,
+		Request:       req,
+	}, nil
+}
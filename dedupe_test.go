@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestDedupeCache(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set("X-Seen", r.Header.Get("X-Team"))
+		io.WriteString(w, "ok")
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		requests func() []*http.Request
+		wantHits int32
+	}{
+		{
+			name: "identical requests",
+			requests: func() []*http.Request {
+				a, _ := http.NewRequest("GET", server.URL+"/health", nil)
+				b, _ := http.NewRequest("GET", server.URL+"/health", nil)
+				return []*http.Request{a, b}
+			},
+			wantHits: 1,
+		},
+		{
+			name: "different headers",
+			requests: func() []*http.Request {
+				a, _ := http.NewRequest("GET", server.URL+"/health", nil)
+				a.Header.Set("X-Team", "payments")
+				b, _ := http.NewRequest("GET", server.URL+"/health", nil)
+				b.Header.Set("X-Team", "search")
+				return []*http.Request{a, b}
+			},
+			wantHits: 2,
+		},
+		{
+			name: "requests with a body",
+			requests: func() []*http.Request {
+				a, _ := http.NewRequest("POST", server.URL+"/login", strings.NewReader("a"))
+				b, _ := http.NewRequest("POST", server.URL+"/login", strings.NewReader("a"))
+				return []*http.Request{a, b}
+			},
+			wantHits: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits.Store(0)
+			cache := newDedupeCache(true)
+			for _, req := range tt.requests() {
+				// Each config file gets its own client, all sharing the cache
+				client := cache.wrap(&http.Client{})
+				resp, err := client.Do(req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				body, _ := io.ReadAll(resp.Body)
+				resp.Body.Close()
+				if resp.StatusCode != 200 || string(body) != "ok" {
+					t.Errorf("got %d %q, want 200 \"ok\"", resp.StatusCode, body)
+				}
+				if resp.Header.Get("X-Seen") != req.Header.Get("X-Team") {
+					t.Errorf("X-Seen = %q, want %q", resp.Header.Get("X-Seen"), req.Header.Get("X-Team"))
+				}
+			}
+			if got := hits.Load(); got != tt.wantHits {
+				t.Errorf("server hits = %d, want %d", got, tt.wantHits)
+			}
+			if got := cache.Shared(); got != int(2-tt.wantHits) {
+				t.Errorf("Shared() = %d, want %d", got, 2-tt.wantHits)
+			}
+		})
+	}
+}
+
+func TestDedupeCacheDisabled(t *testing.T) {
+	client := &http.Client{}
+	if newDedupeCache(false).wrap(client).Transport != nil {
+		t.Error("a disabled cache should leave the client alone")
+	}
+}
+
+func TestDedupeCacheRetry(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	target := TargetConfig{
+		BaseURLs:    []string{server.URL},
+		Endpoints:   []EndpointConfig{{Path: "/health"}},
+		StatusCodes: []int{200},
+		Retries:     1,
+	}
+	client := newDedupeCache(true).wrap(&http.Client{})
+	results := processTarget(context.Background(), client, target, buildResponseChecks(target), nil, nil, false)
+	if len(results) != 1 || results[0].State() != StateFlaky || hits.Load() != 2 {
+		t.Errorf("results = %+v after %d requests, want one flaky result after 2", results, hits.Load())
+	}
+}
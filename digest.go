@@ -0,0 +1,208 @@
+package main
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// digestAlgorithms are the supported Digest algorithms; each also has a -sess variant
+var digestAlgorithms = map[string]func() hash.Hash{
+	"SHA-256": sha256.New,
+	"MD5":     md5.New,
+}
+
+// digestChallenge is a parsed `WWW-Authenticate: Digest` challenge (RFC 7616)
+type digestChallenge struct {
+	realm, nonce, opaque string
+	algorithm            string
+	qop                  []string
+	sess, userhash       bool
+}
+
+// digestTransport answers Digest challenges: a 401 with a supported challenge is retried
+// once with credentials computed for it
+type digestTransport struct {
+	transport          http.RoundTripper
+	username, password string
+}
+
+// digestCnonce returns a fresh client nonce, replaceable in tests
+var digestCnonce = func() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// RoundTrip sends the request, then answers a Digest challenge in the response
+func (t *digestTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.transport.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	challenge, ok := chooseDigestChallenge(resp.Header.Values("WWW-Authenticate"))
+	if !ok {
+		return resp, nil
+	}
+
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return resp, nil
+		}
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, bodyDrainLimit))
+	resp.Body.Close()
+
+	retry.Header.Set("Authorization", challenge.authorization(t.username, t.password, req.Method, req.URL.RequestURI(), digestCnonce()))
+	return t.transport.RoundTrip(retry)
+}
+
+// chooseDigestChallenge picks the challenge with the strongest supported algorithm
+func chooseDigestChallenge(headers []string) (digestChallenge, bool) {
+	var best digestChallenge
+	found := false
+	for _, header := range headers {
+		challenge, ok := parseDigestChallenge(header)
+		if !ok {
+			continue
+		}
+		if !found || challenge.algorithm == "SHA-256" && best.algorithm != "SHA-256" {
+			best, found = challenge, true
+		}
+	}
+	return best, found
+}
+
+// parseDigestChallenge parses one Digest challenge, rejecting unsupported algorithms and
+// quality of protection options
+func parseDigestChallenge(header string) (digestChallenge, bool) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(header), " ")
+	if !strings.EqualFold(scheme, "Digest") {
+		return digestChallenge{}, false
+	}
+
+	params := parseAuthParams(rest)
+	challenge := digestChallenge{
+		realm:     params["realm"],
+		nonce:     params["nonce"],
+		opaque:    params["opaque"],
+		algorithm: "MD5",
+		userhash:  strings.EqualFold(params["userhash"], "true"),
+	}
+	if algorithm := params["algorithm"]; algorithm != "" {
+		name, sess := strings.CutSuffix(strings.ToUpper(algorithm), "-SESS")
+		challenge.algorithm, challenge.sess = name, sess
+	}
+	if _, ok := digestAlgorithms[challenge.algorithm]; !ok || challenge.nonce == "" {
+		return digestChallenge{}, false
+	}
+	for _, qop := range strings.Split(params["qop"], ",") {
+		if qop = strings.TrimSpace(qop); qop != "" {
+			challenge.qop = append(challenge.qop, qop)
+		}
+	}
+	// Only "auth" is supported; auth-int would need the body hashed
+	if len(challenge.qop) > 0 && !slices.Contains(challenge.qop, "auth") {
+		return digestChallenge{}, false
+	}
+	return challenge, true
+}
+
+// parseAuthParams parses comma-separated key=value and key="quoted value" parameters
+func parseAuthParams(s string) map[string]string {
+	params := make(map[string]string)
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimLeft(s, ", \t") {
+		eq := strings.IndexByte(s, '=')
+		if eq < 0 {
+			break
+		}
+		key := strings.ToLower(strings.TrimSpace(s[:eq]))
+		s = strings.TrimLeft(s[eq+1:], " \t")
+
+		var value strings.Builder
+		if strings.HasPrefix(s, `"`) {
+			i := 1
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+				value.WriteByte(s[i])
+			}
+			s = s[min(i+1, len(s)):]
+		} else {
+			end := strings.IndexByte(s, ',')
+			if end < 0 {
+				end = len(s)
+			}
+			value.WriteString(strings.TrimSpace(s[:end]))
+			s = s[end:]
+		}
+		params[key] = value.String()
+	}
+	return params
+}
+
+// quoteParam quotes an auth parameter value, escaping backslashes and quotes
+func quoteParam(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
+
+// authorization computes the Authorization header answering the challenge for a request,
+// counting it as the first use of the nonce
+func (c digestChallenge) authorization(username, password, method, uri, cnonce string) string {
+	newHash := digestAlgorithms[c.algorithm]
+	h := func(parts ...string) string {
+		sum := newHash()
+		io.WriteString(sum, strings.Join(parts, ":"))
+		return hex.EncodeToString(sum.Sum(nil))
+	}
+
+	const nc = "00000001"
+	ha1 := h(username, c.realm, password)
+	if c.sess {
+		ha1 = h(ha1, c.nonce, cnonce)
+	}
+	ha2 := h(method, uri)
+
+	algorithm := c.algorithm
+	if c.sess {
+		algorithm += "-sess"
+	}
+	user := username
+	if c.userhash {
+		user = h(username, c.realm)
+	}
+
+	fields := []string{
+		"username=" + quoteParam(user),
+		"realm=" + quoteParam(c.realm),
+		"uri=" + quoteParam(uri),
+		"algorithm=" + algorithm,
+		"nonce=" + quoteParam(c.nonce),
+	}
+	if len(c.qop) == 0 {
+		// RFC 2069 servers predate qop and the client nonce
+		fields = append(fields, "response="+quoteParam(h(ha1, c.nonce, ha2)))
+	} else {
+		fields = append(fields,
+			"nc="+nc,
+			"cnonce="+quoteParam(cnonce),
+			"qop=auth",
+			"response="+quoteParam(h(ha1, c.nonce, nc, cnonce, "auth", ha2)),
+		)
+	}
+	if c.opaque != "" {
+		fields = append(fields, "opaque="+quoteParam(c.opaque))
+	}
+	if c.userhash {
+		fields = append(fields, "userhash=true")
+	}
+	return "Digest " + strings.Join(fields, ", ")
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestDigestAuthorization(t *testing.T) {
+	// The examples of RFC 7616 section 3.9.1
+	header := func(algorithm string) string {
+		return `Digest realm="http-auth@example.org", qop="auth, auth-int", algorithm=` + algorithm + `, ` +
+			`nonce="7ypf/xlj9XXwfDPEoM4URrv/xwf94BcCAzFZH4GiTo0v", opaque="FQhe/qaU925kfnzjCev0ciny7QMkPqMAFRtzCUYo5tdS"`
+	}
+	const cnonce = "f2/wE4q74E6zIJEtWaHKaf5wv/H5QzzpXusqGemxURZJ"
+
+	tests := []struct {
+		algorithm string
+		want      string
+	}{
+		{"MD5", "8ca523f5e9506fed4657c9700eebdbec"},
+		{"SHA-256", "753927fa0e85d155564e2e272a28d1802ca10daf4496794697cf8db5856cb6c1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.algorithm, func(t *testing.T) {
+			challenge, ok := parseDigestChallenge(header(tt.algorithm))
+			if !ok {
+				t.Fatalf("parseDigestChallenge() rejected %s challenge", tt.algorithm)
+			}
+			got := challenge.authorization("Mufasa", "Circle of Life", "GET", "/dir/index.html", cnonce)
+			params := parseAuthParams(strings.TrimPrefix(got, "Digest "))
+			if params["response"] != tt.want {
+				t.Errorf("response = %s, want %s", params["response"], tt.want)
+			}
+			for key, want := range map[string]string{"username": "Mufasa", "qop": "auth", "nc": "00000001", "cnonce": cnonce, "opaque": "FQhe/qaU925kfnzjCev0ciny7QMkPqMAFRtzCUYo5tdS", "algorithm": tt.algorithm} {
+				if params[key] != want {
+					t.Errorf("%s = %q, want %q", key, params[key], want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseDigestChallenge(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		wantOK bool
+		want   digestChallenge
+	}{
+		{"defaults to MD5", `Digest realm="r", nonce="n"`, true, digestChallenge{realm: "r", nonce: "n", algorithm: "MD5"}},
+		{"session variant", `Digest realm="r", nonce="n", algorithm=SHA-256-sess, qop="auth"`, true, digestChallenge{realm: "r", nonce: "n", algorithm: "SHA-256", sess: true, qop: []string{"auth"}}},
+		{"quoted commas and escapes", `Digest realm="a, \"b\"", nonce="n", userhash=true`, true, digestChallenge{realm: `a, "b"`, nonce: "n", algorithm: "MD5", userhash: true}},
+		{"basic", `Basic realm="r"`, false, digestChallenge{}},
+		{"unsupported algorithm", `Digest realm="r", nonce="n", algorithm=SHA-512-256`, false, digestChallenge{}},
+		{"only auth-int", `Digest realm="r", nonce="n", qop="auth-int"`, false, digestChallenge{}},
+		{"no nonce", `Digest realm="r"`, false, digestChallenge{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseDigestChallenge(tt.header)
+			if ok != tt.wantOK || fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("parseDigestChallenge() = %+v, %v, want %+v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+
+	challenge, _ := chooseDigestChallenge([]string{`Digest realm="r", nonce="n"`, `Basic realm="r"`, `Digest realm="r", nonce="n", algorithm=SHA-256`})
+	if challenge.algorithm != "SHA-256" {
+		t.Errorf("chooseDigestChallenge() picked %s, want SHA-256", challenge.algorithm)
+	}
+}
+
+func TestCheckEndpointDigestAuth(t *testing.T) {
+	md5Hex := func(s string) string {
+		sum := md5.Sum([]byte(s))
+		return hex.EncodeToString(sum[:])
+	}
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		params := parseAuthParams(strings.TrimPrefix(r.Header.Get("Authorization"), "Digest "))
+		ha1 := md5Hex("admin:camera:s3cret")
+		ha2 := md5Hex(r.Method + ":" + r.URL.RequestURI())
+		want := md5Hex(ha1 + ":abc:" + params["nc"] + ":" + params["cnonce"] + ":auth:" + ha2)
+		if params["response"] != want || params["uri"] != r.URL.RequestURI() {
+			w.Header().Set("WWW-Authenticate", `Digest realm="camera", nonce="abc", qop="auth"`)
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	t.Setenv("CAMERA_PASSWORD", "s3cret")
+	tests := []struct {
+		name         string
+		auth         *AuthConfig
+		wantSuccess  bool
+		wantRequests int32
+		wantErr      string
+	}{
+		{"no auth", nil, false, 1, ""},
+		{"digest", &AuthConfig{Type: "digest", Username: "admin", Password: "${CAMERA_PASSWORD}"}, true, 2, ""},
+		{"wrong password", &AuthConfig{Type: "digest", Username: "admin", Password: "guess"}, false, 2, ""},
+		{"unknown type", &AuthConfig{Type: "kerberos"}, false, 0, `unknown auth type "kerberos", expected "digest", "form", or "oauth2"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests.Store(0)
+			target := TargetConfig{StatusCodes: []int{200}, Auth: tt.auth}
+			result := checkEndpoint(server.Client(), server.URL, "/status?verbose=1", target, buildResponseChecks(target), nil, false)
+			if result.Success != tt.wantSuccess || requests.Load() != tt.wantRequests {
+				t.Errorf("success = %v after %d requests, want %v after %d", result.Success, requests.Load(), tt.wantSuccess, tt.wantRequests)
+			}
+			if tt.wantErr != "" && (result.Error == nil || result.Error.Error() != tt.wantErr) {
+				t.Errorf("error = %v, want %s", result.Error, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DiscoveryConfig configures sources that resolve target base URLs at run time
+type DiscoveryConfig struct {
+	Consul *ConsulConfig `toml:"consul"`
+}
+
+// ConsulConfig points vitals at a Consul agent or server
+type ConsulConfig struct {
+	Address    string `toml:"address"`
+	Token      string `toml:"token"`
+	Datacenter string `toml:"datacenter"`
+	// Scheme is used to build base URLs for discovered instances (default http)
+	Scheme string `toml:"scheme"`
+}
+
+// consulServiceEntry is the subset of a /v1/health/service response entry vitals uses
+type consulServiceEntry struct {
+	Node struct {
+		Address string `json:"Address"`
+	} `json:"Node"`
+	Service struct {
+		Address string `json:"Address"`
+		Port    int    `json:"Port"`
+	} `json:"Service"`
+}
+
+// DiscoveryError reports which discovery source failed to resolve
+type DiscoveryError struct {
+	Source string
+	Err    error
+}
+
+func (e *DiscoveryError) Error() string {
+	return e.Err.Error()
+}
+
+// resolveBaseURLs expands a target's discovery references (srv+ base URLs and consul_service)
+// into base URLs for this run
+func resolveBaseURLs(discovery DiscoveryConfig, target TargetConfig) ([]string, error) {
+	baseURLs := make([]string, 0, len(target.BaseURLs))
+	for _, baseURL := range target.BaseURLs {
+		if !strings.HasPrefix(baseURL, "srv+") {
+			baseURLs = append(baseURLs, baseURL)
+			continue
+		}
+		expanded, err := srvBaseURLs(baseURL)
+		if err != nil {
+			return nil, &DiscoveryError{Source: baseURL, Err: err}
+		}
+		baseURLs = append(baseURLs, expanded...)
+	}
+
+	if target.ConsulService == "" {
+		return baseURLs, nil
+	}
+
+	source := "consul://" + target.ConsulService
+	if discovery.Consul == nil {
+		return nil, &DiscoveryError{Source: source, Err: fmt.Errorf("target uses consul_service but [discovery.consul] is not configured")}
+	}
+	discovered, err := consulBaseURLs(*discovery.Consul, target.ConsulService, target.ConsulTags)
+	if err != nil {
+		return nil, &DiscoveryError{Source: source, Err: err}
+	}
+	return append(baseURLs, discovered...), nil
+}
+
+// lookupSRV resolves SRV records, replaceable in tests
+var lookupSRV = net.LookupSRV
+
+// srvBaseURLs expands a base URL like srv+https://_api._tcp.example.com/v1 into one base URL
+// per SRV record, e.g. https://api-1.example.com:8443/v1
+func srvBaseURLs(baseURL string) ([]string, error) {
+	u, err := url.Parse(strings.TrimPrefix(baseURL, "srv+"))
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid SRV base URL %q", baseURL)
+	}
+
+	_, records, err := lookupSRV("", "", u.Hostname())
+	if err != nil {
+		return nil, fmt.Errorf("error resolving SRV records for %s: %s", u.Hostname(), err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("no SRV records for %s", u.Hostname())
+	}
+
+	// Records arrive sorted by priority and randomized by weight, which is fine since all are checked
+	baseURLs := make([]string, 0, len(records))
+	for _, record := range records {
+		expanded := *u
+		expanded.Host = net.JoinHostPort(strings.TrimSuffix(record.Target, "."), strconv.Itoa(int(record.Port)))
+		baseURLs = append(baseURLs, expanded.String())
+	}
+	return baseURLs, nil
+}
+
+// consulBaseURLs asks Consul for the passing instances of a service and returns their base URLs
+func consulBaseURLs(consul ConsulConfig, service string, tags []string) ([]string, error) {
+	address := consul.Address
+	if address == "" {
+		address = "http://127.0.0.1:8500"
+	}
+
+	query := url.Values{"passing": {"true"}}
+	for _, tag := range tags {
+		query.Add("tag", tag)
+	}
+	if consul.Datacenter != "" {
+		query.Set("dc", consul.Datacenter)
+	}
+	endpoint := fmt.Sprintf("%s/v1/health/service/%s?%s", strings.TrimRight(address, "/"), url.PathEscape(service), query.Encode())
+
+	req, err := http.NewRequest("GET", endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating consul request: %s", err)
+	}
+	if consul.Token != "" {
+		req.Header.Set("X-Consul-Token", consul.Token)
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error querying consul for %s: %s", service, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error querying consul for %s: status %d", service, resp.StatusCode)
+	}
+
+	var entries []consulServiceEntry
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("error parsing consul response for %s: %s", service, err)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no healthy instances of %s in consul", service)
+	}
+
+	scheme := consul.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+
+	baseURLs := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		// The service address is optional and defaults to the node's address
+		host := entry.Service.Address
+		if host == "" {
+			host = entry.Node.Address
+		}
+		baseURLs = append(baseURLs, scheme+"://"+net.JoinHostPort(host, strconv.Itoa(entry.Service.Port)))
+	}
+	return baseURLs, nil
+}
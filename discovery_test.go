@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestConsulBaseURLs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/health/service/payments" || r.URL.Query().Get("passing") != "true" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("X-Consul-Token") != "secret" || r.URL.Query().Get("tag") != "prod" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(`[
+			{"Node": {"Address": "10.0.0.1"}, "Service": {"Address": "", "Port": 8080}},
+			{"Node": {"Address": "10.0.0.2"}, "Service": {"Address": "fd00::2", "Port": 8443}}
+		]`))
+	}))
+	defer server.Close()
+
+	consul := ConsulConfig{Address: server.URL, Token: "secret"}
+	got, err := consulBaseURLs(consul, "payments", []string{"prod"})
+	if err != nil {
+		t.Fatalf("consulBaseURLs() error = %v", err)
+	}
+	want := []string{"http://10.0.0.1:8080", "http://[fd00::2]:8443"}
+	if !slices.Equal(got, want) {
+		t.Errorf("consulBaseURLs() = %v, want %v", got, want)
+	}
+
+	if _, err := consulBaseURLs(consul, "unknown", nil); err == nil {
+		t.Error("expected error for unknown service")
+	}
+}
+
+func TestResolveBaseURLs(t *testing.T) {
+	static := TargetConfig{BaseURLs: []string{"https://api.example.com"}}
+	got, err := resolveBaseURLs(DiscoveryConfig{}, static)
+	if err != nil || !slices.Equal(got, static.BaseURLs) {
+		t.Errorf("resolveBaseURLs() static = %v, %v", got, err)
+	}
+
+	if _, err := resolveBaseURLs(DiscoveryConfig{}, TargetConfig{ConsulService: "payments"}); err == nil {
+		t.Error("expected error when consul discovery is not configured")
+	}
+}
+
+func TestSRVBaseURLs(t *testing.T) {
+	original := lookupSRV
+	defer func() { lookupSRV = original }()
+	lookupSRV = func(service, proto, name string) (string, []*net.SRV, error) {
+		if name != "_api._tcp.example.com" {
+			return "", nil, errors.New("no such host")
+		}
+		return "", []*net.SRV{
+			{Target: "api-1.example.com.", Port: 8443, Priority: 10},
+			{Target: "api-2.example.com.", Port: 8443, Priority: 20},
+		}, nil
+	}
+
+	target := TargetConfig{BaseURLs: []string{"https://static.example.com", "srv+https://_api._tcp.example.com/v1"}}
+	got, err := resolveBaseURLs(DiscoveryConfig{}, target)
+	if err != nil {
+		t.Fatalf("resolveBaseURLs() error = %v", err)
+	}
+	want := []string{"https://static.example.com", "https://api-1.example.com:8443/v1", "https://api-2.example.com:8443/v1"}
+	if !slices.Equal(got, want) {
+		t.Errorf("resolveBaseURLs() = %v, want %v", got, want)
+	}
+
+	_, err = resolveBaseURLs(DiscoveryConfig{}, TargetConfig{BaseURLs: []string{"srv+https://_missing._tcp.example.com"}})
+	var discoveryErr *DiscoveryError
+	if !errors.As(err, &discoveryErr) || discoveryErr.Source != "srv+https://_missing._tcp.example.com" {
+		t.Errorf("expected DiscoveryError for missing SRV record, got %v", err)
+	}
+}
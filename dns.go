@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// dnsCache remembers resolved addresses for a TTL so runs with many endpoints on a few hosts
+// resolve each hostname once instead of once per connection
+type dnsCache struct {
+	ttl     time.Duration
+	resolve func(ctx context.Context, host string) ([]net.IPAddr, error)
+
+	mu      sync.Mutex
+	entries map[string]*dnsEntry
+}
+
+// dnsEntry is a lookup in flight or completed; done is closed once addrs and err are set
+type dnsEntry struct {
+	done    chan struct{}
+	addrs   []net.IPAddr
+	err     error
+	expires time.Time
+}
+
+// newDNSCache returns a cache keeping addresses for ttl
+func newDNSCache(ttl time.Duration) *dnsCache {
+	return &dnsCache{ttl: ttl, resolve: net.DefaultResolver.LookupIPAddr, entries: make(map[string]*dnsEntry)}
+}
+
+// lookup returns the addresses of host, resolving it only when no fresh entry exists.
+// Concurrent lookups of the same host share one query, and failures are not cached
+func (c *dnsCache) lookup(ctx context.Context, host string) ([]net.IPAddr, bool, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[host]
+	if ok && !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		ok = false
+	}
+	if !ok {
+		entry = &dnsEntry{done: make(chan struct{})}
+		c.entries[host] = entry
+	}
+	c.mu.Unlock()
+
+	if ok {
+		select {
+		case <-entry.done:
+			return entry.addrs, true, entry.err
+		case <-ctx.Done():
+			return nil, true, ctx.Err()
+		}
+	}
+
+	// The query outlives the request that started it, since others may be waiting on it
+	entry.addrs, entry.err = c.resolve(context.WithoutCancel(ctx), host)
+	c.mu.Lock()
+	if entry.err != nil {
+		delete(c.entries, host)
+	} else {
+		entry.expires = time.Now().Add(c.ttl)
+	}
+	c.mu.Unlock()
+	close(entry.done)
+	return entry.addrs, false, entry.err
+}
+
+// prime resolves hosts concurrently ahead of the checks that will need them
+func (c *dnsCache) prime(ctx context.Context, hosts []string) {
+	var wg sync.WaitGroup
+	for _, host := range hosts {
+		if net.ParseIP(host) != nil {
+			continue
+		}
+		wg.Add(1)
+		go func(host string) {
+			defer wg.Done()
+			c.lookup(ctx, host)
+		}(host)
+	}
+	wg.Wait()
+}
+
+// dialContext dials through dialer using cached addresses, trying each until one connects.
+// It reports the lookup to any httptrace.ClientTrace like the standard dialer does
+func (c *dnsCache) dialContext(dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil || net.ParseIP(host) != nil {
+			return dialer.DialContext(ctx, network, addr)
+		}
+
+		trace := httptrace.ContextClientTrace(ctx)
+		if trace != nil && trace.DNSStart != nil {
+			trace.DNSStart(httptrace.DNSStartInfo{Host: host})
+		}
+		addrs, cached, err := c.lookup(ctx, host)
+		if trace != nil && trace.DNSDone != nil {
+			trace.DNSDone(httptrace.DNSDoneInfo{Addrs: addrs, Err: err, Coalesced: cached})
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		firstErr := error(&net.DNSError{Err: "no such host", Name: host, IsNotFound: true})
+		for i, ip := range addrs {
+			conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+			if err == nil {
+				return conn, nil
+			}
+			if i == 0 {
+				firstErr = err
+			}
+		}
+		return nil, firstErr
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingResolver resolves every host to loopback, or fails for "missing.test"
+func countingResolver(lookups *atomic.Int32) func(ctx context.Context, host string) ([]net.IPAddr, error) {
+	return func(ctx context.Context, host string) ([]net.IPAddr, error) {
+		lookups.Add(1)
+		time.Sleep(10 * time.Millisecond)
+		if host == "missing.test" {
+			return nil, errors.New("no such host")
+		}
+		return []net.IPAddr{{IP: net.IPv4(127, 0, 0, 1)}}, nil
+	}
+}
+
+func TestDNSCacheLookup(t *testing.T) {
+	var lookups atomic.Int32
+	cache := newDNSCache(time.Hour)
+	cache.resolve = countingResolver(&lookups)
+
+	// Concurrent lookups of one host share a single query
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, _, err := cache.lookup(context.Background(), "api.test"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if _, cached, _ := cache.lookup(context.Background(), "api.test"); !cached || lookups.Load() != 1 {
+		t.Errorf("cached = %v after %d lookups, want one cached lookup", cached, lookups.Load())
+	}
+
+	// Failures are retried rather than cached
+	cache.lookup(context.Background(), "missing.test")
+	cache.lookup(context.Background(), "missing.test")
+	if lookups.Load() != 3 {
+		t.Errorf("lookups = %d, want failures to be retried", lookups.Load())
+	}
+
+	// Expired entries are resolved again
+	cache.entries["api.test"].expires = time.Now().Add(-time.Second)
+	if _, cached, _ := cache.lookup(context.Background(), "api.test"); cached || lookups.Load() != 4 {
+		t.Errorf("cached = %v after %d lookups, want a fresh lookup", cached, lookups.Load())
+	}
+}
+
+func TestDNSCacheDial(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	var lookups atomic.Int32
+	transport, cache := newTransport(GlobalConfig{DNSCacheTTL: "1m"})
+	cache.resolve = countingResolver(&lookups)
+	client := &http.Client{Transport: transport, Timeout: time.Second}
+
+	primeDNS(cache, client, []TargetConfig{{BaseURLs: []string{"http://api.test:" + port, "srv+http://_api._tcp.test"}}})
+	for range 3 {
+		// A fresh connection each time, so every request dials
+		transport.CloseIdleConnections()
+		result := checkEndpoint(client, "http://api.test:"+port, "/", TargetConfig{StatusCodes: []int{200}}, ResponseChecks{}, nil, false)
+		if result.Error != nil || !result.Success {
+			t.Fatalf("check failed: %v", result.Error)
+		}
+	}
+	if lookups.Load() != 1 {
+		t.Errorf("lookups = %d, want the primed lookup only", lookups.Load())
+	}
+}
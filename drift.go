@@ -0,0 +1,74 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// normalizeBody removes volatile content from a response body before it is fingerprinted.
+// JSON bodies are re-encoded with sorted keys after dropping the ignored fields, where a
+// dotted entry like "meta.request_id" is a path from the root and a bare name like
+// "timestamp" matches that key at any depth; arrays are traversed transparently. The
+// patterns are then removed from the (re-encoded) text.
+func normalizeBody(body string, ignore []string, patterns []*regexp.Regexp) string {
+	var doc any
+	if err := json.Unmarshal([]byte(body), &doc); err == nil {
+		for _, field := range ignore {
+			if strings.Contains(field, ".") {
+				doc = removePath(doc, strings.Split(field, "."))
+			} else {
+				doc = removeKey(doc, field)
+			}
+		}
+		if encoded, err := json.Marshal(doc); err == nil {
+			body = string(encoded)
+		}
+	}
+
+	for _, pattern := range patterns {
+		body = pattern.ReplaceAllString(body, "")
+	}
+	return body
+}
+
+// removeKey deletes a key from every object in a decoded JSON document
+func removeKey(doc any, key string) any {
+	switch v := doc.(type) {
+	case map[string]any:
+		delete(v, key)
+		for k, child := range v {
+			v[k] = removeKey(child, key)
+		}
+	case []any:
+		for i, child := range v {
+			v[i] = removeKey(child, key)
+		}
+	}
+	return doc
+}
+
+// removePath deletes the field at a dotted path from a decoded JSON document
+func removePath(doc any, path []string) any {
+	switch v := doc.(type) {
+	case map[string]any:
+		if len(path) == 1 {
+			delete(v, path[0])
+		} else if child, ok := v[path[0]]; ok {
+			v[path[0]] = removePath(child, path[1:])
+		}
+	case []any:
+		for i, child := range v {
+			v[i] = removePath(child, path)
+		}
+	}
+	return doc
+}
+
+// contentFingerprint returns the hash stored to detect drift between runs
+func contentFingerprint(body string, target TargetConfig, checks ResponseChecks) string {
+	sum := sha256.Sum256([]byte(normalizeBody(body, target.DriftIgnore, checks.DriftIgnoreRegex)))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func TestNormalizeBody(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		ignore   []string
+		patterns []string
+		want     string
+	}{
+		{
+			name: "json keys are sorted",
+			body: `{"b": 1, "a": 2}`,
+			want: `{"a":2,"b":1}`,
+		},
+		{
+			name:   "bare name matches at any depth",
+			body:   `{"timestamp": 1, "items": [{"id": 1, "timestamp": 2}]}`,
+			ignore: []string{"timestamp"},
+			want:   `{"items":[{"id":1}]}`,
+		},
+		{
+			name:   "dotted path matches from the root",
+			body:   `{"meta": {"request_id": "x", "version": 2}, "request_id": "y"}`,
+			ignore: []string{"meta.request_id"},
+			want:   `{"meta":{"version":2},"request_id":"y"}`,
+		},
+		{
+			name:   "path through arrays",
+			body:   `{"items": [{"id": 1, "etag": "a"}, {"id": 2, "etag": "b"}]}`,
+			ignore: []string{"items.etag"},
+			want:   `{"items":[{"id":1},{"id":2}]}`,
+		},
+		{
+			name:     "patterns on text bodies",
+			body:     "<p>Rendered at 2024-01-01T10:00:00Z</p>",
+			patterns: []string{`\d{4}-\d{2}-\d{2}T[\d:]+Z`},
+			want:     "<p>Rendered at </p>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var patterns []*regexp.Regexp
+			for _, p := range tt.patterns {
+				patterns = append(patterns, regexp.MustCompile(p))
+			}
+			if got := normalizeBody(tt.body, tt.ignore, patterns); got != tt.want {
+				t.Errorf("normalizeBody() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckEndpointDetectsDrift(t *testing.T) {
+	body := `{"version": 1, "generated_at": "10:00"}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, body)
+	}))
+	defer server.Close()
+
+	state, err := loadState(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := TargetConfig{StatusCodes: []int{200}, DetectDrift: true, DriftIgnore: []string{"generated_at"}, FailOnDrift: true}
+
+	steps := []struct {
+		body        string
+		wantChanged bool
+	}{
+		{`{"version": 1, "generated_at": "10:00"}`, false},
+		{`{"generated_at": "10:05", "version": 1}`, false},
+		{`{"version": 2, "generated_at": "10:10"}`, true},
+		{`{"version": 2, "generated_at": "10:15"}`, false},
+	}
+
+	for i, step := range steps {
+		body = step.body
+		result := checkEndpoint(server.Client(), server.URL, "/", target, buildResponseChecks(target), state, false)
+		if result.ContentChanged != step.wantChanged || result.Success == step.wantChanged {
+			t.Errorf("run %d: ContentChanged = %v, Success = %v, want changed %v", i+1, result.ContentChanged, result.Success, step.wantChanged)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EmailConfig sends mail through an SMTP server
+type EmailConfig struct {
+	Host     string   `toml:"host,omitempty"`
+	Port     int      `toml:"port,omitzero"`
+	Username string   `toml:"username,omitempty"`
+	Password string   `toml:"password,omitempty"`
+	From     string   `toml:"from,omitempty"`
+	To       []string `toml:"to,omitempty"`
+
+	// ReportSchedule (cron syntax, e.g. "0 8 * * *") emails the full HTML report in serve mode
+	ReportSchedule string `toml:"report_schedule,omitempty"`
+	ReportSubject  string `toml:"report_subject,omitempty"`
+
+	// TeamTo routes each team's targets to its own recipients, e.g. {payments = ["pay@example.com"]}
+	TeamTo map[string][]string `toml:"team_to,omitempty"`
+}
+
+// NotifiersConfig configures where vitals sends messages for people
+type NotifiersConfig struct {
+	Email *EmailConfig `toml:"email,omitempty"`
+	Slack *SlackConfig `toml:"slack,omitempty"`
+}
+
+// notifiersConfig returns the first [notifiers] section among the configs
+func notifiersConfig(configs []ConfigWithSource) NotifiersConfig {
+	for _, configWithSource := range configs {
+		if notifiers := configWithSource.Config.Notifiers; notifiers.Email != nil || notifiers.Slack != nil {
+			return configWithSource.Config.Notifiers
+		}
+	}
+	return NotifiersConfig{}
+}
+
+// buildEmail renders an HTML message with the headers SMTP servers expect
+func buildEmail(config EmailConfig, subject, html string, at time.Time) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", config.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(config.To, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", at.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/html; charset=UTF-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+	qp := quotedprintable.NewWriter(&b)
+	qp.Write([]byte(html))
+	qp.Close()
+	return b.Bytes()
+}
+
+// sendEmail delivers an HTML message to every recipient, using implicit TLS on port 465 and
+// STARTTLS elsewhere when the server offers it
+func sendEmail(config EmailConfig, subject, html string) error {
+	if config.Host == "" || config.From == "" || len(config.To) == 0 {
+		return fmt.Errorf("email notifier requires host, from, and to")
+	}
+	port := config.Port
+	if port == 0 {
+		port = 587
+	}
+	addr := net.JoinHostPort(config.Host, strconv.Itoa(port))
+	message := buildEmail(config, subject, html, time.Now())
+
+	var auth smtp.Auth
+	if config.Username != "" {
+		password, err := expandSecret(config.Password)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", config.Username, password, config.Host)
+	}
+
+	if port != 465 {
+		if err := smtp.SendMail(addr, auth, config.From, config.To, message); err != nil {
+			return fmt.Errorf("error sending email: %s", err)
+		}
+		return nil
+	}
+
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: 30 * time.Second}, "tcp", addr, &tls.Config{ServerName: config.Host})
+	if err != nil {
+		return fmt.Errorf("error sending email: %s", err)
+	}
+	client, err := smtp.NewClient(conn, config.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("error sending email: %s", err)
+	}
+	defer client.Close()
+
+	if auth != nil {
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("error sending email: %s", err)
+		}
+	}
+	if err := client.Mail(config.From); err != nil {
+		return fmt.Errorf("error sending email: %s", err)
+	}
+	for _, to := range config.To {
+		if err := client.Rcpt(to); err != nil {
+			return fmt.Errorf("error sending email: %s", err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("error sending email: %s", err)
+	}
+	if _, err := w.Write(message); err != nil {
+		return fmt.Errorf("error sending email: %s", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("error sending email: %s", err)
+	}
+	return client.Quit()
+}
+
+// reportSubject returns the subject of a scheduled report, summarizing the checks
+func reportSubject(config EmailConfig, runs []TargetRun) string {
+	if config.ReportSubject != "" {
+		return config.ReportSubject
+	}
+	var total, passed int
+	for _, run := range runs {
+		for _, result := range run.Results {
+			total++
+			if result.State().passing() {
+				passed++
+			}
+		}
+	}
+	return fmt.Sprintf("vitals report: %d/%d checks passing", passed, total)
+}
+
+// teamRuns returns the runs of targets owned by team
+func teamRuns(runs []TargetRun, team string) []TargetRun {
+	var owned []TargetRun
+	for _, run := range runs {
+		if run.Ownership.Team == team {
+			owned = append(owned, run)
+		}
+	}
+	return owned
+}
+
+// renderReport renders runs as the HTML report
+func renderReport(runs []TargetRun) (string, error) {
+	targets := make(map[string]JSONTargetResults, len(runs))
+	for _, run := range runs {
+		targetResults, err := printJSONResults(run.Results, run.TargetName, run.ConfigName, run.Ownership, run.ApdexThreshold, false)
+		if err != nil {
+			return "", err
+		}
+		targetResults.Labels = run.Labels
+		targets[run.Key] = targetResults
+	}
+	return generateHTMLResults(nil, targets, false)
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSMTP accepts one session and returns the envelope and data it received
+func fakeSMTP(t *testing.T) (int, <-chan []string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	received := make(chan []string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var lines []string
+		r := bufio.NewReader(conn)
+		fmt.Fprintf(conn, "220 localhost ESMTP\r\n")
+		inData := false
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				received <- lines
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			if inData {
+				if line == "." {
+					inData = false
+					fmt.Fprintf(conn, "250 OK\r\n")
+				} else {
+					lines = append(lines, "DATA "+line)
+				}
+				continue
+			}
+
+			lines = append(lines, line)
+			switch {
+			case strings.HasPrefix(line, "EHLO"):
+				fmt.Fprintf(conn, "250 localhost\r\n")
+			case line == "DATA":
+				inData = true
+				fmt.Fprintf(conn, "354 Go ahead\r\n")
+			case line == "QUIT":
+				fmt.Fprintf(conn, "221 Bye\r\n")
+				received <- lines
+				return
+			default:
+				fmt.Fprintf(conn, "250 OK\r\n")
+			}
+		}
+	}()
+	return ln.Addr().(*net.TCPAddr).Port, received
+}
+
+func TestSendEmail(t *testing.T) {
+	port, received := fakeSMTP(t)
+	config := EmailConfig{Host: "127.0.0.1", Port: port, From: "vitals@example.com", To: []string{"ops@example.com", "lead@example.com"}}
+
+	if err := sendEmail(config, "vitals report", "<h1>All good</h1>"); err != nil {
+		t.Fatal(err)
+	}
+
+	session := strings.Join(<-received, "\n")
+	for _, want := range []string{
+		"MAIL FROM:<vitals@example.com>",
+		"RCPT TO:<ops@example.com>",
+		"RCPT TO:<lead@example.com>",
+		"DATA Subject: vitals report",
+		"DATA Content-Type: text/html; charset=UTF-8",
+		"DATA <h1>All good</h1>",
+	} {
+		if !strings.Contains(session, want) {
+			t.Errorf("session is missing %q:\n%s", want, session)
+		}
+	}
+}
+
+func TestReportSubject(t *testing.T) {
+	runs := []TargetRun{{Results: []EndpointResult{{Success: true}, {Success: false}, {Success: true}}}}
+	if got := reportSubject(EmailConfig{}, runs); got != "vitals report: 2/3 checks passing" {
+		t.Errorf("reportSubject() = %q", got)
+	}
+	if got := reportSubject(EmailConfig{ReportSubject: "Morning digest"}, runs); got != "Morning digest" {
+		t.Errorf("reportSubject() = %q", got)
+	}
+}
+
+func TestBuildEmailHeaders(t *testing.T) {
+	at := time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)
+	message := string(buildEmail(EmailConfig{From: "a@example.com", To: []string{"b@example.com"}}, "Hi", "x", at))
+	if !strings.HasPrefix(message, "From: a@example.com\r\nTo: b@example.com\r\nSubject: Hi\r\nDate: Mon, 01 Jan 2024 08:00:00 +0000\r\n") {
+		t.Errorf("unexpected headers:\n%s", message)
+	}
+}
+
+func TestTeamRuns(t *testing.T) {
+	runs := []TargetRun{
+		{Key: "a.toml::pay", Ownership: Ownership{Team: "payments"}},
+		{Key: "a.toml::web", Ownership: Ownership{Team: "web"}},
+		{Key: "a.toml::ledger", Ownership: Ownership{Team: "payments"}},
+		{Key: "a.toml::misc"},
+	}
+
+	owned := teamRuns(runs, "payments")
+	if len(owned) != 2 || owned[0].Key != "a.toml::pay" || owned[1].Key != "a.toml::ledger" {
+		t.Errorf("teamRuns(payments) = %+v", owned)
+	}
+	if owned := teamRuns(runs, "search"); len(owned) != 0 {
+		t.Errorf("teamRuns(search) = %+v, want none", owned)
+	}
+}
@@ -0,0 +1,396 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EndpointConfig is one endpoint of a target, written in the config either as a plain path
+// string or as a table that also gives it a display name, description, timeout, method,
+// request body, headers, expected status codes, or validation script:
+//
+//	endpoints = ["/health", { path = "/api/orders", name = "Checkout - create order" }]
+//
+// Tables can also be written as [[targets.NAME.endpoint]] blocks, which are checked after the
+// endpoints list
+type EndpointConfig struct {
+	Path        string
+	Name        string
+	Description string
+
+	// Timeout in seconds replaces the client timeout for this endpoint's requests
+	Timeout int
+
+	// Method replaces the target's HTTP method for this endpoint, e.g. "POST"
+	Method string
+
+	// Body or BodyFile replaces the target's request body for this endpoint
+	Body     string
+	BodyFile string
+
+	// Headers are added to the target's headers, replacing those with the same name, and
+	// QueryParams to the target's query parameters
+	Headers     map[string]string
+	QueryParams map[string]string
+
+	// StatusCodes replace the target's status codes and ranges for this endpoint
+	StatusCodes []int
+
+	// ExpectFailure inverts this endpoint's check, as the target's expect_failure does for all
+	ExpectFailure bool
+
+	// ExpectedContentType replaces the media type the target expects, e.g. "text/csv"
+	ExpectedContentType string
+
+	// Script replaces the target's Lua validation script for this endpoint
+	Script string
+}
+
+// pathEndpoints builds plain endpoints from paths
+func pathEndpoints(paths ...string) []EndpointConfig {
+	endpoints := make([]EndpointConfig, len(paths))
+	for i, path := range paths {
+		endpoints[i] = EndpointConfig{Path: path}
+	}
+	return endpoints
+}
+
+// endpointPaths returns the path of every endpoint
+func endpointPaths(endpoints []EndpointConfig) []string {
+	paths := make([]string, len(endpoints))
+	for i, endpoint := range endpoints {
+		paths[i] = endpoint.Path
+	}
+	return paths
+}
+
+// UnmarshalTOML accepts a path string or a table with path, name, description, timeout,
+// method, body or body_file, headers, query_params, status_codes, expect_failure,
+// expected_content_type, and script
+func (e *EndpointConfig) UnmarshalTOML(data any) error {
+	switch value := data.(type) {
+	case string:
+		*e = EndpointConfig{Path: value}
+		return nil
+	case map[string]any:
+		*e = EndpointConfig{}
+		for key, field := range value {
+			switch key {
+			case "timeout":
+				seconds, ok := field.(int64)
+				if !ok || seconds <= 0 {
+					return fmt.Errorf("endpoint timeout must be a positive number of seconds")
+				}
+				e.Timeout = int(seconds)
+				continue
+			case "headers":
+				headers, err := stringTable(field, "header")
+				if err != nil {
+					return err
+				}
+				e.Headers = headers
+				continue
+			case "query_params":
+				params, err := stringTable(field, "query parameter")
+				if err != nil {
+					return err
+				}
+				e.QueryParams = params
+				continue
+			case "expect_failure":
+				expect, ok := field.(bool)
+				if !ok {
+					return fmt.Errorf("endpoint expect_failure must be true or false")
+				}
+				e.ExpectFailure = expect
+				continue
+			case "status_codes":
+				codes, ok := field.([]any)
+				if !ok {
+					return fmt.Errorf("endpoint status_codes must be an array of status codes")
+				}
+				for _, code := range codes {
+					n, ok := code.(int64)
+					if !ok || n < 100 || n > 599 {
+						return fmt.Errorf("endpoint status code %v must be between 100 and 599", code)
+					}
+					e.StatusCodes = append(e.StatusCodes, int(n))
+				}
+				continue
+			}
+			s, ok := field.(string)
+			if !ok {
+				return fmt.Errorf("endpoint %s must be a string", key)
+			}
+			switch key {
+			case "path":
+				e.Path = s
+			case "name":
+				e.Name = s
+			case "description":
+				e.Description = s
+			case "method":
+				e.Method = s
+			case "body":
+				e.Body = s
+			case "body_file":
+				e.BodyFile = s
+			case "expected_content_type":
+				e.ExpectedContentType = s
+			case "script":
+				e.Script = s
+			default:
+				return fmt.Errorf("unknown endpoint field %q", key)
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("endpoint must be a string or a table, got %T", data)
+	}
+}
+
+// withQueryParams appends encoded query parameters to a URL, after any query it already has
+// and before its fragment
+func withQueryParams(rawURL string, params map[string]string) string {
+	if len(params) == 0 {
+		return rawURL
+	}
+	query := make(url.Values, len(params))
+	for key, value := range params {
+		query.Set(key, value)
+	}
+
+	rawURL, fragment, hasFragment := strings.Cut(rawURL, "#")
+	separator := "?"
+	if strings.Contains(rawURL, "?") {
+		separator = "&"
+		if strings.HasSuffix(rawURL, "?") || strings.HasSuffix(rawURL, "&") {
+			separator = ""
+		}
+	}
+	rawURL += separator + query.Encode()
+	if hasFragment {
+		rawURL += "#" + fragment
+	}
+	return rawURL
+}
+
+// stringTable decodes an endpoint table of strings, such as its headers
+func stringTable(field any, what string) (map[string]string, error) {
+	table, ok := field.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("endpoint %ss must be a table", what)
+	}
+	values := make(map[string]string, len(table))
+	for name, value := range table {
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("endpoint %s %s must be a string", what, name)
+		}
+		values[name] = s
+	}
+	return values, nil
+}
+
+// inlineTable writes a table of strings as a TOML inline table with sorted keys
+func inlineTable(values map[string]string) string {
+	entries := make([]string, 0, len(values))
+	for _, name := range slices.Sorted(maps.Keys(values)) {
+		entries = append(entries, strconv.Quote(name)+" = "+strconv.Quote(values[name]))
+	}
+	return "{ " + strings.Join(entries, ", ") + " }"
+}
+
+// MarshalTOML writes plain endpoints as strings and the others as inline tables
+func (e EndpointConfig) MarshalTOML() ([]byte, error) {
+	if reflect.DeepEqual(e, EndpointConfig{Path: e.Path}) {
+		return []byte(strconv.Quote(e.Path)), nil
+	}
+
+	parts := []string{"path = " + strconv.Quote(e.Path)}
+	if e.Name != "" {
+		parts = append(parts, "name = "+strconv.Quote(e.Name))
+	}
+	if e.Description != "" {
+		parts = append(parts, "description = "+strconv.Quote(e.Description))
+	}
+	if e.Timeout > 0 {
+		parts = append(parts, "timeout = "+strconv.Itoa(e.Timeout))
+	}
+	if e.Method != "" {
+		parts = append(parts, "method = "+strconv.Quote(e.Method))
+	}
+	if e.Body != "" {
+		parts = append(parts, "body = "+strconv.Quote(e.Body))
+	}
+	if e.BodyFile != "" {
+		parts = append(parts, "body_file = "+strconv.Quote(e.BodyFile))
+	}
+	if len(e.Headers) > 0 {
+		parts = append(parts, "headers = "+inlineTable(e.Headers))
+	}
+	if len(e.QueryParams) > 0 {
+		parts = append(parts, "query_params = "+inlineTable(e.QueryParams))
+	}
+	if len(e.StatusCodes) > 0 {
+		codes := make([]string, len(e.StatusCodes))
+		for i, code := range e.StatusCodes {
+			codes[i] = strconv.Itoa(code)
+		}
+		parts = append(parts, "status_codes = ["+strings.Join(codes, ", ")+"]")
+	}
+	if e.ExpectFailure {
+		parts = append(parts, "expect_failure = true")
+	}
+	if e.ExpectedContentType != "" {
+		parts = append(parts, "expected_content_type = "+strconv.Quote(e.ExpectedContentType))
+	}
+	if e.Script != "" {
+		parts = append(parts, "script = "+strconv.Quote(e.Script))
+	}
+	return []byte("{ " + strings.Join(parts, ", ") + " }"), nil
+}
+
+// forEndpoint returns the target as it applies to one of its endpoints, with the endpoint's
+// method, body, headers, query parameters, status codes, and script in place of the target's,
+// and inverted when the endpoint expects to fail
+func (t TargetConfig) forEndpoint(endpoint EndpointConfig) TargetConfig {
+	t.ExpectFailure = t.ExpectFailure || endpoint.ExpectFailure
+	if endpoint.Method != "" {
+		t.Method = endpoint.Method
+	}
+	if endpoint.Body != "" || endpoint.BodyFile != "" {
+		t.Body, t.BodyFile = endpoint.Body, endpoint.BodyFile
+	}
+	if len(endpoint.Headers) > 0 {
+		t.Headers = mergeHeaders(t.Headers, endpoint.Headers)
+	}
+	if len(endpoint.QueryParams) > 0 {
+		params := maps.Clone(t.QueryParams)
+		if params == nil {
+			params = make(map[string]string, len(endpoint.QueryParams))
+		}
+		maps.Copy(params, endpoint.QueryParams)
+		t.QueryParams = params
+	}
+	if len(endpoint.StatusCodes) > 0 {
+		t.StatusCodes = endpoint.StatusCodes
+		t.StatusRanges = nil
+	}
+	if endpoint.ExpectedContentType != "" {
+		t.ExpectedContentType = endpoint.ExpectedContentType
+	}
+	if endpoint.Script != "" {
+		t.Script = endpoint.Script
+	}
+	return t
+}
+
+// mergeHeaders returns the headers with the overrides added, replacing headers of the same
+// name in any case. Neither map is changed
+func mergeHeaders(headers, overrides map[string]string) map[string]string {
+	if len(overrides) == 0 {
+		return headers
+	}
+	merged := make(map[string]string, len(headers)+len(overrides))
+	maps.Copy(merged, headers)
+	for name, value := range overrides {
+		for existing := range merged {
+			if strings.EqualFold(existing, name) {
+				delete(merged, existing)
+			}
+		}
+		merged[name] = value
+	}
+	return merged
+}
+
+// forEndpoint returns the checks as they apply to one of a target's endpoints, without the
+// target's status ranges when the endpoint has its own status codes, and with the endpoint's
+// script in place of the target's
+func (c ResponseChecks) forEndpoint(endpoint EndpointConfig) ResponseChecks {
+	if len(endpoint.StatusCodes) > 0 {
+		c.StatusRanges = nil
+	}
+	if endpoint.Script != "" {
+		c.Script = c.EndpointScripts[endpoint.Script]
+		// The target's other checks may not need the body, but the script does
+		c.SkipBody = false
+	}
+	return c
+}
+
+// mergeEndpointBlocks appends the [[targets.NAME.endpoint]] blocks of every target to its
+// endpoints list
+func (c *Config) mergeEndpointBlocks() {
+	for name, target := range c.Targets {
+		if len(target.EndpointBlocks) == 0 {
+			continue
+		}
+		target.Endpoints = append(target.Endpoints, target.EndpointBlocks...)
+		target.EndpointBlocks = nil
+		c.Targets[name] = target
+	}
+}
+
+// withEndpointTimeout returns a copy of client that gives each request a deadline of timeout
+// instead of the client-wide timeout, so one slow endpoint doesn't set the timeout of all
+func withEndpointTimeout(client *http.Client, timeout time.Duration) *http.Client {
+	transport := client.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	timed := *client
+	timed.Timeout = 0
+	timed.Transport = &deadlineTransport{transport: transport, timeout: timeout}
+	return &timed
+}
+
+// deadlineTransport sends each request with its own context deadline, which also covers
+// reading the response body
+type deadlineTransport struct {
+	transport http.RoundTripper
+	timeout   time.Duration
+}
+
+// RoundTrip sends the request with a deadline that is released when the body is closed
+func (t *deadlineTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+	resp, err := t.transport.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelOnClose releases a request's context once its response body is closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+// Close closes the body and cancels the request context
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// label names a result in notifications: its display name followed by the URL, or just the URL
+func (r EndpointResult) label() string {
+	if r.Name == "" {
+		return r.URL
+	}
+	return r.Name + " (" + r.URL + ")"
+}
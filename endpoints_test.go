@@ -0,0 +1,421 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+func TestEndpointConfigDecode(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		want    []EndpointConfig
+		wantErr string
+	}{
+		{
+			name:   "plain paths",
+			config: `endpoints = ["/health", ""]`,
+			want:   pathEndpoints("/health", ""),
+		},
+		{
+			name:   "mixed strings and tables",
+			config: `endpoints = ["/health", { path = "/api/orders", name = "Checkout - create order", description = "Places a test order" }]`,
+			want: []EndpointConfig{
+				{Path: "/health"},
+				{Path: "/api/orders", Name: "Checkout - create order", Description: "Places a test order"},
+			},
+		},
+		{
+			name:   "timeout",
+			config: `endpoints = [{ path = "/reports/daily", timeout = 30 }]`,
+			want:   []EndpointConfig{{Path: "/reports/daily", Timeout: 30}},
+		},
+		{
+			name:   "method",
+			config: `endpoints = [{ path = "/orders", method = "POST" }]`,
+			want:   []EndpointConfig{{Path: "/orders", Method: "POST"}},
+		},
+		{
+			name:   "body",
+			config: `endpoints = [{ path = "/search", method = "POST", body = '{"q": "health"}' }, { path = "/import", body_file = "payload.json" }]`,
+			want:   []EndpointConfig{{Path: "/search", Method: "POST", Body: `{"q": "health"}`}, {Path: "/import", BodyFile: "payload.json"}},
+		},
+		{
+			name:   "headers and status codes",
+			config: `endpoints = [{ path = "/orders", headers = { "X-Tenant" = "acme" }, status_codes = [201, 202] }]`,
+			want:   []EndpointConfig{{Path: "/orders", Headers: map[string]string{"X-Tenant": "acme"}, StatusCodes: []int{201, 202}}},
+		},
+		{
+			name: "endpoint blocks",
+			config: `endpoints = ["/health"]
+
+[[endpoint]]
+path = "/orders"
+method = "POST"
+status_codes = [201]
+
+[endpoint.headers]
+X-Tenant = "acme"`,
+			want: []EndpointConfig{{Path: "/health"}, {Path: "/orders", Method: "POST", Headers: map[string]string{"X-Tenant": "acme"}, StatusCodes: []int{201}}},
+		},
+		{
+			name:   "query params",
+			config: `endpoints = [{ path = "/search", query_params = { q = "a&b c" } }]`,
+			want:   []EndpointConfig{{Path: "/search", QueryParams: map[string]string{"q": "a&b c"}}},
+		},
+		{
+			name:   "expected content type",
+			config: `endpoints = ["/health", { path = "/export", expected_content_type = "text/csv" }]`,
+			want:   []EndpointConfig{{Path: "/health"}, {Path: "/export", ExpectedContentType: "text/csv"}},
+		},
+		{
+			name:   "script",
+			config: `endpoints = [{ path = "/balance", script = "checks/balance.lua" }]`,
+			want:   []EndpointConfig{{Path: "/balance", Script: "checks/balance.lua"}},
+		},
+		{
+			name:    "invalid status code",
+			config:  `endpoints = [{ path = "/", status_codes = [1000] }]`,
+			wantErr: "endpoint status code 1000 must be between 100 and 599",
+		},
+		{
+			name:    "non-string header",
+			config:  `endpoints = [{ path = "/", headers = { X-Count = 1 } }]`,
+			wantErr: "endpoint header X-Count must be a string",
+		},
+		{
+			name:    "non-positive timeout",
+			config:  `endpoints = [{ path = "/", timeout = 0 }]`,
+			wantErr: "endpoint timeout must be a positive number of seconds",
+		},
+		{
+			name:    "unknown field",
+			config:  `endpoints = [{ path = "/", title = "Home" }]`,
+			wantErr: `unknown endpoint field "title"`,
+		},
+		{
+			name:    "non-string field",
+			config:  `endpoints = [{ path = "/", name = 1 }]`,
+			wantErr: "endpoint name must be a string",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var config Config
+			_, err := toml.Decode("[targets.api]\n"+strings.ReplaceAll(tt.config, "[endpoint", "[targets.api.endpoint"), &config)
+			config.mergeEndpointBlocks()
+			target := config.Targets["api"]
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Decode() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(target.Endpoints, tt.want) {
+				t.Errorf("Endpoints = %+v, want %+v", target.Endpoints, tt.want)
+			}
+		})
+	}
+}
+
+func TestEndpointConfigEncode(t *testing.T) {
+	target := TargetConfig{Endpoints: []EndpointConfig{
+		{Path: "/health"},
+		{Path: "/api/orders", Name: `Checkout "create"`},
+		{Path: "/reports", Timeout: 30},
+		{Path: "/sessions", Method: "DELETE"},
+		{Path: "/search", Method: "POST", Body: `{"q": "health"}`},
+		{Path: "/orders", Headers: map[string]string{"X-Tenant": "acme", "Accept": "text/csv"}, StatusCodes: []int{201, 202}},
+		{Path: "/export", ExpectedContentType: "text/csv"},
+		{Path: "/balance", Script: "checks/balance.lua"},
+	}}
+
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(target); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"/health"`) || !strings.Contains(buf.String(), `{ path = "/api/orders", name = "Checkout \"create\"" }`) || !strings.Contains(buf.String(), `{ path = "/reports", timeout = 30 }`) || !strings.Contains(buf.String(), `{ path = "/sessions", method = "DELETE" }`) ||
+		!strings.Contains(buf.String(), `{ path = "/orders", headers = { "Accept" = "text/csv", "X-Tenant" = "acme" }, status_codes = [201, 202] }`) ||
+		!strings.Contains(buf.String(), `{ path = "/export", expected_content_type = "text/csv" }`) ||
+		!strings.Contains(buf.String(), `{ path = "/balance", script = "checks/balance.lua" }`) {
+		t.Errorf("unexpected encoding:\n%s", buf.String())
+	}
+
+	var decoded TargetConfig
+	if _, err := toml.Decode(buf.String(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded.Endpoints, target.Endpoints) {
+		t.Errorf("round trip = %+v, want %+v", decoded.Endpoints, target.Endpoints)
+	}
+}
+
+func TestEndpointTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	// The slow endpoint's own timeout overrides the much shorter client timeout
+	client := server.Client()
+	client.Timeout = 50 * time.Millisecond
+	target := TargetConfig{
+		BaseURLs:    []string{server.URL},
+		Endpoints:   []EndpointConfig{{Path: "/fast"}, {Path: "/slow", Timeout: 5}},
+		StatusCodes: []int{200},
+	}
+	for _, result := range processTarget(context.Background(), client, target, buildResponseChecks(target), nil, nil, false) {
+		if passed := result.Error == nil && result.Success; passed != (result.Endpoint == "/slow") {
+			t.Errorf("%s: passed = %v, error %v", result.Endpoint, passed, result.Error)
+		}
+	}
+
+	// The endpoint deadline applies per request, not to the client
+	timed := withEndpointTimeout(client, 50*time.Millisecond)
+	if timed.Timeout != 0 || client.Timeout == 0 {
+		t.Errorf("timeouts = %s and %s, want only the original client's", timed.Timeout, client.Timeout)
+	}
+	if _, err := timed.Get(server.URL); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Get() error = %v, want the deadline exceeded", err)
+	}
+}
+
+func TestEndpointMethod(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != r.URL.Query().Get("want") {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	defer server.Close()
+
+	target := TargetConfig{
+		BaseURLs:    []string{server.URL},
+		Endpoints:   []EndpointConfig{{Path: "/?want=PUT"}, {Path: "/?want=HEAD", Method: "head"}, {Path: "/?want=DELETE", Method: "DELETE"}},
+		StatusCodes: []int{200},
+		Method:      "put",
+	}
+	for _, result := range processTarget(context.Background(), server.Client(), target, buildResponseChecks(target), nil, nil, false) {
+		if want := strings.TrimPrefix(result.Endpoint, "/?want="); !result.Success || result.Method != want {
+			t.Errorf("%s: success = %v with method %s, error %v", result.Endpoint, result.Success, result.Method, result.Error)
+		}
+	}
+
+	// A CORS preflight is always an OPTIONS request
+	target.CORS = &CORSConfig{}
+	if got := requestMethod(target.forEndpoint(EndpointConfig{Method: "POST"})); got != "OPTIONS" {
+		t.Errorf("requestMethod() = %s, want OPTIONS", got)
+	}
+}
+
+func TestEndpointHeadersAndStatusCodes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/orders" {
+			w.WriteHeader(http.StatusCreated)
+		}
+	}))
+	defer server.Close()
+
+	target := TargetConfig{
+		BaseURLs:     []string{server.URL},
+		Headers:      map[string]string{"x-tenant": "default", "Accept": "application/json"},
+		StatusRanges: []string{"200-299"},
+		Endpoints: []EndpointConfig{
+			{Path: "/health", StatusCodes: []int{201}},
+			{Path: "/orders", Headers: map[string]string{"X-Tenant": "acme"}, StatusCodes: []int{201}},
+		},
+	}
+	for _, result := range processTarget(context.Background(), server.Client(), target, buildResponseChecks(target), nil, nil, false) {
+		// The endpoint's status codes replace the target's 2xx range, so /health's 200 fails
+		if result.Success != (result.Endpoint == "/orders") {
+			t.Errorf("%s: success = %v with status %d", result.Endpoint, result.Success, result.StatusCode)
+		}
+	}
+
+	// Endpoint headers replace target headers case-insensitively and keep the others
+	headers := target.forEndpoint(target.Endpoints[1]).Headers
+	if !reflect.DeepEqual(headers, map[string]string{"X-Tenant": "acme", "Accept": "application/json"}) {
+		t.Errorf("headers = %v", headers)
+	}
+	if target.Headers["x-tenant"] != "default" {
+		t.Errorf("forEndpoint() changed the target's headers: %v", target.Headers)
+	}
+}
+
+func TestEndpointExpectedContentType(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/export" {
+			w.Header().Set("Content-Type", "text/csv")
+		} else {
+			w.Header().Set("Content-Type", "application/json")
+		}
+	}))
+	defer server.Close()
+
+	// /export replaces the target's media type, and the other endpoints inherit it
+	target := TargetConfig{
+		BaseURLs:            []string{server.URL},
+		StatusCodes:         []int{200},
+		ExpectedContentType: "application/json",
+		Endpoints: []EndpointConfig{
+			{Path: "/health"},
+			{Path: "/export", ExpectedContentType: "text/csv"},
+			{Path: "/report", ExpectedContentType: "text/csv"},
+		},
+	}
+	for _, result := range processTarget(context.Background(), server.Client(), target, buildResponseChecks(target), nil, nil, false) {
+		if result.Success != (result.Endpoint != "/report") {
+			t.Errorf("%s: success = %v (%s)", result.Endpoint, result.Success, result.FailureReason)
+		}
+	}
+}
+
+func TestEndpointScript(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"balance": 10}`)
+	}))
+	defer server.Close()
+
+	balance := writeScript(t, `function check(r) return string.find(r.body, "balance") ~= nil, "no balance" end`)
+	missing := filepath.Join(t.TempDir(), "missing.lua")
+	target := TargetConfig{
+		BaseURLs:    []string{server.URL},
+		StatusCodes: []int{200},
+		Endpoints: []EndpointConfig{
+			{Path: "/health"},
+			{Path: "/balance", Script: balance},
+			{Path: "/broken", Script: missing},
+		},
+	}
+	// The target's checks don't need the body, but the endpoint scripts do
+	checks := buildResponseChecks(target)
+	checks.SkipBody = bodyUnused(target, checks)
+
+	for _, result := range processTarget(context.Background(), server.Client(), target, checks, nil, nil, false) {
+		if result.Success != (result.Endpoint != "/broken") {
+			t.Errorf("%s: success = %v (%s)", result.Endpoint, result.Success, result.FailureReason)
+		}
+	}
+}
+
+func TestGlobalHeaders(t *testing.T) {
+	config := Config{Global: GlobalConfig{
+		UserAgent: "gateway-probe",
+		Headers:   map[string]string{"X-Api-Key": "global", "Accept": "application/json"},
+	}}
+	target, _ := resolveTarget(config, TargetConfig{Headers: map[string]string{"x-api-key": "team"}}, runOptions{})
+	if want := map[string]string{"x-api-key": "team", "Accept": "application/json"}; !reflect.DeepEqual(target.Headers, want) {
+		t.Errorf("headers = %v, want %v", target.Headers, want)
+	}
+	if target.UserAgent != "gateway-probe" {
+		t.Errorf("user agent = %s, want the global one", target.UserAgent)
+	}
+	if config.Global.Headers["X-Api-Key"] != "global" {
+		t.Errorf("resolveTarget() changed the global headers: %v", config.Global.Headers)
+	}
+
+	target, _ = resolveTarget(config, TargetConfig{UserAgent: "custom"}, runOptions{})
+	if !reflect.DeepEqual(target.Headers, config.Global.Headers) || target.UserAgent != "custom" {
+		t.Errorf("headers = %v and user agent %s, want the global headers and the target's user agent", target.Headers, target.UserAgent)
+	}
+}
+
+func TestWithQueryParams(t *testing.T) {
+	params := map[string]string{"q": "café & co", "page": "2"}
+	tests := []struct {
+		rawURL string
+		want   string
+	}{
+		{"https://example.com/search", "https://example.com/search?page=2&q=caf%C3%A9+%26+co"},
+		{"https://example.com/search?sort=asc", "https://example.com/search?sort=asc&page=2&q=caf%C3%A9+%26+co"},
+		{"https://example.com/search?", "https://example.com/search?page=2&q=caf%C3%A9+%26+co"},
+		{"https://example.com/#/search", "https://example.com/?page=2&q=caf%C3%A9+%26+co#/search"},
+	}
+	for _, tt := range tests {
+		if got := withQueryParams(tt.rawURL, params); got != tt.want {
+			t.Errorf("withQueryParams(%q) = %s, want %s", tt.rawURL, got, tt.want)
+		}
+	}
+	if got := withQueryParams("https://example.com", nil); got != "https://example.com" {
+		t.Errorf("withQueryParams() without params = %s", got)
+	}
+
+	// Endpoint parameters are added to the target's, replacing those with the same name
+	target := TargetConfig{QueryParams: map[string]string{"api_key": "k", "page": "1"}}
+	got := target.forEndpoint(EndpointConfig{QueryParams: map[string]string{"page": "2"}}).QueryParams
+	if !reflect.DeepEqual(got, map[string]string{"api_key": "k", "page": "2"}) || target.QueryParams["page"] != "1" {
+		t.Errorf("query params = %v, target's %v", got, target.QueryParams)
+	}
+}
+
+func TestEndpointBody(t *testing.T) {
+	var received string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = r.Header.Get("Content-Type") + " " + string(body)
+	}))
+	defer server.Close()
+
+	bodyFile := filepath.Join(t.TempDir(), "payload.json")
+	os.WriteFile(bodyFile, []byte(`{"from": "file"}`), 0o644)
+
+	tests := []struct {
+		name     string
+		target   TargetConfig
+		endpoint EndpointConfig
+		want     string
+		wantErr  string
+	}{
+		{"no body", TargetConfig{}, EndpointConfig{}, " ", ""},
+		{"JSON", TargetConfig{Body: `{"q": 1}`}, EndpointConfig{}, `application/json {"q": 1}`, ""},
+		{"form", TargetConfig{Body: "user=monitor&q=1"}, EndpointConfig{}, "application/x-www-form-urlencoded user=monitor&q=1", ""},
+		{"header wins", TargetConfig{Body: "ping", Headers: map[string]string{"Content-Type": "text/plain"}}, EndpointConfig{}, "text/plain ping", ""},
+		{"endpoint file replaces target body", TargetConfig{Body: "target"}, EndpointConfig{BodyFile: bodyFile}, `application/json {"from": "file"}`, ""},
+		{"missing file", TargetConfig{BodyFile: "missing.json"}, EndpointConfig{}, "", "error reading body file missing.json: open missing.json: no such file or directory"},
+		{"body and file", TargetConfig{Body: "a", BodyFile: bodyFile}, EndpointConfig{}, "", "body and body_file can't both be set"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.target.Method = "POST"
+			tt.target.StatusCodes = []int{200}
+			received = ""
+			target := tt.target.forEndpoint(tt.endpoint)
+			result := checkEndpoint(server.Client(), server.URL, "/", target, buildResponseChecks(target), nil, false)
+			if tt.wantErr != "" {
+				if result.Error == nil || result.Error.Error() != tt.wantErr {
+					t.Errorf("error = %v, want %s", result.Error, tt.wantErr)
+				}
+				return
+			}
+			if result.Error != nil || received != tt.want {
+				t.Errorf("server received %q (error %v), want %q", received, result.Error, tt.want)
+			}
+		})
+	}
+}
+
+func TestEndpointResultLabel(t *testing.T) {
+	if got := (EndpointResult{URL: "https://example.com/health"}).label(); got != "https://example.com/health" {
+		t.Errorf("label() = %q", got)
+	}
+	named := EndpointResult{URL: "https://example.com/api/orders", Name: "Checkout"}
+	if got := named.label(); got != "Checkout (https://example.com/api/orders)" {
+		t.Errorf("label() = %q", got)
+	}
+}
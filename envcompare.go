@@ -0,0 +1,221 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Environment is one side of `vitals compare`: a name and the configs checked for it
+type Environment struct {
+	Name    string
+	Configs []ConfigWithSource
+}
+
+// EnvDiff is an endpoint whose result differs materially between two environments; a nil
+// result means the endpoint isn't configured in that environment
+type EnvDiff struct {
+	Target   string
+	Endpoint string
+	Results  [2]*EndpointResult
+	Reason   string
+}
+
+// envConfigFile is the config file an --env name refers to, e.g. vitals.staging.toml
+func envConfigFile(name string) string {
+	return "vitals." + name + ".toml"
+}
+
+// runCompare implements `vitals compare`, exiting 1 when the environments differ
+func runCompare(args []string) int {
+	fs := flag.NewFlagSet("compare", flag.ExitOnError)
+	var configFiles, envs, only []string
+	fs.Var((*stringSlice)(&configFiles), "config", "Config file of an environment (give two)")
+	fs.Var((*stringSlice)(&configFiles), "c", "Config file of an environment (shorthand)")
+	fs.Var((*stringSlice)(&envs), "env", "Environment to compare, loaded from vitals.<env>.toml (give two)")
+	fs.Var((*stringSlice)(&only), "for", "Target to compare, by name (repeatable, default all)")
+	timeout := fs.Int("timeout", 0, "Override the global timeout in seconds")
+	fs.IntVar(timeout, "t", 0, "Override the global timeout in seconds (shorthand)")
+	factor := fs.Float64("latency-factor", 2, "Report endpoints at least this many times slower in one environment")
+	minDelta := fs.Duration("min-latency-diff", 100*time.Millisecond, "Ignore latency differences smaller than this")
+	fs.Parse(args)
+
+	for _, env := range envs {
+		configFiles = append(configFiles, envConfigFile(env))
+	}
+	configFiles = append(configFiles, fs.Args()...)
+	if len(configFiles) != 2 {
+		fmt.Fprintf(os.Stderr, "Expected two environments to compare, e.g. --env staging --env prod, got %d\n", len(configFiles))
+		return 2
+	}
+
+	names := envs
+	if len(names) != 2 {
+		names = []string{filepath.Base(configFiles[0]), filepath.Base(configFiles[1])}
+	}
+	environments, err := loadEnvironments(names, configFiles)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 2
+	}
+
+	// Both environments are checked at once so they see the same moment
+	var runs [2][]TargetRun
+	var wg sync.WaitGroup
+	for i := range environments {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			runs[i] = runChecks(environments[i].Configs, runOptions{timeout: *timeout, only: only})
+		}()
+	}
+	wg.Wait()
+
+	diffs := diffEnvironments(runs[0], runs[1], environments[0].Name, environments[1].Name, *factor, *minDelta)
+	if len(diffs) == 0 {
+		fmt.Printf("No material differences between %s and %s\n", environments[0].Name, environments[1].Name)
+		return 0
+	}
+	printEnvDiffs(diffs, environments[0].Name, environments[1].Name)
+	return 1
+}
+
+// loadEnvironments loads the config file of each environment. Secret resolvers are global,
+// so they are set once from both environments, as both are checked at the same time
+func loadEnvironments(names, configFiles []string) ([2]Environment, error) {
+	var environments [2]Environment
+	for i, file := range configFiles {
+		configs, err := loadConfigFiles([]string{file})
+		if err != nil {
+			return environments, err
+		}
+		environments[i] = Environment{Name: names[i], Configs: configs}
+	}
+	if err := setSecretResolvers(slices.Concat(environments[0].Configs, environments[1].Configs)); err != nil {
+		return environments, err
+	}
+	return environments, nil
+}
+
+// envEndpoints indexes a run's endpoint results by target name and endpoint, keeping the
+// worst result of targets with several base URLs: a failure, else the slowest
+func envEndpoints(runs []TargetRun) map[[2]string]*EndpointResult {
+	endpoints := make(map[[2]string]*EndpointResult)
+	for _, run := range runs {
+		for i := range run.Results {
+			result := &run.Results[i]
+			if result.LinkedFrom != "" {
+				continue
+			}
+			key := [2]string{run.TargetName, result.Endpoint}
+			if current, ok := endpoints[key]; ok && !worseResult(result, current) {
+				continue
+			}
+			endpoints[key] = result
+		}
+	}
+	return endpoints
+}
+
+// worseResult reports whether a is worse than b: failing where b passes, or slower
+func worseResult(a, b *EndpointResult) bool {
+	if resultFailed(*a) != resultFailed(*b) {
+		return resultFailed(*a)
+	}
+	return a.Duration > b.Duration
+}
+
+// diffEnvironments returns the endpoints whose outcome, status, or latency differ materially
+// between two runs, sorted by target and endpoint. Latency counts when one side is factor
+// times slower and at least minDelta apart
+func diffEnvironments(left, right []TargetRun, leftName, rightName string, factor float64, minDelta time.Duration) []EnvDiff {
+	sides := [2]map[[2]string]*EndpointResult{envEndpoints(left), envEndpoints(right)}
+	names := [2]string{leftName, rightName}
+
+	var keys [][2]string
+	for _, side := range sides {
+		for key := range side {
+			if !slices.Contains(keys, key) {
+				keys = append(keys, key)
+			}
+		}
+	}
+	slices.SortFunc(keys, func(a, b [2]string) int {
+		return strings.Compare(a[0]+"\x00"+a[1], b[0]+"\x00"+b[1])
+	})
+
+	var diffs []EnvDiff
+	for _, key := range keys {
+		results := [2]*EndpointResult{sides[0][key], sides[1][key]}
+		if reason := envDiffReason(results, names, factor, minDelta); reason != "" {
+			diffs = append(diffs, EnvDiff{Target: key[0], Endpoint: key[1], Results: results, Reason: reason})
+		}
+	}
+	return diffs
+}
+
+// envDiffReason explains how two results of an endpoint differ, or returns an empty string
+// when the difference isn't material
+func envDiffReason(results [2]*EndpointResult, names [2]string, factor float64, minDelta time.Duration) string {
+	a, b := results[0], results[1]
+	switch {
+	case a == nil:
+		return "only in " + names[1]
+	case b == nil:
+		return "only in " + names[0]
+	case resultFailed(*a) && !resultFailed(*b):
+		return "fails in " + names[0]
+	case resultFailed(*b) && !resultFailed(*a):
+		return "fails in " + names[1]
+	case a.StatusCode != b.StatusCode && a.Error == nil && b.Error == nil:
+		return fmt.Sprintf("status %d vs %d", a.StatusCode, b.StatusCode)
+	case resultFailed(*a):
+		// Both failing the same way says nothing about the environments
+		return ""
+	}
+
+	slower, faster, name := a, b, names[0]
+	if b.Duration > a.Duration {
+		slower, faster, name = b, a, names[1]
+	}
+	delta := slower.Duration - faster.Duration
+	if delta < minDelta || faster.Duration <= 0 {
+		return ""
+	}
+	ratio := float64(slower.Duration) / float64(faster.Duration)
+	if ratio < factor {
+		return ""
+	}
+	return fmt.Sprintf("%.1fx slower in %s (+%.2fs)", ratio, name, delta.Seconds())
+}
+
+// printEnvDiffs prints one row per differing endpoint with each environment's result
+func printEnvDiffs(diffs []EnvDiff, leftName, rightName string) {
+	_, red, _ := setupColorOutput()
+
+	headers := []string{"TARGET", "ENDPOINT", strings.ToUpper(leftName), strings.ToUpper(rightName), "DIFFERENCE"}
+	rows := make([][]string, 0, len(diffs))
+	colors := make([]func(a ...interface{}) string, 0, len(diffs))
+	for _, diff := range diffs {
+		endpoint := diff.Endpoint
+		if endpoint == "" {
+			endpoint = "/"
+		}
+		rows = append(rows, []string{
+			diff.Target,
+			endpoint,
+			comparisonCell(diff.Results[0], 0, false),
+			comparisonCell(diff.Results[1], 0, false),
+			diff.Reason,
+		})
+		colors = append(colors, red)
+	}
+
+	title := fmt.Sprintf("%s vs %s: %d differences", leftName, rightName, len(diffs))
+	printBoxTable(title, headers, rows, colors)
+}
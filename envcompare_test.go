@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDiffEnvironments(t *testing.T) {
+	run := func(results ...EndpointResult) []TargetRun {
+		return []TargetRun{{TargetName: "api", Results: results}}
+	}
+	ok := func(endpoint string, duration time.Duration) EndpointResult {
+		return EndpointResult{Endpoint: endpoint, StatusCode: 200, Success: true, Duration: duration}
+	}
+
+	staging := run(
+		ok("/same", 100*time.Millisecond),
+		ok("/slow", 100*time.Millisecond),
+		ok("/noise", 10*time.Millisecond),
+		ok("/broken", 100*time.Millisecond),
+		EndpointResult{Endpoint: "/both-down", Error: errors.New("refused")},
+		ok("/new", 100*time.Millisecond),
+		ok("/status", 100*time.Millisecond),
+	)
+	prod := run(
+		ok("/same", 150*time.Millisecond),
+		ok("/slow", 450*time.Millisecond),
+		ok("/noise", 50*time.Millisecond),
+		EndpointResult{Endpoint: "/broken", StatusCode: 503, Duration: 100 * time.Millisecond},
+		EndpointResult{Endpoint: "/both-down", Error: errors.New("refused")},
+		EndpointResult{Endpoint: "/status", StatusCode: 204, Success: true, Duration: 100 * time.Millisecond},
+	)
+
+	diffs := diffEnvironments(staging, prod, "staging", "prod", 2, 100*time.Millisecond)
+	want := map[string]string{
+		"/broken": "fails in prod",
+		"/new":    "only in staging",
+		"/slow":   "4.5x slower in prod (+0.35s)",
+		"/status": "status 200 vs 204",
+	}
+	if len(diffs) != len(want) {
+		t.Fatalf("diffs = %+v, want %v", diffs, want)
+	}
+	for _, diff := range diffs {
+		if want[diff.Endpoint] != diff.Reason {
+			t.Errorf("%s: reason %q, want %q", diff.Endpoint, diff.Reason, want[diff.Endpoint])
+		}
+	}
+}
+
+func TestEnvEndpointsKeepsWorstResult(t *testing.T) {
+	runs := []TargetRun{{TargetName: "api", Results: []EndpointResult{
+		{BaseURL: "https://a", Endpoint: "/", Success: true, Duration: time.Second},
+		{BaseURL: "https://b", Endpoint: "/", Success: true, Duration: 2 * time.Second},
+		{BaseURL: "https://a", Endpoint: "/x", Error: errors.New("refused")},
+		{BaseURL: "https://b", Endpoint: "/x", Success: true, Duration: 3 * time.Second},
+	}}}
+	endpoints := envEndpoints(runs)
+	if got := endpoints[[2]string{"api", "/"}]; got.BaseURL != "https://b" {
+		t.Errorf("kept %s, want the slowest result", got.BaseURL)
+	}
+	if got := endpoints[[2]string{"api", "/x"}]; got.Error == nil {
+		t.Error("kept a passing result over a failure")
+	}
+}
+
+func TestLoadEnvironmentsSecretResolvers(t *testing.T) {
+	defer func(resolvers []SecretResolver) { secretResolvers = resolvers }(secretResolvers)
+	dir := t.TempDir()
+	var files []string
+	for _, env := range []string{"staging", "prod"} {
+		file := filepath.Join(dir, "vitals."+env+".toml")
+		os.WriteFile(file, []byte(`
+[[secrets.resolvers]]
+prefix = "`+env+`:"
+command = ["echo", "{ref}"]
+`), 0o644)
+		files = append(files, file)
+	}
+
+	// The first environment's resolver survives loading the second
+	if _, err := loadEnvironments([]string{"staging", "prod"}, files); err != nil {
+		t.Fatal(err)
+	}
+	for _, ref := range []string{"staging:db", "prod:db"} {
+		if !isSecretReference(ref) {
+			t.Errorf("isSecretReference(%q) = false", ref)
+		}
+	}
+}
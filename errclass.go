@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"syscall"
+
+	"github.com/fatih/color"
+)
+
+// ErrorClass is the machine-readable category of a request error, so that a timeout storm
+// can be told apart from a DNS outage
+type ErrorClass string
+
+// Error classes, from the most to the least specific
+const (
+	ErrorDNS     ErrorClass = "dns"
+	ErrorRefused ErrorClass = "connection_refused"
+	ErrorTLS     ErrorClass = "tls"
+	ErrorTimeout ErrorClass = "timeout"
+	ErrorReset   ErrorClass = "connection_reset"
+	ErrorOther   ErrorClass = "other"
+)
+
+// errorClassLabels are shown in the STATUS column in place of ERROR
+var errorClassLabels = map[ErrorClass]string{
+	ErrorDNS:     "DNS",
+	ErrorRefused: "REFUSED",
+	ErrorTLS:     "TLS",
+	ErrorTimeout: "TIMEOUT",
+	ErrorReset:   "RESET",
+	ErrorOther:   "ERROR",
+}
+
+// classifyError categorizes a request error, returning "" for nil
+func classifyError(err error) ErrorClass {
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	var recordErr tls.RecordHeaderError
+	var alertErr tls.AlertError
+	var verifyErr *tls.CertificateVerificationError
+	var authorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+
+	switch {
+	case err == nil:
+		return ""
+	case errors.As(err, &dnsErr):
+		return ErrorDNS
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return ErrorRefused
+	case errors.As(err, &recordErr), errors.As(err, &alertErr), errors.As(err, &verifyErr),
+		errors.As(err, &authorityErr), errors.As(err, &hostnameErr), errors.As(err, &invalidErr):
+		return ErrorTLS
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, os.ErrDeadlineExceeded),
+		errors.As(err, &netErr) && netErr.Timeout():
+		return ErrorTimeout
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.EPIPE), errors.Is(err, io.EOF),
+		errors.Is(err, io.ErrUnexpectedEOF):
+		return ErrorReset
+	case strings.Contains(err.Error(), "tls: "):
+		// Handshake failures such as a protocol version mismatch have no error type
+		return ErrorTLS
+	default:
+		return ErrorOther
+	}
+}
+
+// ErrorClass categorizes the result's request error, "" when the request got a response
+func (r EndpointResult) ErrorClass() ErrorClass {
+	return classifyError(r.Error)
+}
+
+// errorColor colors error rows by class: timeouts magenta and DNS failures cyan, so they stand
+// out from hosts that are plainly down, in red
+func errorColor(class ErrorClass, red func(a ...interface{}) string) func(a ...interface{}) string {
+	switch class {
+	case ErrorTimeout:
+		return color.New(color.FgMagenta).SprintFunc()
+	case ErrorDNS:
+		return color.New(color.FgCyan).SprintFunc()
+	default:
+		return red
+	}
+}
+
+// errorCounts tallies errors by class
+type errorCounts map[ErrorClass]int
+
+// countErrors tallies the request errors of results, returning nil when there are none
+func countErrors(results []EndpointResult) errorCounts {
+	var counts errorCounts
+	for _, result := range results {
+		if class := result.ErrorClass(); class != "" && !result.Skipped {
+			if counts == nil {
+				counts = make(errorCounts)
+			}
+			counts[class]++
+		}
+	}
+	return counts
+}
+
+// String lists the counts by class, e.g. "timeout 3, dns 1", most frequent first
+func (c errorCounts) String() string {
+	classes := slices.SortedFunc(maps.Keys(c), func(a, b ErrorClass) int {
+		if c[a] != c[b] {
+			return c[b] - c[a]
+		}
+		return strings.Compare(string(a), string(b))
+	})
+	parts := make([]string, len(classes))
+	for i, class := range classes {
+		parts[i] = fmt.Sprintf("%s %d", class, c[class])
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClassifyError(t *testing.T) {
+	// A port nothing listens on, a TLS server the client doesn't trust, a server too slow to
+	// answer, and one that hangs up without answering
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	refusedURL := "http://" + closed.Addr().String()
+	closed.Close()
+
+	untrusted := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer untrusted.Close()
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer slow.Close()
+	hangUp := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		conn.Close()
+	}))
+	defer hangUp.Close()
+
+	// Only the slow server gets a short timeout, so the others can't time out under -race
+	get := func(client *http.Client, url string) error {
+		resp, err := client.Get(url)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+	client := &http.Client{Timeout: 5 * time.Second}
+	impatient := &http.Client{Timeout: 50 * time.Millisecond}
+
+	tests := []struct {
+		name string
+		err  error
+		want ErrorClass
+	}{
+		{"no error", nil, ""},
+		{"dns", &net.OpError{Op: "dial", Err: &net.DNSError{Err: "no such host", Name: "nope.invalid", IsNotFound: true}}, ErrorDNS},
+		{"refused", get(client, refusedURL), ErrorRefused},
+		{"tls", get(client, untrusted.URL), ErrorTLS},
+		{"timeout", get(impatient, slow.URL), ErrorTimeout},
+		{"reset", get(client, hangUp.URL), ErrorReset},
+		{"other", errors.New("error creating request: bad URL"), ErrorOther},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyError(tt.err); got != tt.want {
+				t.Errorf("classifyError(%v) = %q, want %q", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClassifyBodyReadError(t *testing.T) {
+	// The server answers, then stalls before the body, so the read times out after the status
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	client := server.Client()
+	client.Timeout = 50 * time.Millisecond
+	target := TargetConfig{StatusCodes: []int{200}, BodyNotContains: []string{"error"}}
+	result := checkEndpoint(client, server.URL, "/", target, buildResponseChecks(target), nil, false)
+	if got := result.ErrorClass(); got != ErrorTimeout {
+		t.Errorf("ErrorClass() = %q for %v, want %q", got, result.Error, ErrorTimeout)
+	}
+}
+
+func TestCountErrors(t *testing.T) {
+	timeout := &net.OpError{Op: "read", Err: errTimeout{}}
+	results := []EndpointResult{
+		{Error: timeout}, {Error: timeout}, {Error: errors.New("boom")}, {Success: true},
+		{Error: errors.New("host down"), Skipped: true},
+	}
+	counts := countErrors(results)
+	if got := counts.String(); got != "timeout 2, other 1" {
+		t.Errorf("countErrors() = %q", got)
+	}
+	if countErrors(results[3:]) != nil {
+		t.Error("countErrors() without errors should be nil")
+	}
+}
+
+// errTimeout is a net.Error that timed out
+type errTimeout struct{}
+
+func (errTimeout) Error() string   { return "i/o timeout" }
+func (errTimeout) Timeout() bool   { return true }
+func (errTimeout) Temporary() bool { return true }
@@ -0,0 +1,24 @@
+package main
+
+// reachableReason is why an expect_failure check fails: its request went through
+const reachableReason = "reachable, expected the check to fail"
+
+// invertResult turns the result of an expect_failure check around: a check that errored or
+// failed passes, keeping why it failed, and one that passed fails. Skipped and rate limited
+// checks are left as they are
+func invertResult(result EndpointResult) EndpointResult {
+	if result.Skipped || result.RateLimited {
+		return result
+	}
+	if result.Error == nil && result.Success {
+		result.Success = false
+		result.FailureReason = reachableReason
+		return result
+	}
+
+	result.ExpectedFailure = describeFailure(result)
+	result.Error = nil
+	result.FailureReason = ""
+	result.Success = true
+	return result
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestExpectFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/admin" {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closedURL := closed.URL
+	closed.Close()
+
+	tests := []struct {
+		name        string
+		baseURL     string
+		endpoint    EndpointConfig
+		wantSuccess bool
+		wantReason  string
+		wantFailure string
+	}{
+		{"blocked", server.URL, EndpointConfig{Path: "/admin", ExpectFailure: true}, true, "", "status 403"},
+		{"exposed", server.URL, EndpointConfig{Path: "/debug", ExpectFailure: true}, false, reachableReason, ""},
+		{"unreachable", closedURL, EndpointConfig{Path: "/admin", ExpectFailure: true}, true, "", "error: "},
+		{"not inverted", server.URL, EndpointConfig{Path: "/admin"}, false, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := TargetConfig{BaseURLs: []string{tt.baseURL}, Endpoints: []EndpointConfig{tt.endpoint}, StatusCodes: []int{200}}
+			results := processTarget(context.Background(), server.Client(), target, buildResponseChecks(target), nil, nil, false)
+			if len(results) != 1 {
+				t.Fatalf("got %d results", len(results))
+			}
+			result := results[0]
+			if result.Success != tt.wantSuccess || result.FailureReason != tt.wantReason || resultFailed(result) == tt.wantSuccess {
+				t.Errorf("success = %v, reason %q, want %v, %q", result.Success, result.FailureReason, tt.wantSuccess, tt.wantReason)
+			}
+			if !strings.HasPrefix(result.ExpectedFailure, tt.wantFailure) || (tt.wantFailure == "") != (result.ExpectedFailure == "") {
+				t.Errorf("expected failure = %q, want it to start with %q", result.ExpectedFailure, tt.wantFailure)
+			}
+			if tt.wantFailure != "" && result.Error != nil {
+				t.Errorf("error %v kept on a check that failed as expected", result.Error)
+			}
+		})
+	}
+
+	// Skipped checks stay skipped
+	if got := invertResult(EndpointResult{Skipped: true}); got.Success || got.ExpectedFailure != "" {
+		t.Errorf("invertResult() of a skipped check = %+v", got)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// runExplain implements `vitals explain`, printing how vitals resolves the named targets
+func runExplain(args []string) int {
+	fs := flag.NewFlagSet("explain", flag.ExitOnError)
+	var configFiles []string
+	fs.Var((*stringSlice)(&configFiles), "config", "Path to configuration file(s)")
+	fs.Var((*stringSlice)(&configFiles), "c", "Path to configuration file(s) (shorthand)")
+	timeout := fs.Int("timeout", 0, "Override the global timeout in seconds")
+	fs.IntVar(timeout, "t", 0, "Override the global timeout in seconds (shorthand)")
+	auditHeaders := fs.Bool("audit-headers", false, "Resolve as if run with --audit-headers")
+	noDiscovery := fs.Bool("no-discovery", false, "Leave srv+ and Consul base URLs unresolved")
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: vitals explain [-c vitals.toml] <target or config::target>...")
+		return 2
+	}
+	if len(configFiles) == 0 {
+		configFiles = append(configFiles, "vitals.toml")
+	}
+	configs, err := loadConfigFiles(configFiles)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 1
+	}
+
+	opts := runOptions{timeout: *timeout, auditHeaders: *auditHeaders, only: fs.Args()}
+	code, found := 0, 0
+	for _, configWithSource := range configs {
+		for _, name := range slices.Sorted(maps.Keys(configWithSource.Config.Targets)) {
+			if !opts.selectsTarget(configWithSource.Filename, name) {
+				continue
+			}
+			if found > 0 {
+				fmt.Println()
+			}
+			found++
+			if err := explainTarget(os.Stdout, configWithSource, name, opts, !*noDiscovery); err != nil {
+				fmt.Fprintf(os.Stderr, "Error resolving target '%s': %s\n", runKey(configWithSource.Filename, name), err)
+				code = 1
+			}
+		}
+	}
+	if found == 0 {
+		fmt.Fprintf(os.Stderr, "No target matches %v\n", fs.Args())
+		return 1
+	}
+	return code
+}
+
+// explainTarget writes a target as vitals would run it: a commented summary of the effective
+// timeout, schedule, and endpoint URLs, then the resolved target as TOML. Discovery and schedule
+// problems are returned after everything that could be resolved is written
+func explainTarget(w io.Writer, configWithSource ConfigWithSource, name string, opts runOptions, discover bool) error {
+	config := configWithSource.Config
+	target, checks := resolveTarget(config, config.Targets[name], opts)
+
+	var problem error
+	if discover {
+		baseURLs, err := resolveBaseURLs(config.Discovery, target)
+		if err != nil {
+			problem = err
+		} else {
+			target.BaseURLs = baseURLs
+		}
+	}
+
+	fmt.Fprintf(w, "# Target %s from %s\n", name, configWithSource.Filename)
+	if notes := strings.TrimSpace(target.Notes); notes != "" {
+		fmt.Fprintf(w, "# Notes: %s\n", strings.ReplaceAll(notes, "\n", "\n#   "))
+	}
+	fmt.Fprintf(w, "# Timeout: %s\n", setupHTTPClient(config.Global.Timeout, opts.timeout).Timeout)
+	if schedule, err := parseSchedule(config.Global, target); err != nil {
+		problem = err
+	} else if interval, ok := schedule.(intervalSchedule); ok {
+		fmt.Fprintf(w, "# Serve schedule: every %s\n", time.Duration(interval))
+	} else {
+		fmt.Fprintf(w, "# Serve schedule: cron %s\n", target.Schedule)
+	}
+	if checks.SkipBody {
+		fmt.Fprintln(w, "# Response bodies: not read, no check needs them")
+	} else {
+		fmt.Fprintln(w, "# Response bodies: read")
+	}
+	fmt.Fprintln(w, "# Requests:")
+	for _, baseURL := range target.BaseURLs {
+		for _, endpoint := range target.Endpoints {
+			endpointTarget := target.forEndpoint(endpoint)
+			line := fmt.Sprintf("#   %s %s", requestMethod(endpointTarget), withQueryParams(constructURL(baseURL, endpoint.Path), endpointTarget.QueryParams))
+			if endpoint.Timeout > 0 {
+				line += fmt.Sprintf(" (timeout %s)", time.Duration(endpoint.Timeout)*time.Second)
+			}
+			fmt.Fprintln(w, line)
+		}
+	}
+	fmt.Fprintln(w)
+
+	enc := toml.NewEncoder(w)
+	enc.Indent = ""
+	if err := enc.Encode(struct {
+		Targets map[string]TargetConfig `toml:"targets"`
+	}{map[string]TargetConfig{name: target}}); err != nil {
+		return fmt.Errorf("error encoding target: %s", err)
+	}
+	return problem
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+)
+
+func TestExplainTarget(t *testing.T) {
+	config := Config{
+		Global: GlobalConfig{Timeout: 3, Interval: "30s", UserAgent: "probe/1"},
+		Targets: map[string]TargetConfig{
+			"web":      {BaseURLs: []string{"https://example.com"}, Endpoints: pathEndpoints("/", "health")},
+			"redirect": {BaseURLs: []string{"https://example.com"}, Endpoints: pathEndpoints("/old"), ExpectRedirectTo: "https://example.com/new", Schedule: "*/5 * * * *", Notes: "Old marketing domain\nRetire after the 2027 rebrand\n"},
+			"consul":   {Endpoints: pathEndpoints("/"), ConsulService: "api"},
+		},
+	}
+	source := ConfigWithSource{Config: config, Filename: "vitals.toml"}
+
+	tests := []struct {
+		name        string
+		target      string
+		opts        runOptions
+		wantLines   []string
+		wantStatus  []int
+		wantAudit   bool
+		wantProblem bool
+	}{
+		{
+			name:       "global defaults",
+			target:     "web",
+			wantLines:  []string{"# Timeout: 3s", "# Serve schedule: every 30s", "#   GET https://example.com/health", `user_agent = "probe/1"`},
+			wantStatus: []int{200},
+		},
+		{
+			name:       "CLI overrides",
+			target:     "web",
+			opts:       runOptions{timeout: 9, auditHeaders: true},
+			wantLines:  []string{"# Timeout: 9s"},
+			wantStatus: []int{200},
+			wantAudit:  true,
+		},
+		{
+			name:       "redirect on a cron schedule",
+			target:     "redirect",
+			wantLines:  []string{"# Notes: Old marketing domain", "#   Retire after the 2027 rebrand", "# Serve schedule: cron */5 * * * *"},
+			wantStatus: redirectStatuses,
+		},
+		{
+			name:        "failed discovery",
+			target:      "consul",
+			wantStatus:  []int{200},
+			wantProblem: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := explainTarget(&out, source, tt.target, tt.opts, true)
+			if (err != nil) != tt.wantProblem {
+				t.Fatalf("explainTarget() error = %v, want problem %v", err, tt.wantProblem)
+			}
+			for _, line := range tt.wantLines {
+				if !strings.Contains(out.String(), line+"\n") {
+					t.Errorf("output is missing %q:\n%s", line, out.String())
+				}
+			}
+
+			// The TOML part is a config of its own
+			var resolved Config
+			if _, err := toml.Decode(out.String(), &resolved); err != nil {
+				t.Fatalf("output is not valid TOML: %v\n%s", err, out.String())
+			}
+			target := resolved.Targets[tt.target]
+			if !slices.Equal(target.StatusCodes, tt.wantStatus) {
+				t.Errorf("status_codes = %v, want %v", target.StatusCodes, tt.wantStatus)
+			}
+			if (len(target.Audit) > 0) != tt.wantAudit {
+				t.Errorf("audit = %v, want audits %v", target.Audit, tt.wantAudit)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"sync"
+)
+
+// FormSession holds the cookies of one form login, shared by every check that logs in with
+// the same URL and username
+type FormSession struct {
+	mu  sync.Mutex
+	jar http.CookieJar
+}
+
+// formSessions holds one session per login, so a run logs in once rather than per check
+var (
+	formSessionsMu sync.Mutex
+	formSessions   = make(map[string]*FormSession)
+)
+
+// formSessionFor returns the session of a form login, creating it on first use
+func formSessionFor(auth AuthConfig) *FormSession {
+	formSessionsMu.Lock()
+	defer formSessionsMu.Unlock()
+	key := auth.LoginURL + " " + auth.Username
+	session, ok := formSessions[key]
+	if !ok {
+		session = &FormSession{}
+		formSessions[key] = session
+	}
+	return session
+}
+
+// clearFormSessions forgets every form login, so changed credentials or fields log in again
+func clearFormSessions() {
+	formSessionsMu.Lock()
+	defer formSessionsMu.Unlock()
+	clear(formSessions)
+}
+
+// cookies returns the session's cookie jar, logging in when there is none yet or when the
+// jar is the one a server just logged out. Checks wait for each other so only one logs in
+func (s *FormSession) cookies(client *http.Client, auth AuthConfig, stale http.CookieJar) (http.CookieJar, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.jar != nil && s.jar != stale {
+		return s.jar, nil
+	}
+
+	jar, err := formLogin(client, auth)
+	if err != nil {
+		return nil, err
+	}
+	s.jar = jar
+	return jar, nil
+}
+
+// formLogin posts the credentials to the login form, follows its redirect, and returns the
+// cookies it set
+func formLogin(client *http.Client, auth AuthConfig) (http.CookieJar, error) {
+	password, err := expandSecret(auth.Password)
+	if err != nil {
+		return nil, err
+	}
+	usernameField, passwordField := auth.UsernameField, auth.PasswordField
+	if usernameField == "" {
+		usernameField = "username"
+	}
+	if passwordField == "" {
+		passwordField = "password"
+	}
+	form := url.Values{usernameField: {auth.Username}, passwordField: {password}}
+	for name, value := range auth.FormFields {
+		form.Set(name, value)
+	}
+
+	jar, _ := cookiejar.New(nil)
+	loginClient := *client
+	loginClient.Jar = jar
+	loginClient.CheckRedirect = nil // The login redirect is followed even when checks don't follow theirs
+	resp, err := loginClient.PostForm(auth.LoginURL, form)
+	if err != nil {
+		return nil, fmt.Errorf("error logging in to %s: %w", auth.LoginURL, err)
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, bodyDrainLimit))
+	resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("login to %s returned status %d", auth.LoginURL, resp.StatusCode)
+	}
+	if isLoginPage(resp.Request.URL, auth.LoginURL) {
+		return nil, fmt.Errorf("login to %s answered with the login form again, check the credentials", auth.LoginURL)
+	}
+	if len(jar.Cookies(resp.Request.URL)) == 0 {
+		return nil, fmt.Errorf("login to %s set no session cookie", auth.LoginURL)
+	}
+	return jar, nil
+}
+
+// isLoginPage reports whether u is the login form at loginURL, ignoring its query
+func isLoginPage(u *url.URL, loginURL string) bool {
+	login, err := url.Parse(loginURL)
+	return err == nil && u.Host == login.Host && u.Path == login.Path
+}
+
+// formTransport sends the session cookies of a form login with every request, logging in
+// again once when a response says the session is over
+type formTransport struct {
+	transport http.RoundTripper
+	loginURL  string
+	jar       http.CookieJar
+	renew     func(stale http.CookieJar) (http.CookieJar, error)
+}
+
+// RoundTrip sends the request with the session cookies, retrying once with a new session
+// when the server answers 401 or redirects to the login form
+func (t *formTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.send(req)
+	if err != nil || !t.loggedOut(resp) || (req.Body != nil && req.GetBody == nil) {
+		return resp, err
+	}
+
+	jar, renewErr := t.renew(t.jar)
+	if renewErr != nil {
+		return resp, nil
+	}
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return resp, nil
+		}
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, bodyDrainLimit))
+	resp.Body.Close()
+
+	t.jar = jar
+	return t.send(retry)
+}
+
+// send adds the session cookies to a request and keeps the cookies its response sets
+func (t *formTransport) send(req *http.Request) (*http.Response, error) {
+	withCookies := req.Clone(req.Context())
+	for _, cookie := range t.jar.Cookies(req.URL) {
+		withCookies.AddCookie(cookie)
+	}
+	resp, err := t.transport.RoundTrip(withCookies)
+	if err != nil {
+		return nil, err
+	}
+	t.jar.SetCookies(req.URL, resp.Cookies())
+	return resp, nil
+}
+
+// loggedOut reports whether a response rejects the session: a 401, or a redirect to the
+// login form
+func (t *formTransport) loggedOut(resp *http.Response) bool {
+	if resp.StatusCode == http.StatusUnauthorized {
+		return true
+	}
+	location, err := resp.Location()
+	return err == nil && isLoginPage(location, t.loginURL)
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// formLoginServer is a dashboard behind a login form for tests
+type formLoginServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	sessions map[string]bool
+	logins   atomic.Int32
+}
+
+func newFormLoginServer(t *testing.T) *formLoginServer {
+	s := &formLoginServer{sessions: make(map[string]bool)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /login", func(w http.ResponseWriter, r *http.Request) {
+		s.logins.Add(1)
+		if r.FormValue("email") != "monitor@example.com" || r.FormValue("password") != "s3cret" || r.FormValue("remember") != "1" {
+			http.Redirect(w, r, "/login?failed=1", http.StatusSeeOther)
+			return
+		}
+		s.mu.Lock()
+		id := fmt.Sprintf("session-%d", s.logins.Load())
+		s.sessions[id] = true
+		s.mu.Unlock()
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: id, Path: "/"})
+		http.Redirect(w, r, "/admin", http.StatusSeeOther)
+	})
+	mux.HandleFunc("GET /login", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<form method=post>"))
+	})
+	mux.HandleFunc("/admin", func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie("session")
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if err != nil || !s.sessions[cookie.Value] {
+			http.Redirect(w, r, "/login", http.StatusFound)
+		}
+	})
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *formLoginServer) auth(password string) *AuthConfig {
+	return &AuthConfig{
+		Type:          "form",
+		LoginURL:      s.URL + "/login",
+		Username:      "monitor@example.com",
+		Password:      password,
+		UsernameField: "email",
+		FormFields:    map[string]string{"remember": "1"},
+	}
+}
+
+func checkFormLogin(s *formLoginServer, auth *AuthConfig) EndpointResult {
+	target := TargetConfig{StatusCodes: []int{200}, Auth: auth}
+	return checkEndpoint(s.Client(), s.URL, "/admin", target, buildResponseChecks(target), nil, false)
+}
+
+func TestFormLogin(t *testing.T) {
+	s := newFormLoginServer(t)
+	t.Setenv("DASHBOARD_PASSWORD", "s3cret")
+	auth := s.auth("${DASHBOARD_PASSWORD}")
+
+	for range 2 {
+		if result := checkFormLogin(s, auth); !result.Success || result.Error != nil {
+			t.Fatalf("check failed: %+v", result)
+		}
+	}
+	if n := s.logins.Load(); n != 1 {
+		t.Errorf("logins = %d, want the session reused", n)
+	}
+
+	// An expired session redirects to the login form, which logs in again and retries
+	s.mu.Lock()
+	clear(s.sessions)
+	s.mu.Unlock()
+	if result := checkFormLogin(s, auth); !result.Success || result.Error != nil {
+		t.Errorf("check after the session expired failed: %+v", result)
+	}
+	if n := s.logins.Load(); n != 2 {
+		t.Errorf("logins = %d, want 2", n)
+	}
+
+	// A config reload forgets the session, as the login may have changed
+	clearFormSessions()
+	if result := checkFormLogin(s, auth); !result.Success || result.Error != nil {
+		t.Errorf("check after clearing sessions failed: %+v", result)
+	}
+	if n := s.logins.Load(); n != 3 {
+		t.Errorf("logins = %d, want 3", n)
+	}
+}
+
+func TestFormLoginFailure(t *testing.T) {
+	s := newFormLoginServer(t)
+	tests := []struct {
+		name    string
+		auth    *AuthConfig
+		wantErr string
+	}{
+		{"wrong password", s.auth("guess"), "login to " + s.URL + "/login answered with the login form again, check the credentials"},
+		{"login URL not found", &AuthConfig{Type: "form", LoginURL: s.URL + "/signin", Username: "a"}, "login to " + s.URL + "/signin returned status 404"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := checkFormLogin(s, tt.auth)
+			if result.Success || result.Error == nil || result.Error.Error() != tt.wantErr {
+				t.Errorf("error = %v, want %s", result.Error, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// grafanaTimeout bounds a single annotation request
+const grafanaTimeout = 10 * time.Second
+
+// GrafanaConfig posts a Grafana annotation for every state change, so health events show up
+// on latency dashboards
+type GrafanaConfig struct {
+	// URL is the Grafana server, e.g. "https://grafana.example.com"
+	URL string `toml:"url,omitempty"`
+
+	// APIKey is a service account token and may reference environment variables, e.g.
+	// "${GRAFANA_TOKEN}"
+	APIKey string `toml:"api_key,omitempty"`
+
+	// DashboardUID and PanelID pin annotations to one dashboard or panel; without them
+	// annotations belong to the organization and show on dashboards that query their tags
+	DashboardUID string `toml:"dashboard_uid,omitempty"`
+	PanelID      int    `toml:"panel_id,omitzero"`
+
+	// Tags are added to the vitals, target, team, and status tags of every annotation
+	Tags []string `toml:"tags,omitempty"`
+}
+
+// grafanaAnnotation is the payload of POST /api/annotations
+type grafanaAnnotation struct {
+	DashboardUID string   `json:"dashboardUID,omitempty"`
+	PanelID      int      `json:"panelId,omitempty"`
+	Time         int64    `json:"time"`
+	Tags         []string `json:"tags"`
+	Text         string   `json:"text"`
+}
+
+// grafanaSink annotates state changes through the Grafana HTTP API; results alone aren't
+// annotated
+type grafanaSink struct {
+	config GrafanaConfig
+	token  string
+	client *http.Client
+}
+
+// newGrafanaSink resolves the API key of the Grafana sink
+func newGrafanaSink(config GrafanaConfig) (*grafanaSink, error) {
+	if config.URL == "" {
+		return nil, sinkError("grafana", fmt.Errorf("url is required"))
+	}
+	token, err := expandSecret(config.APIKey)
+	if err != nil {
+		return nil, sinkError("grafana", err)
+	}
+	return &grafanaSink{config: config, token: token, client: &http.Client{Timeout: grafanaTimeout}}, nil
+}
+
+// Publish posts one annotation per state change
+func (s *grafanaSink) Publish(batch SinkBatch) error {
+	for _, change := range batch.Changes {
+		if err := s.annotate(grafanaAnnotationFor(s.config, change)); err != nil {
+			return sinkError("grafana", err)
+		}
+	}
+	return nil
+}
+
+// annotate posts an annotation
+func (s *grafanaSink) annotate(annotation grafanaAnnotation) error {
+	payload, err := json.Marshal(annotation)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", strings.TrimSuffix(s.config.URL, "/")+"/api/annotations", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// Close does nothing, each annotation is its own request
+func (s *grafanaSink) Close() error {
+	return nil
+}
+
+// grafanaAnnotationFor describes a state change as an annotation tagged with its target,
+// team, and new status
+func grafanaAnnotationFor(config GrafanaConfig, change StateChange) grafanaAnnotation {
+	team := change.Team
+	if team == "" {
+		team = unownedTeam
+	}
+	tags := append([]string{"vitals", "target:" + change.Target, "team:" + team, "status:" + change.To}, config.Tags...)
+
+	text := fmt.Sprintf("%s %s %s is %s", change.Target, change.Result.Method, change.Result.URL, change.To)
+	if reason := change.Result.FailureReason; reason != "" {
+		text += ": " + reason
+	} else if change.Result.Error != "" {
+		text += ": " + change.Result.Error
+	}
+	return grafanaAnnotation{
+		DashboardUID: config.DashboardUID,
+		PanelID:      config.PanelID,
+		Time:         change.Time.UnixMilli(),
+		Tags:         tags,
+		Text:         text,
+	}
+}
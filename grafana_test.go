@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGrafanaSinkPublish(t *testing.T) {
+	var annotations []grafanaAnnotation
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/api/annotations" || r.Header.Get("Authorization") != "Bearer glsa_token" {
+			http.Error(w, "unexpected request", http.StatusUnauthorized)
+			return
+		}
+		var annotation grafanaAnnotation
+		json.NewDecoder(r.Body).Decode(&annotation)
+		annotations = append(annotations, annotation)
+	}))
+	defer server.Close()
+
+	t.Setenv("GRAFANA_TOKEN", "glsa_token")
+	sink, err := newGrafanaSink(GrafanaConfig{URL: server.URL + "/", APIKey: "${GRAFANA_TOKEN}", DashboardUID: "latency", Tags: []string{"prod"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2026, 3, 1, 11, 30, 0, 0, time.UTC)
+	err = sink.Publish(SinkBatch{
+		Results: []ResultMessage{{Target: "api", JSONResult: JSONResult{Method: "GET", URL: "https://api.example.com/health"}}},
+		Changes: []StateChange{
+			{Time: at, Target: "api", From: "up", To: "down", Ownership: Ownership{Team: "payments"}, Result: JSONResult{Method: "GET", URL: "https://api.example.com/health", FailureReason: "status 503"}},
+			{Time: at, Target: "web", From: "down", To: "up", Result: JSONResult{Method: "GET", URL: "https://example.com/"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []grafanaAnnotation{
+		{DashboardUID: "latency", Time: at.UnixMilli(), Tags: []string{"vitals", "target:api", "team:payments", "status:down", "prod"}, Text: "api GET https://api.example.com/health is down: status 503"},
+		{DashboardUID: "latency", Time: at.UnixMilli(), Tags: []string{"vitals", "target:web", "team:none", "status:up", "prod"}, Text: "web GET https://example.com/ is up"},
+	}
+	if !reflect.DeepEqual(annotations, want) {
+		t.Errorf("annotations = %+v, want %+v", annotations, want)
+	}
+}
+
+func TestGrafanaSinkErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message":"invalid API key"}`, http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	if _, err := newGrafanaSink(GrafanaConfig{}); err == nil || err.Error() != "error publishing to grafana: url is required" {
+		t.Errorf("error = %v, want the missing url", err)
+	}
+	sink, err := newGrafanaSink(GrafanaConfig{URL: server.URL, APIKey: "wrong"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = sink.Publish(SinkBatch{Changes: []StateChange{{Target: "api", To: "down"}}})
+	if err == nil || !strings.Contains(err.Error(), "401 Unauthorized") || !strings.Contains(err.Error(), "invalid API key") {
+		t.Errorf("error = %v, want the Grafana response", err)
+	}
+	if err := sink.Publish(SinkBatch{Results: []ResultMessage{{Target: "api"}}}); err != nil {
+		t.Errorf("publishing results without changes: %s", err)
+	}
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// GraphStatus is the last known health of a node in the target graph
+type GraphStatus string
+
+const (
+	graphUp      GraphStatus = "up"
+	graphDown    GraphStatus = "down"
+	graphUnknown GraphStatus = "unknown"
+)
+
+// Graph is the structure rendered by `vitals graph`: targets, their base URLs and endpoints,
+// and the depends_on edges between targets
+type Graph struct {
+	Targets []GraphTarget
+}
+
+// GraphTarget is one target node, identified by its config::name run key
+type GraphTarget struct {
+	ID        string
+	Label     string
+	Status    GraphStatus
+	DependsOn []string
+	BaseURLs  []GraphBaseURL
+}
+
+// GraphBaseURL groups the endpoints checked on one base URL
+type GraphBaseURL struct {
+	URL       string
+	Status    GraphStatus
+	Endpoints []GraphEndpoint
+}
+
+// GraphEndpoint is one checked endpoint
+type GraphEndpoint struct {
+	Label  string
+	Status GraphStatus
+}
+
+// combineStatus returns the status of a group: down if anything is down, unknown if nothing
+// was checked, up otherwise
+func combineStatus(statuses []GraphStatus) GraphStatus {
+	if len(statuses) == 0 {
+		return graphUnknown
+	}
+	if slices.Contains(statuses, graphDown) {
+		return graphDown
+	}
+	if slices.Contains(statuses, graphUnknown) {
+		return graphUnknown
+	}
+	return graphUp
+}
+
+// resultStatus maps an endpoint result to a graph status
+func resultStatus(result EndpointResult) GraphStatus {
+	if result.Error != nil || !result.Success {
+		return graphDown
+	}
+	return graphUp
+}
+
+// buildGraph assembles the graph of every target, colored by the given runs (which may be
+// empty to draw the structure only); unknown depends_on entries are reported and skipped
+func buildGraph(configs []ConfigWithSource, runs []TargetRun) Graph {
+	runsByKey := make(map[string]TargetRun, len(runs))
+	for _, run := range runs {
+		runsByKey[run.Key] = run
+	}
+
+	var graph Graph
+	for _, configWithSource := range configs {
+		for targetName, target := range configWithSource.Config.Targets {
+			key := runKey(configWithSource.Filename, targetName)
+			node := GraphTarget{ID: key, Label: targetName}
+			if target.Name != "" {
+				node.Label = target.Name
+			}
+
+			for _, dep := range target.DependsOn {
+				depKey := dep
+				if !strings.Contains(dep, "::") {
+					depKey = runKey(configWithSource.Filename, dep)
+				}
+				if !targetExists(configs, depKey) {
+					fmt.Fprintf(os.Stderr, "Unknown depends_on target '%s' in %s\n", dep, key)
+					continue
+				}
+				node.DependsOn = append(node.DependsOn, depKey)
+			}
+
+			node.BaseURLs = graphBaseURLs(target, runsByKey[key].Results)
+			var statuses []GraphStatus
+			for _, baseURL := range node.BaseURLs {
+				statuses = append(statuses, baseURL.Status)
+			}
+			node.Status = combineStatus(statuses)
+
+			graph.Targets = append(graph.Targets, node)
+		}
+	}
+
+	sort.Slice(graph.Targets, func(i, j int) bool {
+		return graph.Targets[i].ID < graph.Targets[j].ID
+	})
+	return graph
+}
+
+// graphBaseURLs lists a target's base URLs with their endpoints, preferring the base URLs that
+// were actually checked so discovered hosts appear instead of srv+ or Consul placeholders
+func graphBaseURLs(target TargetConfig, results []EndpointResult) []GraphBaseURL {
+	var baseURLs []string
+	for _, result := range results {
+		if result.LinkedFrom == "" && !slices.Contains(baseURLs, result.BaseURL) {
+			baseURLs = append(baseURLs, result.BaseURL)
+		}
+	}
+	sort.Strings(baseURLs)
+	if len(baseURLs) == 0 {
+		baseURLs = target.BaseURLs
+	}
+
+	nodes := make([]GraphBaseURL, 0, len(baseURLs))
+	for _, baseURL := range baseURLs {
+		node := GraphBaseURL{URL: baseURL}
+		var statuses []GraphStatus
+		for _, endpoint := range target.Endpoints {
+			status := graphUnknown
+			for _, result := range results {
+				if result.LinkedFrom == "" && result.BaseURL == baseURL && result.Endpoint == endpoint.Path {
+					status = resultStatus(result)
+				}
+			}
+			label := endpoint.Name
+			if label == "" {
+				label = endpoint.Path
+			}
+			if label == "" {
+				label = "/"
+			}
+			node.Endpoints = append(node.Endpoints, GraphEndpoint{Label: label, Status: status})
+			statuses = append(statuses, status)
+		}
+		node.Status = combineStatus(statuses)
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+// targetExists reports whether a config::name key names a loaded target
+func targetExists(configs []ConfigWithSource, key string) bool {
+	for _, configWithSource := range configs {
+		for targetName := range configWithSource.Config.Targets {
+			if runKey(configWithSource.Filename, targetName) == key {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// dotColors are the fill colors used for each status in DOT output
+var dotColors = map[GraphStatus]string{
+	graphUp:      "#c8e6c9",
+	graphDown:    "#ffcdd2",
+	graphUnknown: "#eeeeee",
+}
+
+// dotQuote quotes a string as a DOT identifier
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// renderDOT writes the graph in Graphviz DOT format
+func renderDOT(w io.Writer, graph Graph) error {
+	var b strings.Builder
+	b.WriteString("digraph vitals {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [style=filled, fontname=\"Helvetica\"];\n")
+
+	for i, target := range graph.Targets {
+		fmt.Fprintf(&b, "\n  subgraph cluster_%d {\n", i)
+		fmt.Fprintf(&b, "    label=%s;\n", dotQuote(target.ID))
+		fmt.Fprintf(&b, "    %s [label=%s, shape=box, fillcolor=%s];\n", dotQuote(target.ID), dotQuote(target.Label), dotQuote(dotColors[target.Status]))
+
+		for _, baseURL := range target.BaseURLs {
+			baseID := target.ID + " " + baseURL.URL
+			fmt.Fprintf(&b, "    %s [label=%s, shape=ellipse, fillcolor=%s];\n", dotQuote(baseID), dotQuote(baseURL.URL), dotQuote(dotColors[baseURL.Status]))
+			fmt.Fprintf(&b, "    %s -> %s;\n", dotQuote(target.ID), dotQuote(baseID))
+
+			for _, endpoint := range baseURL.Endpoints {
+				endpointID := baseID + " " + endpoint.Label
+				fmt.Fprintf(&b, "    %s [label=%s, shape=note, fillcolor=%s];\n", dotQuote(endpointID), dotQuote(endpoint.Label), dotQuote(dotColors[endpoint.Status]))
+				fmt.Fprintf(&b, "    %s -> %s;\n", dotQuote(baseID), dotQuote(endpointID))
+			}
+		}
+		b.WriteString("  }\n")
+	}
+
+	var wroteDeps bool
+	for _, target := range graph.Targets {
+		for _, dep := range target.DependsOn {
+			if !wroteDeps {
+				b.WriteString("\n")
+				wroteDeps = true
+			}
+			fmt.Fprintf(&b, "  %s -> %s [style=dashed, label=\"depends on\"];\n", dotQuote(target.ID), dotQuote(dep))
+		}
+	}
+
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// runGraph implements `vitals graph`, checking every target and printing the graph
+func runGraph(args []string) int {
+	fs := flag.NewFlagSet("graph", flag.ExitOnError)
+	var configFiles []string
+	var opts runOptions
+	fs.Var((*stringSlice)(&configFiles), "config", "Path to configuration file(s)")
+	fs.Var((*stringSlice)(&configFiles), "c", "Path to configuration file(s) (shorthand)")
+	fs.IntVar(&opts.timeout, "timeout", 0, "Override the global timeout in seconds")
+	fs.IntVar(&opts.timeout, "t", 0, "Override the global timeout in seconds (shorthand)")
+	fs.IntVar(&opts.concurrency, "concurrency", 0, "Maximum number of concurrent requests (0 means unlimited)")
+	fs.Bool("dot", true, "Print the graph in Graphviz DOT format (the default)")
+	mermaid := fs.Bool("mermaid", false, "Print the graph as a Mermaid flowchart instead of DOT")
+	noCheck := fs.Bool("no-check", false, "Draw the configured structure without running any checks")
+	configFiles = append(configFiles, parseInterspersed(fs, args)...)
+	if len(configFiles) == 0 {
+		configFiles = append(configFiles, "vitals.toml")
+	}
+	configs, err := loadConfigFiles(configFiles)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 1
+	}
+
+	var runs []TargetRun
+	if !*noCheck {
+		runs = runChecks(configs, opts)
+	}
+
+	render := renderDOT
+	if *mermaid {
+		render = renderMermaid
+	}
+	if err := render(os.Stdout, buildGraph(configs, runs)); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing graph: %s\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestBuildGraph(t *testing.T) {
+	configs := []ConfigWithSource{{Filename: "a.toml", Config: Config{Targets: map[string]TargetConfig{
+		"web": {BaseURLs: []string{"https://web.example.com"}, Endpoints: pathEndpoints("/", "/login"), DependsOn: []string{"api", "missing"}},
+		"api": {BaseURLs: []string{"srv+https://_api._tcp.example.com"}, Endpoints: pathEndpoints("/health")},
+	}}}}
+	runs := []TargetRun{
+		{Key: "a.toml::api", Results: []EndpointResult{
+			{BaseURL: "https://api-1.example.com", Endpoint: "/health", Success: true},
+			{BaseURL: "https://api-2.example.com", Endpoint: "/health", Error: errors.New("refused")},
+		}},
+		{Key: "a.toml::web", Results: []EndpointResult{
+			{BaseURL: "https://web.example.com", Endpoint: "/", Success: true},
+		}},
+	}
+
+	graph := buildGraph(configs, runs)
+	if len(graph.Targets) != 2 {
+		t.Fatalf("expected 2 targets, got %d", len(graph.Targets))
+	}
+
+	api, web := graph.Targets[0], graph.Targets[1]
+	if api.Status != graphDown || len(api.BaseURLs) != 2 || api.BaseURLs[0].URL != "https://api-1.example.com" {
+		t.Errorf("api should use discovered base URLs and be down, got %+v", api)
+	}
+	if len(web.DependsOn) != 1 || web.DependsOn[0] != "a.toml::api" {
+		t.Errorf("web depends_on = %v, want [a.toml::api]", web.DependsOn)
+	}
+	if web.Status != graphUnknown || web.BaseURLs[0].Endpoints[1].Status != graphUnknown {
+		t.Errorf("unchecked /login should leave web unknown, got %+v", web)
+	}
+
+	var b strings.Builder
+	if err := renderDOT(&b, graph); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), `"a.toml::web" -> "a.toml::api" [style=dashed`) {
+		t.Errorf("DOT output is missing the dependency edge:\n%s", b.String())
+	}
+}
+
+func TestCombineStatus(t *testing.T) {
+	tests := []struct {
+		statuses []GraphStatus
+		want     GraphStatus
+	}{
+		{nil, graphUnknown},
+		{[]GraphStatus{graphUp, graphUp}, graphUp},
+		{[]GraphStatus{graphUp, graphUnknown}, graphUnknown},
+		{[]GraphStatus{graphUnknown, graphDown}, graphDown},
+	}
+
+	for _, tt := range tests {
+		if got := combineStatus(tt.statuses); got != tt.want {
+			t.Errorf("combineStatus(%v) = %s, want %s", tt.statuses, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// harFile is the subset of an HTTP Archive (HAR 1.2) needed to derive checks
+type harFile struct {
+	Log struct {
+		Entries []struct {
+			Request struct {
+				Method   string      `json:"method"`
+				URL      string      `json:"url"`
+				Headers  []harHeader `json:"headers"`
+				PostData *struct {
+					MimeType string `json:"mimeType"`
+					Text     string `json:"text"`
+				} `json:"postData"`
+			} `json:"request"`
+			Response struct {
+				Status int `json:"status"`
+			} `json:"response"`
+		} `json:"entries"`
+	} `json:"log"`
+}
+
+// harHeader is a recorded request header
+type harHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// harSkippedHeaders are recorded headers that vitals or the transport set itself, or that
+// would turn the replayed request into a conditional one
+var harSkippedHeaders = map[string]bool{
+	"host":              true,
+	"content-length":    true,
+	"connection":        true,
+	"accept-encoding":   true,
+	"if-none-match":     true,
+	"if-modified-since": true,
+}
+
+// importHAR converts the requests recorded in a HAR file into targets that replay them with
+// their original method, headers, and body and expect the recorded status
+func importHAR(source string, opts importOptions) (map[string]TargetConfig, error) {
+	data, err := fetchSource(source)
+	if err != nil {
+		return nil, err
+	}
+
+	var har harFile
+	if err := json.Unmarshal(data, &har); err != nil {
+		return nil, fmt.Errorf("error parsing HAR file %s: %s", source, err)
+	}
+
+	var requests []importedRequest
+	for _, entry := range har.Log.Entries {
+		req := entry.Request
+		if opts.filter != nil && !opts.filter.MatchString(req.URL) {
+			continue
+		}
+		// Redirects are followed during checks, and the HAR records their destination separately
+		if status := entry.Response.Status; status >= 300 && status < 400 {
+			continue
+		}
+
+		imported := importedRequest{URL: req.URL, Method: req.Method}
+		if req.PostData != nil {
+			imported.Body = req.PostData.Text
+			imported.ContentType, _, _ = strings.Cut(req.PostData.MimeType, ";")
+		}
+
+		headers := make(map[string]string)
+		for _, h := range req.Headers {
+			// HTTP/2 pseudo-headers such as :authority are not real headers
+			if strings.HasPrefix(h.Name, ":") || harSkippedHeaders[strings.ToLower(h.Name)] {
+				continue
+			}
+			// The body's Content-Type belongs to its endpoint, not every request of the target
+			if imported.Body != "" && strings.EqualFold(h.Name, "Content-Type") {
+				if imported.ContentType == "" {
+					imported.ContentType, _, _ = strings.Cut(h.Value, ";")
+				}
+				continue
+			}
+			headers[h.Name] = h.Value
+		}
+		if len(headers) > 0 {
+			imported.Headers = headers
+		}
+
+		// A status of 0 means the browser never got a response (blocked or aborted)
+		if status := entry.Response.Status; status > 0 && status != 200 {
+			imported.StatusCodes = []int{status}
+		}
+		requests = append(requests, imported)
+
+		if opts.limit > 0 && len(requests) == opts.limit {
+			break
+		}
+	}
+
+	if len(requests) == 0 {
+		return nil, fmt.Errorf("no checkable requests found in HAR file %s", source)
+	}
+	return targetsFromRequests(requests, opts.name)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+const testHAR = `{"log": {"entries": [
+  {"request": {"method": "GET", "url": "https://app.example.com/api/me", "headers": [
+    {"name": ":authority", "value": "app.example.com"},
+    {"name": "Accept", "value": "application/json"},
+    {"name": "Accept-Encoding", "value": "gzip"},
+    {"name": "Authorization", "value": "Bearer abc"}
+  ]}, "response": {"status": 200}},
+  {"request": {"method": "GET", "url": "https://app.example.com/api/items?page=2", "headers": [
+    {"name": "Accept", "value": "application/json"},
+    {"name": "Authorization", "value": "Bearer abc"}
+  ]}, "response": {"status": 200}},
+  {"request": {"method": "POST", "url": "https://app.example.com/api/search", "headers": [
+    {"name": "Accept", "value": "application/json"},
+    {"name": "Authorization", "value": "Bearer abc"},
+    {"name": "Content-Type", "value": "application/json"}
+  ], "postData": {"mimeType": "application/json", "text": "{\"q\":\"shoes\"}"}}, "response": {"status": 200}},
+  {"request": {"method": "PUT", "url": "https://app.example.com/api/notes", "headers": [
+    {"name": "Accept", "value": "application/json"},
+    {"name": "Authorization", "value": "Bearer abc"}
+  ], "postData": {"mimeType": "text/plain; charset=utf-8", "text": "hello"}}, "response": {"status": 200}},
+  {"request": {"method": "GET", "url": "https://app.example.com/old", "headers": []}, "response": {"status": 301}},
+  {"request": {"method": "GET", "url": "https://app.example.com/missing", "headers": []}, "response": {"status": 404}}
+]}}`
+
+func TestImportHAR(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "capture.har")
+	if err := os.WriteFile(path, []byte(testHAR), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	targets, err := importHAR(path, importOptions{name: "app"})
+	if err != nil {
+		t.Fatalf("importHAR() error = %v", err)
+	}
+	if len(targets) != 2 {
+		t.Fatalf("expected 2 targets, got %v", targets)
+	}
+
+	api := targets["app"]
+	want := []EndpointConfig{
+		{Path: "/api/me"},
+		{Path: "/api/items?page=2"},
+		{Path: "/api/search", Method: "POST", Body: `{"q":"shoes"}`},
+		{Path: "/api/notes", Method: "PUT", Body: "hello", Headers: map[string]string{"Content-Type": "text/plain"}},
+	}
+	if !reflect.DeepEqual(api.Endpoints, want) {
+		t.Errorf("endpoints = %+v\nwant %+v", api.Endpoints, want)
+	}
+	if len(api.Headers) != 2 || api.Headers["Authorization"] != "Bearer abc" {
+		t.Errorf("unexpected headers: %v", api.Headers)
+	}
+
+	missing := targets["app_2"]
+	if !slices.Equal(missing.StatusCodes, []int{404}) || !slices.Equal(endpointPaths(missing.Endpoints), []string{"/missing"}) {
+		t.Errorf("unexpected not found target: %+v", missing)
+	}
+}
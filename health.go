@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"slices"
+	"strings"
+)
+
+// healthResponse is the subset of the draft-inadarei-api-health-check format vitals understands
+type healthResponse struct {
+	Status string                          `json:"status"`
+	Output string                          `json:"output"`
+	Checks map[string][]healthCheckDetails `json:"checks"`
+}
+
+// healthCheckDetails is a single component measurement within a health response
+type healthCheckDetails struct {
+	ComponentID string `json:"componentId"`
+	Status      string `json:"status"`
+	Output      string `json:"output"`
+}
+
+// HealthComponent is a component of a health+json response that is not passing
+type HealthComponent struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Output string `json:"output,omitempty"`
+}
+
+// isHealthJSON reports whether a Content-Type header denotes application/health+json
+func isHealthJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && strings.EqualFold(mediaType, "application/health+json")
+}
+
+// normalizeHealthStatus maps the status aliases allowed by the draft onto pass, warn, or fail
+func normalizeHealthStatus(status string) string {
+	switch strings.ToLower(status) {
+	case "pass", "ok", "up":
+		return "pass"
+	case "warn":
+		return "warn"
+	default:
+		return "fail"
+	}
+}
+
+// evaluateHealth parses a health+json body and returns the failure reason (empty if the
+// overall status is pass or warn) along with every component that is not passing
+func evaluateHealth(body string) (string, []HealthComponent) {
+	var health healthResponse
+	if err := json.Unmarshal([]byte(body), &health); err != nil {
+		return fmt.Sprintf("invalid health+json body: %s", err), nil
+	}
+
+	var components []HealthComponent
+	for name, details := range health.Checks {
+		for _, detail := range details {
+			status := normalizeHealthStatus(detail.Status)
+			if status == "pass" {
+				continue
+			}
+
+			componentName := name
+			if detail.ComponentID != "" {
+				componentName = fmt.Sprintf("%s (%s)", name, detail.ComponentID)
+			}
+			components = append(components, HealthComponent{
+				Name:   componentName,
+				Status: status,
+				Output: detail.Output,
+			})
+		}
+	}
+
+	// Map iteration order is random, so sort for stable output
+	slices.SortFunc(components, func(a, b HealthComponent) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	if normalizeHealthStatus(health.Status) != "fail" {
+		return "", components
+	}
+
+	reason := "health status " + health.Status
+	if health.Output != "" {
+		reason += ": " + health.Output
+	}
+	return reason, components
+}
@@ -0,0 +1,76 @@
+package main
+
+import "testing"
+
+func TestIsHealthJSON(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        bool
+	}{
+		{contentType: "application/health+json", want: true},
+		{contentType: "application/health+json; charset=utf-8", want: true},
+		{contentType: "application/json", want: false},
+		{contentType: "", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.contentType, func(t *testing.T) {
+			if got := isHealthJSON(tt.contentType); got != tt.want {
+				t.Errorf("isHealthJSON(%q) = %v, want %v", tt.contentType, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluateHealth(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		wantReason     string
+		wantComponents []HealthComponent
+	}{
+		{
+			name:       "passing",
+			body:       `{"status":"pass","checks":{"db:responseTime":[{"status":"pass"}]}}`,
+			wantReason: "",
+		},
+		{
+			name:       "warning is healthy but reports components",
+			body:       `{"status":"warn","checks":{"cache:utilization":[{"componentId":"redis-1","status":"warn","output":"90% full"}]}}`,
+			wantReason: "",
+			wantComponents: []HealthComponent{
+				{Name: "cache:utilization (redis-1)", Status: "warn", Output: "90% full"},
+			},
+		},
+		{
+			name:       "failing with output",
+			body:       `{"status":"fail","output":"database unreachable","checks":{"db:connections":[{"status":"fail","output":"refused"}],"disk:free":[{"status":"pass"}]}}`,
+			wantReason: "health status fail: database unreachable",
+			wantComponents: []HealthComponent{
+				{Name: "db:connections", Status: "fail", Output: "refused"},
+			},
+		},
+		{
+			name:       "status aliases",
+			body:       `{"status":"down"}`,
+			wantReason: "health status down",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason, components := evaluateHealth(tt.body)
+			if reason != tt.wantReason {
+				t.Errorf("evaluateHealth() reason = %q, want %q", reason, tt.wantReason)
+			}
+			if len(components) != len(tt.wantComponents) {
+				t.Fatalf("evaluateHealth() components = %v, want %v", components, tt.wantComponents)
+			}
+			for i := range components {
+				if components[i] != tt.wantComponents[i] {
+					t.Errorf("evaluateHealth() component %d = %v, want %v", i, components[i], tt.wantComponents[i])
+				}
+			}
+		})
+	}
+}
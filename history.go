@@ -0,0 +1,463 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultHistoryPath is the directory of the embedded history store when `path` is not set
+const defaultHistoryPath = ".vitals-history"
+
+// HistoryConfig enables recording every result for long-term availability data
+type HistoryConfig struct {
+	// Driver is "file" (the default, an embedded store at Path) or "postgres" (using DSN)
+	Driver string `toml:"driver,omitempty"`
+	Path   string `toml:"path,omitempty"`
+	DSN    string `toml:"dsn,omitempty"`
+
+	// Retention is how long raw results are kept before `vitals history prune` compacts them
+	// into daily rollups, e.g. "90d"
+	Retention string `toml:"retention,omitempty"`
+}
+
+// enabled reports whether a config has a [history] section
+func (h HistoryConfig) enabled() bool {
+	return h != HistoryConfig{}
+}
+
+// HistoryRecord is one endpoint result as stored in history
+type HistoryRecord struct {
+	Time       time.Time `json:"time"`
+	Target     string    `json:"target"`
+	URL        string    `json:"url"`
+	Method     string    `json:"method"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Success    bool      `json:"success"`
+	Duration   float64   `json:"duration_seconds"`
+	Error      string    `json:"error,omitempty"`
+	Labels     Labels    `json:"labels,omitempty"`
+}
+
+// HistoryRollup summarizes a day of results for one endpoint after raw rows are pruned
+type HistoryRollup struct {
+	Day       string  `json:"day"`
+	Target    string  `json:"target"`
+	URL       string  `json:"url"`
+	Checks    int     `json:"checks"`
+	Successes int     `json:"successes"`
+	Uptime    float64 `json:"uptime_percent"`
+	P95       float64 `json:"p95_seconds"`
+}
+
+// PruneStats reports what a prune compacted
+type PruneStats struct {
+	Compacted int
+	Rollups   int
+	Kept      int
+}
+
+// HistoryStore persists results between runs
+type HistoryStore interface {
+	Append(records []HistoryRecord) error
+
+	// Prune compacts raw records older than cutoff into daily rollups
+	Prune(cutoff time.Time) (PruneStats, error)
+
+	// Since returns the raw records from cutoff on, oldest first
+	Since(cutoff time.Time) ([]HistoryRecord, error)
+
+	Close() error
+}
+
+// historyConfig returns the first [history] section among the configs
+func historyConfig(configs []ConfigWithSource) (HistoryConfig, bool) {
+	for _, configWithSource := range configs {
+		if configWithSource.Config.History.enabled() {
+			return configWithSource.Config.History, true
+		}
+	}
+	return HistoryConfig{}, false
+}
+
+// openHistory opens the store described by a [history] section
+func openHistory(config HistoryConfig) (HistoryStore, error) {
+	switch config.Driver {
+	case "", "file":
+	case "postgres":
+		// Expand environment variables or read the keychain so credentials can stay out of the
+		// config file
+		dsn, err := expandSecret(config.DSN)
+		if err != nil {
+			return nil, err
+		}
+		if dsn == "" {
+			return nil, fmt.Errorf("history driver postgres requires a dsn")
+		}
+		return openPostgresHistory(dsn)
+	default:
+		return nil, fmt.Errorf("unknown history driver %q, expected file or postgres", config.Driver)
+	}
+
+	path := config.Path
+	if path == "" {
+		path = defaultHistoryPath
+	}
+	if err := os.MkdirAll(path, 0o755); err != nil {
+		return nil, fmt.Errorf("error creating history directory %s: %s", path, err)
+	}
+	return &fileHistory{dir: path}, nil
+}
+
+// parseRetention parses a retention period, accepting days ("90d") besides Go durations
+func parseRetention(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid retention %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid retention %q", s)
+	}
+	return d, nil
+}
+
+// historyRecords converts runs into history records stamped with the given time
+func historyRecords(runs []TargetRun, at time.Time) []HistoryRecord {
+	var records []HistoryRecord
+	for _, run := range runs {
+		for _, result := range run.Results {
+			record := HistoryRecord{
+				Time:       at.UTC(),
+				Target:     run.Key,
+				URL:        result.URL,
+				Method:     result.Method,
+				StatusCode: result.StatusCode,
+				Success:    result.Error == nil && result.Success,
+				Duration:   result.Duration.Seconds(),
+				Labels:     run.Labels,
+			}
+			if result.Error != nil {
+				record.Error = result.Error.Error()
+			}
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+// rollup compacts records into one rollup per day, target, and URL, sorted by those keys
+func rollup(records []HistoryRecord) []HistoryRollup {
+	type key struct{ day, target, url string }
+	groups := make(map[key][]HistoryRecord)
+	for _, record := range records {
+		k := key{record.Time.UTC().Format(time.DateOnly), record.Target, record.URL}
+		groups[k] = append(groups[k], record)
+	}
+
+	rollups := make([]HistoryRollup, 0, len(groups))
+	for k, group := range groups {
+		durations := make([]float64, len(group))
+		var successes int
+		for i, record := range group {
+			durations[i] = record.Duration
+			if record.Success {
+				successes++
+			}
+		}
+		rollups = append(rollups, HistoryRollup{
+			Day:       k.day,
+			Target:    k.target,
+			URL:       k.url,
+			Checks:    len(group),
+			Successes: successes,
+			Uptime:    100 * float64(successes) / float64(len(group)),
+			P95:       percentile(durations, 95),
+		})
+	}
+
+	sort.Slice(rollups, func(i, j int) bool {
+		a, b := rollups[i], rollups[j]
+		if a.Day != b.Day {
+			return a.Day < b.Day
+		}
+		if a.Target != b.Target {
+			return a.Target < b.Target
+		}
+		return a.URL < b.URL
+	})
+	return rollups
+}
+
+// percentile returns the nearest-rank percentile of values
+func percentile(values []float64, p float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[max(rank, 1)-1]
+}
+
+// fileHistoryMu serializes access to history files, as serve mode appends from many targets
+var fileHistoryMu sync.Mutex
+
+// fileHistory is the embedded store: raw results and rollups as JSON lines in a directory
+type fileHistory struct {
+	dir string
+}
+
+func (h *fileHistory) resultsPath() string { return filepath.Join(h.dir, "results.jsonl") }
+func (h *fileHistory) rollupsPath() string { return filepath.Join(h.dir, "rollups.jsonl") }
+
+// Append adds records to the results file
+func (h *fileHistory) Append(records []HistoryRecord) error {
+	fileHistoryMu.Lock()
+	defer fileHistoryMu.Unlock()
+
+	f, err := os.OpenFile(h.resultsPath(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("error opening history: %s", err)
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, record := range records {
+		if err := enc.Encode(record); err != nil {
+			return fmt.Errorf("error writing history: %s", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("error writing history: %s", err)
+	}
+	return nil
+}
+
+// Prune rewrites the results file without records older than cutoff, merging them into the
+// rollups file
+func (h *fileHistory) Prune(cutoff time.Time) (PruneStats, error) {
+	fileHistoryMu.Lock()
+	defer fileHistoryMu.Unlock()
+
+	var records []HistoryRecord
+	if err := readJSONLines(h.resultsPath(), &records); err != nil {
+		return PruneStats{}, err
+	}
+
+	var kept, old []HistoryRecord
+	for _, record := range records {
+		if record.Time.Before(cutoff) {
+			old = append(old, record)
+		} else {
+			kept = append(kept, record)
+		}
+	}
+	if len(old) == 0 {
+		return PruneStats{Kept: len(kept)}, nil
+	}
+
+	var rollups []HistoryRollup
+	if err := readJSONLines(h.rollupsPath(), &rollups); err != nil {
+		return PruneStats{}, err
+	}
+	fresh := rollup(old)
+	rollups = mergeRollups(rollups, fresh)
+
+	// Write the rollups first so an interrupted prune can only duplicate data, never lose it
+	if err := writeJSONLines(h.rollupsPath(), rollups); err != nil {
+		return PruneStats{}, err
+	}
+	if err := writeJSONLines(h.resultsPath(), kept); err != nil {
+		return PruneStats{}, err
+	}
+	return PruneStats{Compacted: len(old), Rollups: len(fresh), Kept: len(kept)}, nil
+}
+
+// Since returns the raw records from cutoff on, in the order they were appended
+func (h *fileHistory) Since(cutoff time.Time) ([]HistoryRecord, error) {
+	fileHistoryMu.Lock()
+	defer fileHistoryMu.Unlock()
+
+	var records []HistoryRecord
+	if err := readJSONLines(h.resultsPath(), &records); err != nil {
+		return nil, err
+	}
+	recent := records[:0]
+	for _, record := range records {
+		if !record.Time.Before(cutoff) {
+			recent = append(recent, record)
+		}
+	}
+	return recent, nil
+}
+
+// Close implements HistoryStore
+func (h *fileHistory) Close() error {
+	return nil
+}
+
+// mergeRollups adds fresh rollups to existing ones, combining any for the same day, target,
+// and URL; the combined p95 is the larger of the two, as the raw durations are gone
+func mergeRollups(existing, fresh []HistoryRollup) []HistoryRollup {
+	merged := append([]HistoryRollup(nil), existing...)
+	for _, r := range fresh {
+		i := slices.IndexFunc(merged, func(m HistoryRollup) bool {
+			return m.Day == r.Day && m.Target == r.Target && m.URL == r.URL
+		})
+		if i < 0 {
+			merged = append(merged, r)
+			continue
+		}
+		m := &merged[i]
+		m.Checks += r.Checks
+		m.Successes += r.Successes
+		m.Uptime = 100 * float64(m.Successes) / float64(m.Checks)
+		m.P95 = max(m.P95, r.P95)
+	}
+	return merged
+}
+
+// readJSONLines decodes every line of a file into the slice v points to; a missing file is empty
+func readJSONLines[T any](path string, v *[]T) error {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading %s: %s", path, err)
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(f)
+	for dec.More() {
+		var item T
+		if err := dec.Decode(&item); err != nil {
+			return fmt.Errorf("error parsing %s: %s", path, err)
+		}
+		*v = append(*v, item)
+	}
+	return nil
+}
+
+// writeJSONLines atomically replaces a file with one JSON document per line
+func writeJSONLines[T any](path string, items []T) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".vitals-history-*")
+	if err != nil {
+		return fmt.Errorf("error writing %s: %s", path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	for _, item := range items {
+		if err := enc.Encode(item); err != nil {
+			tmp.Close()
+			return fmt.Errorf("error writing %s: %s", path, err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error writing %s: %s", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error writing %s: %s", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("error writing %s: %s", path, err)
+	}
+	return nil
+}
+
+// recordHistory appends runs to the configured history store, if any
+func recordHistory(configs []ConfigWithSource, runs []TargetRun) error {
+	config, ok := historyConfig(configs)
+	if !ok {
+		return nil
+	}
+	return appendHistory(config, runs)
+}
+
+// appendHistory appends runs to the store described by a [history] section
+func appendHistory(config HistoryConfig, runs []TargetRun) error {
+	store, err := openHistory(config)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	return store.Append(historyRecords(runs, time.Now()))
+}
+
+// pruneHistory compacts results older than the retention period
+func pruneHistory(config HistoryConfig, retention string) (PruneStats, error) {
+	if retention == "" {
+		retention = config.Retention
+	}
+	if retention == "" {
+		return PruneStats{}, fmt.Errorf("no retention set, add `retention` to [history] or pass --retention")
+	}
+	d, err := parseRetention(retention)
+	if err != nil {
+		return PruneStats{}, err
+	}
+
+	store, err := openHistory(config)
+	if err != nil {
+		return PruneStats{}, err
+	}
+	defer store.Close()
+	return store.Prune(time.Now().Add(-d))
+}
+
+// runHistory implements `vitals history prune`
+func runHistory(args []string) int {
+	if len(args) == 0 || args[0] != "prune" {
+		fmt.Fprintln(os.Stderr, "usage: vitals history prune [-c vitals.toml] [--retention 90d]")
+		return 2
+	}
+
+	fs := flag.NewFlagSet("history prune", flag.ExitOnError)
+	var configFiles []string
+	fs.Var((*stringSlice)(&configFiles), "config", "Path to configuration file(s)")
+	fs.Var((*stringSlice)(&configFiles), "c", "Path to configuration file(s) (shorthand)")
+	retention := fs.String("retention", "", "Override the configured retention, e.g. 30d")
+	fs.Parse(args[1:])
+
+	if len(configFiles) == 0 {
+		configFiles = append(configFiles, "vitals.toml")
+	}
+	configs, err := loadConfigFiles(configFiles)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 1
+	}
+	config, ok := historyConfig(configs)
+	if !ok {
+		fmt.Fprintln(os.Stderr, "No [history] section in the config")
+		return 1
+	}
+
+	stats, err := pruneHistory(config, *retention)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 1
+	}
+	fmt.Printf("Compacted %d results into %d daily rollups, kept %d\n", stats.Compacted, stats.Rollups, stats.Kept)
+	return 0
+}
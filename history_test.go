@@ -0,0 +1,130 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRetention(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"90d", 90 * 24 * time.Hour, false},
+		{"36h", 36 * time.Hour, false},
+		{"0d", 0, true},
+		{"forever", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parseRetention(tt.in)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("parseRetention() = %v, %v, want %v (error %v)", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	values := []float64{0.5, 0.1, 0.2, 0.3, 0.4, 0.6, 0.7, 0.8, 0.9, 1.0}
+	if got := percentile(values, 95); got != 1.0 {
+		t.Errorf("p95 = %v, want 1.0", got)
+	}
+	if got := percentile(values, 50); got != 0.5 {
+		t.Errorf("p50 = %v, want 0.5", got)
+	}
+	if got := percentile(nil, 95); got != 0 {
+		t.Errorf("p95 of nothing = %v, want 0", got)
+	}
+}
+
+func TestFileHistoryPrune(t *testing.T) {
+	store, err := openHistory(HistoryConfig{Path: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	day := func(d int, hour int) time.Time {
+		return time.Date(2024, 1, d, hour, 0, 0, 0, time.UTC)
+	}
+	record := func(at time.Time, success bool, seconds float64) HistoryRecord {
+		return HistoryRecord{Time: at, Target: "a.toml::api", URL: "https://api.example.com/health", Success: success, Duration: seconds}
+	}
+
+	if err := store.Append([]HistoryRecord{
+		record(day(1, 1), true, 0.1),
+		record(day(1, 2), false, 0.3),
+		record(day(1, 3), true, 0.2),
+		record(day(1, 4), true, 0.1),
+		record(day(2, 1), true, 0.4),
+		record(day(5, 1), true, 0.1),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := store.Prune(day(3, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats != (PruneStats{Compacted: 5, Rollups: 2, Kept: 1}) {
+		t.Errorf("Prune() = %+v", stats)
+	}
+
+	// A second prune of a later day merges into the existing rollups
+	if err := store.Append([]HistoryRecord{record(day(2, 5), false, 0.9)}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Prune(day(3, 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	var rollups []HistoryRollup
+	if err := readJSONLines(store.(*fileHistory).rollupsPath(), &rollups); err != nil {
+		t.Fatal(err)
+	}
+	want := []HistoryRollup{
+		{Day: "2024-01-01", Target: "a.toml::api", URL: "https://api.example.com/health", Checks: 4, Successes: 3, Uptime: 75, P95: 0.3},
+		{Day: "2024-01-02", Target: "a.toml::api", URL: "https://api.example.com/health", Checks: 2, Successes: 1, Uptime: 50, P95: 0.9},
+	}
+	if len(rollups) != len(want) {
+		t.Fatalf("got %d rollups, want %d: %+v", len(rollups), len(want), rollups)
+	}
+	for i := range want {
+		if rollups[i] != want[i] {
+			t.Errorf("rollup %d = %+v, want %+v", i, rollups[i], want[i])
+		}
+	}
+
+	var kept []HistoryRecord
+	if err := readJSONLines(store.(*fileHistory).resultsPath(), &kept); err != nil {
+		t.Fatal(err)
+	}
+	if len(kept) != 1 || !kept[0].Time.Equal(day(5, 1)) {
+		t.Errorf("kept = %+v", kept)
+	}
+}
+
+func TestOpenHistoryDrivers(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  HistoryConfig
+		wantErr string
+	}{
+		{"unknown driver", HistoryConfig{Driver: "sqlite"}, `unknown history driver "sqlite"`},
+		{"postgres without dsn", HistoryConfig{Driver: "postgres"}, "requires a dsn"},
+		{"postgres dsn from missing env var", HistoryConfig{Driver: "postgres", DSN: "${VITALS_TEST_UNSET_DSN}"}, "requires a dsn"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := openHistory(tt.config)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("openHistory() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// defaultHookTimeout bounds a hook command when no timeout is configured
+const defaultHookTimeout = time.Minute
+
+// HooksConfig runs shell commands around checks, e.g. to open a tunnel first and close it after
+type HooksConfig struct {
+	PreRun  string `toml:"pre_run,omitempty"`
+	PostRun string `toml:"post_run,omitempty"`
+	Timeout string `toml:"timeout,omitempty"`
+
+	// IgnoreFailure reports a failed pre_run hook but runs the checks anyway instead of aborting
+	IgnoreFailure bool `toml:"ignore_failure,omitempty"`
+}
+
+// timeout returns how long each hook command may run
+func (h HooksConfig) timeout() time.Duration {
+	if timeout, err := time.ParseDuration(h.Timeout); err == nil && timeout > 0 {
+		return timeout
+	}
+	return defaultHookTimeout
+}
+
+// runHook runs a hook command with sh, sending its output to stderr so it never mixes with
+// JSON or HTML output; env is added to the environment
+func runHook(phase, command string, timeout time.Duration, env ...string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Env = append(append(os.Environ(), "VITALS_HOOK="+phase), env...)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	killProcessGroup(cmd)
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("%s hook timed out after %s", phase, timeout)
+		}
+		return fmt.Errorf("%s hook failed: %s", phase, err)
+	}
+	return nil
+}
+
+// hookResult reports a failed hook as a failed check so it shows up with the target's results
+func hookResult(phase string, err error) EndpointResult {
+	return EndpointResult{URL: phase + " hook", Method: "HOOK", Error: err}
+}
+
+// runTargetHooks wraps a target's checks in its pre_run and post_run hooks. A failed pre_run
+// skips the checks unless ignore_failure is set, and post_run always runs to clean up; any
+// failure is added as a HOOK result. targetName is the target's key, as its name is optional
+func runTargetHooks(hooks *HooksConfig, targetName string, check func() []EndpointResult) []EndpointResult {
+	if hooks == nil {
+		return check()
+	}
+	env := []string{"VITALS_TARGET=" + targetName}
+
+	var results, checked []EndpointResult
+	aborted := false
+	if hooks.PreRun != "" {
+		if err := runHook("pre_run", hooks.PreRun, hooks.timeout(), env...); err != nil {
+			results = append(results, hookResult("pre_run", err))
+			aborted = !hooks.IgnoreFailure
+		}
+	}
+	if !aborted {
+		checked = check()
+		results = append(results, checked...)
+	}
+
+	if hooks.PostRun != "" {
+		env = append(env, fmt.Sprintf("VITALS_FAILED=%d", failedChecks([]TargetRun{{Results: checked}})))
+		if err := runHook("post_run", hooks.PostRun, hooks.timeout(), env...); err != nil {
+			results = append(results, hookResult("post_run", err))
+		}
+	}
+	return results
+}
+
+// runPreRunHooks runs the pre_run hook of every config, returning the error that should abort
+// the run; failures of hooks with ignore_failure are only reported
+func runPreRunHooks(configs []ConfigWithSource) error {
+	for _, configWithSource := range configs {
+		hooks := configWithSource.Config.Hooks
+		if hooks.PreRun == "" {
+			continue
+		}
+		err := runHook("pre_run", hooks.PreRun, hooks.timeout(), "VITALS_CONFIG="+configWithSource.Filename)
+		if err == nil {
+			continue
+		}
+		if !hooks.IgnoreFailure {
+			return fmt.Errorf("error running hooks from %s: %s", configWithSource.Filename, err)
+		}
+		fmt.Fprintf(os.Stderr, "Warning: %s in %s, running checks anyway\n", err, configWithSource.Filename)
+	}
+	return nil
+}
+
+// runPostRunHooks runs the post_run hook of every config, reporting failures without
+// affecting the run's outcome
+func runPostRunHooks(configs []ConfigWithSource, failed int) {
+	for _, configWithSource := range configs {
+		hooks := configWithSource.Config.Hooks
+		if hooks.PostRun == "" {
+			continue
+		}
+		err := runHook("post_run", hooks.PostRun, hooks.timeout(),
+			"VITALS_CONFIG="+configWithSource.Filename, fmt.Sprintf("VITALS_FAILED=%d", failed))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error running hooks from %s: %s\n", configWithSource.Filename, err)
+		}
+	}
+}
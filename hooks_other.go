@@ -0,0 +1,8 @@
+//go:build !unix
+
+package main
+
+import "os/exec"
+
+// killProcessGroup is a no-op where process groups aren't available; only the shell is killed
+func killProcessGroup(cmd *exec.Cmd) {}
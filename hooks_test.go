@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunTargetHooks(t *testing.T) {
+	dir := t.TempDir()
+	log := filepath.Join(dir, "log")
+	passing := func() []EndpointResult { return []EndpointResult{{URL: "http://x/", Success: true}} }
+	failing := func() []EndpointResult { return []EndpointResult{{URL: "http://x/"}} }
+
+	tests := []struct {
+		name        string
+		hooks       *HooksConfig
+		check       func() []EndpointResult
+		wantURLs    []string
+		wantLog     string
+		wantErrText string
+	}{
+		{
+			name:     "no hooks",
+			check:    passing,
+			wantURLs: []string{"http://x/"},
+		},
+		{
+			name: "pre and post run",
+			hooks: &HooksConfig{
+				PreRun:  `echo "pre $VITALS_TARGET" >> ` + log,
+				PostRun: `echo "post $VITALS_FAILED" >> ` + log,
+			},
+			check:    failing,
+			wantURLs: []string{"http://x/"},
+			wantLog:  "pre api\npost 1\n",
+		},
+		{
+			name:        "failed pre_run aborts",
+			hooks:       &HooksConfig{PreRun: "exit 3", PostRun: "echo post >> " + log},
+			check:       passing,
+			wantURLs:    []string{"pre_run hook"},
+			wantLog:     "post\n",
+			wantErrText: "pre_run hook failed: exit status 3",
+		},
+		{
+			name:        "failed pre_run ignored",
+			hooks:       &HooksConfig{PreRun: "exit 3", IgnoreFailure: true},
+			check:       passing,
+			wantURLs:    []string{"pre_run hook", "http://x/"},
+			wantErrText: "exit status 3",
+		},
+		{
+			name:        "failed post_run annotates",
+			hooks:       &HooksConfig{PostRun: "exit 1"},
+			check:       passing,
+			wantURLs:    []string{"http://x/", "post_run hook"},
+			wantErrText: "post_run hook failed",
+		},
+		{
+			name:        "timeout",
+			hooks:       &HooksConfig{PreRun: "sleep 5", Timeout: "50ms"},
+			check:       passing,
+			wantURLs:    []string{"pre_run hook"},
+			wantErrText: "timed out after 50ms",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Remove(log)
+			results := runTargetHooks(tt.hooks, "api", tt.check)
+
+			var urls []string
+			var errs []string
+			for _, result := range results {
+				urls = append(urls, result.URL)
+				if result.Error != nil {
+					errs = append(errs, result.Error.Error())
+				}
+			}
+			if strings.Join(urls, " ") != strings.Join(tt.wantURLs, " ") {
+				t.Errorf("result URLs = %v, want %v", urls, tt.wantURLs)
+			}
+			if tt.wantErrText != "" && !strings.Contains(strings.Join(errs, "\n"), tt.wantErrText) {
+				t.Errorf("errors = %v, want %q", errs, tt.wantErrText)
+			}
+			got, _ := os.ReadFile(log)
+			if string(got) != tt.wantLog {
+				t.Errorf("hook log = %q, want %q", got, tt.wantLog)
+			}
+		})
+	}
+}
+
+func TestRunTargetHookEnv(t *testing.T) {
+	log := filepath.Join(t.TempDir(), "log")
+	// An unnamed target is identified by its key
+	target := TargetConfig{Hooks: &HooksConfig{PreRun: `echo "$VITALS_TARGET" > ` + log}}
+	runTarget(context.Background(), http.DefaultClient, Config{}, "internal", target, nil, runOptions{})
+
+	got, _ := os.ReadFile(log)
+	if string(got) != "internal\n" {
+		t.Errorf("VITALS_TARGET = %q, want the target key", got)
+	}
+}
+
+func TestRunPreRunHooks(t *testing.T) {
+	configs := []ConfigWithSource{
+		{Filename: "a.toml", Config: Config{Hooks: HooksConfig{PreRun: "true"}}},
+		{Filename: "b.toml", Config: Config{Hooks: HooksConfig{PreRun: "false", IgnoreFailure: true}}},
+	}
+	if err := runPreRunHooks(configs); err != nil {
+		t.Fatalf("runPreRunHooks() error = %v, want ignored failure", err)
+	}
+
+	configs = append(configs, ConfigWithSource{Filename: "c.toml", Config: Config{Hooks: HooksConfig{PreRun: "false"}}})
+	err := runPreRunHooks(configs)
+	if err == nil || !strings.Contains(err.Error(), "c.toml") {
+		t.Errorf("runPreRunHooks() error = %v, want failure from c.toml", err)
+	}
+}
+
+func TestHooksTimeout(t *testing.T) {
+	if got := (HooksConfig{}).timeout(); got != defaultHookTimeout {
+		t.Errorf("timeout() = %s, want default", got)
+	}
+	if got := (HooksConfig{Timeout: "5s"}).timeout(); got != 5*time.Second {
+		t.Errorf("timeout() = %s, want 5s", got)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"net"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// normalizeBaseURL prepares a base URL for requests: internationalized domain names are
+// converted to punycode, and the zone of an IPv6 literal such as [fe80::1%eth0] is escaped.
+// URLs that don't parse are returned unchanged for the request to report
+func normalizeBaseURL(baseURL string) string {
+	escaped := escapeIPv6Zone(baseURL)
+	u, err := url.Parse(escaped)
+	if err != nil || u.Host == "" {
+		return baseURL
+	}
+
+	host := u.Hostname()
+	if !strings.Contains(host, ":") && !isASCII(host) {
+		ascii, err := idna.Lookup.ToASCII(host)
+		if err != nil {
+			return baseURL
+		}
+		host = ascii
+	}
+	normalized := host
+	if port := u.Port(); port != "" {
+		normalized = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		normalized = "[" + host + "]"
+	}
+
+	// Leave the rest of the URL as written unless the host changed
+	if normalized == u.Host {
+		return escaped
+	}
+	u.Host = normalized
+	return u.String()
+}
+
+// escapeIPv6Zone escapes the % before the zone of a bracketed IPv6 host, which URLs require
+// as %25 but people write as is
+func escapeIPv6Zone(rawURL string) string {
+	start := strings.Index(rawURL, "://[")
+	if start < 0 {
+		return rawURL
+	}
+	end := strings.Index(rawURL[start:], "]")
+	if end < 0 {
+		return rawURL
+	}
+	host := rawURL[start+3 : start+end]
+	if i := strings.Index(host, "%"); i >= 0 && !strings.HasPrefix(host[i:], "%25") {
+		host = host[:i] + "%25" + host[i+1:]
+	}
+	return rawURL[:start+3] + host + rawURL[start+end:]
+}
+
+// displayURL shows the punycode labels of a URL's host in Unicode, for tables and reports
+func displayURL(rawURL string) string {
+	if !strings.Contains(rawURL, "xn--") {
+		return rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	host := u.Hostname()
+	unicode, err := idna.Display.ToUnicode(host)
+	if err != nil || unicode == host {
+		return rawURL
+	}
+	return strings.Replace(rawURL, host, unicode, 1)
+}
+
+// isASCII reports whether s has only ASCII characters
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
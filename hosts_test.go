@@ -0,0 +1,40 @@
+package main
+
+import "testing"
+
+func TestNormalizeBaseURL(t *testing.T) {
+	tests := []struct {
+		baseURL string
+		want    string
+	}{
+		{"https://example.com/v1", "https://example.com/v1"},
+		{"https://bücher.example", "https://xn--bcher-kva.example"},
+		{"https://bücher.example:8443/api", "https://xn--bcher-kva.example:8443/api"},
+		{"http://[::1]:8080", "http://[::1]:8080"},
+		{"http://[fe80::1%eth0]:8080", "http://[fe80::1%25eth0]:8080"},
+		{"http://[fe80::1%25eth0]", "http://[fe80::1%25eth0]"},
+		{"http://bad host", "http://bad host"},
+	}
+	for _, tt := range tests {
+		if got := normalizeBaseURL(tt.baseURL); got != tt.want {
+			t.Errorf("normalizeBaseURL(%q) = %s, want %s", tt.baseURL, got, tt.want)
+		}
+	}
+}
+
+func TestDisplayURL(t *testing.T) {
+	tests := []struct {
+		rawURL string
+		want   string
+	}{
+		{"https://example.com/health", "https://example.com/health"},
+		{"https://xn--bcher-kva.example:8443/api", "https://bücher.example:8443/api"},
+		{"http://[::1]:8080/health", "http://[::1]:8080/health"},
+		{"https://xn--a.example", "https://xn--a.example"},
+	}
+	for _, tt := range tests {
+		if got := displayURL(tt.rawURL); got != tt.want {
+			t.Errorf("displayURL(%q) = %s, want %s", tt.rawURL, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// importOptions are the flags shared by every `vitals import` source
+type importOptions struct {
+	name    string
+	output  string
+	filter  *regexp.Regexp
+	limit   int
+	baseURL string
+	vars    map[string]string
+}
+
+// importers maps `vitals import <kind>` to the function converting a source into targets
+var importers = map[string]func(source string, opts importOptions) (map[string]TargetConfig, error){
+	"sitemap": importSitemap,
+	"openapi": importOpenAPI,
+	"postman": importPostman,
+	"har":     importHAR,
+	"urls":    importURLList,
+}
+
+// runImport implements `vitals import <kind> <source>`, printing a ready-to-run config
+func runImport(args []string) int {
+	if len(args) == 0 || importers[args[0]] == nil {
+		fmt.Fprintf(os.Stderr, "usage: vitals import <%s> <source> [options]\n", strings.Join(importerKinds(), "|"))
+		return 2
+	}
+	kind := args[0]
+
+	fs := flag.NewFlagSet("import "+kind, flag.ExitOnError)
+	var opts importOptions
+	var filter string
+	fs.StringVar(&opts.name, "name", "", "Name of the generated target (derived from the host by default)")
+	fs.StringVar(&opts.output, "output", "", "Write the config to this file instead of stdout")
+	fs.StringVar(&opts.output, "o", "", "Write the config to this file instead of stdout (shorthand)")
+	fs.StringVar(&filter, "filter", "", "Only import URLs matching this regular expression")
+	fs.IntVar(&opts.limit, "limit", 0, "Maximum number of URLs to import (0 means unlimited)")
+	fs.StringVar(&opts.baseURL, "base-url", "", "Base URL to use instead of the one in the source")
+	var vars []string
+	fs.Var((*stringSlice)(&vars), "var", "Set a variable referenced by the source, as key=value (repeatable)")
+
+	positional := parseInterspersed(fs, args[1:])
+	if len(positional) != 1 {
+		fmt.Fprintf(os.Stderr, "usage: vitals import %s <source> [options]\n", kind)
+		return 2
+	}
+
+	opts.vars = make(map[string]string)
+	for _, v := range vars {
+		key, value, ok := strings.Cut(v, "=")
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Invalid --var '%s', expected key=value\n", v)
+			return 2
+		}
+		opts.vars[key] = value
+	}
+
+	if filter != "" {
+		re, err := regexp.Compile(filter)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error parsing filter '%s': %s\n", filter, err)
+			return 2
+		}
+		opts.filter = re
+	}
+
+	targets, err := importers[kind](positional[0], opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 1
+	}
+
+	out := io.Writer(os.Stdout)
+	if opts.output != "" {
+		f, err := os.Create(opts.output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating %s: %s\n", opts.output, err)
+			return 1
+		}
+		defer f.Close()
+		out = f
+	}
+
+	if err := writeImportedConfig(out, fmt.Sprintf("vitals import %s %s", kind, positional[0]), targets); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 1
+	}
+	return 0
+}
+
+// importerKinds returns the supported import sources in a stable order
+func importerKinds() []string {
+	kinds := make([]string, 0, len(importers))
+	for kind := range importers {
+		kinds = append(kinds, kind)
+	}
+	slices.Sort(kinds)
+	return kinds
+}
+
+// parseInterspersed parses flags that may appear before or after positional arguments,
+// returning the positional arguments in order
+func parseInterspersed(fs *flag.FlagSet, args []string) []string {
+	var positional []string
+	for {
+		fs.Parse(args)
+		if fs.NArg() == 0 {
+			return positional
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// writeImportedConfig encodes the generated targets as TOML
+func writeImportedConfig(w io.Writer, generatedBy string, targets map[string]TargetConfig) error {
+	if _, err := fmt.Fprintf(w, "# Generated by %s\n\n", generatedBy); err != nil {
+		return err
+	}
+
+	enc := toml.NewEncoder(w)
+	enc.Indent = ""
+	if err := enc.Encode(struct {
+		Targets map[string]TargetConfig `toml:"targets"`
+	}{targets}); err != nil {
+		return fmt.Errorf("error encoding config: %s", err)
+	}
+	return nil
+}
+
+// fetchSource reads an import source from a local file or an http(s) URL
+func fetchSource(source string) ([]byte, error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		data, err := os.ReadFile(source)
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %s", source, err)
+		}
+		return data, nil
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	req, err := http.NewRequest("GET", source, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %s", err)
+	}
+	req.Header.Set("User-Agent", resolveUserAgent("", ""))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching %s: %s", source, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error fetching %s: status %d", source, resp.StatusCode)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %s", source, err)
+	}
+	return data, nil
+}
+
+// filterURLs applies the --filter and --limit options to a list of URLs
+func filterURLs(urls []string, opts importOptions) []string {
+	var kept []string
+	for _, u := range urls {
+		if opts.filter != nil && !opts.filter.MatchString(u) {
+			continue
+		}
+		kept = append(kept, u)
+		if opts.limit > 0 && len(kept) == opts.limit {
+			break
+		}
+	}
+	return kept
+}
+
+// importedRequest is a single request recovered from an import source
+type importedRequest struct {
+	URL         string
+	Headers     map[string]string
+	StatusCodes []int
+
+	// Method (empty for GET), Body, and the body's ContentType become endpoint settings
+	Method      string
+	Body        string
+	ContentType string
+}
+
+// endpoint returns the endpoint table that sends the request to path
+func (r importedRequest) endpoint(path string) EndpointConfig {
+	endpoint := EndpointConfig{Path: path, Body: r.Body}
+	if method := strings.ToUpper(r.Method); method != "GET" {
+		endpoint.Method = method
+	}
+	// JSON and form bodies get their Content-Type from the body itself
+	if r.Body != "" && r.ContentType != "" && r.ContentType != bodyContentType([]byte(r.Body)) {
+		endpoint.Headers = map[string]string{"Content-Type": r.ContentType}
+	}
+	return endpoint
+}
+
+// targetsFromURLs groups absolute URLs by origin into one target per origin
+func targetsFromURLs(urls []string, name string) (map[string]TargetConfig, error) {
+	requests := make([]importedRequest, 0, len(urls))
+	for _, u := range urls {
+		requests = append(requests, importedRequest{URL: u})
+	}
+	return targetsFromRequests(requests, name)
+}
+
+// targetsFromRequests groups requests sharing an origin, headers, and statuses into targets, each with
+// the origin as its base URL and the paths (including query strings) as endpoints
+func targetsFromRequests(requests []importedRequest, name string) (map[string]TargetConfig, error) {
+	var groups []string
+	grouped := make(map[string]*TargetConfig)
+
+	for _, req := range requests {
+		u, err := url.Parse(strings.TrimSpace(req.URL))
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("invalid URL %q", req.URL)
+		}
+
+		origin := u.Scheme + "://" + u.Host
+		group := fmt.Sprintf("%s\n%v\n%s", origin, req.StatusCodes, headerSignature(req.Headers))
+		target, ok := grouped[group]
+		if !ok {
+			target = &TargetConfig{
+				Name:        u.Host,
+				BaseURLs:    []string{origin},
+				Headers:     req.Headers,
+				StatusCodes: req.StatusCodes,
+			}
+			grouped[group] = target
+			groups = append(groups, group)
+		}
+
+		endpoint := u.EscapedPath()
+		if endpoint == "" {
+			endpoint = "/"
+		}
+		if u.RawQuery != "" {
+			endpoint += "?" + u.RawQuery
+		}
+		if imported := req.endpoint(endpoint); !slices.ContainsFunc(target.Endpoints, func(e EndpointConfig) bool {
+			return reflect.DeepEqual(e, imported)
+		}) {
+			target.Endpoints = append(target.Endpoints, imported)
+		}
+	}
+
+	// Name targets after the origin (or the given name), numbering any that collide
+	targets := make(map[string]TargetConfig, len(groups))
+	for _, group := range groups {
+		target := grouped[group]
+		base := targetKey(name, target.BaseURLs[0])
+		key := base
+		for n := 2; ; n++ {
+			if _, taken := targets[key]; !taken {
+				break
+			}
+			key = fmt.Sprintf("%s_%d", base, n)
+		}
+		targets[key] = *target
+	}
+	return targets, nil
+}
+
+// headerSignature returns a canonical string for a set of headers, for grouping requests
+func headerSignature(headers map[string]string) string {
+	keys := make([]string, 0, len(headers))
+	for k := range headers {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s: %s\n", strings.ToLower(k), headers[k])
+	}
+	return b.String()
+}
+
+// nonKeyChars matches characters that would need quoting in a TOML table key
+var nonKeyChars = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// targetKey returns the given target name, or one derived from the origin's host
+func targetKey(name, origin string) string {
+	if name != "" {
+		return name
+	}
+	host := strings.TrimPrefix(strings.TrimPrefix(origin, "https://"), "http://")
+	return strings.Trim(nonKeyChars.ReplaceAllString(host, "_"), "_")
+}
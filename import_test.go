@@ -0,0 +1,80 @@
+package main
+
+import (
+	"regexp"
+	"slices"
+	"testing"
+)
+
+func TestTargetsFromURLs(t *testing.T) {
+	urls := []string{
+		"https://example.com/",
+		"https://example.com/docs?page=2",
+		"https://example.com/docs?page=2",
+		"https://status.example.com",
+	}
+
+	targets, err := targetsFromURLs(urls, "")
+	if err != nil {
+		t.Fatalf("targetsFromURLs() error = %v", err)
+	}
+
+	main, ok := targets["example_com"]
+	if !ok {
+		t.Fatalf("expected target example_com, got %v", targets)
+	}
+	if !slices.Equal(main.BaseURLs, []string{"https://example.com"}) {
+		t.Errorf("unexpected base_urls: %v", main.BaseURLs)
+	}
+	if !slices.Equal(endpointPaths(main.Endpoints), []string{"/", "/docs?page=2"}) {
+		t.Errorf("unexpected endpoints: %v", main.Endpoints)
+	}
+
+	status, ok := targets["status_example_com"]
+	if !ok || !slices.Equal(endpointPaths(status.Endpoints), []string{"/"}) {
+		t.Errorf("unexpected status target: %v", status)
+	}
+
+	if _, err := targetsFromURLs([]string{"not a url"}, ""); err == nil {
+		t.Error("expected error for relative URL")
+	}
+}
+
+func TestFilterURLs(t *testing.T) {
+	urls := []string{"https://a.com/blog/1", "https://a.com/docs/1", "https://a.com/docs/2", "https://a.com/docs/3"}
+	opts := importOptions{filter: regexp.MustCompile(`/docs/`), limit: 2}
+
+	got := filterURLs(urls, opts)
+	want := []string{"https://a.com/docs/1", "https://a.com/docs/2"}
+	if !slices.Equal(got, want) {
+		t.Errorf("filterURLs() = %v, want %v", got, want)
+	}
+}
+
+func TestParseSitemap(t *testing.T) {
+	index := `<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap><loc>https://example.com/sitemap-pages.xml</loc></sitemap>
+</sitemapindex>`
+	urlset := `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>https://example.com/</loc></url>
+  <url><loc>https://example.com/about</loc><lastmod>2024-01-01</lastmod></url>
+</urlset>`
+
+	doc, err := parseSitemap([]byte(index))
+	if err != nil {
+		t.Fatalf("parseSitemap() index error = %v", err)
+	}
+	if len(doc.Sitemaps) != 1 || doc.Sitemaps[0].Loc != "https://example.com/sitemap-pages.xml" {
+		t.Errorf("unexpected sitemap index entries: %v", doc.Sitemaps)
+	}
+
+	doc, err = parseSitemap([]byte(urlset))
+	if err != nil {
+		t.Fatalf("parseSitemap() urlset error = %v", err)
+	}
+	if len(doc.URLs) != 2 || doc.URLs[1].Loc != "https://example.com/about" {
+		t.Errorf("unexpected sitemap URLs: %v", doc.URLs)
+	}
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html/template"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// resolvedIncidentRetention is how long resolved incidents stay in the state file
+const resolvedIncidentRetention = 30 * 24 * time.Hour
+
+// incidentFailureHistory is how many of an incident's latest failures are kept
+const incidentFailureHistory = 10
+
+// Incident is a period during which an endpoint kept failing
+type Incident struct {
+	ID         string     `json:"id"`
+	Target     string     `json:"target"`
+	ConfigFile string     `json:"config_file"`
+	Method     string     `json:"method"`
+	URL        string     `json:"url"`
+	Name       string     `json:"name,omitempty"`
+	OpenedAt   time.Time  `json:"opened_at"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+
+	// FailedChecks counts the failing checks while open, and Reason is the latest failure
+	FailedChecks int    `json:"failed_checks"`
+	Reason       string `json:"reason"`
+
+	// Failures are the latest failing checks, oldest first
+	Failures []IncidentFailure `json:"failures,omitempty"`
+
+	// Issue and IssueURL identify the issue filed for the incident, e.g. "42" or "OPS-17"
+	Issue    string `json:"issue,omitempty"`
+	IssueURL string `json:"issue_url,omitempty"`
+}
+
+// IncidentFailure is one failing check of an incident
+type IncidentFailure struct {
+	Time   time.Time `json:"time"`
+	Reason string    `json:"reason"`
+}
+
+// addFailure counts a failing check, keeping the latest incidentFailureHistory of them
+func (i *Incident) addFailure(at time.Time, reason string) {
+	i.FailedChecks++
+	i.Reason = reason
+	i.Failures = append(i.Failures, IncidentFailure{Time: at.UTC(), Reason: reason})
+	if extra := len(i.Failures) - incidentFailureHistory; extra > 0 {
+		i.Failures = append([]IncidentFailure(nil), i.Failures[extra:]...)
+	}
+}
+
+// Open reports whether the incident has not been resolved yet
+func (i Incident) Open() bool {
+	return i.ResolvedAt == nil
+}
+
+// Duration is how long the incident lasted, or has lasted so far
+func (i Incident) Duration(now time.Time) time.Duration {
+	if i.ResolvedAt != nil {
+		return i.ResolvedAt.Sub(i.OpenedAt)
+	}
+	return now.Sub(i.OpenedAt)
+}
+
+// incidentsEnabled reports whether any config turns on incident tracking, which filing
+// issues also needs
+func incidentsEnabled(configs []ConfigWithSource) bool {
+	for _, configWithSource := range configs {
+		if configWithSource.Config.Global.Incidents || configWithSource.Config.Issues.enabled() {
+			return true
+		}
+	}
+	return false
+}
+
+// incidentID derives a short, stable ID from the endpoint and when the incident opened
+func incidentID(target, method, url string, opened time.Time) string {
+	sum := sha256.Sum256([]byte(target + " " + method + " " + url + " " + opened.Format(time.RFC3339Nano)))
+	return hex.EncodeToString(sum[:4])
+}
+
+// TrackIncidents opens an incident for every newly failing endpoint, updates open ones, and
+// resolves those that recovered, returning the incidents that were opened or resolved
+func (s *StateStore) TrackIncidents(runs []TargetRun, now time.Time) (opened, resolved []Incident) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	open := make(map[string]int)
+	for i, incident := range s.state.Incidents {
+		if incident.Open() {
+			open[runKey(incident.ConfigFile, incident.Target)+" "+incident.Method+" "+incident.URL] = i
+		}
+	}
+
+	for _, run := range runs {
+		for _, result := range run.Results {
+			key := run.Key + " " + result.Method + " " + result.URL
+			i, isOpen := open[key]
+			failing := resultFailed(result)
+
+			switch {
+			case failing && isOpen:
+				s.state.Incidents[i].addFailure(now, describeFailure(result))
+			case failing:
+				incident := Incident{
+					ID:         incidentID(run.Key, result.Method, result.URL, now),
+					Target:     run.TargetName,
+					ConfigFile: run.ConfigName,
+					Method:     result.Method,
+					URL:        result.URL,
+					Name:       result.Name,
+					OpenedAt:   now.UTC(),
+				}
+				incident.addFailure(now, describeFailure(result))
+				s.state.Incidents = append(s.state.Incidents, incident)
+				open[key] = len(s.state.Incidents) - 1
+				opened = append(opened, incident)
+			case isOpen:
+				resolvedAt := now.UTC()
+				s.state.Incidents[i].ResolvedAt = &resolvedAt
+				delete(open, key)
+				resolved = append(resolved, s.state.Incidents[i])
+			}
+		}
+	}
+
+	// Forget incidents resolved long ago so the state file stays small
+	kept := s.state.Incidents[:0]
+	for _, incident := range s.state.Incidents {
+		if incident.Open() || now.Sub(*incident.ResolvedAt) < resolvedIncidentRetention {
+			kept = append(kept, incident)
+		}
+	}
+	s.state.Incidents = kept
+	return opened, resolved
+}
+
+// SetIncidentIssue records the issue filed for an incident
+func (s *StateStore) SetIncidentIssue(id, issue, issueURL string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.state.Incidents {
+		if s.state.Incidents[i].ID == id {
+			s.state.Incidents[i].Issue = issue
+			s.state.Incidents[i].IssueURL = issueURL
+		}
+	}
+}
+
+// Incidents returns the stored incidents, newest first, optionally including resolved ones
+func (s *StateStore) Incidents(includeResolved bool) []Incident {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var incidents []Incident
+	for _, incident := range s.state.Incidents {
+		if includeResolved || incident.Open() {
+			incidents = append(incidents, incident)
+		}
+	}
+	sort.SliceStable(incidents, func(i, j int) bool {
+		return incidents[i].OpenedAt.After(incidents[j].OpenedAt)
+	})
+	return incidents
+}
+
+// printIncidents prints incidents as a table
+func printIncidents(incidents []Incident, now time.Time) {
+	if len(incidents) == 0 {
+		fmt.Println("No incidents")
+		return
+	}
+
+	rows := [][]string{{"ID", "STATUS", "TARGET", "ENDPOINT", "OPENED", "DURATION", "CHECKS", "REASON"}}
+	for _, incident := range incidents {
+		status := "open"
+		if !incident.Open() {
+			status = "resolved"
+		}
+		rows = append(rows, []string{
+			incident.ID,
+			status,
+			incident.Target,
+			incident.Method + " " + incident.URL,
+			incident.OpenedAt.Local().Format("2006-01-02 15:04"),
+			incident.Duration(now).Round(time.Second).String(),
+			fmt.Sprint(incident.FailedChecks),
+			incident.Reason,
+		})
+	}
+
+	widths := make([]int, len(rows[0]))
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], len(cell))
+		}
+	}
+	for _, row := range rows {
+		for i, cell := range row {
+			if i == len(row)-1 {
+				fmt.Println(cell)
+			} else {
+				fmt.Printf("%-*s  ", widths[i], cell)
+			}
+		}
+	}
+}
+
+// runIncidents implements `vitals incidents`, listing incidents from the state file
+func runIncidents(args []string) int {
+	fs := flag.NewFlagSet("incidents", flag.ExitOnError)
+	stateFile := fs.String("state-file", defaultStateFile, "File used to persist state between runs")
+	all := fs.Bool("all", false, "Include incidents resolved in the last 30 days")
+	jsonOutput := fs.Bool("json", false, "Output incidents in JSON format")
+	fs.BoolVar(jsonOutput, "j", false, "Output incidents in JSON format (shorthand)")
+	fs.Parse(args)
+
+	state, err := loadState(*stateFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 1
+	}
+	incidents := state.Incidents(*all)
+
+	if *jsonOutput {
+		data, err := json.MarshalIndent(struct {
+			Incidents []Incident `json:"incidents"`
+		}{incidents}, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error marshaling JSON output: %s\n", err)
+			return 1
+		}
+		fmt.Println(string(data))
+		return 0
+	}
+
+	printIncidents(incidents, time.Now())
+	return 0
+}
+
+// StatusPageTarget is one target's row on the status page
+type StatusPageTarget struct {
+	Name   string
+	Passed int
+	Total  int
+}
+
+// StatusPageIncident is an incident on the status page with its duration resolved
+type StatusPageIncident struct {
+	Incident
+	Duration time.Duration
+}
+
+// StatusPageData is the data passed to the status page template
+type StatusPageData struct {
+	Targets   []StatusPageTarget
+	Incidents []StatusPageIncident
+	Timestamp string
+}
+
+// renderStatusPage renders the latest run of every target along with recent incidents
+func renderStatusPage(runs []TargetRun, incidents []Incident, now time.Time) (string, error) {
+	data := StatusPageData{Timestamp: now.Format("2006-01-02 15:04:05")}
+	for _, run := range runs {
+		target := StatusPageTarget{Name: run.TargetName, Total: len(run.Results)}
+		for _, result := range run.Results {
+			if result.State().passing() {
+				target.Passed++
+			}
+		}
+		data.Targets = append(data.Targets, target)
+	}
+	for _, incident := range incidents {
+		data.Incidents = append(data.Incidents, StatusPageIncident{
+			Incident: incident,
+			Duration: incident.Duration(now).Round(time.Second),
+		})
+	}
+
+	tmpl, err := template.ParseFS(templateFS, "templates/status.html")
+	if err != nil {
+		return "", fmt.Errorf("error parsing HTML template: %v", err)
+	}
+	var buf strings.Builder
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("error executing HTML template: %v", err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTrackIncidents(t *testing.T) {
+	store, err := loadState(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(results ...EndpointResult) []TargetRun {
+		return []TargetRun{{Key: runKey("a.toml", "api"), TargetName: "api", ConfigName: "a.toml", Results: results}}
+	}
+	up := EndpointResult{Method: "GET", URL: "http://x/health", StatusCode: 200, Success: true}
+	down := EndpointResult{Method: "GET", URL: "http://x/health", StatusCode: 503}
+	refused := EndpointResult{Method: "GET", URL: "http://x/health", Error: errors.New("connection refused")}
+
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	steps := []struct {
+		results      []EndpointResult
+		wantOpened   int
+		wantResolved int
+	}{
+		{[]EndpointResult{up}, 0, 0},
+		{[]EndpointResult{down}, 1, 0},
+		{[]EndpointResult{refused}, 0, 0},
+		{[]EndpointResult{up}, 0, 1},
+		{[]EndpointResult{up}, 0, 0},
+	}
+	for i, step := range steps {
+		opened, resolved := store.TrackIncidents(run(step.results...), start.Add(time.Duration(i)*time.Minute))
+		if len(opened) != step.wantOpened || len(resolved) != step.wantResolved {
+			t.Fatalf("step %d: opened %d, resolved %d, want %d, %d", i, len(opened), len(resolved), step.wantOpened, step.wantResolved)
+		}
+	}
+
+	if open := store.Incidents(false); len(open) != 0 {
+		t.Fatalf("Incidents(false) = %v, want none open", open)
+	}
+	all := store.Incidents(true)
+	if len(all) != 1 {
+		t.Fatalf("Incidents(true) returned %d incidents, want 1", len(all))
+	}
+	incident := all[0]
+	if incident.FailedChecks != 2 {
+		t.Errorf("FailedChecks = %d, want 2", incident.FailedChecks)
+	}
+	if incident.Reason != "error: connection refused" {
+		t.Errorf("Reason = %q, want the latest failure", incident.Reason)
+	}
+	if got := incident.Duration(time.Time{}); got != 2*time.Minute {
+		t.Errorf("Duration() = %s, want 2m0s", got)
+	}
+	if len(incident.Failures) != 2 || !incident.Failures[0].Time.Equal(start.Add(time.Minute)) || incident.Failures[1].Reason != "error: connection refused" {
+		t.Errorf("Failures = %+v, want both failing checks", incident.Failures)
+	}
+
+	// Resolved incidents are dropped once they're past retention
+	store.TrackIncidents(run(up), start.Add(resolvedIncidentRetention+time.Hour))
+	if all := store.Incidents(true); len(all) != 0 {
+		t.Errorf("expected resolved incident to be pruned, got %v", all)
+	}
+}
+
+func TestRenderStatusPage(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	runs := []TargetRun{{
+		TargetName: "api",
+		Results: []EndpointResult{
+			{Method: "GET", URL: "http://x/", Success: true},
+			{Method: "GET", URL: "http://x/down", StatusCode: 503},
+		},
+	}}
+	incidents := []Incident{{Target: "api", Method: "GET", URL: "http://x/down", OpenedAt: now.Add(-90 * time.Second), FailedChecks: 3, Reason: "status 503"}}
+
+	page, err := renderStatusPage(runs, incidents, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"1/2 passing", "Degraded", "GET http://x/down", "1m30s (ongoing)", "status 503"} {
+		if !strings.Contains(page, want) {
+			t.Errorf("status page missing %q", want)
+		}
+	}
+}
+
+func TestIncidentFailureHistory(t *testing.T) {
+	var incident Incident
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i := range incidentFailureHistory + 3 {
+		incident.addFailure(start.Add(time.Duration(i)*time.Minute), fmt.Sprintf("failure %d", i))
+	}
+	if incident.FailedChecks != incidentFailureHistory+3 {
+		t.Errorf("FailedChecks = %d, want every failure counted", incident.FailedChecks)
+	}
+	if len(incident.Failures) != incidentFailureHistory || incident.Failures[0].Reason != "failure 3" || incident.Reason != fmt.Sprintf("failure %d", incidentFailureHistory+2) {
+		t.Errorf("Failures = %+v, want the latest %d", incident.Failures, incidentFailureHistory)
+	}
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// defaultIssueAfter is how long an endpoint keeps failing before an issue is filed when
+// after is not set
+const defaultIssueAfter = 15 * time.Minute
+
+// IssuesConfig files an issue in GitHub or Jira for endpoints that keep failing, and comments
+// on and closes it once they recover. Filing issues turns on incident tracking
+type IssuesConfig struct {
+	// After is how long an incident has to last before its issue is filed, e.g. "30m"
+	After string `toml:"after,omitempty"`
+
+	GitHub *GitHubIssuesConfig `toml:"github,omitempty"`
+	Jira   *JiraIssuesConfig   `toml:"jira,omitempty"`
+}
+
+// enabled reports whether an issue tracker is configured
+func (c IssuesConfig) enabled() bool {
+	return c.GitHub != nil || c.Jira != nil
+}
+
+// issuesConfig returns the first [issues] section with a tracker among the configs
+func issuesConfig(configs []ConfigWithSource) (IssuesConfig, bool) {
+	for _, configWithSource := range configs {
+		if configWithSource.Config.Issues.enabled() {
+			return configWithSource.Config.Issues, true
+		}
+	}
+	return IssuesConfig{}, false
+}
+
+// configTargets looks targets up by run key among configs
+func configTargets(configs []ConfigWithSource) func(key string) (Config, TargetConfig, bool) {
+	return func(key string) (Config, TargetConfig, bool) {
+		for _, configWithSource := range configs {
+			for name, target := range configWithSource.Config.Targets {
+				if runKey(configWithSource.Filename, name) == key {
+					return configWithSource.Config, target, true
+				}
+			}
+		}
+		return Config{}, TargetConfig{}, false
+	}
+}
+
+// issueAfter parses the after duration of config
+func issueAfter(config IssuesConfig) (time.Duration, error) {
+	if config.After == "" {
+		return defaultIssueAfter, nil
+	}
+	after, err := time.ParseDuration(config.After)
+	if err != nil || after < 0 {
+		return 0, fmt.Errorf("invalid issues after %q, expected a duration such as \"30m\"", config.After)
+	}
+	return after, nil
+}
+
+// issueReport is what an issue says about an incident, rendered by each tracker in its
+// own markup
+type issueReport struct {
+	title   string
+	summary string
+	history []string
+	repro   string
+}
+
+// issueTracker files and closes the issues of incidents
+type issueTracker interface {
+	// open files an issue, returning its ID and a link to it
+	open(report issueReport) (id, url string, err error)
+
+	// resolve comments on an issue and closes it
+	resolve(id, comment string) error
+}
+
+// newIssueTracker returns the tracker of config
+func newIssueTracker(config IssuesConfig) (issueTracker, error) {
+	switch {
+	case config.GitHub != nil && config.Jira != nil:
+		return nil, fmt.Errorf("issues can't be filed in both github and jira")
+	case config.GitHub != nil:
+		return newGitHubIssues(*config.GitHub)
+	case config.Jira != nil:
+		return newJiraIssues(*config.Jira)
+	}
+	return nil, fmt.Errorf("issues need a [issues.github] or [issues.jira] section")
+}
+
+// fileIssues files an issue for every incident of runs that has been open for at least
+// after, and comments on and closes the issues of resolved incidents. lookup finds the
+// target of a run key for the curl command in the issue. It returns the incidents it filed
+// issues for and every failure
+func fileIssues(config IssuesConfig, runs []TargetRun, state *StateStore, resolved []Incident, lookup func(key string) (Config, TargetConfig, bool), now time.Time) ([]Incident, []error) {
+	after, err := issueAfter(config)
+	if err != nil {
+		return nil, []error{err}
+	}
+	tracker, err := newIssueTracker(config)
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	// Only the incidents of these runs, so concurrent target runs don't file the same issue
+	keys := make(map[string]bool, len(runs))
+	for _, run := range runs {
+		keys[run.Key] = true
+	}
+
+	var filed []Incident
+	var errs []error
+	for _, incident := range state.Incidents(false) {
+		key := runKey(incident.ConfigFile, incident.Target)
+		if !keys[key] || incident.Issue != "" || incident.Duration(now) < after {
+			continue
+		}
+		var repro string
+		if config, target, ok := lookup(key); ok {
+			repro = curlCommand(config, target, incident.Method, incident.URL)
+		}
+		id, url, err := tracker.open(newIssueReport(incident, repro, now))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error filing issue for incident %s: %s", incident.ID, err))
+			continue
+		}
+		state.SetIncidentIssue(incident.ID, id, url)
+		incident.Issue, incident.IssueURL = id, url
+		filed = append(filed, incident)
+	}
+
+	for _, incident := range resolved {
+		if incident.Issue == "" {
+			continue
+		}
+		comment := fmt.Sprintf("%s %s recovered at %s after %s and %d failed checks.",
+			incident.Method, incident.URL, incident.ResolvedAt.Format(issueTimeLayout),
+			incident.Duration(now).Round(time.Second), incident.FailedChecks)
+		if err := tracker.resolve(incident.Issue, comment); err != nil {
+			errs = append(errs, fmt.Errorf("error closing issue %s of incident %s: %s", incident.Issue, incident.ID, err))
+		}
+	}
+	return filed, errs
+}
+
+// issueTimeLayout formats times in issues
+const issueTimeLayout = "2006-01-02 15:04:05 UTC"
+
+// newIssueReport describes an open incident with its latest failures and a command that
+// repeats the failing request
+func newIssueReport(incident Incident, repro string, now time.Time) issueReport {
+	endpoint := incident.Method + " " + incident.URL
+	if incident.Name != "" {
+		endpoint = incident.Name + " (" + endpoint + ")"
+	}
+	report := issueReport{
+		title: fmt.Sprintf("[vitals] %s: %s is failing", incident.Target, endpoint),
+		summary: fmt.Sprintf("%s of target %s (%s) has been failing for %s, with %d failed checks since %s. Incident %s, latest failure: %s",
+			endpoint, incident.Target, incident.ConfigFile, incident.Duration(now).Round(time.Second), incident.FailedChecks,
+			incident.OpenedAt.Format(issueTimeLayout), incident.ID, incident.Reason),
+		repro: repro,
+	}
+	for _, failure := range incident.Failures {
+		report.history = append(report.history, failure.Time.Format(issueTimeLayout)+": "+failure.Reason)
+	}
+	return report
+}
+
+// curlCommand returns a curl command that sends an endpoint's request the way the target
+// does, with the overrides of the endpoint whose request it is. Credentials are left out
+// unless they are secret references such as ${API_TOKEN}
+func curlCommand(config Config, target TargetConfig, method, url string) string {
+	target, _ = resolveTarget(config, target, runOptions{})
+	if endpoint, ok := requestEndpoint(target, method, url); ok {
+		target = target.forEndpoint(endpoint)
+	}
+	args := []string{"curl", "-i"}
+	if method != "GET" {
+		args = append(args, "-X", method)
+	}
+	args = append(args, "-A", shellQuote(target.UserAgent))
+	for _, name := range slices.Sorted(maps.Keys(target.Headers)) {
+		value := target.Headers[name]
+		if sensitiveHeader(name) && !credentialReference(value) {
+			value = "<redacted>"
+		}
+		args = append(args, "-H", shellQuote(name+": "+value))
+	}
+	if target.BasicAuth != nil {
+		password := target.BasicAuth.Password
+		if !credentialReference(password) {
+			password = "<password>"
+		}
+		args = append(args, "-u", shellQuote(target.BasicAuth.Username+":"+password))
+	}
+
+	if body, err := requestBody(target); err == nil && body != nil {
+		if _, set := headerValue(target.Headers, "Content-Type"); !set {
+			args = append(args, "-H", shellQuote("Content-Type: "+bodyContentType(body)))
+		}
+		if target.BodyFile != "" {
+			args = append(args, "--data-binary", shellQuote("@"+target.BodyFile))
+		} else {
+			args = append(args, "--data-raw", shellQuote(target.Body))
+		}
+	}
+
+	if target.ClientCert != "" {
+		args = append(args, "--cert", shellQuote(target.ClientCert), "--key", shellQuote(target.ClientKey))
+	}
+	if target.CAFile != "" {
+		args = append(args, "--cacert", shellQuote(target.CAFile))
+	}
+	if target.InsecureSkipVerify {
+		args = append(args, "-k")
+	}
+	return strings.Join(append(args, shellQuote(url)), " ")
+}
+
+// requestEndpoint finds the endpoint of a target that sends method to url
+func requestEndpoint(target TargetConfig, method, url string) (EndpointConfig, bool) {
+	for _, baseURL := range target.BaseURLs {
+		for _, endpoint := range target.Endpoints {
+			endpointTarget := target.forEndpoint(endpoint)
+			if requestMethod(endpointTarget) == method && withQueryParams(constructURL(baseURL, endpoint.Path), endpointTarget.QueryParams) == url {
+				return endpoint, true
+			}
+		}
+	}
+	return EndpointConfig{}, false
+}
+
+// sensitiveHeader reports whether a header is likely to carry a credential
+func sensitiveHeader(name string) bool {
+	name = strings.ToLower(name)
+	switch name {
+	case "authorization", "proxy-authorization", "cookie":
+		return true
+	}
+	return strings.Contains(name, "token") || strings.Contains(name, "key") || strings.Contains(name, "secret")
+}
+
+// envReferencePattern matches a credential that is only an environment variable reference,
+// optionally after an authorization scheme, e.g. "${API_TOKEN}" or "Bearer $API_TOKEN"
+var envReferencePattern = regexp.MustCompile(`^(?:[A-Za-z][A-Za-z0-9._-]* )?(?:\$\{[A-Za-z_][A-Za-z0-9_]*\}|\$[A-Za-z_][A-Za-z0-9_]*)$`)
+
+// credentialReference reports whether a credential setting names where the secret comes
+// from, an environment variable or a secret store, rather than holding it. Anything else with
+// a $ in it, such as "hunter$2" or "Bearer ab$cd", may be the secret itself
+func credentialReference(value string) bool {
+	return envReferencePattern.MatchString(value) || isSecretReference(value)
+}
+
+// headerValue looks a header up by case-insensitive name
+func headerValue(headers map[string]string, name string) (string, bool) {
+	for key, value := range headers {
+		if strings.EqualFold(key, name) {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// shellQuote quotes s for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCurlCommand(t *testing.T) {
+	config := Config{Global: GlobalConfig{UserAgent: "vitals-probe", Headers: map[string]string{"Accept": "application/json"}}}
+	tests := []struct {
+		name   string
+		target TargetConfig
+		method string
+		want   string
+	}{
+		{
+			"get",
+			TargetConfig{},
+			"GET",
+			`curl -i -A 'vitals-probe' -H 'Accept: application/json' 'https://api.example.com/health'`,
+		},
+		{
+			"credentials",
+			TargetConfig{
+				Headers:   map[string]string{"Authorization": "Bearer s3cret", "X-Api-Key": "${API_KEY}", "X-Team": "o'brien"},
+				BasicAuth: &BasicAuth{Username: "monitor", Password: "hunter2"},
+			},
+			"GET",
+			`curl -i -A 'vitals-probe' -H 'Accept: application/json' -H 'Authorization: <redacted>' -H 'X-Api-Key: ${API_KEY}' -H 'X-Team: o'\''brien' -u 'monitor:<password>' 'https://api.example.com/health'`,
+		},
+		{
+			"body and tls",
+			TargetConfig{Body: `{"ping":true}`, CAFile: "/etc/ca.pem", InsecureSkipVerify: true},
+			"POST",
+			`curl -i -X POST -A 'vitals-probe' -H 'Accept: application/json' -H 'Content-Type: application/json' --data-raw '{"ping":true}' --cacert '/etc/ca.pem' -k 'https://api.example.com/health'`,
+		},
+		{
+			"literal dollars",
+			TargetConfig{
+				Headers:   map[string]string{"Authorization": "Bearer ab$cd", "X-Api-Key": "hunter$2"},
+				BasicAuth: &BasicAuth{Username: "monitor", Password: "pa$$word"},
+			},
+			"GET",
+			`curl -i -A 'vitals-probe' -H 'Accept: application/json' -H 'Authorization: <redacted>' -H 'X-Api-Key: <redacted>' -u 'monitor:<password>' 'https://api.example.com/health'`,
+		},
+		{
+			"endpoint overrides",
+			TargetConfig{
+				BaseURLs: []string{"https://api.example.com"},
+				Headers:  map[string]string{"X-Team": "ops"},
+				Endpoints: []EndpointConfig{
+					{Path: "/status"},
+					{Path: "/health", Method: "put", Body: "up", Headers: map[string]string{"x-team": "payments", "Content-Type": "text/plain"}},
+				},
+			},
+			"PUT",
+			`curl -i -X PUT -A 'vitals-probe' -H 'Accept: application/json' -H 'Content-Type: text/plain' -H 'x-team: payments' --data-raw 'up' 'https://api.example.com/health'`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := curlCommand(config, tt.target, tt.method, "https://api.example.com/health"); got != tt.want {
+				t.Errorf("curlCommand() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCredentialReference(t *testing.T) {
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{"${API_TOKEN}", true},
+		{"$API_TOKEN", true},
+		{"Bearer ${API_TOKEN}", true},
+		{"keyring:api-token", true},
+		{"s3cret", false},
+		{"hunter$2", false},
+		{"pa$$word", false},
+		{"Bearer ab$cd", false},
+		{"${API_TOKEN}extra", false},
+	}
+	for _, tt := range tests {
+		if got := credentialReference(tt.value); got != tt.want {
+			t.Errorf("credentialReference(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+// fakeGitHub records the issue API calls it answers
+type fakeGitHub struct {
+	*httptest.Server
+	mu     sync.Mutex
+	calls  []string
+	issues []map[string]any
+}
+
+func newFakeGitHub(t *testing.T) *fakeGitHub {
+	g := &fakeGitHub{}
+	g.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		if r.Header.Get("Authorization") != "Bearer ghp_token" {
+			http.Error(w, `{"message":"Bad credentials"}`, http.StatusUnauthorized)
+			return
+		}
+		var payload map[string]any
+		json.NewDecoder(r.Body).Decode(&payload)
+		g.calls = append(g.calls, r.Method+" "+r.URL.Path)
+		if r.Method == "POST" && r.URL.Path == "/repos/acme/ops/issues" {
+			g.issues = append(g.issues, payload)
+			json.NewEncoder(w).Encode(map[string]any{"number": 42, "html_url": "https://github.com/acme/ops/issues/42"})
+			return
+		}
+		w.Write([]byte("{}"))
+	}))
+	t.Cleanup(g.Close)
+	return g
+}
+
+func TestFileIssues(t *testing.T) {
+	github := newFakeGitHub(t)
+	t.Setenv("GITHUB_TOKEN", "ghp_token")
+	issues := IssuesConfig{After: "10m", GitHub: &GitHubIssuesConfig{Repo: "acme/ops", Token: "${GITHUB_TOKEN}", Labels: []string{"outage"}, APIURL: github.URL}}
+	configs := []ConfigWithSource{{Filename: "a.toml", Config: Config{Targets: map[string]TargetConfig{"api": {}}}}}
+
+	store, err := loadState(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	runs := func(results ...EndpointResult) []TargetRun {
+		return []TargetRun{{Key: runKey("a.toml", "api"), TargetName: "api", ConfigName: "a.toml", Results: results}}
+	}
+	down := EndpointResult{Method: "GET", URL: "http://x/health", StatusCode: 503}
+	up := EndpointResult{Method: "GET", URL: "http://x/health", StatusCode: 200, Success: true}
+
+	start := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	steps := []struct {
+		at        time.Duration
+		result    EndpointResult
+		wantFiled int
+		wantCalls int
+	}{
+		{0, down, 0, 0},
+		{5 * time.Minute, down, 0, 0},
+		{10 * time.Minute, down, 1, 1},
+		{15 * time.Minute, down, 0, 1},
+		{20 * time.Minute, up, 0, 3},
+	}
+	for i, step := range steps {
+		now := start.Add(step.at)
+		_, resolved := store.TrackIncidents(runs(step.result), now)
+		filed, errs := fileIssues(issues, runs(step.result), store, resolved, configTargets(configs), now)
+		if len(errs) > 0 {
+			t.Fatalf("step %d: %v", i, errs)
+		}
+		if len(filed) != step.wantFiled || len(github.calls) != step.wantCalls {
+			t.Fatalf("step %d: filed %d issues after %d calls, want %d after %d: %v", i, len(filed), len(github.calls), step.wantFiled, step.wantCalls, github.calls)
+		}
+		if len(filed) > 0 && (filed[0].Issue != "42" || filed[0].IssueURL != "https://github.com/acme/ops/issues/42") {
+			t.Errorf("filed %+v, want issue 42", filed[0])
+		}
+	}
+
+	wantCalls := []string{"POST /repos/acme/ops/issues", "POST /repos/acme/ops/issues/42/comments", "PATCH /repos/acme/ops/issues/42"}
+	if strings.Join(github.calls, ", ") != strings.Join(wantCalls, ", ") {
+		t.Errorf("calls = %v, want %v", github.calls, wantCalls)
+	}
+	issue := github.issues[0]
+	body, _ := issue["body"].(string)
+	if issue["title"] != "[vitals] api: GET http://x/health is failing" {
+		t.Errorf("title = %v", issue["title"])
+	}
+	for _, want := range []string{
+		"has been failing for 10m0s, with 3 failed checks since 2026-10-14 12:00:00 UTC",
+		"### Failure history\n\n- 2026-10-14 12:00:00 UTC: status 503\n- 2026-10-14 12:05:00 UTC: status 503\n",
+		"```sh\ncurl -i -A 'vitals/" + version + "' 'http://x/health'\n```",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("body is missing %q:\n%s", want, body)
+		}
+	}
+	if incidents := store.Incidents(true); len(incidents) != 1 || incidents[0].Issue != "42" {
+		t.Errorf("incidents = %+v, want the issue recorded", incidents)
+	}
+}
+
+func TestFileIssuesErrors(t *testing.T) {
+	github := newFakeGitHub(t)
+	store, err := loadState(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	runs := []TargetRun{{Key: runKey("a.toml", "api"), TargetName: "api", ConfigName: "a.toml", Results: []EndpointResult{{Method: "GET", URL: "http://x/health", StatusCode: 503}}}}
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	store.TrackIncidents(runs, now)
+	noTargets := configTargets(nil)
+
+	tests := []struct {
+		name    string
+		config  IssuesConfig
+		wantErr string
+	}{
+		{"invalid after", IssuesConfig{After: "soon", GitHub: &GitHubIssuesConfig{Repo: "acme/ops"}}, `invalid issues after "soon", expected a duration such as "30m"`},
+		{"both trackers", IssuesConfig{GitHub: &GitHubIssuesConfig{Repo: "acme/ops"}, Jira: &JiraIssuesConfig{}}, "issues can't be filed in both github and jira"},
+		{"bad repo", IssuesConfig{GitHub: &GitHubIssuesConfig{Repo: "ops"}}, `github issues need repo as owner/name, got "ops"`},
+		{"rejected", IssuesConfig{After: "0s", GitHub: &GitHubIssuesConfig{Repo: "acme/ops", Token: "wrong", APIURL: github.URL}}, "error filing issue for incident "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, errs := fileIssues(tt.config, runs, store, nil, noTargets, now)
+			if len(errs) != 1 || !strings.HasPrefix(errs[0].Error(), tt.wantErr) {
+				t.Errorf("errors = %v, want %s", errs, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// issueTimeout bounds a single issue tracker request
+const issueTimeout = 10 * time.Second
+
+// GitHubIssuesConfig files issues in a GitHub repository
+type GitHubIssuesConfig struct {
+	// Repo is the repository as owner/name, e.g. "acme/ops"
+	Repo string `toml:"repo,omitempty"`
+
+	// Token needs permission to write issues and may reference environment variables, e.g.
+	// "${GITHUB_TOKEN}"
+	Token string `toml:"token,omitempty"`
+
+	Labels []string `toml:"labels,omitempty"`
+
+	// APIURL is the API of GitHub Enterprise Server, e.g. "https://github.example.com/api/v3"
+	// (default https://api.github.com)
+	APIURL string `toml:"api_url,omitempty"`
+}
+
+// JiraIssuesConfig files issues in a Jira project
+type JiraIssuesConfig struct {
+	// URL is the Jira site, e.g. "https://acme.atlassian.net"
+	URL     string `toml:"url,omitempty"`
+	Project string `toml:"project,omitempty"`
+
+	// IssueType is the type of filed issues (default "Bug")
+	IssueType string `toml:"issue_type,omitempty"`
+
+	// Email and Token authenticate with an Atlassian API token; without Email, Token is sent
+	// as a personal access token of Jira Data Center. Token may reference environment variables
+	Email string `toml:"email,omitempty"`
+	Token string `toml:"token,omitempty"`
+
+	Labels []string `toml:"labels,omitempty"`
+
+	// CloseTransition is the workflow transition that closes an issue (default "Done")
+	CloseTransition string `toml:"close_transition,omitempty"`
+}
+
+// issueAPI sends JSON requests to an issue tracker's REST API
+type issueAPI struct {
+	baseURL   string
+	authorize func(req *http.Request)
+	headers   map[string]string
+	client    *http.Client
+}
+
+// call sends payload (nil for none) to path and decodes the response into result (nil to
+// ignore it)
+func (a issueAPI) call(method, path string, payload, result any) error {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, a.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for name, value := range a.headers {
+		req.Header.Set(name, value)
+	}
+	a.authorize(req)
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s %s returned %s: %s", method, path, resp.Status, strings.TrimSpace(string(data)))
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("error decoding %s %s response: %s", method, path, err)
+	}
+	return nil
+}
+
+// gitHubIssues files issues through the GitHub REST API
+type gitHubIssues struct {
+	config GitHubIssuesConfig
+	api    issueAPI
+}
+
+// newGitHubIssues resolves the token of the GitHub tracker
+func newGitHubIssues(config GitHubIssuesConfig) (*gitHubIssues, error) {
+	if owner, name, ok := strings.Cut(config.Repo, "/"); !ok || owner == "" || name == "" {
+		return nil, fmt.Errorf("github issues need repo as owner/name, got %q", config.Repo)
+	}
+	token, err := expandSecret(config.Token)
+	if err != nil {
+		return nil, err
+	}
+	baseURL := strings.TrimSuffix(config.APIURL, "/")
+	if baseURL == "" {
+		baseURL = "https://api.github.com"
+	}
+	return &gitHubIssues{
+		config: config,
+		api: issueAPI{
+			baseURL: baseURL + "/repos/" + config.Repo,
+			authorize: func(req *http.Request) {
+				if token != "" {
+					req.Header.Set("Authorization", "Bearer "+token)
+				}
+			},
+			headers: map[string]string{"Accept": "application/vnd.github+json", "X-GitHub-Api-Version": "2022-11-28"},
+			client:  &http.Client{Timeout: issueTimeout},
+		},
+	}, nil
+}
+
+// open creates an issue with the report as Markdown
+func (g *gitHubIssues) open(report issueReport) (string, string, error) {
+	var b strings.Builder
+	b.WriteString(report.summary + "\n")
+	if len(report.history) > 0 {
+		b.WriteString("\n### Failure history\n\n")
+		for _, line := range report.history {
+			b.WriteString("- " + line + "\n")
+		}
+	}
+	if report.repro != "" {
+		b.WriteString("\n### Reproduce\n\n```sh\n" + report.repro + "\n```\n")
+	}
+
+	var created struct {
+		Number  int    `json:"number"`
+		HTMLURL string `json:"html_url"`
+	}
+	payload := map[string]any{"title": report.title, "body": b.String()}
+	if len(g.config.Labels) > 0 {
+		payload["labels"] = g.config.Labels
+	}
+	if err := g.api.call("POST", "/issues", payload, &created); err != nil {
+		return "", "", err
+	}
+	return fmt.Sprint(created.Number), created.HTMLURL, nil
+}
+
+// resolve comments on the issue and closes it as completed
+func (g *gitHubIssues) resolve(id, comment string) error {
+	if err := g.api.call("POST", "/issues/"+id+"/comments", map[string]string{"body": comment}, nil); err != nil {
+		return err
+	}
+	return g.api.call("PATCH", "/issues/"+id, map[string]string{"state": "closed", "state_reason": "completed"}, nil)
+}
+
+// jiraIssues files issues through the Jira REST API version 2, which takes wiki markup
+type jiraIssues struct {
+	config JiraIssuesConfig
+	api    issueAPI
+}
+
+// newJiraIssues resolves the token of the Jira tracker
+func newJiraIssues(config JiraIssuesConfig) (*jiraIssues, error) {
+	if config.URL == "" || config.Project == "" {
+		return nil, fmt.Errorf("jira issues need url and project")
+	}
+	if config.IssueType == "" {
+		config.IssueType = "Bug"
+	}
+	if config.CloseTransition == "" {
+		config.CloseTransition = "Done"
+	}
+	token, err := expandSecret(config.Token)
+	if err != nil {
+		return nil, err
+	}
+	return &jiraIssues{
+		config: config,
+		api: issueAPI{
+			baseURL: strings.TrimSuffix(config.URL, "/") + "/rest/api/2",
+			authorize: func(req *http.Request) {
+				switch {
+				case config.Email != "":
+					req.SetBasicAuth(config.Email, token)
+				case token != "":
+					req.Header.Set("Authorization", "Bearer "+token)
+				}
+			},
+			client: &http.Client{Timeout: issueTimeout},
+		},
+	}, nil
+}
+
+// open creates an issue with the report as wiki markup
+func (j *jiraIssues) open(report issueReport) (string, string, error) {
+	var b strings.Builder
+	b.WriteString(report.summary + "\n")
+	if len(report.history) > 0 {
+		b.WriteString("\nh3. Failure history\n\n")
+		for _, line := range report.history {
+			b.WriteString("* " + line + "\n")
+		}
+	}
+	if report.repro != "" {
+		b.WriteString("\nh3. Reproduce\n\n{noformat}\n" + report.repro + "\n{noformat}\n")
+	}
+
+	fields := map[string]any{
+		"project":     map[string]string{"key": j.config.Project},
+		"issuetype":   map[string]string{"name": j.config.IssueType},
+		"summary":     report.title,
+		"description": b.String(),
+	}
+	if len(j.config.Labels) > 0 {
+		fields["labels"] = j.config.Labels
+	}
+	var created struct {
+		Key string `json:"key"`
+	}
+	if err := j.api.call("POST", "/issue", map[string]any{"fields": fields}, &created); err != nil {
+		return "", "", err
+	}
+	return created.Key, strings.TrimSuffix(j.config.URL, "/") + "/browse/" + created.Key, nil
+}
+
+// resolve comments on the issue and moves it through the close transition
+func (j *jiraIssues) resolve(id, comment string) error {
+	if err := j.api.call("POST", "/issue/"+id+"/comment", map[string]string{"body": comment}, nil); err != nil {
+		return err
+	}
+
+	var available struct {
+		Transitions []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"transitions"`
+	}
+	if err := j.api.call("GET", "/issue/"+id+"/transitions", nil, &available); err != nil {
+		return err
+	}
+	for _, transition := range available.Transitions {
+		if strings.EqualFold(transition.Name, j.config.CloseTransition) {
+			return j.api.call("POST", "/issue/"+id+"/transitions", map[string]any{"transition": map[string]string{"id": transition.ID}}, nil)
+		}
+	}
+	return fmt.Errorf("issue %s has no %q transition", id, j.config.CloseTransition)
+}
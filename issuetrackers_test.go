@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestJiraIssues(t *testing.T) {
+	var calls []string
+	var created, comment, transition map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, token, ok := r.BasicAuth(); !ok || user != "monitor@example.com" || token != "jira_token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		var payload map[string]any
+		json.NewDecoder(r.Body).Decode(&payload)
+		switch r.Method + " " + r.URL.Path {
+		case "POST /rest/api/2/issue":
+			created = payload
+			w.Write([]byte(`{"id":"10001","key":"OPS-17"}`))
+		case "POST /rest/api/2/issue/OPS-17/comment":
+			comment = payload
+			w.Write([]byte(`{}`))
+		case "GET /rest/api/2/issue/OPS-17/transitions":
+			w.Write([]byte(`{"transitions":[{"id":"11","name":"In Progress"},{"id":"31","name":"Done"}]}`))
+		case "POST /rest/api/2/issue/OPS-17/transitions":
+			transition = payload
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	t.Setenv("JIRA_TOKEN", "jira_token")
+	jira, err := newJiraIssues(JiraIssuesConfig{URL: server.URL, Project: "OPS", Email: "monitor@example.com", Token: "${JIRA_TOKEN}", Labels: []string{"vitals"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, url, err := jira.open(issueReport{title: "api is failing", summary: "GET http://x/health has been failing", history: []string{"12:00: status 503"}, repro: "curl -i 'http://x/health'"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "OPS-17" || url != server.URL+"/browse/OPS-17" {
+		t.Errorf("open() = %s, %s, want OPS-17 and its link", id, url)
+	}
+	fields, _ := created["fields"].(map[string]any)
+	description, _ := fields["description"].(string)
+	if fields["summary"] != "api is failing" || !strings.Contains(description, "h3. Failure history\n\n* 12:00: status 503\n") || !strings.Contains(description, "{noformat}\ncurl -i 'http://x/health'\n{noformat}") {
+		t.Errorf("fields = %+v", fields)
+	}
+	if issueType, _ := fields["issuetype"].(map[string]any); issueType["name"] != "Bug" {
+		t.Errorf("issuetype = %v, want the default Bug", fields["issuetype"])
+	}
+
+	if err := jira.resolve("OPS-17", "recovered"); err != nil {
+		t.Fatal(err)
+	}
+	if comment["body"] != "recovered" {
+		t.Errorf("comment = %v", comment)
+	}
+	if id, _ := transition["transition"].(map[string]any); id["id"] != "31" {
+		t.Errorf("transition = %v, want Done", transition)
+	}
+
+	// A workflow without the close transition leaves the issue open with an error
+	jira.config.CloseTransition = "Closed"
+	if err := jira.resolve("OPS-17", "recovered"); err == nil || err.Error() != `issue OPS-17 has no "Closed" transition` {
+		t.Errorf("error = %v, want the missing transition", err)
+	}
+}
+
+func TestNewIssueTrackerErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  IssuesConfig
+		wantErr string
+	}{
+		{"none", IssuesConfig{}, "issues need a [issues.github] or [issues.jira] section"},
+		{"jira without project", IssuesConfig{Jira: &JiraIssuesConfig{URL: "https://acme.atlassian.net"}}, "jira issues need url and project"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newIssueTracker(tt.config); err == nil || err.Error() != tt.wantErr {
+				t.Errorf("error = %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// kafkaTimeout bounds writing one batch to Kafka
+const kafkaTimeout = 10 * time.Second
+
+// KafkaConfig streams every result to a Kafka topic, keyed by target
+type KafkaConfig struct {
+	Brokers []string `toml:"brokers,omitempty"`
+	Topic   string   `toml:"topic,omitempty"`
+
+	// ChangesTopic also receives state changes as JSON when set
+	ChangesTopic string `toml:"changes_topic,omitempty"`
+
+	// Format is "json" (the default) or "avro", encoded with resultAvroSchema; a SchemaID
+	// prefixes Avro messages with the Confluent schema registry wire header
+	Format   string `toml:"format,omitempty"`
+	SchemaID int    `toml:"schema_id,omitzero"`
+}
+
+// resultAvroSchema describes the Avro encoding of a result message
+const resultAvroSchema = `{
+  "type": "record",
+  "name": "EndpointResult",
+  "namespace": "vitals",
+  "fields": [
+    {"name": "time", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+    {"name": "target", "type": "string"},
+    {"name": "config_file", "type": "string"},
+    {"name": "url", "type": "string"},
+    {"name": "method", "type": "string"},
+    {"name": "status_code", "type": ["null", "int"], "default": null},
+    {"name": "success", "type": "boolean"},
+    {"name": "duration_seconds", "type": "double"},
+    {"name": "error", "type": ["null", "string"], "default": null},
+    {"name": "failure_reason", "type": ["null", "string"], "default": null},
+    {"name": "runbook_url", "type": ["null", "string"], "default": null},
+    {"name": "labels", "type": {"type": "map", "values": "string"}, "default": {}}
+  ]
+}`
+
+// kafkaSink produces messages with a kafka-go writer
+type kafkaSink struct {
+	config KafkaConfig
+	writer *kafka.Writer
+}
+
+// newKafkaSink validates the config and prepares a writer
+func newKafkaSink(config KafkaConfig) (*kafkaSink, error) {
+	if len(config.Brokers) == 0 || config.Topic == "" {
+		return nil, fmt.Errorf("kafka sink requires brokers and a topic")
+	}
+	switch config.Format {
+	case "", "json", "avro":
+	default:
+		return nil, fmt.Errorf("unknown kafka format %q, expected json or avro", config.Format)
+	}
+
+	return &kafkaSink{
+		config: config,
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(config.Brokers...),
+			Balancer:     &kafka.Hash{},
+			RequiredAcks: kafka.RequireAll,
+		},
+	}, nil
+}
+
+// Publish writes every result (and state change, if a changes topic is set) in one batch
+func (s *kafkaSink) Publish(batch SinkBatch) error {
+	messages := make([]kafka.Message, 0, len(batch.Results)+len(batch.Changes))
+	for _, result := range batch.Results {
+		value, err := s.encodeResult(result)
+		if err != nil {
+			return sinkError("kafka", err)
+		}
+		messages = append(messages, kafka.Message{
+			Topic: s.config.Topic,
+			Key:   []byte(result.ConfigFile + "::" + result.Target),
+			Value: value,
+		})
+	}
+	if s.config.ChangesTopic != "" {
+		for _, change := range batch.Changes {
+			value, err := json.Marshal(change)
+			if err != nil {
+				return sinkError("kafka", err)
+			}
+			messages = append(messages, kafka.Message{
+				Topic: s.config.ChangesTopic,
+				Key:   []byte(change.ConfigFile + "::" + change.Target),
+				Value: value,
+			})
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), kafkaTimeout)
+	defer cancel()
+	if err := s.writer.WriteMessages(ctx, messages...); err != nil {
+		return sinkError("kafka", err)
+	}
+	return nil
+}
+
+// encodeResult encodes a result in the configured format
+func (s *kafkaSink) encodeResult(result ResultMessage) ([]byte, error) {
+	if s.config.Format != "avro" {
+		return json.Marshal(result)
+	}
+
+	var header []byte
+	if s.config.SchemaID > 0 {
+		header = binary.BigEndian.AppendUint32([]byte{0}, uint32(s.config.SchemaID))
+	}
+	return append(header, encodeResultAvro(result)...), nil
+}
+
+// Close flushes and closes the writer
+func (s *kafkaSink) Close() error {
+	return s.writer.Close()
+}
+
+// encodeResultAvro encodes a result as an Avro binary record following resultAvroSchema
+func encodeResultAvro(result ResultMessage) []byte {
+	var b []byte
+	b = avroLong(b, result.Time.UnixMilli())
+	b = avroString(b, result.Target)
+	b = avroString(b, result.ConfigFile)
+	b = avroString(b, result.URL)
+	b = avroString(b, result.Method)
+	if result.StatusCode != 0 {
+		b = avroLong(avroLong(b, 1), int64(result.StatusCode))
+	} else {
+		b = avroLong(b, 0)
+	}
+	if result.Success {
+		b = append(b, 1)
+	} else {
+		b = append(b, 0)
+	}
+	b = binary.LittleEndian.AppendUint64(b, math.Float64bits(result.Duration))
+	b = avroOptionalString(b, result.Error)
+	b = avroOptionalString(b, result.FailureReason)
+	b = avroOptionalString(b, result.RunbookURL)
+	b = avroStringMap(b, result.Labels)
+	return b
+}
+
+// avroLong appends a zigzag varint, Avro's encoding of int and long
+func avroLong(b []byte, n int64) []byte {
+	return binary.AppendUvarint(b, uint64((n<<1)^(n>>63)))
+}
+
+// avroString appends a length-prefixed string
+func avroString(b []byte, s string) []byte {
+	return append(avroLong(b, int64(len(s))), s...)
+}
+
+// avroOptionalString appends a ["null", "string"] union, using null for an empty string
+func avroOptionalString(b []byte, s string) []byte {
+	if s == "" {
+		return avroLong(b, 0)
+	}
+	return avroString(avroLong(b, 1), s)
+}
+
+// avroStringMap appends a map of strings as a single block sorted by key, followed by the
+// empty block that ends it
+func avroStringMap(b []byte, m map[string]string) []byte {
+	if len(m) > 0 {
+		b = avroLong(b, int64(len(m)))
+		for _, key := range slices.Sorted(maps.Keys(m)) {
+			b = avroString(avroString(b, key), m[key])
+		}
+	}
+	return avroLong(b, 0)
+}
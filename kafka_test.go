@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestEncodeResultAvro(t *testing.T) {
+	result := ResultMessage{
+		Time:   time.UnixMilli(1),
+		Target: "api",
+		Labels: Labels{"env": "prod"},
+		JSONResult: JSONResult{
+			URL:        "u",
+			Method:     "GET",
+			StatusCode: 200,
+			Success:    true,
+			Duration:   0.5,
+		},
+	}
+
+	want := []byte{
+		0x02,                // time: 1 as zigzag
+		0x06, 'a', 'p', 'i', // target
+		0x00,      // config_file: ""
+		0x02, 'u', // url
+		0x06, 'G', 'E', 'T', // method
+		0x02, 0x90, 0x03, // status_code: union branch 1, 200 as zigzag
+		0x01,                                           // success
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xe0, 0x3f, // duration: 0.5
+		0x00,                // error: null
+		0x00,                // failure_reason: null
+		0x00,                // runbook_url: null
+		0x02,                // labels: one entry
+		0x06, 'e', 'n', 'v', // key
+		0x08, 'p', 'r', 'o', 'd', // value
+		0x00, // end of map
+	}
+	if got := encodeResultAvro(result); !bytes.Equal(got, want) {
+		t.Errorf("encodeResultAvro() = % x\nwant                % x", got, want)
+	}
+}
+
+func TestKafkaSchemaRegistryHeader(t *testing.T) {
+	sink, err := newKafkaSink(KafkaConfig{Brokers: []string{"localhost:9092"}, Topic: "vitals", Format: "avro", SchemaID: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	value, err := sink.encodeResult(ResultMessage{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(value, []byte{0, 0, 0, 0, 7}) {
+		t.Errorf("value = % x, want the magic byte and schema id 7 first", value)
+	}
+}
+
+func TestNewKafkaSinkValidation(t *testing.T) {
+	tests := []KafkaConfig{
+		{Topic: "vitals"},
+		{Brokers: []string{"localhost:9092"}},
+		{Brokers: []string{"localhost:9092"}, Topic: "vitals", Format: "protobuf"},
+	}
+	for _, config := range tests {
+		if _, err := newKafkaSink(config); err == nil {
+			t.Errorf("newKafkaSink(%+v) should fail", config)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+//go:build darwin
+
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// keychainEncoding marks values vitals stored base64 encoded, which keeps any value safe to
+// hand to the security tool
+const keychainEncoding = "vitals-base64:"
+
+// keyringGet reads a secret from the login keychain
+func keyringGet(name string) (string, error) {
+	out, err := exec.Command("security", "find-generic-password", "-s", keyringService, "-a", name, "-w").Output()
+	if err != nil {
+		return "", securityError(err)
+	}
+	value := strings.TrimSuffix(string(out), "\n")
+	if encoded, ok := strings.CutPrefix(value, keychainEncoding); ok {
+		decoded, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return "", fmt.Errorf("error decoding secret: %s", err)
+		}
+		return string(decoded), nil
+	}
+	return value, nil
+}
+
+// keyringSet stores a secret in the login keychain, replacing any previous value. The command
+// goes through security's stdin so the value never appears in a process listing
+func keyringSet(name, value string) error {
+	encoded := keychainEncoding + base64.StdEncoding.EncodeToString([]byte(value))
+	cmd := exec.Command("security", "-i")
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s %s -a %s -w %s\n", keyringService, name, encoded))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// keyringDelete removes a secret from the login keychain
+func keyringDelete(name string) error {
+	if err := exec.Command("security", "delete-generic-password", "-s", keyringService, "-a", name).Run(); err != nil {
+		return securityError(err)
+	}
+	return nil
+}
+
+// securityError explains the security tool's exit status 44, which means no such item
+func securityError(err error) error {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 44 {
+		return fmt.Errorf("not found, store it with vitals secret set")
+	}
+	return err
+}
@@ -0,0 +1,56 @@
+//go:build linux
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// secretTool runs secret-tool, the libsecret client, with the given stdin
+func secretTool(stdin string, args ...string) (string, error) {
+	path, err := exec.LookPath("secret-tool")
+	if err != nil {
+		return "", errors.New("secret-tool not found, install libsecret-tools")
+	}
+	cmd := exec.Command(path, args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s: %s", err, msg)
+		}
+		return "", err
+	}
+	return string(out), nil
+}
+
+// keyringGet reads a secret from the Secret Service keyring
+func keyringGet(name string) (string, error) {
+	value, err := secretTool("", "lookup", "service", keyringService, "account", name)
+	if err != nil {
+		// lookup exits 1 without a message when nothing matches
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			return "", errors.New("not found, store it with vitals secret set")
+		}
+		return "", err
+	}
+	return value, nil
+}
+
+// keyringSet stores a secret in the Secret Service keyring, passing it on stdin
+func keyringSet(name, value string) error {
+	_, err := secretTool(value, "store", "--label", keyringService+" "+name, "service", keyringService, "account", name)
+	return err
+}
+
+// keyringDelete removes a secret from the Secret Service keyring
+func keyringDelete(name string) error {
+	_, err := secretTool("", "clear", "service", keyringService, "account", name)
+	return err
+}
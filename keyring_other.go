@@ -0,0 +1,15 @@
+//go:build !linux && !darwin && !windows
+
+package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// errKeyringUnsupported is returned on platforms without a supported keychain
+var errKeyringUnsupported = fmt.Errorf("no supported keychain on %s, use environment variables instead", runtime.GOOS)
+
+func keyringGet(name string) (string, error) { return "", errKeyringUnsupported }
+func keyringSet(name, value string) error    { return errKeyringUnsupported }
+func keyringDelete(name string) error        { return errKeyringUnsupported }
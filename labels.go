@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Labels are key=value metadata given with --label, such as a deploy SHA or CI job URL,
+// attached to every result of a run so it can be correlated with the change that triggered it
+type Labels map[string]string
+
+// String formats the labels as key=value pairs sorted by key
+func (l Labels) String() string {
+	keys := make([]string, 0, len(l))
+	for key := range l {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		pairs[i] = key + "=" + l[key]
+	}
+	return strings.Join(pairs, ", ")
+}
+
+// Set adds a key=value label, letting a repeated --label flag fill the map
+func (l *Labels) Set(value string) error {
+	key, val, ok := strings.Cut(value, "=")
+	key = strings.TrimSpace(key)
+	if !ok || key == "" {
+		return fmt.Errorf("expected key=value, got %q", value)
+	}
+	if *l == nil {
+		*l = make(Labels)
+	}
+	(*l)[key] = val
+	return nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLabelsSet(t *testing.T) {
+	tests := []struct {
+		values  []string
+		want    string
+		wantErr bool
+	}{
+		{[]string{"sha=abc123", "env=prod"}, "env=prod, sha=abc123", false},
+		{[]string{"job=https://ci.example.com/jobs/1?a=b"}, "job=https://ci.example.com/jobs/1?a=b", false},
+		{[]string{"env=staging", "env=prod"}, "env=prod", false},
+		{[]string{"empty="}, "empty=", false},
+		{[]string{"noequals"}, "", true},
+		{[]string{"=value"}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.values, " "), func(t *testing.T) {
+			var labels Labels
+			var err error
+			for _, value := range tt.values {
+				if err = labels.Set(value); err != nil {
+					break
+				}
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Set() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && labels.String() != tt.want {
+				t.Errorf("String() = %q, want %q", labels.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestLabelsInOutputs(t *testing.T) {
+	labels := Labels{"sha": "abc123"}
+	runs := []TargetRun{{Key: "a.toml::api", TargetName: "api", Labels: labels, Results: []EndpointResult{{URL: "https://api.example.com", Success: true}}}}
+
+	if records := historyRecords(runs, time.Now()); records[0].Labels["sha"] != "abc123" {
+		t.Errorf("history record labels = %v", records[0].Labels)
+	}
+	if batch := buildSinkBatch(runs, nil, time.Now()); batch.Results[0].Labels["sha"] != "abc123" {
+		t.Errorf("sink message labels = %v", batch.Results[0].Labels)
+	}
+	if message := alertMessage(alertRule{AlertRule: AlertRule{Name: "down"}}, runs[0], true, "1 failure"); !strings.Contains(message, "Labels: sha=abc123") {
+		t.Errorf("alert message missing labels:\n%s", message)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// latencyBands color the DURATION column by speed: green below slow, yellow below critical,
+// and red from there on. The zero value leaves durations the color of their row
+type latencyBands struct {
+	slow, critical time.Duration
+}
+
+// parseLatencyBands parses the two thresholds of a latency_colors setting, e.g. ["300ms", "1s"]
+func parseLatencyBands(values []string) (latencyBands, error) {
+	if len(values) == 0 {
+		return latencyBands{}, nil
+	}
+	if len(values) != 2 {
+		return latencyBands{}, fmt.Errorf("expected two thresholds such as [\"300ms\", \"1s\"], got %d", len(values))
+	}
+	var thresholds [2]time.Duration
+	for i, value := range values {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return latencyBands{}, err
+		}
+		if d <= 0 {
+			return latencyBands{}, fmt.Errorf("threshold %s is not positive", value)
+		}
+		thresholds[i] = d
+	}
+	if thresholds[0] >= thresholds[1] {
+		return latencyBands{}, fmt.Errorf("threshold %s is not below %s", values[0], values[1])
+	}
+	return latencyBands{slow: thresholds[0], critical: thresholds[1]}, nil
+}
+
+// latencyBandsByConfig returns the latency bands of every config that sets them, keyed by
+// config file, reporting invalid settings
+func latencyBandsByConfig(configs []ConfigWithSource) map[string]latencyBands {
+	bands := make(map[string]latencyBands)
+	for _, config := range configs {
+		b, err := parseLatencyBands(config.Config.Global.LatencyColors)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error parsing latency_colors in %s: %s\n", config.Filename, err)
+			continue
+		}
+		bands[config.Filename] = b
+	}
+	return bands
+}
+
+// color returns the color for a duration, or nil when no bands are configured
+func (b latencyBands) color(d time.Duration, green, red func(a ...interface{}) string) func(a ...interface{}) string {
+	switch {
+	case b.critical == 0:
+		return nil
+	case d < b.slow:
+		return green
+	case d < b.critical:
+		return color.New(color.FgYellow).SprintFunc()
+	default:
+		return red
+	}
+}
+
+// LatencyPercentiles are the min, p50, p95, and max durations of a target's answered checks,
+// which show the tail latency an average hides
+type LatencyPercentiles struct {
+	Min float64 `json:"min_seconds"`
+	P50 float64 `json:"p50_seconds"`
+	P95 float64 `json:"p95_seconds"`
+	Max float64 `json:"max_seconds"`
+}
+
+// newLatencyPercentiles summarizes the durations of answered checks, returning nil for fewer
+// than two since they'd only repeat the average
+func newLatencyPercentiles(results []EndpointResult) *LatencyPercentiles {
+	var values []float64
+	for _, result := range results {
+		if result.Error == nil && !result.Skipped {
+			values = append(values, result.Duration.Seconds())
+		}
+	}
+	if len(values) < 2 {
+		return nil
+	}
+	return &LatencyPercentiles{
+		Min: slices.Min(values),
+		P50: percentile(values, 50),
+		P95: percentile(values, 95),
+		Max: slices.Max(values),
+	}
+}
+
+// String formats the durations for summary rows, e.g. "Min: 0.05s, p50: 0.10s, p95: 0.40s, Max: 0.41s"
+func (l LatencyPercentiles) String() string {
+	return fmt.Sprintf("Min: %.2fs, p50: %.2fs, p95: %.2fs, Max: %.2fs", l.Min, l.P50, l.P95, l.Max)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseLatencyBands(t *testing.T) {
+	tests := []struct {
+		values  []string
+		want    latencyBands
+		wantErr bool
+	}{
+		{nil, latencyBands{}, false},
+		{[]string{"300ms", "1s"}, latencyBands{300 * time.Millisecond, time.Second}, false},
+		{[]string{"300ms"}, latencyBands{}, true},
+		{[]string{"1s", "300ms"}, latencyBands{}, true},
+		{[]string{"0s", "1s"}, latencyBands{}, true},
+		{[]string{"fast", "1s"}, latencyBands{}, true},
+	}
+	for _, tt := range tests {
+		got, err := parseLatencyBands(tt.values)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("parseLatencyBands(%v) = %v, %v, want %v, error %v", tt.values, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestLatencyBandsColor(t *testing.T) {
+	green := func(a ...interface{}) string { return "green" }
+	red := func(a ...interface{}) string { return "red" }
+
+	if (latencyBands{}).color(time.Hour, green, red) != nil {
+		t.Error("unconfigured bands should leave the row color")
+	}
+
+	bands := latencyBands{slow: 300 * time.Millisecond, critical: time.Second}
+	tests := []struct {
+		duration time.Duration
+		want     string
+	}{
+		{100 * time.Millisecond, "green"},
+		{300 * time.Millisecond, "yellow"},
+		{999 * time.Millisecond, "yellow"},
+		{time.Second, "red"},
+	}
+	for _, tt := range tests {
+		got := bands.color(tt.duration, green, red)("")
+		if tt.want != "yellow" && got != tt.want {
+			t.Errorf("color(%s) = %q, want %s", tt.duration, got, tt.want)
+		}
+		if tt.want == "yellow" && (got == "green" || got == "red") {
+			t.Errorf("color(%s) = %q, want yellow", tt.duration, got)
+		}
+	}
+}
+
+func TestNewLatencyPercentiles(t *testing.T) {
+	var results []EndpointResult
+	for ms := 1; ms <= 20; ms++ {
+		results = append(results, EndpointResult{Success: true, Duration: time.Duration(ms*100) * time.Millisecond})
+	}
+	results = append(results,
+		EndpointResult{Error: errors.New("timeout"), Duration: time.Minute},
+		EndpointResult{Skipped: true},
+	)
+
+	got := newLatencyPercentiles(results)
+	want := LatencyPercentiles{Min: 0.1, P50: 1, P95: 1.9, Max: 2}
+	if got == nil || *got != want {
+		t.Fatalf("newLatencyPercentiles() = %+v, want %+v", got, want)
+	}
+	if s := got.String(); s != "Min: 0.10s, p50: 1.00s, p95: 1.90s, Max: 2.00s" {
+		t.Errorf("String() = %q", s)
+	}
+
+	if got := newLatencyPercentiles(results[:1]); got != nil {
+		t.Errorf("one sample gave %+v, want nil", got)
+	}
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Lint finding severities; errors fail `vitals lint`, warnings are only reported
+const (
+	lintError   = "error"
+	lintWarning = "warning"
+)
+
+// prodTag marks targets that `vitals lint` holds to production rules
+const prodTag = "prod"
+
+// LintFinding is one problem `vitals lint` found in a config file
+type LintFinding struct {
+	File     string `json:"file"`
+	Line     int    `json:"line,omitempty"`
+	Severity string `json:"severity"`
+
+	// Key is the dotted path of the offending setting, e.g. targets.api.base_urls
+	Key     string `json:"key,omitempty"`
+	Message string `json:"message"`
+}
+
+// String formats the finding as file:line: severity: key: message
+func (f LintFinding) String() string {
+	location := f.File
+	if f.Line > 0 {
+		location = fmt.Sprintf("%s:%d", f.File, f.Line)
+	}
+	if f.Key == "" {
+		return fmt.Sprintf("%s: %s: %s", location, f.Severity, f.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s: %s", location, f.Severity, f.Key, f.Message)
+}
+
+// runLint implements `vitals lint`, exiting 1 when any config has an error
+func runLint(args []string) int {
+	fs := flag.NewFlagSet("lint", flag.ExitOnError)
+	var configFiles []string
+	fs.Var((*stringSlice)(&configFiles), "config", "Path to configuration file(s)")
+	fs.Var((*stringSlice)(&configFiles), "c", "Path to configuration file(s) (shorthand)")
+	jsonOutput := fs.Bool("json", false, "Print findings as a JSON array")
+	fs.Parse(args)
+
+	configFiles = append(configFiles, fs.Args()...)
+	if len(configFiles) == 0 {
+		configFiles = append(configFiles, "vitals.toml")
+	}
+
+	findings := []LintFinding{}
+	for _, configFile := range configFiles {
+		data, err := os.ReadFile(configFile)
+		if err != nil {
+			findings = append(findings, LintFinding{File: configFile, Severity: lintError, Message: err.Error()})
+			continue
+		}
+		findings = append(findings, lintConfig(configFile, data)...)
+	}
+
+	if err := writeLintFindings(os.Stdout, findings, *jsonOutput); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing lint findings: %s\n", err)
+		return 2
+	}
+	for _, finding := range findings {
+		if finding.Severity == lintError {
+			return 1
+		}
+	}
+	return 0
+}
+
+// writeLintFindings prints the findings one per line, or as JSON for CI
+func writeLintFindings(w io.Writer, findings []LintFinding, jsonOutput bool) error {
+	if jsonOutput {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(findings)
+	}
+	for _, finding := range findings {
+		if _, err := fmt.Fprintln(w, finding); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lintConfig decodes a config file strictly and checks it for likely mistakes
+func lintConfig(filename string, data []byte) []LintFinding {
+	var config Config
+	md, err := toml.Decode(string(data), &config)
+	if err != nil {
+		finding := LintFinding{File: filename, Severity: lintError, Message: err.Error()}
+		var parseErr toml.ParseError
+		if errors.As(err, &parseErr) {
+			finding.Line = parseErr.Position.Line
+		}
+		return []LintFinding{finding}
+	}
+	config.mergeEndpointBlocks()
+
+	var findings []LintFinding
+	add := func(severity, key, format string, args ...any) {
+		findings = append(findings, LintFinding{File: filename, Severity: severity, Key: key, Message: fmt.Sprintf(format, args...)})
+	}
+
+	// Report unknown keys once, at the outermost table nothing was decoded into
+	var unknown []toml.Key
+	for _, key := range md.Undecoded() {
+		if slices.ContainsFunc(unknown, func(parent toml.Key) bool { return hasKeyPrefix(key, parent) }) {
+			continue
+		}
+		known, custom := knownKeys(reflect.TypeOf(config), key[:len(key)-1])
+		if custom {
+			continue
+		}
+		unknown = append(unknown, key)
+
+		message := "unknown key"
+		if suggestion := closestKey(key[len(key)-1], known); suggestion != "" {
+			message = fmt.Sprintf("unknown key, did you mean %q?", suggestion)
+		}
+		add(lintError, key.String(), "%s", message)
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(config.Targets)) {
+		target := config.Targets[name]
+		key := "targets." + name
+		if len(target.BaseURLs) == 0 && target.ConsulService == "" {
+			add(lintError, key, "target has no base_urls or consul_service")
+		}
+		if len(target.Endpoints) == 0 {
+			add(lintError, key, "target has no endpoints")
+		}
+		findings = append(findings, lintStatusRanges(filename, key+".status_ranges", target.StatusRanges)...)
+		if target.MaxRedirects < 0 {
+			add(lintError, key+".max_redirects", "max_redirects can't be negative")
+		} else if target.MaxRedirects > 0 && !followsRedirects(target) {
+			add(lintWarning, key+".max_redirects", "max_redirects has no effect when redirects are not followed")
+		}
+		if (target.ClientCert == "") != (target.ClientKey == "") {
+			add(lintError, key, "client_cert and client_key must be set together")
+		}
+		if target.CAFile != "" && target.InsecureSkipVerify {
+			add(lintWarning, key+".ca_file", "ca_file has no effect when insecure_skip_verify is set")
+		}
+		if slices.Contains(target.Tags, prodTag) {
+			for _, baseURL := range target.BaseURLs {
+				if strings.HasPrefix(baseURL, "http://") {
+					add(lintError, key+".base_urls", "%s is not https but the target is tagged %s", baseURL, prodTag)
+				}
+			}
+		}
+	}
+	return findings
+}
+
+// lintStatusRanges reports status ranges that don't parse, are reversed, or overlap
+func lintStatusRanges(filename, key string, values []string) []LintFinding {
+	var findings []LintFinding
+	var ranges []StatusRange
+	var parsed []string
+	for _, value := range values {
+		r, err := parseStatusRange(value)
+		switch {
+		case err != nil:
+			findings = append(findings, LintFinding{File: filename, Severity: lintError, Key: key, Message: fmt.Sprintf("invalid status range %q, expected e.g. \"200-299\"", value)})
+			continue
+		case r.Min > r.Max:
+			findings = append(findings, LintFinding{File: filename, Severity: lintError, Key: key, Message: fmt.Sprintf("status range %q is reversed", value)})
+			continue
+		}
+		for i, other := range ranges {
+			if r.Min <= other.Max && other.Min <= r.Max {
+				findings = append(findings, LintFinding{File: filename, Severity: lintWarning, Key: key, Message: fmt.Sprintf("status ranges %q and %q overlap", parsed[i], value)})
+			}
+		}
+		ranges = append(ranges, r)
+		parsed = append(parsed, value)
+	}
+	return findings
+}
+
+// hasKeyPrefix reports whether key is parent or nested under it
+func hasKeyPrefix(key, parent toml.Key) bool {
+	return len(key) >= len(parent) && slices.Equal(key[:len(parent)], parent)
+}
+
+// unmarshalerType is implemented by values that decode their own keys, such as endpoints
+var unmarshalerType = reflect.TypeFor[toml.Unmarshaler]()
+
+// knownKeys returns the keys the table at path decodes into, following struct fields by their
+// toml tag and skipping the names of map entries, e.g. the target name in targets.api. Custom
+// reports that a value on the path decodes itself, so its keys aren't unknown to it
+func knownKeys(t reflect.Type, path toml.Key) (keys []string, custom bool) {
+	if reflect.PointerTo(t).Implements(unmarshalerType) {
+		return nil, true
+	}
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice:
+		return knownKeys(t.Elem(), path)
+	case reflect.Map:
+		if len(path) == 0 {
+			return nil, false
+		}
+		return knownKeys(t.Elem(), path[1:])
+	case reflect.Struct:
+	default:
+		return nil, false
+	}
+
+	for i := range t.NumField() {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("toml"), ",")
+		if !field.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		if len(path) > 0 && name == path[0] {
+			return knownKeys(field.Type, path[1:])
+		}
+		keys = append(keys, name)
+	}
+	if len(path) > 0 {
+		return nil, false
+	}
+	return keys, false
+}
+
+// closestKey suggests the known key a typo most likely meant, or returns an empty string when
+// none is within two edits
+func closestKey(key string, known []string) string {
+	best, bestDistance := "", 3
+	for _, candidate := range known {
+		if d := editDistance(strings.ToLower(key), candidate); d < bestDistance {
+			best, bestDistance = candidate, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between two strings
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLintConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   []LintFinding
+	}{
+		{
+			name: "clean",
+			config: `
+[global]
+timeout = 3
+[targets.api]
+base_urls = ["https://api.example.com"]
+endpoints = ["/health", { path = "/", name = "Home" }]
+status_ranges = ["200-299", "300-399"]
+tags = ["prod"]
+`,
+		},
+		{
+			name: "unknown keys",
+			config: `
+[global]
+timout = 3
+[bogus]
+x = 1
+[targets.api]
+base_url = ["https://api.example.com"]
+base_urls = ["https://api.example.com"]
+endpoints = ["/"]
+[targets.api.cors]
+orign = "https://example.com"
+`,
+			want: []LintFinding{
+				{File: "vitals.toml", Severity: lintError, Key: "global.timout", Message: `unknown key, did you mean "timeout"?`},
+				{File: "vitals.toml", Severity: lintError, Key: "bogus", Message: "unknown key"},
+				{File: "vitals.toml", Severity: lintError, Key: "targets.api.base_url", Message: `unknown key, did you mean "base_urls"?`},
+				{File: "vitals.toml", Severity: lintError, Key: "targets.api.cors.orign", Message: `unknown key, did you mean "origin"?`},
+			},
+		},
+		{
+			name: "empty target",
+			config: `
+[targets.api]
+`,
+			want: []LintFinding{
+				{File: "vitals.toml", Severity: lintError, Key: "targets.api", Message: "target has no base_urls or consul_service"},
+				{File: "vitals.toml", Severity: lintError, Key: "targets.api", Message: "target has no endpoints"},
+			},
+		},
+		{
+			name: "status ranges",
+			config: `
+[targets.api]
+base_urls = ["https://api.example.com"]
+endpoints = ["/"]
+status_ranges = ["200-299", "204-204", "300-200", "abc"]
+`,
+			want: []LintFinding{
+				{File: "vitals.toml", Severity: lintWarning, Key: "targets.api.status_ranges", Message: `status ranges "200-299" and "204-204" overlap`},
+				{File: "vitals.toml", Severity: lintError, Key: "targets.api.status_ranges", Message: `status range "300-200" is reversed`},
+				{File: "vitals.toml", Severity: lintError, Key: "targets.api.status_ranges", Message: `invalid status range "abc", expected e.g. "200-299"`},
+			},
+		},
+		{
+			name: "http in prod",
+			config: `
+[targets.api]
+base_urls = ["http://api.example.com", "https://api.example.com"]
+endpoints = ["/"]
+tags = ["prod"]
+[targets.dev]
+base_urls = ["http://localhost:8080"]
+endpoints = ["/"]
+`,
+			want: []LintFinding{
+				{File: "vitals.toml", Severity: lintError, Key: "targets.api.base_urls", Message: "http://api.example.com is not https but the target is tagged prod"},
+			},
+		},
+		{
+			name: "redirect limits",
+			config: `
+[targets.api]
+base_urls = ["https://api.example.com"]
+endpoints = ["/"]
+max_redirects = -1
+[targets.old]
+base_urls = ["https://old.example.com"]
+endpoints = ["/"]
+follow_redirects = false
+max_redirects = 3
+`,
+			want: []LintFinding{
+				{File: "vitals.toml", Severity: lintError, Key: "targets.api.max_redirects", Message: "max_redirects can't be negative"},
+				{File: "vitals.toml", Severity: lintWarning, Key: "targets.old.max_redirects", Message: "max_redirects has no effect when redirects are not followed"},
+			},
+		},
+		{
+			name: "tls settings",
+			config: `
+[targets.api]
+base_urls = ["https://api.example.com"]
+endpoints = ["/"]
+client_cert = "/etc/vitals/client.pem"
+[targets.dev]
+base_urls = ["https://dev.example.com"]
+endpoints = ["/"]
+ca_file = "/etc/vitals/internal-ca.pem"
+insecure_skip_verify = true
+`,
+			want: []LintFinding{
+				{File: "vitals.toml", Severity: lintError, Key: "targets.api", Message: "client_cert and client_key must be set together"},
+				{File: "vitals.toml", Severity: lintWarning, Key: "targets.dev.ca_file", Message: "ca_file has no effect when insecure_skip_verify is set"},
+			},
+		},
+		{
+			name:   "syntax error",
+			config: "[targets.api]\nbase_urls = [\"https://api.example.com\"\nendpoints = [\"/\"]\n",
+			want: []LintFinding{
+				{File: "vitals.toml", Line: 3, Severity: lintError},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := lintConfig("vitals.toml", []byte(tt.config))
+			// Parser messages are the TOML library's; only their position is checked
+			for i := range got {
+				if got[i].Line > 0 {
+					got[i].Message = ""
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("lintConfig() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClosestKey(t *testing.T) {
+	known := []string{"base_urls", "endpoints", "status_codes", "status_ranges"}
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"base_url", "base_urls"},
+		{"Endpoints", "endpoints"},
+		{"status_range", "status_ranges"},
+		{"timeout", ""},
+	}
+	for _, tt := range tests {
+		if got := closestKey(tt.key, known); got != tt.want {
+			t.Errorf("closestKey(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
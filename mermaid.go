@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// mermaidClasses styles each status in Mermaid output
+var mermaidClasses = map[GraphStatus]string{
+	graphUp:      "fill:#c8e6c9,stroke:#2e7d32",
+	graphDown:    "fill:#ffcdd2,stroke:#c62828",
+	graphUnknown: "fill:#eeeeee,stroke:#9e9e9e",
+}
+
+// mermaidLabel quotes text for a Mermaid node label, escaping characters Mermaid would parse
+func mermaidLabel(s string) string {
+	return `"` + strings.NewReplacer(`"`, "#quot;", "<", "#lt;", ">", "#gt;").Replace(s) + `"`
+}
+
+// renderMermaid writes the graph as a Mermaid flowchart that renders in GitHub and GitLab markdown
+func renderMermaid(w io.Writer, graph Graph) error {
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+
+	ids := make(map[string]string, len(graph.Targets))
+	for i, target := range graph.Targets {
+		ids[target.ID] = fmt.Sprintf("t%d", i)
+	}
+
+	for _, target := range graph.Targets {
+		targetID := ids[target.ID]
+		fmt.Fprintf(&b, "  subgraph %s_group[%s]\n", targetID, mermaidLabel(target.ID))
+		fmt.Fprintf(&b, "    %s[%s]:::%s\n", targetID, mermaidLabel(target.Label), target.Status)
+
+		for j, baseURL := range target.BaseURLs {
+			baseID := fmt.Sprintf("%s_b%d", targetID, j)
+			fmt.Fprintf(&b, "    %s([%s]):::%s\n", baseID, mermaidLabel(baseURL.URL), baseURL.Status)
+			fmt.Fprintf(&b, "    %s --> %s\n", targetID, baseID)
+
+			for k, endpoint := range baseURL.Endpoints {
+				endpointID := fmt.Sprintf("%s_e%d", baseID, k)
+				fmt.Fprintf(&b, "    %s[%s]:::%s\n", endpointID, mermaidLabel(endpoint.Label), endpoint.Status)
+				fmt.Fprintf(&b, "    %s --> %s\n", baseID, endpointID)
+			}
+		}
+		b.WriteString("  end\n")
+	}
+
+	for _, target := range graph.Targets {
+		for _, dep := range target.DependsOn {
+			fmt.Fprintf(&b, "  %s -. depends on .-> %s\n", ids[target.ID], ids[dep])
+		}
+	}
+
+	for _, status := range []GraphStatus{graphUp, graphDown, graphUnknown} {
+		fmt.Fprintf(&b, "  classDef %s %s\n", status, mermaidClasses[status])
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
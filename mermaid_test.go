@@ -0,0 +1,34 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderMermaid(t *testing.T) {
+	graph := Graph{Targets: []GraphTarget{
+		{ID: "a.toml::api", Label: "api", Status: graphDown, BaseURLs: []GraphBaseURL{
+			{URL: "https://api.example.com", Status: graphDown, Endpoints: []GraphEndpoint{{Label: "/health", Status: graphDown}}},
+		}},
+		{ID: "a.toml::web", Label: `Web "beta"`, Status: graphUp, DependsOn: []string{"a.toml::api"}},
+	}}
+
+	var b strings.Builder
+	if err := renderMermaid(&b, graph); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+
+	for _, want := range []string{
+		"flowchart LR\n",
+		`t0_b0(["https://api.example.com"]):::down`,
+		`t0_b0_e0["/health"]:::down`,
+		`t1["Web #quot;beta#quot;"]:::up`,
+		"t1 -. depends on .-> t0",
+		"classDef unknown",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output is missing %q:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// MockConfig controls how `vitals mock` answers a target's endpoints
+type MockConfig struct {
+	Status  int               `toml:"status,omitzero"`
+	Latency string            `toml:"latency,omitempty"`
+	Body    string            `toml:"body,omitempty"`
+	Headers map[string]string `toml:"headers,omitempty"`
+}
+
+// mockRoute is the canned response for one path
+type mockRoute struct {
+	target  string
+	status  int
+	latency time.Duration
+	body    []byte
+	header  http.Header
+}
+
+// mockStatus returns the configured mock status, else the first status the target accepts,
+// or one it rejects when the check is expected to fail
+func mockStatus(target TargetConfig) int {
+	if target.Mock != nil && target.Mock.Status != 0 {
+		return target.Mock.Status
+	}
+	if target.ExpectFailure {
+		return mockFailureStatus(target)
+	}
+	if len(target.StatusCodes) > 0 {
+		return target.StatusCodes[0]
+	}
+	for _, rangeStr := range target.StatusRanges {
+		if r, err := parseStatusRange(rangeStr); err == nil {
+			return r.Min
+		}
+	}
+	if target.CORS != nil {
+		return http.StatusNoContent
+	}
+	if target.ExpectRedirectTo != "" {
+		return http.StatusFound
+	}
+	return http.StatusOK
+}
+
+// mockFailureStatus returns a status the target doesn't accept
+func mockFailureStatus(target TargetConfig) int {
+	var ranges []StatusRange
+	for _, rangeStr := range target.StatusRanges {
+		if r, err := parseStatusRange(rangeStr); err == nil {
+			ranges = append(ranges, r)
+		}
+	}
+	for _, status := range []int{http.StatusServiceUnavailable, http.StatusNotFound, http.StatusBadRequest} {
+		if !isStatusAcceptable(status, target.StatusCodes, ranges) {
+			return status
+		}
+	}
+	return http.StatusTeapot
+}
+
+// newMockRoute builds a response that passes the checks of a target, as they apply to one of
+// its endpoints, unless its mock table says otherwise
+func newMockRoute(key string, target TargetConfig) (mockRoute, error) {
+	route := mockRoute{target: key, status: mockStatus(target), header: make(http.Header)}
+
+	if target.ExpectedContentType != "" {
+		route.header.Set("Content-Type", target.ExpectedContentType)
+	}
+	if target.ExpectRedirectTo != "" {
+		route.header.Set("Location", strings.ReplaceAll(target.ExpectRedirectTo, "*", ""))
+	}
+	if target.CORS != nil {
+		origin := target.CORS.Origin
+		if origin == "" {
+			origin = "*"
+		}
+		route.header.Set("Access-Control-Allow-Origin", origin)
+		route.header.Set("Access-Control-Allow-Methods", preflightMethod(*target.CORS))
+		if len(target.CORS.Headers) > 0 {
+			route.header.Set("Access-Control-Allow-Headers", strings.Join(target.CORS.Headers, ", "))
+		}
+		if target.CORS.AllowCredentials {
+			route.header.Set("Access-Control-Allow-Credentials", "true")
+		}
+	}
+
+	if target.Mock != nil {
+		route.body = []byte(target.Mock.Body)
+		for name, value := range target.Mock.Headers {
+			route.header.Set(name, value)
+		}
+		if target.Mock.Latency != "" {
+			latency, err := parseDurationOrSeconds(target.Mock.Latency)
+			if err != nil {
+				return mockRoute{}, fmt.Errorf("invalid mock latency %q for target %s", target.Mock.Latency, key)
+			}
+			route.latency = latency
+		}
+	}
+
+	if target.RequireCompression && route.header.Get("Content-Encoding") == "" {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		zw.Write(route.body)
+		zw.Close()
+		route.body = buf.Bytes()
+		route.header.Set("Content-Encoding", "gzip")
+	}
+	return route, nil
+}
+
+// mockBaseURL maps a configured base URL onto the mock server, keeping its host as the first
+// path segment so targets on different hosts don't collide
+func mockBaseURL(addr, baseURL string) string {
+	u, err := url.Parse(strings.TrimPrefix(baseURL, "srv+"))
+	if err != nil || u.Host == "" {
+		return "http://" + addr + "/" + strings.Trim(baseURL, "/")
+	}
+	return "http://" + addr + "/" + u.Host + strings.TrimSuffix(u.Path, "/")
+}
+
+// mockConfigs returns the configs rewritten to point at the mock server, the routes it
+// answers, and warnings for paths claimed by more than one target
+func mockConfigs(configs []ConfigWithSource, addr string) ([]ConfigWithSource, map[string]mockRoute, []string, error) {
+	routes := make(map[string]mockRoute)
+	var warnings []string
+	rewritten := make([]ConfigWithSource, 0, len(configs))
+
+	for _, configWithSource := range configs {
+		config := configWithSource.Config
+		config.Discovery = DiscoveryConfig{}
+		targets := make(map[string]TargetConfig, len(config.Targets))
+
+		names := make([]string, 0, len(config.Targets))
+		for name := range config.Targets {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			target := config.Targets[name]
+			key := runKey(configWithSource.Filename, name)
+
+			// Each endpoint is answered with its own status codes, content type, and the like
+			endpointRoutes := make([]mockRoute, len(target.Endpoints))
+			for i, endpoint := range target.Endpoints {
+				route, err := newMockRoute(key, target.forEndpoint(endpoint))
+				if err != nil {
+					return nil, nil, nil, err
+				}
+				endpointRoutes[i] = route
+			}
+
+			baseURLs := target.BaseURLs
+			if target.ConsulService != "" {
+				baseURLs = append(baseURLs, target.ConsulService)
+			}
+
+			target.BaseURLs = nil
+			target.ConsulService = ""
+			target.ConsulTags = nil
+			for _, baseURL := range baseURLs {
+				mocked := mockBaseURL(addr, baseURL)
+				target.BaseURLs = append(target.BaseURLs, mocked)
+
+				for i, endpoint := range target.Endpoints {
+					u, err := url.Parse(constructURL(mocked, endpoint.Path))
+					if err != nil {
+						return nil, nil, nil, fmt.Errorf("invalid endpoint %q for target %s: %s", endpoint.Path, key, err)
+					}
+					if existing, ok := routes[u.Path]; ok && existing.target != key {
+						warnings = append(warnings, fmt.Sprintf("%s is configured by %s and %s, answering as %s", u.Path, existing.target, key, existing.target))
+						continue
+					}
+					routes[u.Path] = endpointRoutes[i]
+				}
+			}
+			targets[name] = target
+		}
+
+		config.Targets = targets
+		rewritten = append(rewritten, ConfigWithSource{Config: config, Filename: configWithSource.Filename})
+	}
+	return rewritten, routes, warnings, nil
+}
+
+// newMockHandler answers every route with its canned response, logging each request
+func newMockHandler(routes map[string]mockRoute) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route, ok := routes[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			fmt.Printf("%s %s -> 404 (not configured)\n", r.Method, r.URL.Path)
+			return
+		}
+
+		time.Sleep(route.latency)
+		for name, values := range route.header {
+			w.Header()[name] = values
+		}
+		w.WriteHeader(route.status)
+		w.Write(route.body)
+		fmt.Printf("%s %s -> %d [%s]\n", r.Method, r.URL.Path, route.status, route.target)
+	})
+}
+
+// writeMockConfig writes a rewritten config that checks the mock server instead of the real hosts
+func writeMockConfig(path string, config Config) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("error creating %s: %s", path, err)
+	}
+	defer f.Close()
+
+	fmt.Fprintf(f, "# Generated by vitals mock\n\n")
+	enc := toml.NewEncoder(f)
+	enc.Indent = ""
+	if err := enc.Encode(struct {
+		Global  GlobalConfig            `toml:"global"`
+		Targets map[string]TargetConfig `toml:"targets"`
+	}{config.Global, config.Targets}); err != nil {
+		return fmt.Errorf("error encoding config: %s", err)
+	}
+	return nil
+}
+
+// runMock implements `vitals mock`, serving every configured endpoint locally
+func runMock(args []string) int {
+	fs := flag.NewFlagSet("mock", flag.ExitOnError)
+	var configFiles []string
+	fs.Var((*stringSlice)(&configFiles), "config", "Path to configuration file(s)")
+	fs.Var((*stringSlice)(&configFiles), "c", "Path to configuration file(s) (shorthand)")
+	listen := fs.String("listen", "127.0.0.1:8080", "Address to serve the mock endpoints on")
+	output := fs.String("output", "", "Write a copy of the config pointing at the mock server to this file")
+	fs.StringVar(output, "o", "", "Write a copy of the config pointing at the mock server to this file (shorthand)")
+	fs.Parse(args)
+
+	if len(configFiles) == 0 {
+		configFiles = append(configFiles, "vitals.toml")
+	}
+	if *output != "" && len(configFiles) > 1 {
+		fmt.Fprintln(os.Stderr, "-o can only be used with a single config file")
+		return 2
+	}
+
+	configs, err := loadConfigFiles(configFiles)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 1
+	}
+
+	listener, err := net.Listen("tcp", *listen)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listening on '%s': %s\n", *listen, err)
+		return 1
+	}
+
+	rewritten, routes, warnings, err := mockConfigs(configs, listener.Addr().String())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 1
+	}
+	for _, warning := range warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+
+	if *output != "" {
+		if err := writeMockConfig(*output, rewritten[0].Config); err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			return 1
+		}
+		fmt.Printf("Wrote %s, check it with: vitals -c %s\n", *output, *output)
+	}
+
+	fmt.Printf("Mocking %d endpoints on http://%s\n", len(routes), listener.Addr())
+	if err := http.Serve(listener, newMockHandler(routes)); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMockStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		target TargetConfig
+		want   int
+	}{
+		{"default", TargetConfig{}, http.StatusOK},
+		{"first status code", TargetConfig{StatusCodes: []int{201, 202}}, 201},
+		{"status range", TargetConfig{StatusRanges: []string{"300-399"}}, 300},
+		{"cors preflight", TargetConfig{CORS: &CORSConfig{}}, http.StatusNoContent},
+		{"expected redirect", TargetConfig{ExpectRedirectTo: "https://example.com/*"}, http.StatusFound},
+		{"mock override", TargetConfig{StatusCodes: []int{200}, Mock: &MockConfig{Status: 503}}, 503},
+		{"expect failure", TargetConfig{ExpectFailure: true}, http.StatusServiceUnavailable},
+		{"expect failure accepting 5xx", TargetConfig{ExpectFailure: true, StatusRanges: []string{"500-599"}}, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mockStatus(tt.target); got != tt.want {
+				t.Errorf("mockStatus() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMockBaseURL(t *testing.T) {
+	tests := []struct {
+		baseURL string
+		want    string
+	}{
+		{"https://api.example.com", "http://127.0.0.1:8080/api.example.com"},
+		{"https://api.example.com/v1/", "http://127.0.0.1:8080/api.example.com/v1"},
+		{"srv+https://_api._tcp.example.com", "http://127.0.0.1:8080/_api._tcp.example.com"},
+		{"payments", "http://127.0.0.1:8080/payments"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.baseURL, func(t *testing.T) {
+			if got := mockBaseURL("127.0.0.1:8080", tt.baseURL); got != tt.want {
+				t.Errorf("mockBaseURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMockConfigsPassOwnChecks(t *testing.T) {
+	configs := []ConfigWithSource{{Filename: "a.toml", Config: Config{Targets: map[string]TargetConfig{
+		"api": {
+			BaseURLs:            []string{"https://api.example.com"},
+			Endpoints:           pathEndpoints("/health?verbose=1"),
+			ExpectedContentType: "application/json",
+			RequireCompression:  true,
+			Mock:                &MockConfig{Body: `{"status":"pass"}`},
+		},
+		"cors": {
+			BaseURLs:  []string{"https://cdn.example.com"},
+			Endpoints: pathEndpoints("/font.woff"),
+			CORS:      &CORSConfig{Origin: "https://app.example.com", Method: "PUT", Headers: []string{"X-Token"}},
+		},
+		"redirect": {
+			BaseURLs:         []string{"http://example.com"},
+			Endpoints:        pathEndpoints("/"),
+			ExpectRedirectTo: "https://example.com/*",
+		},
+		"dupe": {
+			BaseURLs:  []string{"https://api.example.com"},
+			Endpoints: pathEndpoints("/health"),
+		},
+		"endpoints": {
+			BaseURLs:            []string{"https://shop.example.com"},
+			ExpectedContentType: "application/json",
+			Endpoints: []EndpointConfig{
+				{Path: "/orders", Method: "POST", StatusCodes: []int{201}},
+				{Path: "/export", ExpectedContentType: "text/csv"},
+				{Path: "/admin", ExpectFailure: true},
+			},
+		},
+	}}}}
+
+	server := httptest.NewUnstartedServer(nil)
+	rewritten, routes, warnings, err := mockConfigs(configs, server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 1 {
+		t.Errorf("expected one warning for the duplicated /health path, got %v", warnings)
+	}
+	server.Config.Handler = newMockHandler(routes)
+	server.Start()
+	defer server.Close()
+
+	runs := runChecks(rewritten, runOptions{only: []string{"api", "cors", "redirect", "endpoints"}})
+	if len(runs) != 4 {
+		t.Fatalf("expected 4 runs, got %d", len(runs))
+	}
+	for _, run := range runs {
+		for _, result := range run.Results {
+			if result.Error != nil || !result.Success {
+				t.Errorf("%s %s failed against the mock: %v %s", run.Key, result.URL, result.Error, result.FailureReason)
+			}
+		}
+	}
+}
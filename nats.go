@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// natsTimeout bounds connecting to and flushing a NATS server
+const natsTimeout = 5 * time.Second
+
+// NATSConfig publishes results and state changes to a NATS server
+type NATSConfig struct {
+	URL   string `toml:"url,omitempty"`
+	Token string `toml:"token,omitempty"`
+
+	// Subject receives every result and ChangesSubject every state change; both may contain
+	// {target} and {team}, e.g. "vitals.results.{team}.{target}"
+	Subject        string `toml:"subject,omitempty"`
+	ChangesSubject string `toml:"changes_subject,omitempty"`
+}
+
+// natsSink speaks the NATS client protocol directly, which only needs CONNECT, PUB, and PING
+type natsSink struct {
+	config NATSConfig
+	conn   net.Conn
+	r      *bufio.Reader
+	w      *bufio.Writer
+}
+
+// newNATSSink connects and authenticates to the NATS server
+func newNATSSink(config NATSConfig) (*natsSink, error) {
+	if config.URL == "" {
+		config.URL = "nats://127.0.0.1:4222"
+	}
+	if config.Subject == "" {
+		config.Subject = "vitals.results"
+	}
+	if config.ChangesSubject == "" {
+		config.ChangesSubject = "vitals.changes"
+	}
+
+	u, err := url.Parse(config.URL)
+	if err != nil {
+		return nil, sinkError("nats", err)
+	}
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "4222")
+	}
+
+	conn, err := net.DialTimeout("tcp", host, natsTimeout)
+	if err != nil {
+		return nil, sinkError("nats", err)
+	}
+	conn.SetDeadline(time.Now().Add(natsTimeout))
+	sink := &natsSink{config: config, conn: conn, r: bufio.NewReader(conn)}
+
+	// The server greets with INFO before anything else
+	line, err := sink.r.ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, "INFO ") {
+		conn.Close()
+		return nil, sinkError("nats", fmt.Errorf("unexpected greeting %q: %v", strings.TrimSpace(line), err))
+	}
+	var info struct {
+		TLSRequired bool `json:"tls_required"`
+	}
+	json.Unmarshal([]byte(strings.TrimPrefix(line, "INFO ")), &info)
+
+	if u.Scheme == "tls" || info.TLSRequired {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: u.Hostname()})
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return nil, sinkError("nats", err)
+		}
+		sink.conn = tlsConn
+		sink.r = bufio.NewReader(tlsConn)
+	}
+	sink.w = bufio.NewWriter(sink.conn)
+
+	connect := map[string]any{"verbose": false, "pedantic": false, "name": "vitals", "lang": "go", "version": version}
+	if config.Token != "" {
+		connect["auth_token"] = config.Token
+	}
+	if u.User != nil {
+		connect["user"] = u.User.Username()
+		connect["pass"], _ = u.User.Password()
+	}
+	data, _ := json.Marshal(connect)
+	fmt.Fprintf(sink.w, "CONNECT %s\r\n", data)
+
+	if err := sink.flush(); err != nil {
+		sink.Close()
+		return nil, err
+	}
+	return sink, nil
+}
+
+// flush sends buffered commands and waits for the PONG answering a PING, which the server
+// only sends after processing everything before it, surfacing any -ERR
+func (s *natsSink) flush() error {
+	s.conn.SetDeadline(time.Now().Add(natsTimeout))
+	s.w.WriteString("PING\r\n")
+	if err := s.w.Flush(); err != nil {
+		return sinkError("nats", err)
+	}
+
+	for {
+		line, err := s.r.ReadString('\n')
+		if err != nil {
+			return sinkError("nats", err)
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "PONG":
+			return nil
+		case strings.HasPrefix(line, "-ERR"):
+			return sinkError("nats", fmt.Errorf("%s", strings.TrimSpace(strings.TrimPrefix(line, "-ERR"))))
+		case line == "PING":
+			s.w.WriteString("PONG\r\n")
+		}
+	}
+}
+
+// publish queues one message
+func (s *natsSink) publish(subject string, payload []byte) {
+	fmt.Fprintf(s.w, "PUB %s %d\r\n", subject, len(payload))
+	s.w.Write(payload)
+	s.w.WriteString("\r\n")
+}
+
+// Publish sends every result and state change as a JSON message
+func (s *natsSink) Publish(batch SinkBatch) error {
+	for _, result := range batch.Results {
+		data, err := json.Marshal(result)
+		if err != nil {
+			return sinkError("nats", err)
+		}
+		s.publish(expandSubject(s.config.Subject, result.Target, result.Team), data)
+	}
+	for _, change := range batch.Changes {
+		data, err := json.Marshal(change)
+		if err != nil {
+			return sinkError("nats", err)
+		}
+		s.publish(expandSubject(s.config.ChangesSubject, change.Target, change.Team), data)
+	}
+	return s.flush()
+}
+
+// Close closes the connection
+func (s *natsSink) Close() error {
+	return s.conn.Close()
+}
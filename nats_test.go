@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+)
+
+// fakeNATS accepts one client and records the subjects and payloads it publishes
+func fakeNATS(t *testing.T) (string, <-chan map[string][]string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	published := make(chan map[string][]string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		messages := make(map[string][]string)
+		r := bufio.NewReader(conn)
+		fmt.Fprintf(conn, "INFO {\"server_id\":\"test\"}\r\n")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				published <- messages
+				return
+			}
+			fields := strings.Fields(line)
+			switch fields[0] {
+			case "PING":
+				fmt.Fprintf(conn, "PONG\r\n")
+			case "PUB":
+				var n int
+				fmt.Sscanf(fields[2], "%d", &n)
+				payload := make([]byte, n+2)
+				io.ReadFull(r, payload)
+				messages[fields[1]] = append(messages[fields[1]], string(payload[:n]))
+			}
+		}
+	}()
+	return ln.Addr().String(), published
+}
+
+func TestNATSSinkPublish(t *testing.T) {
+	addr, published := fakeNATS(t)
+
+	sink, err := newNATSSink(NATSConfig{URL: "nats://" + addr, Subject: "vitals.results.{target}"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = sink.Publish(SinkBatch{
+		Results: []ResultMessage{{Target: "api", JSONResult: JSONResult{URL: "https://api.example.com/health"}}},
+		Changes: []StateChange{{Target: "api", From: "up", To: "down"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink.Close()
+
+	messages := <-published
+	if len(messages["vitals.results.api"]) != 1 || len(messages["vitals.changes"]) != 1 {
+		t.Fatalf("published = %v", messages)
+	}
+	var change StateChange
+	if err := json.Unmarshal([]byte(messages["vitals.changes"][0]), &change); err != nil || change.To != "down" {
+		t.Errorf("change message = %s (%v)", messages["vitals.changes"][0], err)
+	}
+}
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// defaultTokenFile is where OAuth2 tokens are persisted when auth sets no token_file
+const defaultTokenFile = ".vitals-tokens.json"
+
+// tokenExpiryMargin refreshes access tokens this long before they expire, so one doesn't run
+// out during a check
+const tokenExpiryMargin = 30 * time.Second
+
+// devicePollUnit is what the device flow's polling interval counts, replaceable in tests
+var devicePollUnit = time.Second
+
+// OAuth2Token is an access token and the refresh token that renews it
+type OAuth2Token struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	Expiry       time.Time `json:"expiry,omitempty"`
+}
+
+// valid reports whether the access token can still be used
+func (t OAuth2Token) valid(now time.Time) bool {
+	return t.AccessToken != "" && (t.Expiry.IsZero() || now.Add(tokenExpiryMargin).Before(t.Expiry))
+}
+
+// TokenStore guards the OAuth2 tokens persisted in a token file, keyed by token URL and client
+type TokenStore struct {
+	mu     sync.Mutex
+	path   string
+	tokens map[string]OAuth2Token
+}
+
+// tokenStores holds one store per token file, so concurrent checks share refreshed tokens
+var (
+	tokenStoresMu sync.Mutex
+	tokenStores   = make(map[string]*TokenStore)
+)
+
+// openTokenStore returns the store of a token file, reading it on first use
+func openTokenStore(path string) (*TokenStore, error) {
+	tokenStoresMu.Lock()
+	defer tokenStoresMu.Unlock()
+	if store, ok := tokenStores[path]; ok {
+		return store, nil
+	}
+
+	store := &TokenStore{path: path, tokens: make(map[string]OAuth2Token)}
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("error reading token file %s: %s", path, err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &store.tokens); err != nil {
+			return nil, fmt.Errorf("error parsing token file %s: %s", path, err)
+		}
+	}
+	tokenStores[path] = store
+	return store, nil
+}
+
+// save writes the token file atomically, readable only by its owner. The caller holds mu
+func (s *TokenStore) save() error {
+	data, err := json.MarshalIndent(s.tokens, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding tokens: %s", err)
+	}
+
+	// CreateTemp makes the file with mode 0600, which the rename keeps
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".vitals-tokens-*")
+	if err != nil {
+		return fmt.Errorf("error writing token file %s: %s", s.path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error writing token file %s: %s", s.path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error writing token file %s: %s", s.path, err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("error writing token file %s: %s", s.path, err)
+	}
+	return nil
+}
+
+// oauth2Key identifies the tokens of an auth config in the token file
+func oauth2Key(auth AuthConfig) string {
+	return auth.TokenURL + " " + auth.ClientID
+}
+
+// accessToken returns a usable access token for auth, renewing it with the refresh token when
+// it expired or is the one a server just rejected, and authorizing through the device flow
+// when there is no refresh token yet. Checks wait for each other so only one renews the token
+func (s *TokenStore) accessToken(client *http.Client, auth AuthConfig, rejected string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := oauth2Key(auth)
+	token := s.tokens[key]
+	if token.valid(time.Now()) && token.AccessToken != rejected {
+		return token.AccessToken, nil
+	}
+
+	refreshToken := token.RefreshToken
+	if refreshToken == "" {
+		refreshToken = auth.RefreshToken
+	}
+
+	var renewed OAuth2Token
+	var err error
+	if refreshToken != "" {
+		renewed, err = requestToken(client, auth.TokenURL, oauth2Form(auth, url.Values{
+			"grant_type":    {"refresh_token"},
+			"refresh_token": {refreshToken},
+		}))
+		if err == nil && renewed.RefreshToken == "" {
+			renewed.RefreshToken = refreshToken
+		}
+	}
+
+	// A revoked or expired refresh token is replaced by authorizing again
+	var oauthErr *oauth2Error
+	revoked := errors.As(err, &oauthErr) && oauthErr.Code == "invalid_grant"
+	switch {
+	case refreshToken != "" && !(revoked && auth.DeviceAuthURL != ""):
+		if err != nil {
+			return "", fmt.Errorf("error refreshing OAuth2 token: %w", err)
+		}
+	case auth.DeviceAuthURL != "":
+		if renewed, err = deviceFlow(client, auth); err != nil {
+			return "", err
+		}
+	default:
+		return "", errors.New("no OAuth2 refresh token; set refresh_token or device_authorization_url")
+	}
+
+	s.tokens[key] = renewed
+	if err := s.save(); err != nil {
+		return "", err
+	}
+	return renewed.AccessToken, nil
+}
+
+// oauth2Form adds the client credentials of auth to a token request
+func oauth2Form(auth AuthConfig, form url.Values) url.Values {
+	form.Set("client_id", auth.ClientID)
+	if auth.ClientSecret != "" {
+		form.Set("client_secret", auth.ClientSecret)
+	}
+	if len(auth.Scopes) > 0 {
+		form.Set("scope", strings.Join(auth.Scopes, " "))
+	}
+	return form
+}
+
+// oauth2Error is the error response of an OAuth2 endpoint (RFC 6749 section 5.2)
+type oauth2Error struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+// Error returns the error code and its description
+func (e *oauth2Error) Error() string {
+	if e.Description == "" {
+		return e.Code
+	}
+	return e.Code + ": " + e.Description
+}
+
+// postForm posts a form to an OAuth2 endpoint and decodes its JSON response into v, returning
+// an *oauth2Error for error responses
+func postForm(client *http.Client, endpoint string, form url.Values, v any) error {
+	resp, err := client.PostForm(endpoint, form)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("error reading response from %s: %w", endpoint, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var oauthErr oauth2Error
+		if json.Unmarshal(body, &oauthErr) == nil && oauthErr.Code != "" {
+			return &oauthErr
+		}
+		return fmt.Errorf("%s returned status %d", endpoint, resp.StatusCode)
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("error parsing response from %s: %s", endpoint, err)
+	}
+	return nil
+}
+
+// requestToken sends a token request and returns the token it grants, or the *oauth2Error
+// the server answered with
+func requestToken(client *http.Client, tokenURL string, form url.Values) (OAuth2Token, error) {
+	var resp struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int    `json:"expires_in"`
+	}
+	if err := postForm(client, tokenURL, form, &resp); err != nil {
+		return OAuth2Token{}, err
+	}
+	if resp.AccessToken == "" {
+		return OAuth2Token{}, fmt.Errorf("no access_token in response from %s", tokenURL)
+	}
+
+	token := OAuth2Token{AccessToken: resp.AccessToken, RefreshToken: resp.RefreshToken}
+	if resp.ExpiresIn > 0 {
+		token.Expiry = time.Now().Add(time.Duration(resp.ExpiresIn) * time.Second)
+	}
+	return token, nil
+}
+
+// stderrIsTerminal reports whether someone can see the device flow's code, replaceable in tests
+var stderrIsTerminal = func() bool {
+	return term.IsTerminal(int(os.Stderr.Fd()))
+}
+
+// deviceFlow authorizes vitals with the device authorization grant (RFC 8628): it prints the
+// code to enter on stderr and polls until the user approves it, is denied, or the code expires
+func deviceFlow(client *http.Client, auth AuthConfig) (OAuth2Token, error) {
+	if !stderrIsTerminal() {
+		return OAuth2Token{}, errors.New("no refresh token yet; run vitals in a terminal once to authorize it")
+	}
+
+	var device struct {
+		DeviceCode              string `json:"device_code"`
+		UserCode                string `json:"user_code"`
+		VerificationURI         string `json:"verification_uri"`
+		VerificationURIComplete string `json:"verification_uri_complete"`
+		ExpiresIn               int    `json:"expires_in"`
+		Interval                int    `json:"interval"`
+	}
+	if err := postForm(client, auth.DeviceAuthURL, oauth2Form(auth, url.Values{}), &device); err != nil {
+		return OAuth2Token{}, fmt.Errorf("error starting device authorization: %s", err)
+	}
+	if device.VerificationURIComplete != "" {
+		fmt.Fprintf(os.Stderr, "To authorize vitals, visit %s (code %s)\n", device.VerificationURIComplete, device.UserCode)
+	} else {
+		fmt.Fprintf(os.Stderr, "To authorize vitals, visit %s and enter the code %s\n", device.VerificationURI, device.UserCode)
+	}
+
+	interval := device.Interval
+	if interval <= 0 {
+		interval = 5
+	}
+	deadline := time.Now().Add(time.Duration(device.ExpiresIn) * devicePollUnit)
+	for device.ExpiresIn <= 0 || time.Now().Before(deadline) {
+		time.Sleep(time.Duration(interval) * devicePollUnit)
+
+		token, err := requestToken(client, auth.TokenURL, oauth2Form(auth, url.Values{
+			"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+			"device_code": {device.DeviceCode},
+		}))
+		var oauthErr *oauth2Error
+		switch {
+		case err == nil:
+			fmt.Fprintln(os.Stderr, "Authorized vitals")
+			return token, nil
+		case errors.As(err, &oauthErr) && oauthErr.Code == "authorization_pending":
+		case errors.As(err, &oauthErr) && oauthErr.Code == "slow_down":
+			interval += 5
+		default:
+			return OAuth2Token{}, fmt.Errorf("error polling for device authorization: %s", err)
+		}
+	}
+	return OAuth2Token{}, errors.New("device authorization expired before it was approved")
+}
+
+// bearerTransport sends an OAuth2 access token with every request, renewing it once when a
+// response says it was rejected
+type bearerTransport struct {
+	transport http.RoundTripper
+	token     string
+	renew     func(rejected string) (string, error)
+}
+
+// RoundTrip sends the request with the access token, retrying once with a renewed one on 401
+func (t *bearerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	authed := req.Clone(req.Context())
+	authed.Header.Set("Authorization", "Bearer "+t.token)
+	resp, err := t.transport.RoundTrip(authed)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || (req.Body != nil && req.GetBody == nil) {
+		return resp, err
+	}
+
+	token, renewErr := t.renew(t.token)
+	if renewErr != nil {
+		return resp, nil
+	}
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return resp, nil
+		}
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, bodyDrainLimit))
+	resp.Body.Close()
+
+	t.token = token
+	retry.Header.Set("Authorization", "Bearer "+token)
+	return t.transport.RoundTrip(retry)
+}
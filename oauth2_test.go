@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// oauth2Server is a token endpoint, device authorization endpoint, and API for tests
+type oauth2Server struct {
+	*httptest.Server
+	mu            sync.Mutex
+	refreshTokens map[string]string // refresh token -> access token it grants
+	accepted      map[string]bool   // access tokens the API accepts
+	pending       int               // device polls answered with authorization_pending
+	tokenRequests atomic.Int32
+}
+
+func newOAuth2Server(t *testing.T) *oauth2Server {
+	s := &oauth2Server{refreshTokens: make(map[string]string), accepted: make(map[string]bool)}
+	mux := http.NewServeMux()
+	reply := func(w http.ResponseWriter, status int, v any) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(v)
+	}
+	mux.HandleFunc("/device", func(w http.ResponseWriter, r *http.Request) {
+		reply(w, 200, map[string]any{"device_code": "dev", "user_code": "ABCD-EFGH", "verification_uri": "https://example.com/device", "expires_in": 1000, "interval": 1})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		s.tokenRequests.Add(1)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if r.FormValue("client_id") != "vitals" {
+			reply(w, 401, map[string]string{"error": "invalid_client"})
+			return
+		}
+		switch r.FormValue("grant_type") {
+		case "refresh_token":
+			access, ok := s.refreshTokens[r.FormValue("refresh_token")]
+			if !ok {
+				reply(w, 400, map[string]string{"error": "invalid_grant", "error_description": "refresh token revoked"})
+				return
+			}
+			reply(w, 200, map[string]any{"access_token": access, "refresh_token": "rotated", "expires_in": 3600})
+		case "urn:ietf:params:oauth:grant-type:device_code":
+			if s.pending > 0 {
+				s.pending--
+				reply(w, 400, map[string]string{"error": "authorization_pending"})
+				return
+			}
+			reply(w, 200, map[string]any{"access_token": "from-device", "refresh_token": "device-refresh", "expires_in": 3600})
+		}
+	})
+	mux.HandleFunc("/api", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if !s.accepted[strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")] {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	})
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *oauth2Server) auth(tokenFile string) *AuthConfig {
+	return &AuthConfig{
+		Type:          "oauth2",
+		TokenURL:      s.URL + "/token",
+		DeviceAuthURL: s.URL + "/device",
+		ClientID:      "vitals",
+		RefreshToken:  "${VITALS_TEST_REFRESH_TOKEN}",
+		TokenFile:     tokenFile,
+	}
+}
+
+func checkOAuth2(s *oauth2Server, auth *AuthConfig) EndpointResult {
+	target := TargetConfig{StatusCodes: []int{200}, Auth: auth}
+	return checkEndpoint(s.Client(), s.URL, "/api", target, buildResponseChecks(target), nil, false)
+}
+
+func TestOAuth2RefreshToken(t *testing.T) {
+	s := newOAuth2Server(t)
+	s.refreshTokens["initial"] = "access-1"
+	s.accepted["access-1"] = true
+	t.Setenv("VITALS_TEST_REFRESH_TOKEN", "initial")
+	tokenFile := filepath.Join(t.TempDir(), "tokens.json")
+	auth := s.auth(tokenFile)
+
+	if result := checkOAuth2(s, auth); !result.Success || result.Error != nil {
+		t.Fatalf("first check failed: %+v", result)
+	}
+	if result := checkOAuth2(s, auth); !result.Success {
+		t.Fatalf("second check failed: %+v", result)
+	}
+	if n := s.tokenRequests.Load(); n != 1 {
+		t.Errorf("token requests = %d, want the access token reused", n)
+	}
+
+	// The rotated refresh token is persisted privately
+	info, err := os.Stat(tokenFile)
+	if err != nil {
+		t.Fatalf("token file not written: %v", err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("token file mode = %v, want 0600", info.Mode().Perm())
+	}
+	data, _ := os.ReadFile(tokenFile)
+	var tokens map[string]OAuth2Token
+	json.Unmarshal(data, &tokens)
+	if got := tokens[oauth2Key(*auth)]; got.AccessToken != "access-1" || got.RefreshToken != "rotated" || got.Expiry.Before(time.Now()) {
+		t.Errorf("stored token = %+v", got)
+	}
+
+	// A rejected access token is renewed with the stored refresh token and the request retried
+	s.mu.Lock()
+	s.accepted["access-1"] = false
+	s.refreshTokens["rotated"] = "access-2"
+	s.accepted["access-2"] = true
+	s.mu.Unlock()
+	if result := checkOAuth2(s, auth); !result.Success {
+		t.Errorf("check after revocation failed: %+v", result)
+	}
+}
+
+func TestOAuth2DeviceFlow(t *testing.T) {
+	defer func(unit time.Duration, terminal func() bool) {
+		devicePollUnit, stderrIsTerminal = unit, terminal
+	}(devicePollUnit, stderrIsTerminal)
+	devicePollUnit = time.Millisecond
+
+	tests := []struct {
+		name         string
+		refreshToken string
+		terminal     bool
+		wantErr      string
+	}{
+		{"first run", "", true, ""},
+		{"revoked refresh token", "revoked", true, ""},
+		{"not a terminal", "", false, "no refresh token yet; run vitals in a terminal once to authorize it"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newOAuth2Server(t)
+			s.accepted["from-device"] = true
+			s.pending = 2
+			stderrIsTerminal = func() bool { return tt.terminal }
+			t.Setenv("VITALS_TEST_REFRESH_TOKEN", tt.refreshToken)
+
+			result := checkOAuth2(s, s.auth(filepath.Join(t.TempDir(), "tokens.json")))
+			if tt.wantErr != "" {
+				if result.Error == nil || result.Error.Error() != tt.wantErr {
+					t.Errorf("error = %v, want %s", result.Error, tt.wantErr)
+				}
+				return
+			}
+			if !result.Success || result.Error != nil {
+				t.Errorf("check failed: %+v", result)
+			}
+		})
+	}
+}
@@ -0,0 +1,405 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// openAPISpec is the subset of OpenAPI 3 and Swagger 2 documents needed to derive checks
+type openAPISpec struct {
+	Info struct {
+		Title string `yaml:"title"`
+	} `yaml:"info"`
+
+	// OpenAPI 3
+	Servers []struct {
+		URL       string `yaml:"url"`
+		Variables map[string]struct {
+			Default string `yaml:"default"`
+		} `yaml:"variables"`
+	} `yaml:"servers"`
+
+	// Swagger 2
+	Host     string   `yaml:"host"`
+	BasePath string   `yaml:"basePath"`
+	Schemes  []string `yaml:"schemes"`
+
+	Paths map[string]openAPIPathItem `yaml:"paths"`
+}
+
+// openAPIPathItem holds the operations defined for a single path
+type openAPIPathItem struct {
+	Parameters []openAPIParameter `yaml:"parameters"`
+	Get        *openAPIOperation  `yaml:"get"`
+	Put        *openAPIOperation  `yaml:"put"`
+	Post       *openAPIOperation  `yaml:"post"`
+	Delete     *openAPIOperation  `yaml:"delete"`
+	Patch      *openAPIOperation  `yaml:"patch"`
+	Head       *openAPIOperation  `yaml:"head"`
+	Options    *openAPIOperation  `yaml:"options"`
+}
+
+// operations returns the path's operations keyed by upper-case HTTP method
+func (p openAPIPathItem) operations() map[string]*openAPIOperation {
+	ops := map[string]*openAPIOperation{
+		"GET": p.Get, "PUT": p.Put, "POST": p.Post, "DELETE": p.Delete,
+		"PATCH": p.Patch, "HEAD": p.Head, "OPTIONS": p.Options,
+	}
+	for method, op := range ops {
+		if op == nil {
+			delete(ops, method)
+		}
+	}
+	return ops
+}
+
+// openAPIOperation is a single method on a path
+type openAPIOperation struct {
+	Parameters  []openAPIParameter   `yaml:"parameters"`
+	RequestBody *openAPIRequestBody  `yaml:"requestBody"`
+	Responses   map[string]yaml.Node `yaml:"responses"`
+}
+
+// openAPIRequestBody is the OpenAPI 3 request body of an operation, keyed by media type
+type openAPIRequestBody struct {
+	Required bool                        `yaml:"required"`
+	Content  map[string]openAPIMediaType `yaml:"content"`
+}
+
+// openAPIMediaType holds the examples of one request body encoding
+type openAPIMediaType struct {
+	Example  any `yaml:"example"`
+	Examples map[string]struct {
+		Value any `yaml:"value"`
+	} `yaml:"examples"`
+	Schema struct {
+		Example any `yaml:"example"`
+	} `yaml:"schema"`
+}
+
+// openAPIParameter is a path, query, header, or (Swagger 2) body parameter with an optional
+// example value
+type openAPIParameter struct {
+	Name     string `yaml:"name"`
+	In       string `yaml:"in"`
+	Required bool   `yaml:"required"`
+	Example  any    `yaml:"example"`
+	Default  any    `yaml:"default"`
+	Schema   struct {
+		Example any   `yaml:"example"`
+		Default any   `yaml:"default"`
+		Enum    []any `yaml:"enum"`
+	} `yaml:"schema"`
+}
+
+// exampleValue returns a usable value for the parameter, if the spec provides one
+func (p openAPIParameter) exampleValue() (string, bool) {
+	for _, v := range []any{p.Example, p.Schema.Example, p.Default, p.Schema.Default} {
+		if v != nil {
+			return fmt.Sprint(v), true
+		}
+	}
+	if len(p.Schema.Enum) > 0 {
+		return fmt.Sprint(p.Schema.Enum[0]), true
+	}
+	return "", false
+}
+
+// pathTemplate matches {param} placeholders in OpenAPI paths and server URLs
+var pathTemplate = regexp.MustCompile(`\{([^}]+)\}`)
+
+// importOpenAPI converts the operations of an OpenAPI 3 or Swagger 2 spec into a target whose
+// endpoints send each operation's method and example body and expect its documented success
+// statuses. Operations documenting a 2XX range go in a second target accepting any 2xx
+func importOpenAPI(source string, opts importOptions) (map[string]TargetConfig, error) {
+	data, err := fetchSource(source)
+	if err != nil {
+		return nil, err
+	}
+
+	// YAML is a superset of JSON, so this handles both spec encodings
+	var spec openAPISpec
+	if err := yaml.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("error parsing OpenAPI spec %s: %s", source, err)
+	}
+	if len(spec.Paths) == 0 {
+		return nil, fmt.Errorf("no paths found in OpenAPI spec %s", source)
+	}
+
+	baseURLs, err := openAPIBaseURLs(spec, source, opts.baseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	name := opts.name
+	if name == "" {
+		name = targetKey("", spec.Info.Title)
+	}
+	if name == "" {
+		name = targetKey("", baseURLs[0])
+	}
+
+	paths := make([]string, 0, len(spec.Paths))
+	for path := range spec.Paths {
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+
+	targets := make(map[string]TargetConfig)
+	var count int
+	for _, path := range paths {
+		item := spec.Paths[path]
+		ops := item.operations()
+
+		methods := make([]string, 0, len(ops))
+		for method := range ops {
+			methods = append(methods, method)
+		}
+		slices.Sort(methods)
+
+		for _, method := range methods {
+			op := ops[method]
+			params := append(slices.Clone(item.Parameters), op.Parameters...)
+			endpointPath, err := openAPIEndpoint(path, params)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Skipping %s %s: %s\n", method, path, err)
+				continue
+			}
+			if opts.filter != nil && !opts.filter.MatchString(constructURL(baseURLs[0], endpointPath)) {
+				continue
+			}
+			endpoint := EndpointConfig{Path: endpointPath}
+			if method != "GET" {
+				endpoint.Method = method
+			}
+			if err := openAPIBody(&endpoint, op, params); err != nil {
+				fmt.Fprintf(os.Stderr, "Skipping %s %s: %s\n", method, path, err)
+				continue
+			}
+
+			// Endpoints can only replace status codes, so 2XX ranges get a target of their own
+			codes, ranges := openAPISuccessStatuses(op.Responses)
+			key := name
+			if len(ranges) > 0 {
+				key = name + "_2xx"
+			} else {
+				endpoint.StatusCodes = codes
+			}
+
+			target := targets[key]
+			if target.BaseURLs == nil {
+				target = TargetConfig{
+					Name:         spec.Info.Title,
+					BaseURLs:     baseURLs,
+					StatusRanges: ranges,
+				}
+			}
+			target.Endpoints = append(target.Endpoints, endpoint)
+			targets[key] = target
+
+			count++
+			if opts.limit > 0 && count == opts.limit {
+				return targets, nil
+			}
+		}
+	}
+
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no checkable endpoints found in OpenAPI spec %s", source)
+	}
+	return targets, nil
+}
+
+// openAPIBaseURLs resolves the spec's servers (or Swagger host/basePath) into absolute base
+// URLs, using the override or the spec's own URL for relative servers
+func openAPIBaseURLs(spec openAPISpec, source, override string) ([]string, error) {
+	if override != "" {
+		return []string{strings.TrimRight(override, "/")}, nil
+	}
+
+	var servers []string
+	for _, server := range spec.Servers {
+		serverURL := pathTemplate.ReplaceAllStringFunc(server.URL, func(m string) string {
+			return server.Variables[m[1:len(m)-1]].Default
+		})
+		servers = append(servers, serverURL)
+	}
+	if spec.Host != "" {
+		scheme := "https"
+		if len(spec.Schemes) > 0 {
+			scheme = spec.Schemes[0]
+		}
+		servers = append(servers, scheme+"://"+spec.Host+spec.BasePath)
+	}
+
+	var base *url.URL
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		base, _ = url.Parse(source)
+	}
+
+	var baseURLs []string
+	for _, server := range servers {
+		u, err := url.Parse(server)
+		if err != nil {
+			return nil, fmt.Errorf("invalid server URL %q: %s", server, err)
+		}
+		if !u.IsAbs() {
+			if base == nil {
+				return nil, fmt.Errorf("server URL %q is relative, use --base-url to set the host", server)
+			}
+			u = base.ResolveReference(u)
+		}
+		baseURLs = append(baseURLs, strings.TrimRight(u.String(), "/"))
+	}
+
+	if len(baseURLs) == 0 {
+		return nil, fmt.Errorf("OpenAPI spec defines no servers, use --base-url to set the host")
+	}
+	return baseURLs, nil
+}
+
+// openAPIEndpoint fills path parameters and required query parameters from their examples
+func openAPIEndpoint(path string, params []openAPIParameter) (string, error) {
+	values := make(map[string]string)
+	query := url.Values{}
+
+	for _, p := range params {
+		value, ok := p.exampleValue()
+		switch p.In {
+		case "path":
+			if !ok {
+				return "", fmt.Errorf("no example for path parameter %s", p.Name)
+			}
+			values[p.Name] = url.PathEscape(value)
+		case "query":
+			if ok {
+				query.Set(p.Name, value)
+			} else if p.Required {
+				return "", fmt.Errorf("no example for required query parameter %s", p.Name)
+			}
+		}
+	}
+
+	var missing string
+	endpoint := pathTemplate.ReplaceAllStringFunc(path, func(m string) string {
+		name := m[1 : len(m)-1]
+		if v, ok := values[name]; ok {
+			return v
+		}
+		missing = name
+		return m
+	})
+	if missing != "" {
+		return "", fmt.Errorf("no example for path parameter %s", missing)
+	}
+
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+	return endpoint, nil
+}
+
+// openAPIBody sets the endpoint's body from the example of the operation's request body,
+// preferring JSON, or of a Swagger 2 body parameter. A required body without an example is an
+// error
+func openAPIBody(endpoint *EndpointConfig, op *openAPIOperation, params []openAPIParameter) error {
+	var example any
+	var mediaType string
+	required := false
+	if op.RequestBody != nil {
+		required = op.RequestBody.Required
+		mediaTypes := slices.Sorted(maps.Keys(op.RequestBody.Content))
+		if i := slices.Index(mediaTypes, "application/json"); i > 0 {
+			mediaTypes[0], mediaTypes[i] = mediaTypes[i], mediaTypes[0]
+		}
+		for _, candidate := range mediaTypes {
+			if value, ok := op.RequestBody.Content[candidate].exampleValue(); ok {
+				example, mediaType = value, candidate
+				break
+			}
+		}
+	}
+	for _, p := range params {
+		if p.In != "body" {
+			continue
+		}
+		required = required || p.Required
+		if example == nil {
+			example, mediaType = p.Schema.Example, "application/json"
+			if example == nil {
+				example = p.Example
+			}
+		}
+	}
+	if example == nil {
+		if required {
+			return fmt.Errorf("no example for the required request body")
+		}
+		return nil
+	}
+
+	fields, isObject := example.(map[string]any)
+	switch value, isString := example.(string); {
+	case isString:
+		endpoint.Body = value
+	case isObject && mediaType == "application/x-www-form-urlencoded":
+		form := url.Values{}
+		for key, v := range fields {
+			form.Set(key, fmt.Sprint(v))
+		}
+		endpoint.Body = form.Encode()
+	default:
+		data, err := json.Marshal(example)
+		if err != nil {
+			return fmt.Errorf("error encoding the request body example: %s", err)
+		}
+		endpoint.Body = string(data)
+	}
+
+	// JSON and form bodies get their Content-Type from the body itself
+	if mediaType != "" && mediaType != bodyContentType([]byte(endpoint.Body)) {
+		endpoint.Headers = map[string]string{"Content-Type": mediaType}
+	}
+	return nil
+}
+
+// exampleValue returns the first example of a media type, if the spec provides one
+func (m openAPIMediaType) exampleValue() (any, bool) {
+	if m.Example != nil {
+		return m.Example, true
+	}
+	for _, name := range slices.Sorted(maps.Keys(m.Examples)) {
+		if m.Examples[name].Value != nil {
+			return m.Examples[name].Value, true
+		}
+	}
+	if m.Schema.Example != nil {
+		return m.Schema.Example, true
+	}
+	return nil, false
+}
+
+// openAPISuccessStatuses returns the documented 2xx codes and 2XX-style ranges of an operation
+func openAPISuccessStatuses(responses map[string]yaml.Node) ([]int, []string) {
+	var codes []int
+	var ranges []string
+	for key := range responses {
+		if code, err := strconv.Atoi(key); err == nil && code >= 200 && code < 300 {
+			codes = append(codes, code)
+		}
+		if strings.EqualFold(key, "2XX") {
+			ranges = append(ranges, "200-299")
+		}
+	}
+	slices.Sort(codes)
+	return codes, ranges
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+const testOpenAPISpec = `
+openapi: 3.0.0
+info:
+  title: Pet Store
+servers:
+  - url: https://{env}.example.com/v1
+    variables:
+      env:
+        default: api
+paths:
+  /pets:
+    get:
+      parameters:
+        - name: limit
+          in: query
+          required: true
+          schema:
+            type: integer
+            example: 10
+      responses:
+        "200":
+          description: ok
+    post:
+      requestBody:
+        required: true
+        content:
+          application/json:
+            example:
+              name: Rex
+      responses:
+        "201":
+          description: created
+  /pets/{petId}:
+    parameters:
+      - name: petId
+        in: path
+        required: true
+        example: 42
+    get:
+      responses:
+        "200":
+          description: ok
+    put:
+      requestBody:
+        content:
+          text/plain:
+            examples:
+              sold:
+                value: sold
+      responses:
+        "204":
+          description: updated
+    delete:
+      requestBody:
+        required: true
+        content:
+          application/json: {}
+      responses:
+        "204":
+          description: deleted
+  /status:
+    get:
+      responses:
+        "2XX":
+          description: ok
+  /owners/{ownerId}:
+    get:
+      parameters:
+        - name: ownerId
+          in: path
+          required: true
+      responses:
+        "200":
+          description: ok
+  /health:
+    get:
+      responses:
+        "204":
+          description: healthy
+        default:
+          description: error
+`
+
+func TestImportOpenAPI(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spec.yaml")
+	if err := os.WriteFile(path, []byte(testOpenAPISpec), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	targets, err := importOpenAPI(path, importOptions{})
+	if err != nil {
+		t.Fatalf("importOpenAPI() error = %v", err)
+	}
+
+	target, found := targets["Pet_Store"]
+	if !found {
+		t.Fatalf("expected target Pet_Store, got %v", targets)
+	}
+	if !slices.Equal(target.BaseURLs, []string{"https://api.example.com/v1"}) {
+		t.Errorf("unexpected base_urls: %v", target.BaseURLs)
+	}
+	// The DELETE is skipped for its required body without an example
+	want := []EndpointConfig{
+		{Path: "/health", StatusCodes: []int{204}},
+		{Path: "/pets?limit=10", StatusCodes: []int{200}},
+		{Path: "/pets", Method: "POST", Body: `{"name":"Rex"}`, StatusCodes: []int{201}},
+		{Path: "/pets/42", StatusCodes: []int{200}},
+		{Path: "/pets/42", Method: "PUT", Body: "sold", Headers: map[string]string{"Content-Type": "text/plain"}, StatusCodes: []int{204}},
+	}
+	if !reflect.DeepEqual(target.Endpoints, want) {
+		t.Errorf("endpoints = %+v\nwant %+v", target.Endpoints, want)
+	}
+
+	ranged, found := targets["Pet_Store_2xx"]
+	if !found || !slices.Equal(ranged.StatusRanges, []string{"200-299"}) || !slices.Equal(endpointPaths(ranged.Endpoints), []string{"/status"}) {
+		t.Errorf("unexpected 2xx target: %v", ranged)
+	}
+}
+
+func TestOpenAPIBaseURLs(t *testing.T) {
+	var spec openAPISpec
+	spec.Host = "petstore.swagger.io"
+	spec.BasePath = "/v2"
+	spec.Schemes = []string{"http"}
+
+	got, err := openAPIBaseURLs(spec, "swagger.json", "")
+	if err != nil || !slices.Equal(got, []string{"http://petstore.swagger.io/v2"}) {
+		t.Errorf("openAPIBaseURLs() swagger 2 = %v, %v", got, err)
+	}
+
+	spec = openAPISpec{}
+	spec.Servers = append(spec.Servers, struct {
+		URL       string `yaml:"url"`
+		Variables map[string]struct {
+			Default string `yaml:"default"`
+		} `yaml:"variables"`
+	}{URL: "/api"})
+
+	if _, err := openAPIBaseURLs(spec, "spec.yaml", ""); err == nil {
+		t.Error("expected error for relative server without a base URL")
+	}
+	got, err = openAPIBaseURLs(spec, "https://example.com/openapi.yaml", "")
+	if err != nil || !slices.Equal(got, []string{"https://example.com/api"}) {
+		t.Errorf("openAPIBaseURLs() relative server = %v, %v", got, err)
+	}
+}
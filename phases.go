@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// checkPhases returns why a response's timing breakdown is over a target's phase limits, or
+// an empty string when every phase is within them
+func checkPhases(result EndpointResult, target TargetConfig) string {
+	phases := []struct {
+		name    string
+		setting string
+		took    time.Duration
+		limitMS int
+	}{
+		{"DNS lookup", "max_dns_ms", result.DNSDuration, target.MaxDNSMS},
+		{"TCP connect", "max_connect_ms", result.ConnectDuration, target.MaxConnectMS},
+		{"TLS handshake", "max_tls_handshake_ms", result.TLSDuration, target.MaxTLSHandshakeMS},
+		{"first byte", "max_ttfb_ms", result.TTFB, target.MaxTTFBMS},
+	}
+	for _, phase := range phases {
+		limit := time.Duration(phase.limitMS) * time.Millisecond
+		if phase.limitMS > 0 && phase.took > limit {
+			return fmt.Sprintf("%s took %s, over %s %d", phase.name, phase.took.Round(100*time.Microsecond), phase.setting, phase.limitMS)
+		}
+	}
+	return ""
+}
+
+// phasesLine describes the connection phases of a result for verbose tables
+func phasesLine(result EndpointResult) string {
+	var parts []string
+	if result.ConnectDuration > 0 {
+		parts = append(parts, fmt.Sprintf("connect %.3fs", result.ConnectDuration.Seconds()))
+	}
+	if result.TLSDuration > 0 {
+		parts = append(parts, fmt.Sprintf("TLS %.3fs", result.TLSDuration.Seconds()))
+	}
+	parts = append(parts, fmt.Sprintf("first byte %.3fs", result.TTFB.Seconds()))
+	return "Timing: " + strings.Join(parts, ", ")
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckPhases(t *testing.T) {
+	result := EndpointResult{
+		DNSDuration:     5 * time.Millisecond,
+		ConnectDuration: 20 * time.Millisecond,
+		TLSDuration:     812 * time.Millisecond,
+		TTFB:            900 * time.Millisecond,
+	}
+	tests := []struct {
+		name   string
+		target TargetConfig
+		want   string
+	}{
+		{"no limits", TargetConfig{}, ""},
+		{"within limits", TargetConfig{MaxDNSMS: 10, MaxConnectMS: 50, MaxTLSHandshakeMS: 1000, MaxTTFBMS: 1000}, ""},
+		{"slow TLS", TargetConfig{MaxTLSHandshakeMS: 500, MaxTTFBMS: 500}, "TLS handshake took 812ms, over max_tls_handshake_ms 500"},
+		{"slow first byte", TargetConfig{MaxTTFBMS: 800}, "first byte took 900ms, over max_ttfb_ms 800"},
+		{"slow DNS", TargetConfig{MaxDNSMS: 1}, "DNS lookup took 5ms, over max_dns_ms 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := checkPhases(result, tt.target); got != tt.want {
+				t.Errorf("checkPhases() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckEndpointPhases(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	target := TargetConfig{StatusCodes: []int{200}, MaxTTFBMS: 20}
+	result := checkEndpoint(server.Client(), server.URL, "/", target, buildResponseChecks(target), nil, false)
+	if result.TLSDuration <= 0 || result.ConnectDuration <= 0 || result.TTFB < 50*time.Millisecond {
+		t.Errorf("phases = connect %s, TLS %s, first byte %s", result.ConnectDuration, result.TLSDuration, result.TTFB)
+	}
+	if result.Success || !strings.HasPrefix(result.FailureReason, "first byte took ") {
+		t.Errorf("success = %v, reason %q, want a slow first byte", result.Success, result.FailureReason)
+	}
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+)
+
+// defaultPluginTimeout bounds a plugin check when the config sets no timeout
+const defaultPluginTimeout = 10 * time.Second
+
+// PluginConfig replaces a target's HTTP requests with a WebAssembly check module
+type PluginConfig struct {
+	Path string `toml:"path,omitempty"`
+
+	// Config is passed to the module unchanged, e.g. a greeting for a binary protocol
+	Config map[string]string `toml:"config,omitempty"`
+}
+
+// PluginInput is the JSON document a module's check function receives
+type PluginInput struct {
+	URL       string            `json:"url"`
+	BaseURL   string            `json:"base_url"`
+	Endpoint  string            `json:"endpoint"`
+	Config    map[string]string `json:"config,omitempty"`
+	TimeoutMS int64             `json:"timeout_ms"`
+}
+
+// PluginOutput is the JSON document a module's check function returns
+type PluginOutput struct {
+	OK      bool   `json:"ok"`
+	Message string `json:"message,omitempty"`
+	Status  int    `json:"status,omitempty"`
+}
+
+// Plugin is a compiled check module. Modules export memory plus
+//
+//	alloc(size i32) i32               reserve size bytes for the input
+//	check(ptr i32, len i32) i64       run the check on the PluginInput JSON at ptr, returning
+//	                                  the PluginOutput JSON as ptr<<32 | len
+//
+// and may import from the "vitals" module
+//
+//	exchange(addr_ptr, addr_len, req_ptr, req_len, out_ptr, out_cap i32) i32
+//	                                  send req to a TCP host:port and read up to out_cap reply
+//	                                  bytes into out_ptr, returning the count or -1 on error
+//	log(ptr, len i32)                 print a message in verbose mode
+//
+// WASI is available without filesystem or network access
+type Plugin struct {
+	path     string
+	compiled wazero.CompiledModule
+}
+
+var (
+	// pluginRuntime is shared by every plugin so compiled modules are reused across runs
+	pluginRuntime     wazero.Runtime
+	pluginRuntimeOnce sync.Once
+
+	pluginCacheMu sync.Mutex
+	pluginCache   = make(map[string]pluginCacheEntry)
+)
+
+// pluginCacheEntry is a compiled module and the modification time of the file it came from
+type pluginCacheEntry struct {
+	modTime time.Time
+	plugin  *Plugin
+}
+
+// pluginVerboseKey carries the verbose flag to host functions through the call context
+type pluginVerboseKey struct{}
+
+// pluginDeadlineKey carries the check's deadline to the exchange host function
+type pluginDeadlineKey struct{}
+
+// newPluginRuntime creates the runtime with WASI and the vitals host module
+func newPluginRuntime() wazero.Runtime {
+	ctx := context.Background()
+	runtime := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().WithCloseOnContextDone(true))
+	wasi_snapshot_preview1.MustInstantiate(ctx, runtime)
+
+	_, err := runtime.NewHostModuleBuilder("vitals").
+		NewFunctionBuilder().WithFunc(pluginExchange).Export("exchange").
+		NewFunctionBuilder().WithFunc(pluginLog).Export("log").
+		Instantiate(ctx)
+	if err != nil {
+		panic(err)
+	}
+	return runtime
+}
+
+// loadPlugin compiles a module, reusing the compiled module until the file changes
+func loadPlugin(path string) (*Plugin, error) {
+	pluginRuntimeOnce.Do(func() { pluginRuntime = newPluginRuntime() })
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pluginCacheMu.Lock()
+	defer pluginCacheMu.Unlock()
+	if entry, ok := pluginCache[path]; ok && entry.modTime.Equal(info.ModTime()) {
+		return entry.plugin, nil
+	}
+
+	code, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	compiled, err := pluginRuntime.CompileModule(context.Background(), code)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range []string{"alloc", "check"} {
+		if _, ok := compiled.ExportedFunctions()[name]; !ok {
+			compiled.Close(context.Background())
+			return nil, fmt.Errorf("module does not export %s", name)
+		}
+	}
+
+	if entry, ok := pluginCache[path]; ok {
+		entry.plugin.compiled.Close(context.Background())
+	}
+	plugin := &Plugin{path: path, compiled: compiled}
+	pluginCache[path] = pluginCacheEntry{modTime: info.ModTime(), plugin: plugin}
+	return plugin, nil
+}
+
+// check instantiates the module and runs its check function with a fresh memory
+func (p *Plugin) check(input PluginInput, timeout time.Duration, verbose bool) (PluginOutput, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	ctx = context.WithValue(ctx, pluginVerboseKey{}, verbose)
+	if deadline, ok := ctx.Deadline(); ok {
+		ctx = context.WithValue(ctx, pluginDeadlineKey{}, deadline)
+	}
+
+	module, err := pluginRuntime.InstantiateModule(ctx, p.compiled, wazero.NewModuleConfig().
+		WithName("").
+		WithStartFunctions("_initialize"))
+	if err != nil {
+		return PluginOutput{}, fmt.Errorf("error instantiating plugin %s: %s", p.path, err)
+	}
+	defer module.Close(ctx)
+
+	data, err := json.Marshal(input)
+	if err != nil {
+		return PluginOutput{}, err
+	}
+	allocated, err := module.ExportedFunction("alloc").Call(ctx, uint64(len(data)))
+	if err != nil {
+		return PluginOutput{}, fmt.Errorf("plugin %s alloc: %s", p.path, err)
+	}
+	ptr := uint32(allocated[0])
+	if !module.Memory().Write(ptr, data) {
+		return PluginOutput{}, fmt.Errorf("plugin %s alloc returned an invalid pointer", p.path)
+	}
+
+	returned, err := module.ExportedFunction("check").Call(ctx, uint64(ptr), uint64(len(data)))
+	if err != nil {
+		return PluginOutput{}, fmt.Errorf("plugin %s check: %s", p.path, err)
+	}
+	outPtr, outLen := uint32(returned[0]>>32), uint32(returned[0])
+	raw, ok := module.Memory().Read(outPtr, outLen)
+	if !ok {
+		return PluginOutput{}, fmt.Errorf("plugin %s returned an out of range result", p.path)
+	}
+
+	var output PluginOutput
+	if err := json.Unmarshal(raw, &output); err != nil {
+		return PluginOutput{}, fmt.Errorf("error parsing plugin %s result: %s", p.path, err)
+	}
+	return output, nil
+}
+
+// pluginExchange implements vitals.exchange: one TCP request and reply within the check deadline
+func pluginExchange(ctx context.Context, m api.Module, addrPtr, addrLen, reqPtr, reqLen, outPtr, outCap uint32) int32 {
+	addr, ok := m.Memory().Read(addrPtr, addrLen)
+	if !ok {
+		return -1
+	}
+	request, ok := m.Memory().Read(reqPtr, reqLen)
+	if !ok {
+		return -1
+	}
+
+	deadline, _ := ctx.Value(pluginDeadlineKey{}).(time.Time)
+	conn, err := (&net.Dialer{Deadline: deadline}).DialContext(ctx, "tcp", string(addr))
+	if err != nil {
+		return -1
+	}
+	defer conn.Close()
+	conn.SetDeadline(deadline)
+
+	if _, err := conn.Write(request); err != nil {
+		return -1
+	}
+	reply := make([]byte, outCap)
+	n, err := conn.Read(reply)
+	if err != nil && n == 0 {
+		return -1
+	}
+	if !m.Memory().Write(outPtr, reply[:n]) {
+		return -1
+	}
+	return int32(n)
+}
+
+// pluginLog implements vitals.log, printing plugin messages in verbose mode
+func pluginLog(ctx context.Context, m api.Module, ptr, length uint32) {
+	if verbose, _ := ctx.Value(pluginVerboseKey{}).(bool); !verbose {
+		return
+	}
+	if message, ok := m.Memory().Read(ptr, length); ok {
+		fmt.Printf("plugin: %s\n", message)
+	}
+}
+
+// checkPlugin runs a plugin check for one endpoint in place of an HTTP request
+func checkPlugin(client *http.Client, baseURL, endpoint string, target TargetConfig, checks ResponseChecks, verbose bool) EndpointResult {
+	result := EndpointResult{
+		BaseURL:    baseURL,
+		Endpoint:   endpoint,
+		URL:        constructURL(baseURL, endpoint),
+		Method:     "PLUGIN",
+		RunbookURL: target.RunbookURL,
+	}
+	if checks.Plugin == nil {
+		result.Error = fmt.Errorf("plugin %s is not loaded", target.Plugin.Path)
+		return result
+	}
+
+	timeout := client.Timeout
+	if timeout <= 0 {
+		timeout = defaultPluginTimeout
+	}
+
+	startTime := time.Now()
+	output, err := checks.Plugin.check(PluginInput{
+		URL:       result.URL,
+		BaseURL:   baseURL,
+		Endpoint:  endpoint,
+		Config:    target.Plugin.Config,
+		TimeoutMS: timeout.Milliseconds(),
+	}, timeout, verbose)
+	result.Duration = time.Since(startTime)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+
+	result.StatusCode = output.Status
+	result.Success = output.OK
+	if !output.OK {
+		result.FailureReason = output.Message
+		if result.FailureReason == "" {
+			result.FailureReason = "plugin check failed"
+		}
+	}
+	return result
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// uleb128 and sleb128 encode integers the way WebAssembly binaries do
+func uleb128(n uint64) []byte {
+	var b []byte
+	for {
+		c := byte(n & 0x7f)
+		n >>= 7
+		if n != 0 {
+			c |= 0x80
+		}
+		b = append(b, c)
+		if n == 0 {
+			return b
+		}
+	}
+}
+
+func sleb128(n int64) []byte {
+	var b []byte
+	for {
+		c := byte(n & 0x7f)
+		n >>= 7
+		if (n == 0 && c&0x40 == 0) || (n == -1 && c&0x40 != 0) {
+			return append(b, c)
+		}
+		b = append(b, c|0x80)
+	}
+}
+
+func wasmSection(id byte, content ...byte) []byte {
+	return append(append([]byte{id}, uleb128(uint64(len(content)))...), content...)
+}
+
+func wasmName(name string) []byte {
+	return append(uleb128(uint64(len(name))), name...)
+}
+
+// testPluginModule assembles a module whose check always returns output, or traps when
+// output is empty
+func testPluginModule(output string) []byte {
+	const inputPtr, outputPtr = 1024, 2048
+
+	module := []byte{0x00, 'a', 's', 'm', 0x01, 0x00, 0x00, 0x00}
+	module = append(module, wasmSection(1, // types: (i32) -> i32, (i32, i32) -> i64
+		0x02, 0x60, 0x01, 0x7f, 0x01, 0x7f, 0x60, 0x02, 0x7f, 0x7f, 0x01, 0x7e)...)
+	module = append(module, wasmSection(3, 0x02, 0x00, 0x01)...) // alloc and check
+	module = append(module, wasmSection(5, 0x01, 0x00, 0x01)...) // one page of memory
+
+	var exports []byte
+	exports = append(exports, 0x03)
+	exports = append(append(exports, wasmName("memory")...), 0x02, 0x00)
+	exports = append(append(exports, wasmName("alloc")...), 0x00, 0x00)
+	exports = append(append(exports, wasmName("check")...), 0x00, 0x01)
+	module = append(module, wasmSection(7, exports...)...)
+
+	alloc := append(append([]byte{0x00, 0x41}, sleb128(inputPtr)...), 0x0b)
+	check := []byte{0x00, 0x00, 0x0b} // unreachable
+	if output != "" {
+		check = append(append([]byte{0x00, 0x42}, sleb128(outputPtr<<32|int64(len(output)))...), 0x0b)
+	}
+	code := []byte{0x02}
+	code = append(append(code, uleb128(uint64(len(alloc)))...), alloc...)
+	code = append(append(code, uleb128(uint64(len(check)))...), check...)
+	module = append(module, wasmSection(10, code...)...)
+
+	data := append([]byte{0x01, 0x00, 0x41}, sleb128(outputPtr)...)
+	data = append(append(append(data, 0x0b), uleb128(uint64(len(output)))...), output...)
+	return append(module, wasmSection(11, data...)...)
+}
+
+func writePlugin(t *testing.T, module []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "check.wasm")
+	if err := os.WriteFile(path, module, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCheckPlugin(t *testing.T) {
+	tests := []struct {
+		name        string
+		output      string
+		wantSuccess bool
+		wantStatus  int
+		wantReason  string
+		wantErr     string
+	}{
+		{"passes", `{"ok":true,"status":1}`, true, 1, "", ""},
+		{"fails with message", `{"ok":false,"message":"license expired"}`, false, 0, "license expired", ""},
+		{"fails without message", `{"ok":false}`, false, 0, "plugin check failed", ""},
+		{"invalid result", `not json`, false, 0, "", "error parsing plugin"},
+		{"trap", ``, false, 0, "", "unreachable"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := TargetConfig{Plugin: &PluginConfig{Path: writePlugin(t, testPluginModule(tt.output))}}
+			checks := buildResponseChecks(target)
+			if checks.Plugin == nil {
+				t.Fatal("plugin was not loaded")
+			}
+
+			result := checkPlugin(&http.Client{Timeout: time.Second}, "tcp://127.0.0.1:7000", "/", target, checks, false)
+			if tt.wantErr != "" {
+				if result.Error == nil || !strings.Contains(result.Error.Error(), tt.wantErr) {
+					t.Fatalf("Error = %v, want %q", result.Error, tt.wantErr)
+				}
+				return
+			}
+			if result.Error != nil {
+				t.Fatal(result.Error)
+			}
+			if result.Success != tt.wantSuccess || result.StatusCode != tt.wantStatus || result.FailureReason != tt.wantReason {
+				t.Errorf("result = %v %d %q, want %v %d %q", result.Success, result.StatusCode, result.FailureReason,
+					tt.wantSuccess, tt.wantStatus, tt.wantReason)
+			}
+			if result.Method != "PLUGIN" || result.URL != "tcp://127.0.0.1:7000/" {
+				t.Errorf("Method, URL = %s, %s", result.Method, result.URL)
+			}
+		})
+	}
+}
+
+func TestLoadPluginErrors(t *testing.T) {
+	if _, err := loadPlugin(filepath.Join(t.TempDir(), "missing.wasm")); err == nil {
+		t.Error("expected an error for a missing module")
+	}
+	if _, err := loadPlugin(writePlugin(t, []byte("not wasm"))); err == nil {
+		t.Error("expected an error for an invalid module")
+	}
+
+	// A valid module without the check ABI exports
+	empty := []byte{0x00, 'a', 's', 'm', 0x01, 0x00, 0x00, 0x00}
+	if _, err := loadPlugin(writePlugin(t, empty)); err == nil || !strings.Contains(err.Error(), "does not export alloc") {
+		t.Errorf("loadPlugin() error = %v, want missing export", err)
+	}
+}
+
+func TestCheckPluginNotLoaded(t *testing.T) {
+	target := TargetConfig{Plugin: &PluginConfig{Path: "missing.wasm"}}
+	result := checkPlugin(&http.Client{}, "tcp://x", "", target, ResponseChecks{}, false)
+	if result.Error == nil || !strings.Contains(result.Error.Error(), "not loaded") {
+		t.Errorf("Error = %v, want not loaded", result.Error)
+	}
+}
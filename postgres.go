@@ -0,0 +1,188 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+// postgresSchema creates the history tables on first use
+const postgresSchema = `
+CREATE TABLE IF NOT EXISTS vitals_results (
+	id               BIGSERIAL PRIMARY KEY,
+	time             TIMESTAMPTZ NOT NULL,
+	target           TEXT NOT NULL,
+	url              TEXT NOT NULL,
+	method           TEXT NOT NULL,
+	status_code      INTEGER,
+	success          BOOLEAN NOT NULL,
+	duration_seconds DOUBLE PRECISION NOT NULL,
+	error            TEXT
+);
+ALTER TABLE vitals_results ADD COLUMN IF NOT EXISTS labels JSONB;
+CREATE INDEX IF NOT EXISTS vitals_results_time ON vitals_results (time);
+CREATE INDEX IF NOT EXISTS vitals_results_target_time ON vitals_results (target, time);
+
+CREATE TABLE IF NOT EXISTS vitals_rollups (
+	day            DATE NOT NULL,
+	target         TEXT NOT NULL,
+	url            TEXT NOT NULL,
+	checks         INTEGER NOT NULL,
+	successes      INTEGER NOT NULL,
+	uptime_percent DOUBLE PRECISION NOT NULL,
+	p95_seconds    DOUBLE PRECISION NOT NULL,
+	PRIMARY KEY (day, target, url)
+);`
+
+// postgresRollup moves results older than $1 into daily rollups, merging with existing days
+// the same way the file store does
+const postgresRollup = `
+INSERT INTO vitals_rollups (day, target, url, checks, successes, uptime_percent, p95_seconds)
+SELECT (time AT TIME ZONE 'UTC')::date, target, url,
+	count(*),
+	count(*) FILTER (WHERE success),
+	100.0 * count(*) FILTER (WHERE success) / count(*),
+	percentile_disc(0.95) WITHIN GROUP (ORDER BY duration_seconds)
+FROM vitals_results
+WHERE time < $1
+GROUP BY 1, 2, 3
+ON CONFLICT (day, target, url) DO UPDATE SET
+	checks = vitals_rollups.checks + EXCLUDED.checks,
+	successes = vitals_rollups.successes + EXCLUDED.successes,
+	uptime_percent = 100.0 * (vitals_rollups.successes + EXCLUDED.successes) / (vitals_rollups.checks + EXCLUDED.checks),
+	p95_seconds = GREATEST(vitals_rollups.p95_seconds, EXCLUDED.p95_seconds)`
+
+// postgresHistory stores history in a shared PostgreSQL database so several vitals
+// instances can write to one place
+type postgresHistory struct {
+	db *sql.DB
+}
+
+// openPostgresHistory connects to the database and creates the tables if needed
+func openPostgresHistory(dsn string) (*postgresHistory, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("error opening postgres history: %s", err)
+	}
+	if _, err := db.Exec(postgresSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error creating postgres history tables: %s", err)
+	}
+	return &postgresHistory{db: db}, nil
+}
+
+// Append inserts records in a single transaction
+func (h *postgresHistory) Append(records []HistoryRecord) error {
+	tx, err := h.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error writing history: %s", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`INSERT INTO vitals_results
+		(time, target, url, method, status_code, success, duration_seconds, error, labels)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`)
+	if err != nil {
+		return fmt.Errorf("error writing history: %s", err)
+	}
+	defer stmt.Close()
+
+	for _, r := range records {
+		if _, err := stmt.Exec(r.Time, r.Target, r.URL, r.Method, nullInt(r.StatusCode), r.Success, r.Duration, nullString(r.Error), nullLabels(r.Labels)); err != nil {
+			return fmt.Errorf("error writing history: %s", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error writing history: %s", err)
+	}
+	return nil
+}
+
+// Prune compacts results older than cutoff into rollups and deletes them, atomically
+func (h *postgresHistory) Prune(cutoff time.Time) (PruneStats, error) {
+	tx, err := h.db.Begin()
+	if err != nil {
+		return PruneStats{}, fmt.Errorf("error pruning history: %s", err)
+	}
+	defer tx.Rollback()
+
+	var stats PruneStats
+	res, err := tx.Exec(postgresRollup, cutoff)
+	if err != nil {
+		return PruneStats{}, fmt.Errorf("error compacting history: %s", err)
+	}
+	rollups, _ := res.RowsAffected()
+	stats.Rollups = int(rollups)
+
+	res, err = tx.Exec(`DELETE FROM vitals_results WHERE time < $1`, cutoff)
+	if err != nil {
+		return PruneStats{}, fmt.Errorf("error pruning history: %s", err)
+	}
+	compacted, _ := res.RowsAffected()
+	stats.Compacted = int(compacted)
+
+	if err := tx.QueryRow(`SELECT count(*) FROM vitals_results`).Scan(&stats.Kept); err != nil {
+		return PruneStats{}, fmt.Errorf("error pruning history: %s", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return PruneStats{}, fmt.Errorf("error pruning history: %s", err)
+	}
+	return stats, nil
+}
+
+// Since returns the results from cutoff on, oldest first
+func (h *postgresHistory) Since(cutoff time.Time) ([]HistoryRecord, error) {
+	rows, err := h.db.Query(`SELECT time, target, url, method, status_code, success, duration_seconds, error, labels
+		FROM vitals_results WHERE time >= $1 ORDER BY time, id`, cutoff)
+	if err != nil {
+		return nil, fmt.Errorf("error reading history: %s", err)
+	}
+	defer rows.Close()
+
+	var records []HistoryRecord
+	for rows.Next() {
+		var r HistoryRecord
+		var status sql.NullInt64
+		var errText, labels sql.NullString
+		if err := rows.Scan(&r.Time, &r.Target, &r.URL, &r.Method, &status, &r.Success, &r.Duration, &errText, &labels); err != nil {
+			return nil, fmt.Errorf("error reading history: %s", err)
+		}
+		r.StatusCode = int(status.Int64)
+		r.Error = errText.String
+		if labels.Valid {
+			json.Unmarshal([]byte(labels.String), &r.Labels)
+		}
+		records = append(records, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading history: %s", err)
+	}
+	return records, nil
+}
+
+// Close closes the database connection
+func (h *postgresHistory) Close() error {
+	return h.db.Close()
+}
+
+// nullInt stores zero (no status, e.g. a connection error) as NULL
+func nullInt(n int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(n), Valid: n != 0}
+}
+
+// nullString stores an empty string as NULL
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// nullLabels stores labels as a JSON object, or NULL when the run had none
+func nullLabels(labels Labels) sql.NullString {
+	if len(labels) == 0 {
+		return sql.NullString{}
+	}
+	data, _ := json.Marshal(labels)
+	return sql.NullString{String: string(data), Valid: true}
+}
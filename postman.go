@@ -0,0 +1,245 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// postmanCollection is the subset of a Postman v2.1 collection needed to derive checks
+type postmanCollection struct {
+	Info struct {
+		Name string `json:"name"`
+	} `json:"info"`
+	Item     []postmanItem     `json:"item"`
+	Auth     *postmanAuth      `json:"auth"`
+	Variable []postmanKeyValue `json:"variable"`
+}
+
+// postmanItem is either a folder (with nested items) or a single request
+type postmanItem struct {
+	Name    string          `json:"name"`
+	Item    []postmanItem   `json:"item"`
+	Auth    *postmanAuth    `json:"auth"`
+	Request *postmanRequest `json:"request"`
+}
+
+// postmanRequest is a saved request; its URL may be a plain string or an object
+type postmanRequest struct {
+	Method string            `json:"method"`
+	Header []postmanKeyValue `json:"header"`
+	URL    postmanURL        `json:"url"`
+	Auth   *postmanAuth      `json:"auth"`
+	Body   *postmanBody      `json:"body"`
+}
+
+// postmanBody is a request body, raw text or urlencoded fields
+type postmanBody struct {
+	Mode       string            `json:"mode"`
+	Raw        string            `json:"raw"`
+	URLEncoded []postmanKeyValue `json:"urlencoded"`
+	Options    struct {
+		Raw struct {
+			Language string `json:"language"`
+		} `json:"raw"`
+	} `json:"options"`
+}
+
+// postmanURL accepts both the string and the structured URL forms
+type postmanURL struct {
+	Raw string `json:"raw"`
+}
+
+func (u *postmanURL) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		return json.Unmarshal(data, &u.Raw)
+	}
+	type plain postmanURL
+	return json.Unmarshal(data, (*plain)(u))
+}
+
+// postmanAuth is a request or folder auth definition, e.g. {"type": "bearer", "bearer": [...]}
+type postmanAuth struct {
+	Type   string            `json:"type"`
+	Bearer []postmanKeyValue `json:"bearer"`
+	Basic  []postmanKeyValue `json:"basic"`
+	APIKey []postmanKeyValue `json:"apikey"`
+}
+
+// postmanKeyValue is the key/value pair Postman uses for headers, variables, and auth fields
+type postmanKeyValue struct {
+	Key      string `json:"key"`
+	Value    any    `json:"value"`
+	Disabled bool   `json:"disabled"`
+}
+
+// lookup returns the value for key in a list of Postman key/value pairs
+func postmanLookup(pairs []postmanKeyValue, key string) string {
+	for _, p := range pairs {
+		if p.Key == key && p.Value != nil {
+			return fmt.Sprint(p.Value)
+		}
+	}
+	return ""
+}
+
+// postmanVariable matches {{name}} references
+var postmanVariable = regexp.MustCompile(`\{\{\s*([^}\s]+)\s*\}\}`)
+
+// importPostman converts the requests of a Postman v2.1 collection into targets
+func importPostman(source string, opts importOptions) (map[string]TargetConfig, error) {
+	data, err := fetchSource(source)
+	if err != nil {
+		return nil, err
+	}
+
+	var collection postmanCollection
+	if err := json.Unmarshal(data, &collection); err != nil {
+		return nil, fmt.Errorf("error parsing Postman collection %s: %s", source, err)
+	}
+
+	// Collection variables, overridden by --var values
+	vars := make(map[string]string)
+	for _, v := range collection.Variable {
+		if v.Value != nil {
+			vars[v.Key] = fmt.Sprint(v.Value)
+		}
+	}
+	for k, v := range opts.vars {
+		vars[k] = v
+	}
+
+	var requests []importedRequest
+	var walk func(items []postmanItem, path string, auth *postmanAuth)
+	walk = func(items []postmanItem, path string, auth *postmanAuth) {
+		for _, item := range items {
+			itemPath := strings.TrimPrefix(path+" / "+item.Name, " / ")
+			itemAuth := auth
+			if item.Auth != nil {
+				itemAuth = item.Auth
+			}
+
+			if item.Request == nil {
+				walk(item.Item, itemPath, itemAuth)
+				continue
+			}
+
+			if item.Request.Auth != nil {
+				itemAuth = item.Request.Auth
+			}
+			req, err := postmanToRequest(*item.Request, itemAuth, vars)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Skipping %q: %s\n", itemPath, err)
+				continue
+			}
+			requests = append(requests, req)
+		}
+	}
+	walk(collection.Item, "", collection.Auth)
+
+	// Apply --filter and --limit on the resolved URLs
+	var kept []importedRequest
+	for _, req := range requests {
+		if opts.filter != nil && !opts.filter.MatchString(req.URL) {
+			continue
+		}
+		kept = append(kept, req)
+		if opts.limit > 0 && len(kept) == opts.limit {
+			break
+		}
+	}
+	if len(kept) == 0 {
+		return nil, fmt.Errorf("no checkable requests found in Postman collection %s", source)
+	}
+
+	name := opts.name
+	if name == "" {
+		name = targetKey("", collection.Info.Name)
+	}
+	return targetsFromRequests(kept, name)
+}
+
+// postmanToRequest resolves variables, headers, auth, and the body for a single Postman request
+func postmanToRequest(r postmanRequest, auth *postmanAuth, vars map[string]string) (importedRequest, error) {
+	var unresolved string
+	expand := func(s string) string {
+		return postmanVariable.ReplaceAllStringFunc(s, func(m string) string {
+			name := postmanVariable.FindStringSubmatch(m)[1]
+			if v, ok := vars[name]; ok {
+				return v
+			}
+			unresolved = name
+			return m
+		})
+	}
+
+	rawURL := expand(r.URL.Raw)
+	headers := make(map[string]string)
+	for _, h := range r.Header {
+		if !h.Disabled {
+			headers[h.Key] = expand(fmt.Sprint(h.Value))
+		}
+	}
+
+	if auth != nil {
+		switch auth.Type {
+		case "bearer":
+			headers["Authorization"] = "Bearer " + expand(postmanLookup(auth.Bearer, "token"))
+		case "basic":
+			credentials := expand(postmanLookup(auth.Basic, "username")) + ":" + expand(postmanLookup(auth.Basic, "password"))
+			headers["Authorization"] = "Basic " + base64.StdEncoding.EncodeToString([]byte(credentials))
+		case "apikey":
+			key := expand(postmanLookup(auth.APIKey, "key"))
+			value := expand(postmanLookup(auth.APIKey, "value"))
+			if postmanLookup(auth.APIKey, "in") == "query" {
+				u, err := url.Parse(rawURL)
+				if err != nil {
+					return importedRequest{}, fmt.Errorf("invalid URL %q", rawURL)
+				}
+				q := u.Query()
+				q.Set(key, value)
+				u.RawQuery = q.Encode()
+				rawURL = u.String()
+			} else {
+				headers[key] = value
+			}
+		case "", "noauth":
+		default:
+			return importedRequest{}, fmt.Errorf("unsupported auth type %s", auth.Type)
+		}
+	}
+
+	imported := importedRequest{URL: rawURL, Method: r.Method}
+	if r.Body != nil {
+		switch r.Body.Mode {
+		case "raw":
+			imported.Body = expand(r.Body.Raw)
+			if r.Body.Options.Raw.Language == "xml" {
+				imported.ContentType = "application/xml"
+			}
+		case "urlencoded":
+			form := url.Values{}
+			for _, field := range r.Body.URLEncoded {
+				if !field.Disabled {
+					form.Add(expand(field.Key), expand(fmt.Sprint(field.Value)))
+				}
+			}
+			imported.Body = form.Encode()
+		case "", "none":
+		default:
+			return importedRequest{}, fmt.Errorf("unsupported body mode %s", r.Body.Mode)
+		}
+	}
+
+	if unresolved != "" {
+		return importedRequest{}, fmt.Errorf("undefined variable {{%s}}, set it with --var %s=value", unresolved, unresolved)
+	}
+	if len(headers) > 0 {
+		imported.Headers = headers
+	}
+	return imported, nil
+}
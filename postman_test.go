@@ -0,0 +1,71 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const testPostmanCollection = `{
+  "info": {"name": "Shop API"},
+  "variable": [{"key": "baseUrl", "value": "https://shop.example.com"}],
+  "auth": {"type": "bearer", "bearer": [{"key": "token", "value": "{{token}}"}]},
+  "item": [
+    {
+      "name": "Catalog",
+      "item": [
+        {"name": "List products", "request": {"method": "GET", "url": {"raw": "{{baseUrl}}/products?page=1"}}},
+        {"name": "Create product", "request": {"method": "POST", "url": "{{baseUrl}}/products", "body": {"mode": "raw", "raw": "{\"sku\": \"{{sku}}\"}", "options": {"raw": {"language": "json"}}}}},
+        {"name": "Search", "request": {"method": "POST", "url": "{{baseUrl}}/search", "body": {"mode": "urlencoded", "urlencoded": [{"key": "q", "value": "shoes"}, {"key": "debug", "value": "1", "disabled": true}]}}},
+        {"name": "Upload", "request": {"method": "PUT", "url": "{{baseUrl}}/images", "body": {"mode": "file"}}}
+      ]
+    },
+    {
+      "name": "Status",
+      "auth": {"type": "noauth"},
+      "request": {
+        "method": "GET",
+        "header": [{"key": "Accept", "value": "application/json"}, {"key": "X-Debug", "value": "1", "disabled": true}],
+        "url": "{{baseUrl}}/status"
+      }
+    }
+  ]
+}`
+
+func TestImportPostman(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "collection.json")
+	if err := os.WriteFile(path, []byte(testPostmanCollection), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Requests referencing the undefined {{token}} are skipped
+	targets, err := importPostman(path, importOptions{})
+	if err != nil || len(targets) != 1 {
+		t.Fatalf("importPostman() without token = %v, %v, want only the unauthenticated target", targets, err)
+	}
+
+	targets, err = importPostman(path, importOptions{vars: map[string]string{"token": "secret", "sku": "A-1"}})
+	if err != nil {
+		t.Fatalf("importPostman() error = %v", err)
+	}
+	if len(targets) != 2 {
+		t.Fatalf("expected 2 targets, got %v", targets)
+	}
+
+	authed := targets["Shop_API"]
+	// The upload is skipped for its file body
+	want := []EndpointConfig{
+		{Path: "/products?page=1"},
+		{Path: "/products", Method: "POST", Body: `{"sku": "A-1"}`},
+		{Path: "/search", Method: "POST", Body: "q=shoes"},
+	}
+	if authed.Headers["Authorization"] != "Bearer secret" || !reflect.DeepEqual(authed.Endpoints, want) {
+		t.Errorf("unexpected authenticated target: %+v", authed)
+	}
+
+	status := targets["Shop_API_2"]
+	if status.Headers["Accept"] != "application/json" || status.Headers["X-Debug"] != "" || status.Headers["Authorization"] != "" {
+		t.Errorf("unexpected status target headers: %v", status.Headers)
+	}
+}
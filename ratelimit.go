@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// isRateLimited reports whether a response asks the client to back off: any 429, or a 503
+// that says when to come back
+func isRateLimited(resp *http.Response) bool {
+	return resp.StatusCode == http.StatusTooManyRequests ||
+		(resp.StatusCode == http.StatusServiceUnavailable && resp.Header.Get("Retry-After") != "")
+}
+
+// retryAfter parses a Retry-After header given either as delay seconds or as an HTTP date
+func retryAfter(header string, now time.Time) (time.Duration, bool) {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(header); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	at, err := http.ParseTime(header)
+	if err != nil {
+		return 0, false
+	}
+	return max(at.Sub(now), 0), true
+}
+
+// rateLimitReason describes a rate limited response, including when it may be retried
+func rateLimitReason(resp *http.Response) (string, time.Duration) {
+	wait, ok := retryAfter(resp.Header.Get("Retry-After"), time.Now())
+	if !ok {
+		return "rate limited", 0
+	}
+	return fmt.Sprintf("rate limited, retry after %s", wait.Round(time.Second)), wait
+}
+
+// sendRespectingRetryAfter sends req, and when the response is rate limited with a Retry-After
+// of at most limit, waits that long and sends it once more. It returns when the answered
+// attempt started so only that attempt counts towards the duration
+func sendRespectingRetryAfter(client *http.Client, req *http.Request, limit time.Duration) (*http.Response, time.Time, error) {
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil || limit <= 0 || !isRateLimited(resp) {
+		return resp, start, err
+	}
+
+	wait, ok := retryAfter(resp.Header.Get("Retry-After"), time.Now())
+	if !ok || wait > limit {
+		return resp, start, nil
+	}
+
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return resp, start, nil
+		}
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, bodyDrainLimit))
+	resp.Body.Close()
+
+	select {
+	case <-time.After(wait):
+	case <-req.Context().Done():
+		return nil, start, req.Context().Err()
+	}
+	start = time.Now()
+	resp, err = client.Do(retry)
+	return resp, start, err
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		header string
+		want   time.Duration
+		wantOK bool
+	}{
+		{"", 0, false},
+		{"30", 30 * time.Second, true},
+		{"-1", 0, false},
+		{"Thu, 01 Jan 2026 12:00:45 GMT", 45 * time.Second, true},
+		{"Thu, 01 Jan 2026 11:00:00 GMT", 0, true},
+		{"soon", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := retryAfter(tt.header, now)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("retryAfter(%q) = %s, %v, want %s, %v", tt.header, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestCheckEndpointRateLimited(t *testing.T) {
+	tests := []struct {
+		name            string
+		status          int
+		retryAfter      string
+		limit           string
+		throttled       int32
+		wantRequests    int32
+		wantSuccess     bool
+		wantRateLimited bool
+		wantReason      string
+	}{
+		{"429 without retry", 429, "1", "", 1, 1, false, true, "rate limited, retry after 1s"},
+		{"429 waited out", 429, "0", "5s", 1, 2, true, false, ""},
+		{"Retry-After over the limit", 429, "60", "5s", 1, 1, false, true, "rate limited, retry after 1m0s"},
+		{"still throttled after retry", 429, "0", "5s", 2, 2, false, true, "rate limited, retry after 0s"},
+		{"503 with Retry-After", 503, "120", "", 1, 1, false, true, "rate limited, retry after 2m0s"},
+		{"429 without Retry-After", 429, "", "5s", 1, 1, false, true, "rate limited"},
+		{"503 without Retry-After is downtime", 503, "", "", 1, 1, false, false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if requests.Add(1) <= tt.throttled {
+					if tt.retryAfter != "" {
+						w.Header().Set("Retry-After", tt.retryAfter)
+					}
+					w.WriteHeader(tt.status)
+				}
+			}))
+			defer server.Close()
+
+			target := TargetConfig{StatusCodes: []int{200}, RetryAfterLimit: tt.limit}
+			result := checkEndpoint(server.Client(), server.URL, "/", target, buildResponseChecks(target), nil, false)
+			if requests.Load() != tt.wantRequests {
+				t.Errorf("requests = %d, want %d", requests.Load(), tt.wantRequests)
+			}
+			if result.Success != tt.wantSuccess || result.RateLimited != tt.wantRateLimited || result.FailureReason != tt.wantReason {
+				t.Errorf("Success = %v, RateLimited = %v, FailureReason = %q, want %v, %v, %q",
+					result.Success, result.RateLimited, result.FailureReason, tt.wantSuccess, tt.wantRateLimited, tt.wantReason)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// redirectStatuses are accepted by default for targets that expect a redirect
+var redirectStatuses = []int{
+	http.StatusMovedPermanently,
+	http.StatusFound,
+	http.StatusSeeOther,
+	http.StatusTemporaryRedirect,
+	http.StatusPermanentRedirect,
+}
+
+// withoutRedirects returns a copy of client that hands back redirects instead of following them
+func withoutRedirects(client *http.Client) *http.Client {
+	noFollow := *client
+	noFollow.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	return &noFollow
+}
+
+// followsRedirects reports whether checks of target follow redirects, which they do unless
+// follow_redirects is false or the target expects a redirect
+func followsRedirects(target TargetConfig) bool {
+	return target.ExpectRedirectTo == "" && (target.FollowRedirects == nil || *target.FollowRedirects)
+}
+
+// withMaxRedirects returns a copy of client that gives up after following max redirects
+func withMaxRedirects(client *http.Client, max int) *http.Client {
+	limited := *client
+	limited.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) > max {
+			return fmt.Errorf("stopped after %d redirects, over max_redirects", max)
+		}
+		return nil
+	}
+	return &limited
+}
+
+// unexpectedRedirect says where a redirect response with an unacceptable status leads, or
+// returns an empty string for other responses
+func unexpectedRedirect(resp *http.Response) string {
+	if resp.StatusCode < 300 || resp.StatusCode >= 400 {
+		return ""
+	}
+	location, err := resp.Location()
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("redirected to %s", location)
+}
+
+// redirectMatches compares a redirect destination against an expected URL in which * matches
+// anything but a slash, and a trailing * matches the rest of the URL. Keeping wildcards within
+// one segment stops "https://*.example.com/" from matching "https://evil.test/?.example.com/"
+func redirectMatches(expected, location string) bool {
+	if !strings.Contains(expected, "*") {
+		return location == expected
+	}
+	rest, trailing := strings.CutSuffix(expected, "*")
+	parts := strings.Split(rest, "*")
+	for i := range parts {
+		parts[i] = regexp.QuoteMeta(parts[i])
+	}
+	pattern := "^" + strings.Join(parts, "[^/]*")
+	if trailing {
+		pattern += ".*"
+	}
+	return regexp.MustCompile(pattern + "$").MatchString(location)
+}
+
+// checkRedirect checks the Location of a redirect response against the expected URL, both
+// resolved against the request URL, and returns the reason it does not match or an empty string
+func checkRedirect(resp *http.Response, expected string) string {
+	if expected == "" {
+		return ""
+	}
+	location, err := resp.Location()
+	if err != nil {
+		return fmt.Sprintf("no redirect, expected redirect to %s", expected)
+	}
+	pattern := expected
+	if resp.Request != nil {
+		if resolved, err := resp.Request.URL.Parse(expected); err == nil {
+			pattern = resolved.String()
+		}
+	}
+	if !redirectMatches(pattern, location.String()) {
+		return fmt.Sprintf("redirected to %s, expected %s", location, expected)
+	}
+	return ""
+}
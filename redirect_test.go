@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestRedirectMatches(t *testing.T) {
+	tests := []struct {
+		expected, location string
+		want               bool
+	}{
+		{"https://example.com/", "https://example.com/", true},
+		{"https://example.com/", "https://example.com/login", false},
+		{"https://example.com/*", "https://example.com/login?next=%2F", true},
+		{"https://*.example.com/", "https://www.example.com/", true},
+		{"https://*.example.com/", "https://evil.test/?.example.com/", false},
+		{"https://example.com/a.b", "https://example.com/aXb", false},
+		{"https://example.com/*/edit", "https://example.com/docs/1/edit", false},
+		{"https://example.com/*/edit", "https://example.com/docs/edit", true},
+	}
+	for _, tt := range tests {
+		if got := redirectMatches(tt.expected, tt.location); got != tt.want {
+			t.Errorf("redirectMatches(%q, %q) = %v, want %v", tt.expected, tt.location, got, tt.want)
+		}
+	}
+}
+
+func TestCheckEndpointExpectRedirect(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/old":
+			http.Redirect(w, r, "/new", http.StatusMovedPermanently)
+		case "/elsewhere":
+			http.Redirect(w, r, "https://other.example.com/", http.StatusFound)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		endpoint    string
+		expected    string
+		wantStatus  int
+		wantSuccess bool
+		wantReason  string
+	}{
+		{"relative location resolved", "/old", server.URL + "/new", 301, true, ""},
+		{"pattern", "/old", server.URL + "/*", 301, true, ""},
+		{"wrong destination", "/elsewhere", server.URL + "/*", 302, false, "redirected to https://other.example.com/, expected " + server.URL + "/*"},
+		{"no redirect", "/new", server.URL + "/", 200, false, ""},
+		{"relative expected", "/old", "/new", 301, true, ""},
+		{"relative pattern", "/old", "/n*", 301, true, ""},
+		{"relative wrong destination", "/elsewhere", "/*", 302, false, "redirected to https://other.example.com/, expected /*"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := TargetConfig{StatusCodes: redirectStatuses, ExpectRedirectTo: tt.expected}
+			result := checkEndpoint(server.Client(), server.URL, tt.endpoint, target, ResponseChecks{}, nil, false)
+			if result.Error != nil {
+				t.Fatal(result.Error)
+			}
+			if result.StatusCode != tt.wantStatus || result.FailureReason != tt.wantReason || result.Success != tt.wantSuccess {
+				t.Errorf("status %d, success %v, reason %q, want %d, %v, %q", result.StatusCode, result.Success, result.FailureReason, tt.wantStatus, tt.wantSuccess, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestCheckEndpointFollowRedirects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/decommissioned":
+			http.Redirect(w, r, "/landing", http.StatusMovedPermanently)
+		case "/chain/1", "/chain/2", "/chain/3":
+			next, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/chain/"))
+			http.Redirect(w, r, fmt.Sprintf("/chain/%d", next+1), http.StatusFound)
+		}
+	}))
+	defer server.Close()
+
+	follow := func(b bool) *bool { return &b }
+	tests := []struct {
+		name        string
+		endpoint    string
+		target      TargetConfig
+		wantStatus  int
+		wantSuccess bool
+		wantReason  string
+		wantErr     string
+	}{
+		{"followed by default", "/decommissioned", TargetConfig{}, 200, true, "", ""},
+		{"not followed", "/decommissioned", TargetConfig{FollowRedirects: follow(false)}, 301, false, "redirected to " + server.URL + "/landing", ""},
+		{"within max_redirects", "/chain/1", TargetConfig{MaxRedirects: 3}, 200, true, "", ""},
+		{"over max_redirects", "/chain/1", TargetConfig{MaxRedirects: 2}, 0, false, "", "stopped after 2 redirects, over max_redirects"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.target.StatusCodes = []int{200}
+			result := checkEndpoint(server.Client(), server.URL, tt.endpoint, tt.target, ResponseChecks{}, nil, false)
+			if tt.wantErr != "" {
+				if result.Error == nil || !strings.HasSuffix(result.Error.Error(), tt.wantErr) {
+					t.Errorf("error = %v, want %s", result.Error, tt.wantErr)
+				}
+				return
+			}
+			if result.Error != nil {
+				t.Fatal(result.Error)
+			}
+			if result.StatusCode != tt.wantStatus || result.FailureReason != tt.wantReason || result.Success != tt.wantSuccess {
+				t.Errorf("status %d, success %v, reason %q, want %d, %v, %q", result.StatusCode, result.Success, result.FailureReason, tt.wantStatus, tt.wantSuccess, tt.wantReason)
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// redisTimeout bounds connecting to Redis and running one batch of commands
+const redisTimeout = 5 * time.Second
+
+// RedisConfig writes the latest result of each endpoint to Redis keys and publishes state
+// changes on a channel
+type RedisConfig struct {
+	URL       string `toml:"url,omitempty"`
+	KeyPrefix string `toml:"key_prefix,omitempty"`
+	TTL       string `toml:"ttl,omitempty"`
+	Channel   string `toml:"channel,omitempty"`
+}
+
+// redisSink speaks RESP directly, pipelining every command of a batch
+type redisSink struct {
+	config RedisConfig
+	ttl    time.Duration
+	conn   net.Conn
+	r      *bufio.Reader
+	w      *bufio.Writer
+
+	// pending counts commands whose replies have not been read yet
+	pending int
+}
+
+// newRedisSink connects to Redis, authenticating and selecting the database from the URL
+func newRedisSink(config RedisConfig) (*redisSink, error) {
+	if config.URL == "" {
+		config.URL = "redis://127.0.0.1:6379"
+	}
+	if config.KeyPrefix == "" {
+		config.KeyPrefix = "vitals:"
+	}
+	if config.Channel == "" {
+		config.Channel = "vitals:changes"
+	}
+
+	sink := &redisSink{config: config}
+	if config.TTL != "" {
+		ttl, err := parseDurationOrSeconds(config.TTL)
+		if err != nil || ttl < time.Second {
+			return nil, fmt.Errorf("invalid redis ttl %q", config.TTL)
+		}
+		sink.ttl = ttl
+	}
+
+	u, err := url.Parse(config.URL)
+	if err != nil {
+		return nil, sinkError("redis", err)
+	}
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "6379")
+	}
+
+	dialer := &net.Dialer{Timeout: redisTimeout}
+	if u.Scheme == "rediss" {
+		sink.conn, err = tls.DialWithDialer(dialer, "tcp", host, &tls.Config{ServerName: u.Hostname()})
+	} else {
+		sink.conn, err = dialer.Dial("tcp", host)
+	}
+	if err != nil {
+		return nil, sinkError("redis", err)
+	}
+	sink.r = bufio.NewReader(sink.conn)
+	sink.w = bufio.NewWriter(sink.conn)
+
+	if u.User != nil {
+		if password, ok := u.User.Password(); ok {
+			if u.User.Username() != "" {
+				sink.command("AUTH", u.User.Username(), password)
+			} else {
+				sink.command("AUTH", password)
+			}
+		}
+	}
+	if db := strings.TrimPrefix(u.Path, "/"); db != "" {
+		sink.command("SELECT", db)
+	}
+	if err := sink.flush(); err != nil {
+		sink.Close()
+		return nil, err
+	}
+	return sink, nil
+}
+
+// command queues a command as a RESP array of bulk strings
+func (s *redisSink) command(args ...string) {
+	fmt.Fprintf(s.w, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(s.w, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	s.pending++
+}
+
+// flush sends queued commands and reads their replies, returning the first error reply
+func (s *redisSink) flush() error {
+	s.conn.SetDeadline(time.Now().Add(redisTimeout))
+	if err := s.w.Flush(); err != nil {
+		return sinkError("redis", err)
+	}
+
+	var firstErr error
+	for ; s.pending > 0; s.pending-- {
+		if err := s.readReply(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// readReply reads one reply, which for the commands used here is a status, error, or integer
+func (s *redisSink) readReply() error {
+	line, err := s.r.ReadString('\n')
+	if err != nil {
+		return sinkError("redis", err)
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if len(line) == 0 {
+		return sinkError("redis", fmt.Errorf("empty reply"))
+	}
+
+	switch line[0] {
+	case '+', ':':
+		return nil
+	case '-':
+		return sinkError("redis", fmt.Errorf("%s", line[1:]))
+	case '$':
+		// Skip a bulk string reply, though none of the commands used return one
+		n, _ := strconv.Atoi(line[1:])
+		if n >= 0 {
+			if _, err := s.r.Discard(n + 2); err != nil {
+				return sinkError("redis", err)
+			}
+		}
+		return nil
+	}
+	return sinkError("redis", fmt.Errorf("unexpected reply %q", line))
+}
+
+// resultKey is the key holding the latest result of an endpoint
+func (s *redisSink) resultKey(result ResultMessage) string {
+	return fmt.Sprintf("%s%s::%s:%s %s", s.config.KeyPrefix, result.ConfigFile, result.Target, result.Method, result.URL)
+}
+
+// Publish stores every result under its endpoint key and publishes each state change
+func (s *redisSink) Publish(batch SinkBatch) error {
+	for _, result := range batch.Results {
+		data, err := json.Marshal(result)
+		if err != nil {
+			return sinkError("redis", err)
+		}
+		if s.ttl > 0 {
+			s.command("SET", s.resultKey(result), string(data), "EX", strconv.Itoa(int(s.ttl.Seconds())))
+		} else {
+			s.command("SET", s.resultKey(result), string(data))
+		}
+	}
+	for _, change := range batch.Changes {
+		data, err := json.Marshal(change)
+		if err != nil {
+			return sinkError("redis", err)
+		}
+		s.command("PUBLISH", s.config.Channel, string(data))
+	}
+	return s.flush()
+}
+
+// Close closes the connection
+func (s *redisSink) Close() error {
+	return s.conn.Close()
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// fakeRedis accepts one client, answering every command and recording it
+func fakeRedis(t *testing.T) (string, <-chan [][]string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	received := make(chan [][]string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var commands [][]string
+		r := bufio.NewReader(conn)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				received <- commands
+				return
+			}
+			n, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+			args := make([]string, n)
+			for i := range args {
+				header, _ := r.ReadString('\n')
+				size, _ := strconv.Atoi(strings.TrimSpace(header[1:]))
+				buf := make([]byte, size+2)
+				io.ReadFull(r, buf)
+				args[i] = string(buf[:size])
+			}
+			commands = append(commands, args)
+
+			switch args[0] {
+			case "PUBLISH":
+				fmt.Fprintf(conn, ":1\r\n")
+			case "AUTH":
+				if args[len(args)-1] != "secret" {
+					fmt.Fprintf(conn, "-WRONGPASS invalid password\r\n")
+					continue
+				}
+				fmt.Fprintf(conn, "+OK\r\n")
+			default:
+				fmt.Fprintf(conn, "+OK\r\n")
+			}
+		}
+	}()
+	return ln.Addr().String(), received
+}
+
+func TestRedisSinkPublish(t *testing.T) {
+	addr, received := fakeRedis(t)
+
+	sink, err := newRedisSink(RedisConfig{URL: "redis://:secret@" + addr + "/2", TTL: "10m"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = sink.Publish(SinkBatch{
+		Results: []ResultMessage{{Target: "api", ConfigFile: "a.toml", JSONResult: JSONResult{Method: "GET", URL: "https://api.example.com/health"}}},
+		Changes: []StateChange{{Target: "api", From: "up", To: "down"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink.Close()
+
+	commands := <-received
+	if len(commands) != 4 {
+		t.Fatalf("got %d commands: %v", len(commands), commands)
+	}
+	if strings.Join(commands[0], " ") != "AUTH secret" || strings.Join(commands[1], " ") != "SELECT 2" {
+		t.Errorf("setup commands = %v", commands[:2])
+	}
+	set := commands[2]
+	if set[0] != "SET" || set[1] != "vitals:a.toml::api:GET https://api.example.com/health" || set[3] != "EX" || set[4] != "600" {
+		t.Errorf("SET = %v", set)
+	}
+	if commands[3][0] != "PUBLISH" || commands[3][1] != "vitals:changes" {
+		t.Errorf("PUBLISH = %v", commands[3])
+	}
+}
+
+func TestRedisSinkAuthError(t *testing.T) {
+	addr, _ := fakeRedis(t)
+	if _, err := newRedisSink(RedisConfig{URL: "redis://:wrong@" + addr}); err == nil || !strings.Contains(err.Error(), "WRONGPASS") {
+		t.Errorf("expected the AUTH error, got %v", err)
+	}
+}
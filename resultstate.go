@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// ResultState is the outcome of an endpoint check
+type ResultState string
+
+// Result states. Only failed and error checks fail a run; flaky ones passed on a retry,
+// skipped ones were never sent, maintenance ones failed during a maintenance window, and
+// rate limited ones failed because the server throttled them
+const (
+	StatePassed      ResultState = "passed"
+	StateFailed      ResultState = "failed"
+	StateError       ResultState = "error"
+	StateSkipped     ResultState = "skipped"
+	StateMaintenance ResultState = "maintenance"
+	StateFlaky       ResultState = "flaky"
+	StateRateLimited ResultState = "rate_limited"
+)
+
+// failing reports whether the state fails the run
+func (s ResultState) failing() bool {
+	return s == StateFailed || s == StateError
+}
+
+// passing reports whether the endpoint answered its checks, possibly after a retry
+func (s ResultState) passing() bool {
+	return s == StatePassed || s == StateFlaky
+}
+
+// State derives the outcome of a check from its result
+func (r EndpointResult) State() ResultState {
+	answered := r.Error == nil && r.Success
+	switch {
+	case r.Skipped:
+		return StateSkipped
+	case r.Maintenance && !answered:
+		return StateMaintenance
+	case r.RateLimited && !answered:
+		return StateRateLimited
+	case r.Error != nil:
+		return StateError
+	case !r.Success:
+		return StateFailed
+	case r.Attempts > 1:
+		return StateFlaky
+	default:
+		return StatePassed
+	}
+}
+
+// inMaintenance reports whether the target's maintenance window is still open at now
+func (t TargetConfig) inMaintenance(now time.Time) bool {
+	return now.Before(t.MaintenanceUntil)
+}
+
+// checkWithRetries checks an endpoint, repeating a failed check up to the target's retries
+// right away. Rate limited checks aren't repeated, as they already waited out Retry-After
+func checkWithRetries(check func(attempt int) EndpointResult, retries int) EndpointResult {
+	var result EndpointResult
+	for attempt := 1; attempt <= retries+1; attempt++ {
+		result = check(attempt)
+		result.Attempts = attempt
+		if !resultFailed(result) || result.RateLimited {
+			break
+		}
+	}
+	return result
+}
+
+// stateCounts tallies the results that are neither passed nor failing, for summaries
+type stateCounts struct {
+	Skipped     int `json:"skipped,omitempty"`
+	Maintenance int `json:"maintenance,omitempty"`
+	Flaky       int `json:"flaky,omitempty"`
+	RateLimited int `json:"rate_limited,omitempty"`
+}
+
+// add counts a result's state
+func (c *stateCounts) add(state ResultState) {
+	switch state {
+	case StateSkipped:
+		c.Skipped++
+	case StateMaintenance:
+		c.Maintenance++
+	case StateFlaky:
+		c.Flaky++
+	case StateRateLimited:
+		c.RateLimited++
+	}
+}
+
+// String lists the non-zero counts, e.g. ", Flaky: 1, Skipped: 2"
+func (c stateCounts) String() string {
+	var s string
+	if c.Flaky > 0 {
+		s += fmt.Sprintf(", Flaky: %d", c.Flaky)
+	}
+	if c.Skipped > 0 {
+		s += fmt.Sprintf(", Skipped: %d", c.Skipped)
+	}
+	if c.Maintenance > 0 {
+		s += fmt.Sprintf(", Maintenance: %d", c.Maintenance)
+	}
+	if c.RateLimited > 0 {
+		s += fmt.Sprintf(", Rate limited: %d", c.RateLimited)
+	}
+	return s
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestResultState(t *testing.T) {
+	tests := []struct {
+		name   string
+		result EndpointResult
+		want   ResultState
+	}{
+		{"passed", EndpointResult{Success: true, Attempts: 1}, StatePassed},
+		{"failed", EndpointResult{StatusCode: 500}, StateFailed},
+		{"error", EndpointResult{Error: errors.New("refused")}, StateError},
+		{"skipped", EndpointResult{Skipped: true, Maintenance: true}, StateSkipped},
+		{"flaky", EndpointResult{Success: true, Attempts: 2}, StateFlaky},
+		{"failed in maintenance", EndpointResult{StatusCode: 503, Maintenance: true}, StateMaintenance},
+		{"passed in maintenance", EndpointResult{Success: true, Maintenance: true}, StatePassed},
+		{"rate limited", EndpointResult{StatusCode: 429, RateLimited: true}, StateRateLimited},
+		{"rate limited in maintenance", EndpointResult{StatusCode: 429, RateLimited: true, Maintenance: true}, StateMaintenance},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.result.State(); got != tt.want {
+				t.Errorf("State() = %s, want %s", got, tt.want)
+			}
+			if got, want := resultFailed(tt.result), tt.want == StateFailed || tt.want == StateError; got != want {
+				t.Errorf("resultFailed() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestCheckWithRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		outcomes     []EndpointResult
+		retries      int
+		wantAttempts int
+		want         ResultState
+	}{
+		{"passes first time", []EndpointResult{{Success: true}}, 2, 1, StatePassed},
+		{"passes on a retry", []EndpointResult{{Error: errors.New("reset")}, {Success: true}}, 2, 2, StateFlaky},
+		{"keeps failing", []EndpointResult{{}, {}, {}}, 2, 3, StateFailed},
+		{"no retries", []EndpointResult{{}, {Success: true}}, 0, 1, StateFailed},
+		{"rate limited", []EndpointResult{{RateLimited: true}, {Success: true}}, 2, 1, StateRateLimited},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			result := checkWithRetries(func(int) EndpointResult {
+				calls++
+				return tt.outcomes[calls-1]
+			}, tt.retries)
+			if calls != tt.wantAttempts || result.Attempts != tt.wantAttempts || result.State() != tt.want {
+				t.Errorf("%d calls, attempts %d, state %s, want %d attempts and %s", calls, result.Attempts, result.State(), tt.wantAttempts, tt.want)
+			}
+		})
+	}
+}
+
+func TestInMaintenance(t *testing.T) {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	if (TargetConfig{}).inMaintenance(now) {
+		t.Error("a target without maintenance_until is in maintenance")
+	}
+	if !(TargetConfig{MaintenanceUntil: now.Add(time.Hour)}).inMaintenance(now) {
+		t.Error("an open maintenance window is not in effect")
+	}
+	if (TargetConfig{MaintenanceUntil: now.Add(-time.Hour)}).inMaintenance(now) {
+		t.Error("a past maintenance window is still in effect")
+	}
+}
+
+func TestStateCountsString(t *testing.T) {
+	var counts stateCounts
+	for _, state := range []ResultState{StatePassed, StateSkipped, StateFlaky, StateSkipped, StateFailed, StateRateLimited} {
+		counts.add(state)
+	}
+	if got := counts.String(); got != ", Flaky: 1, Skipped: 2, Rate limited: 1" {
+		t.Errorf("String() = %q", got)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// resultFailed reports whether a result is a failed check
+func resultFailed(result EndpointResult) bool {
+	return result.State().failing()
+}
+
+// jsonResultFailed reports whether a JSON result is a failed check
+func jsonResultFailed(result JSONResult) bool {
+	// Results written before result states existed only have success and error
+	if result.State == "" {
+		return result.Error != "" || !result.Success
+	}
+	return result.State.failing()
+}
+
+// limitRows keeps at most max rows (all of them when max is 0) in their original order,
+// preferring failures over passing rows, and returns how many of each were left out
+func limitRows[T any](rows []T, max int, failed func(T) bool) (shown []T, hiddenFailed, hiddenPassed int) {
+	if max <= 0 || len(rows) <= max {
+		return rows, 0, 0
+	}
+
+	var failures int
+	for _, row := range rows {
+		if failed(row) {
+			failures++
+		}
+	}
+	keepFailed := min(failures, max)
+	keepPassed := max - keepFailed
+
+	shown = make([]T, 0, max)
+	for _, row := range rows {
+		switch {
+		case failed(row) && keepFailed > 0:
+			keepFailed--
+		case failed(row):
+			hiddenFailed++
+			continue
+		case keepPassed > 0:
+			keepPassed--
+		default:
+			hiddenPassed++
+			continue
+		}
+		shown = append(shown, row)
+	}
+	return shown, hiddenFailed, hiddenPassed
+}
+
+// hiddenRowsLine describes the rows left out of a table, or returns an empty string
+func hiddenRowsLine(hiddenFailed, hiddenPassed int) string {
+	var parts []string
+	if hiddenFailed > 0 {
+		parts = append(parts, fmt.Sprintf("%d more failed", hiddenFailed))
+	}
+	if hiddenPassed > 0 {
+		parts = append(parts, fmt.Sprintf("%d more succeeded", hiddenPassed))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "... and " + strings.Join(parts, ", ")
+}
+
+// limitReportRows bounds the rows of every target in an HTML report, recording what was left
+// out so the report can say so; the JSON output keeps every row
+func limitReportRows(targets map[string]JSONTargetResults, max int) map[string]JSONTargetResults {
+	if max <= 0 {
+		return targets
+	}
+	limited := make(map[string]JSONTargetResults, len(targets))
+	for key, target := range targets {
+		var hiddenFailed, hiddenPassed int
+		target.Results, hiddenFailed, hiddenPassed = limitRows(target.Results, max, jsonResultFailed)
+		target.HiddenRows = hiddenRowsLine(hiddenFailed, hiddenPassed)
+		limited[key] = target
+	}
+	return limited
+}
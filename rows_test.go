@@ -0,0 +1,64 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestLimitRows(t *testing.T) {
+	// Upper case letters are failures
+	failed := func(s string) bool { return s >= "A" && s <= "Z" }
+	rows := []string{"a", "B", "c", "d", "E", "f"}
+
+	tests := []struct {
+		name       string
+		max        int
+		want       []string
+		wantFailed int
+		wantPassed int
+	}{
+		{"no limit", 0, rows, 0, 0},
+		{"limit above row count", 10, rows, 0, 0},
+		{"failures kept first", 3, []string{"a", "B", "E"}, 0, 3},
+		{"only failures fit", 2, []string{"B", "E"}, 0, 4},
+		{"failures over the limit", 1, []string{"B"}, 1, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, hiddenFailed, hiddenPassed := limitRows(rows, tt.max, failed)
+			if !slices.Equal(got, tt.want) || hiddenFailed != tt.wantFailed || hiddenPassed != tt.wantPassed {
+				t.Errorf("limitRows() = %v, %d, %d, want %v, %d, %d", got, hiddenFailed, hiddenPassed, tt.want, tt.wantFailed, tt.wantPassed)
+			}
+		})
+	}
+}
+
+func TestHiddenRowsLine(t *testing.T) {
+	tests := []struct {
+		failed, passed int
+		want           string
+	}{
+		{0, 0, ""},
+		{0, 4950, "... and 4950 more succeeded"},
+		{3, 4950, "... and 3 more failed, 4950 more succeeded"},
+	}
+	for _, tt := range tests {
+		if got := hiddenRowsLine(tt.failed, tt.passed); got != tt.want {
+			t.Errorf("hiddenRowsLine(%d, %d) = %q, want %q", tt.failed, tt.passed, got, tt.want)
+		}
+	}
+}
+
+func TestLimitReportRows(t *testing.T) {
+	targets := map[string]JSONTargetResults{
+		"a.toml::api": {Results: []JSONResult{{Success: true}, {Error: "refused"}, {Success: true}}},
+	}
+	limited := limitReportRows(targets, 1)
+	if got := limited["a.toml::api"]; len(got.Results) != 1 || got.Results[0].Error != "refused" || got.HiddenRows != "... and 2 more succeeded" {
+		t.Errorf("limited = %+v", got)
+	}
+	if len(targets["a.toml::api"].Results) != 3 {
+		t.Error("limitReportRows should not modify the JSON results")
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"crypto/rand"
+	"fmt"
+	"os"
+	"time"
+)
+
+// RunInfo identifies a run in JSON output and HTML reports, so archived reports and streamed
+// results can be correlated and ordered
+type RunInfo struct {
+	ID       string    `json:"id"`
+	Started  time.Time `json:"started_at"`
+	Finished time.Time `json:"finished_at"`
+	Hostname string    `json:"hostname,omitempty"`
+	Version  string    `json:"vitals_version"`
+}
+
+// newRunInfo stamps a run starting at started with a new ID and this host and version
+func newRunInfo(started time.Time) RunInfo {
+	hostname, _ := os.Hostname()
+	return RunInfo{
+		ID:       newRunID(),
+		Started:  started.UTC(),
+		Hostname: hostname,
+		Version:  version,
+	}
+}
+
+// finish records when the run ended
+func (r *RunInfo) finish(at time.Time) {
+	r.Finished = at.UTC()
+}
+
+// newRunID returns a random (version 4) UUID
+func newRunID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
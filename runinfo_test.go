@@ -0,0 +1,45 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewRunID(t *testing.T) {
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	seen := make(map[string]bool)
+	for range 100 {
+		id := newRunID()
+		if !uuid.MatchString(id) {
+			t.Fatalf("newRunID() = %s, want a version 4 UUID", id)
+		}
+		if seen[id] {
+			t.Fatalf("newRunID() repeated %s", id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestRunInfoReport(t *testing.T) {
+	started := time.Date(2026, 10, 14, 9, 30, 0, 0, time.FixedZone("CEST", 2*60*60))
+	run := newRunInfo(started)
+	run.finish(started.Add(3 * time.Second))
+	if run.Version != version || run.Started.Location() != time.UTC || run.Finished.Sub(run.Started) != 3*time.Second {
+		t.Errorf("run = %+v", run)
+	}
+
+	html, err := generateHTMLResults(&run, map[string]JSONTargetResults{}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Run " + run.ID + " on "; !strings.Contains(html, want) || !strings.Contains(html, "2026-10-14 07:30:00 UTC to 2026-10-14 07:30:03 UTC") {
+		t.Errorf("report header is missing the run:\n%s", html)
+	}
+
+	batch := buildSinkBatch([]TargetRun{{TargetName: "api", RunID: run.ID, Results: []EndpointResult{{Success: true}}}}, nil, started)
+	if batch.Results[0].RunID != run.ID {
+		t.Errorf("sink message run_id = %q, want %s", batch.Results[0].RunID, run.ID)
+	}
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// runOptions are the settings that apply to every target in a run
+type runOptions struct {
+	timeout      int
+	verbose      bool
+	concurrency  int
+	auditHeaders bool
+	crawlDepth   int
+	state        *StateStore
+	cassette     *Cassette
+	dedupe       *dedupeCache
+
+	// saveBodies reads every response body, for --save-bodies to write out
+	saveBodies bool
+
+	// caFile and insecure override the ca_file and insecure_skip_verify of every target, for
+	// --ca-file and --insecure
+	caFile   string
+	insecure bool
+
+	// labels are attached to every run's results
+	labels Labels
+
+	// runID stamps the runs of one invocation, see RunInfo
+	runID string
+
+	// only restricts the run to these targets, by name or config::name key (empty means all)
+	only []string
+}
+
+// TargetRun holds the results of checking one target from one config file
+type TargetRun struct {
+	Key        string
+	TargetName string
+	ConfigName string
+	Ownership  Ownership
+	Labels     Labels
+	Notes      string
+	Results    []EndpointResult
+
+	// ApdexThreshold is the target's apdex_threshold_ms, zero when it isn't scored
+	ApdexThreshold time.Duration
+
+	// RunID is the ID of the run the results came from, passed on to sinks
+	RunID string
+}
+
+// httpClient returns the client used for a config's targets, resolving the hosts of targets
+// up front when the config enables the DNS cache
+func (o runOptions) httpClient(config Config, targets ...TargetConfig) *http.Client {
+	return o.newClient(config, nil, targets...)
+}
+
+// tlsClient returns a client of its own for a target with TLS settings, reporting false for
+// targets that can share their config's client. Settings that can't be loaded fail every
+// request of the target
+func (o runOptions) tlsClient(config Config, target TargetConfig) (*http.Client, bool) {
+	if o.caFile != "" {
+		target.CAFile = o.caFile
+	}
+	if o.insecure {
+		target.InsecureSkipVerify = true
+	}
+	tlsConfig, err := targetTLSConfig(target)
+	if err != nil {
+		client := setupHTTPClient(config.Global.Timeout, o.timeout)
+		client.Transport = failingTransport{err}
+		return client, true
+	}
+	if tlsConfig == nil {
+		return nil, false
+	}
+	return o.newClient(config, tlsConfig, target), true
+}
+
+// newClient builds a client for a config's targets, with tlsConfig when it is set
+func (o runOptions) newClient(config Config, tlsConfig *tls.Config, targets ...TargetConfig) *http.Client {
+	client := setupHTTPClient(config.Global.Timeout, o.timeout)
+	transport, cache := newTransport(config.Global)
+	if tlsConfig != nil {
+		if transport == nil {
+			transport = http.DefaultTransport.(*http.Transport).Clone()
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+	if transport != nil {
+		client.Transport = transport
+		primeDNS(cache, client, targets)
+	}
+
+	// Responses over custom TLS settings aren't shared with targets that may use others
+	if tlsConfig != nil {
+		return o.cassette.wrap(client)
+	}
+	return o.dedupe.wrap(o.cassette.wrap(client))
+}
+
+// runKey uniquely identifies a target across config files
+func runKey(configName, targetName string) string {
+	return fmt.Sprintf("%s::%s", configName, targetName)
+}
+
+// selectsTarget reports whether a target is included by the --for style filter
+func (o runOptions) selectsTarget(configName, targetName string) bool {
+	if len(o.only) == 0 {
+		return true
+	}
+	return slices.Contains(o.only, targetName) || slices.Contains(o.only, runKey(configName, targetName))
+}
+
+// runChecks checks every selected target of every config concurrently and returns the
+// results sorted by key
+func runChecks(configs []ConfigWithSource, opts runOptions) []TargetRun {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var runs []TargetRun
+
+	// Create a semaphore if concurrency is limited
+	var sem chan struct{}
+	if opts.concurrency > 0 {
+		sem = make(chan struct{}, opts.concurrency)
+	}
+
+	for _, configWithSource := range configs {
+		config := configWithSource.Config
+		configName := configWithSource.Filename
+
+		selected := make(map[string]TargetConfig)
+		for targetName, target := range config.Targets {
+			if opts.selectsTarget(configName, targetName) {
+				selected[targetName] = target
+			}
+		}
+
+		// Set up HTTP client with timeout from this config
+		client := opts.httpClient(config, slices.Collect(maps.Values(selected))...)
+
+		for targetName, target := range selected {
+			wg.Add(1)
+			go func(targetName string, target TargetConfig) {
+				defer wg.Done()
+
+				client := client
+				if tlsClient, ok := opts.tlsClient(config, target); ok {
+					client = tlsClient
+				}
+				results := runTarget(context.Background(), client, config, targetName, target, sem, opts)
+
+				mu.Lock()
+				runs = append(runs, TargetRun{
+					Key:        runKey(configName, targetName),
+					TargetName: targetName,
+					ConfigName: configName,
+					Ownership:  target.ownership(),
+					Labels:     opts.labels,
+					Notes:      target.Notes,
+					Results:    results,
+
+					ApdexThreshold: target.apdexThreshold(),
+					RunID:          opts.runID,
+				})
+				mu.Unlock()
+			}(targetName, target)
+		}
+	}
+
+	wg.Wait()
+
+	slices.SortFunc(runs, func(a, b TargetRun) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+	return runs
+}
+
+// runTarget applies config defaults to a target, resolves its base URLs, and checks every endpoint
+func runTarget(ctx context.Context, client *http.Client, config Config, targetName string, target TargetConfig, sem chan struct{}, opts runOptions) []EndpointResult {
+	target, checks := resolveTarget(config, target, opts)
+
+	// Hooks wrap discovery too, as it may need the tunnel a pre_run hook opens
+	return runTargetHooks(target.Hooks, targetName, func() []EndpointResult {
+		// Resolve discovered base URLs, reporting a failed discovery as a failed check
+		baseURLs, err := resolveBaseURLs(config.Discovery, target)
+		var discoveryErr *DiscoveryError
+		if errors.As(err, &discoveryErr) {
+			return []EndpointResult{{URL: discoveryErr.Source, Method: "GET", Error: discoveryErr.Err}}
+		}
+		target.BaseURLs = baseURLs
+
+		results := processTarget(ctx, client, target, checks, opts.state, sem, opts.verbose)
+		if opts.crawlDepth > 0 {
+			results = append(results, crawlLinks(ctx, client, target, results, opts.crawlDepth, sem, opts.verbose)...)
+		}
+		return results
+	})
+}
+
+// resolveTarget applies the config and CLI defaults to a target and parses its response checks
+func resolveTarget(config Config, target TargetConfig, opts runOptions) (TargetConfig, ResponseChecks) {
+	// The CLI flag enables every audit for targets that don't choose their own
+	if opts.auditHeaders && len(target.Audit) == 0 {
+		target.Audit = allHeaderAudits()
+	}
+
+	// Parse status ranges, body patterns, and header audits
+	checks := buildResponseChecks(target)
+	checks.SkipBody = !opts.verbose && opts.crawlDepth == 0 && !opts.saveBodies && bodyUnused(target, checks)
+
+	target.UserAgent = resolveUserAgent(config.Global.UserAgent, target.UserAgent)
+	target.Headers = mergeHeaders(config.Global.Headers, target.Headers)
+	target.TraceHeaders = target.TraceHeaders || config.Global.TraceHeaders
+
+	// Default to 200 if no status codes or ranges specified, 200/204 for CORS preflights, or
+	// any redirect status when a redirect is expected
+	if len(target.StatusCodes) == 0 && len(checks.StatusRanges) == 0 {
+		target.StatusCodes = []int{200}
+		if target.CORS != nil {
+			target.StatusCodes = []int{200, 204}
+		}
+		if target.ExpectRedirectTo != "" {
+			target.StatusCodes = redirectStatuses
+		}
+	}
+	return target, checks
+}
+
+// runsSucceeded reports whether every check in every run passed
+func runsSucceeded(runs []TargetRun) bool {
+	for _, run := range runs {
+		for _, result := range run.Results {
+			if resultFailed(result) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// failedChecks counts the endpoint checks that errored or failed their assertions
+func failedChecks(runs []TargetRun) int {
+	var failed int
+	for _, run := range runs {
+		for _, result := range run.Results {
+			if resultFailed(result) {
+				failed++
+			}
+		}
+	}
+	return failed
+}
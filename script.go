@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+	"github.com/yuin/gopher-lua/parse"
+)
+
+// scriptTimeout bounds how long a validation script may run for one response
+const scriptTimeout = 5 * time.Second
+
+// Script is a compiled Lua validation script. It must define a global function
+//
+//	function check(response) return ok, message end
+//
+// where response has status, headers (lowercase names), body, duration_ms, the dns_ms,
+// connect_ms, tls_ms, and ttfb_ms phases of it, url, and method.
+// Only the base, string, table, and math libraries are available
+type Script struct {
+	path  string
+	proto *lua.FunctionProto
+
+	// err is why the script could not be loaded, which fails every check it validates
+	err error
+}
+
+// compileScript reads and compiles a Lua script
+func compileScript(path string) (*Script, error) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	chunk, err := parse.Parse(strings.NewReader(string(source)), path)
+	if err != nil {
+		return nil, err
+	}
+	proto, err := lua.Compile(chunk, path)
+	if err != nil {
+		return nil, err
+	}
+	return &Script{path: path, proto: proto}, nil
+}
+
+// loadScript compiles a Lua script, reporting an error but still returning a Script that
+// fails its checks, so a broken script never lets responses through unvalidated
+func loadScript(path string) *Script {
+	script, err := compileScript(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading script '%s': %s\n", path, err)
+		return &Script{path: path, err: err}
+	}
+	return script
+}
+
+// newScriptState creates a Lua state with only the sandboxed libraries loaded
+func newScriptState() *lua.LState {
+	L := lua.NewState(lua.Options{SkipOpenLibs: true})
+	for _, lib := range []struct {
+		name string
+		open lua.LGFunction
+	}{
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.StringLibName, lua.OpenString},
+		{lua.MathLibName, lua.OpenMath},
+	} {
+		L.Push(L.NewFunction(lib.open))
+		L.Push(lua.LString(lib.name))
+		L.Call(1, 0)
+	}
+	// The base library can still load code from disk
+	for _, name := range []string{"dofile", "loadfile"} {
+		L.SetGlobal(name, lua.LNil)
+	}
+	return L
+}
+
+// check runs the script's check function against a response, returning the failure reason or ""
+func (s *Script) check(resp *http.Response, body string, result EndpointResult) string {
+	if s.err != nil {
+		return fmt.Sprintf("script %s: %s", s.path, s.err)
+	}
+
+	L := newScriptState()
+	defer L.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), scriptTimeout)
+	defer cancel()
+	L.SetContext(ctx)
+
+	L.Push(L.NewFunctionFromProto(s.proto))
+	if err := L.PCall(0, lua.MultRet, nil); err != nil {
+		return fmt.Sprintf("script %s: %s", s.path, scriptError(err))
+	}
+	check, ok := L.GetGlobal("check").(*lua.LFunction)
+	if !ok {
+		return fmt.Sprintf("script %s does not define a check function", s.path)
+	}
+
+	headers := L.NewTable()
+	for name := range resp.Header {
+		headers.RawSetString(strings.ToLower(name), lua.LString(resp.Header.Get(name)))
+	}
+	response := L.NewTable()
+	response.RawSetString("status", lua.LNumber(resp.StatusCode))
+	response.RawSetString("headers", headers)
+	response.RawSetString("body", lua.LString(body))
+	response.RawSetString("duration_ms", lua.LNumber(result.Duration.Milliseconds()))
+	response.RawSetString("dns_ms", lua.LNumber(result.DNSDuration.Milliseconds()))
+	response.RawSetString("connect_ms", lua.LNumber(result.ConnectDuration.Milliseconds()))
+	response.RawSetString("tls_ms", lua.LNumber(result.TLSDuration.Milliseconds()))
+	response.RawSetString("ttfb_ms", lua.LNumber(result.TTFB.Milliseconds()))
+	response.RawSetString("url", lua.LString(result.URL))
+	response.RawSetString("method", lua.LString(result.Method))
+
+	if err := L.CallByParam(lua.P{Fn: check, NRet: 2, Protect: true}, response); err != nil {
+		return fmt.Sprintf("script %s: %s", s.path, scriptError(err))
+	}
+	passed, message := L.Get(-2), L.Get(-1)
+	if lua.LVAsBool(passed) {
+		return ""
+	}
+	if message == lua.LNil {
+		return fmt.Sprintf("script %s failed", s.path)
+	}
+	return message.String()
+}
+
+// scriptError returns a Lua error's message without the stack traceback
+func scriptError(err error) string {
+	if apiErr, ok := err.(*lua.ApiError); ok && apiErr.Object != nil {
+		return apiErr.Object.String()
+	}
+	return err.Error()
+}
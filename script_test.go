@@ -0,0 +1,99 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeScript(t *testing.T, source string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "check.lua")
+	if err := os.WriteFile(path, []byte(source), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestScriptCheck(t *testing.T) {
+	resp := &http.Response{StatusCode: 200, Header: http.Header{"X-Region": []string{"eu-west-1"}}}
+	result := EndpointResult{URL: "http://x/pay", Method: "GET", Duration: 250 * time.Millisecond, TTFB: 120 * time.Millisecond}
+
+	tests := []struct {
+		name       string
+		source     string
+		body       string
+		wantReason string
+	}{
+		{
+			name: "passes",
+			source: `function check(r)
+				return r.status == 200 and r.headers["x-region"] == "eu-west-1" and r.duration_ms == 250 and
+					r.ttfb_ms == 120 and r.dns_ms == 0
+			end`,
+			body: "ok",
+		},
+		{
+			name: "fails with message",
+			source: `function check(r)
+				if not string.find(r.body, "balance") then
+					return false, "no balance in " .. r.url
+				end
+				return true
+			end`,
+			body:       "ok",
+			wantReason: "no balance in http://x/pay",
+		},
+		{
+			name:       "fails without message",
+			source:     `function check(r) return false end`,
+			wantReason: "failed",
+		},
+		{
+			name:       "no check function",
+			source:     `x = 1`,
+			wantReason: "does not define a check function",
+		},
+		{
+			name:       "runtime error",
+			source:     `function check(r) return r.nope.field end`,
+			wantReason: "attempt to index",
+		},
+		{
+			name:       "sandboxed",
+			source:     `function check(r) return os.exit(1) end`,
+			wantReason: "with key 'exit'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script, err := compileScript(writeScript(t, tt.source))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := script.check(resp, tt.body, result)
+			if (tt.wantReason == "") != (got == "") || !strings.Contains(got, tt.wantReason) {
+				t.Errorf("check() = %q, want %q", got, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestCompileScriptErrors(t *testing.T) {
+	if _, err := compileScript(filepath.Join(t.TempDir(), "missing.lua")); err == nil {
+		t.Error("expected an error for a missing script")
+	}
+	if _, err := compileScript(writeScript(t, "function check(")); err == nil {
+		t.Error("expected an error for a syntax error")
+	}
+
+	// A script that fails to load fails its checks instead of skipping them
+	script := loadScript(writeScript(t, "function check("))
+	if got := script.check(&http.Response{StatusCode: 200}, "", EndpointResult{}); got == "" {
+		t.Error("check() passed with a broken script")
+	}
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// keyringPrefix marks a credential setting stored in the OS keychain, e.g. "keyring:api-token"
+const keyringPrefix = "keyring:"
+
+// keyringService is the service (or target prefix) vitals stores its secrets under
+const keyringService = "vitals"
+
+// secretNamePattern keeps secret names safe to pass to the keychain tools unquoted
+var secretNamePattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// secretResolverTimeout bounds a secret command, which may wait for the user to unlock it
+const secretResolverTimeout = time.Minute
+
+// SecretResolver reads secrets that a config setting refers to instead of containing
+type SecretResolver interface {
+	// Resolves reports whether value is a reference this resolver reads
+	Resolves(value string) bool
+	Resolve(value string) (string, error)
+}
+
+// SecretResolverConfig adds a resolver that runs a command for references starting with
+// Prefix, replacing {ref} in its arguments with the rest of the reference
+type SecretResolverConfig struct {
+	Prefix  string   `toml:"prefix"`
+	Command []string `toml:"command"`
+}
+
+// SecretsConfig holds the secret resolvers a config adds to the built-in ones
+type SecretsConfig struct {
+	Resolvers []SecretResolverConfig `toml:"resolvers"`
+}
+
+// keyringResolver reads keyring:NAME references from the OS keychain
+type keyringResolver struct{}
+
+// Resolves reports whether value is a keyring: reference
+func (keyringResolver) Resolves(value string) bool {
+	return strings.HasPrefix(value, keyringPrefix)
+}
+
+// Resolve reads the named secret from the keychain
+func (keyringResolver) Resolve(value string) (string, error) {
+	name := strings.TrimPrefix(value, keyringPrefix)
+	if !secretNamePattern.MatchString(name) {
+		return "", fmt.Errorf("invalid secret name %q, expected letters, digits, '.', '_', or '-'", name)
+	}
+	secret, err := keyringGet(name)
+	if err != nil {
+		return "", fmt.Errorf("error reading secret %q from the keychain: %s", name, err)
+	}
+	return secret, nil
+}
+
+// commandResolver reads secrets by running a command that prints them, e.g. the 1Password CLI
+type commandResolver SecretResolverConfig
+
+// Resolves reports whether value starts with the resolver's prefix
+func (r commandResolver) Resolves(value string) bool {
+	return strings.HasPrefix(value, r.Prefix)
+}
+
+// Resolve runs the command for a reference and returns its output without the final newline
+func (r commandResolver) Resolve(value string) (string, error) {
+	ref := strings.TrimPrefix(value, r.Prefix)
+	args := make([]string, len(r.Command))
+	for i, arg := range r.Command {
+		args[i] = strings.ReplaceAll(arg, "{ref}", ref)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), secretResolverTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = fmt.Errorf("%s: %s", err, msg)
+		}
+		return "", fmt.Errorf("error resolving secret %s with %s: %s", value, args[0], err)
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(string(out), "\n"), "\r"), nil
+}
+
+// onePasswordResolver reads op://vault/item/field references with the 1Password CLI
+var onePasswordResolver = commandResolver{Prefix: "op://", Command: []string{"op", "read", "--no-newline", "op://{ref}"}}
+
+// builtinSecretResolvers are available without any configuration
+var builtinSecretResolvers = []SecretResolver{keyringResolver{}, onePasswordResolver}
+
+var (
+	// secretResolvers are consulted in order, configured resolvers before the built-in ones
+	secretResolversMu sync.Mutex
+	secretResolvers   = builtinSecretResolvers
+
+	// secretCache remembers resolved secrets, as resolvers are slow and may prompt
+	secretCache sync.Map
+)
+
+// setSecretResolvers replaces the configured resolvers with those of configs, where later
+// resolvers take precedence over earlier ones with the same prefix
+func setSecretResolvers(configs []ConfigWithSource) error {
+	resolvers := builtinSecretResolvers
+	for _, configWithSource := range configs {
+		for _, resolver := range configWithSource.Config.Secrets.Resolvers {
+			if resolver.Prefix == "" || len(resolver.Command) == 0 {
+				return fmt.Errorf("error reading config file %s: secret resolvers need a prefix and a command", configWithSource.Filename)
+			}
+			resolvers = append([]SecretResolver{commandResolver(resolver)}, resolvers...)
+		}
+	}
+	secretResolversMu.Lock()
+	defer secretResolversMu.Unlock()
+	secretResolvers = resolvers
+	return nil
+}
+
+// clearSecretCache forgets resolved secrets, so rotated ones are read again
+func clearSecretCache() {
+	secretCache.Clear()
+}
+
+// secretResolverFor returns the resolver that reads a secret reference, or nil for values
+// that aren't references
+func secretResolverFor(value string) SecretResolver {
+	secretResolversMu.Lock()
+	defer secretResolversMu.Unlock()
+	for _, resolver := range secretResolvers {
+		if resolver.Resolves(value) {
+			return resolver
+		}
+	}
+	return nil
+}
+
+// isSecretReference reports whether a setting refers to a secret kept elsewhere
+func isSecretReference(value string) bool {
+	return secretResolverFor(value) != nil
+}
+
+// expandSecret resolves a credential setting: a secret reference such as keyring:NAME or
+// op://vault/item/field is read at run time, and anything else has its environment variables
+// expanded
+func expandSecret(value string) (string, error) {
+	resolver := secretResolverFor(value)
+	if resolver == nil {
+		return os.ExpandEnv(value), nil
+	}
+	if secret, ok := secretCache.Load(value); ok {
+		return secret.(string), nil
+	}
+	secret, err := resolver.Resolve(value)
+	if err != nil {
+		return "", err
+	}
+	secretCache.Store(value, secret)
+	return secret, nil
+}
+
+// runSecret implements `vitals secret set|delete NAME`, managing secrets in the OS keychain
+func runSecret(args []string) int {
+	if len(args) != 2 || (args[0] != "set" && args[0] != "delete") {
+		fmt.Fprintln(os.Stderr, "usage: vitals secret set|delete <name>")
+		return 2
+	}
+	action, name := args[0], args[1]
+	if !secretNamePattern.MatchString(name) {
+		fmt.Fprintf(os.Stderr, "Invalid secret name '%s': expected letters, digits, '.', '_', or '-'\n", name)
+		return 2
+	}
+
+	if action == "delete" {
+		if err := keyringDelete(name); err != nil {
+			fmt.Fprintf(os.Stderr, "Error deleting secret '%s': %s\n", name, err)
+			return 1
+		}
+		fmt.Printf("Deleted secret %s\n", name)
+		return 0
+	}
+
+	value, err := readSecretValue(os.Stdin)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading secret '%s': %s\n", name, err)
+		return 1
+	}
+	if value == "" {
+		fmt.Fprintln(os.Stderr, "Not storing an empty secret")
+		return 1
+	}
+	if err := keyringSet(name, value); err != nil {
+		fmt.Fprintf(os.Stderr, "Error storing secret '%s': %s\n", name, err)
+		return 1
+	}
+	fmt.Printf("Stored secret %s, use it in a config as \"%s%s\"\n", name, keyringPrefix, name)
+	return 0
+}
+
+// readSecretValue prompts for a secret without echoing it when stdin is a terminal, and
+// otherwise reads the first line piped in
+func readSecretValue(stdin *os.File) (string, error) {
+	if fd := int(stdin.Fd()); term.IsTerminal(fd) {
+		fmt.Fprint(os.Stderr, "Value: ")
+		value, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		return string(value), err
+	}
+	line, err := bufio.NewReader(stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExpandSecret(t *testing.T) {
+	t.Setenv("VITALS_TEST_TOKEN", "from-env")
+	secretCache.Store("keyring:cached-token", "from-keychain")
+	defer secretCache.Delete("keyring:cached-token")
+
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"plain", "plain", false},
+		{"${VITALS_TEST_TOKEN}", "from-env", false},
+		{"keyring:cached-token", "from-keychain", false},
+		{"keyring:", "", true},
+		{"keyring:has space", "", true},
+		{"keyring:../etc", "", true},
+	}
+	for _, tt := range tests {
+		got, err := expandSecret(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("expandSecret(%q) = %q, %v, want %q, error %v", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestSecretResolvers(t *testing.T) {
+	// A fake op CLI on PATH, and a config adding a resolver for another tool
+	bin := t.TempDir()
+	os.WriteFile(filepath.Join(bin, "op"), []byte("#!/bin/sh\n[ \"$3\" = op://Ops/api/token ] && printf 'from-1password' || { echo \"no item $3\" >&2; exit 1; }\n"), 0o755)
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	defer func(resolvers []SecretResolver) { secretResolvers = resolvers }(secretResolvers)
+	config := filepath.Join(t.TempDir(), "vitals.toml")
+	os.WriteFile(config, []byte(`
+[[secrets.resolvers]]
+prefix = "vault:"
+command = ["echo", "secret/{ref}"]
+`), 0o644)
+	if _, err := loadConfigFiles([]string{config}); err != nil {
+		t.Fatalf("loadConfigFiles() error: %v", err)
+	}
+
+	tests := []struct {
+		value   string
+		want    string
+		wantErr string
+	}{
+		{"op://Ops/api/token", "from-1password", ""},
+		{"op://Ops/missing/token", "", "no item op://Ops/missing/token"},
+		{"vault:db/password", "secret/db/password", ""},
+	}
+	for _, tt := range tests {
+		if !isSecretReference(tt.value) {
+			t.Errorf("isSecretReference(%q) = false", tt.value)
+		}
+		got, err := expandSecret(tt.value)
+		if got != tt.want || (err == nil) != (tt.wantErr == "") || (err != nil && !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("expandSecret(%q) = %q, %v, want %q, error %q", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+	if isSecretReference("https://example.com") {
+		t.Error("isSecretReference() matched a plain URL")
+	}
+}
+
+func TestReadSecretValue(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	w.WriteString("s3cret value\r\nignored\n")
+	w.Close()
+
+	got, err := readSecretValue(r)
+	if err != nil || got != "s3cret value" {
+		t.Errorf("readSecretValue() = %q, %v, want the first line", got, err)
+	}
+}
+
+func TestSetSecretResolvers(t *testing.T) {
+	defer func(resolvers []SecretResolver) { secretResolvers = resolvers }(secretResolvers)
+	withVault := []ConfigWithSource{{Filename: "a.toml", Config: Config{Secrets: SecretsConfig{
+		Resolvers: []SecretResolverConfig{{Prefix: "vault:", Command: []string{"echo", "{ref}"}}},
+	}}}}
+
+	// Loading the same configs again, as serve does on reload, doesn't add the resolver twice
+	for range 2 {
+		if err := setSecretResolvers(withVault); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(secretResolvers) != len(builtinSecretResolvers)+1 {
+		t.Errorf("%d resolvers after reloading, want %d", len(secretResolvers), len(builtinSecretResolvers)+1)
+	}
+
+	// A resolver removed from the configs stops resolving
+	if err := setSecretResolvers([]ConfigWithSource{{Filename: "a.toml"}}); err != nil {
+		t.Fatal(err)
+	}
+	if isSecretReference("vault:db/password") {
+		t.Error("removed resolver still resolves references")
+	}
+
+	invalid := []ConfigWithSource{{Filename: "b.toml", Config: Config{Secrets: SecretsConfig{
+		Resolvers: []SecretResolverConfig{{Prefix: "vault:"}},
+	}}}}
+	if err := setSecretResolvers(invalid); err == nil || !strings.Contains(err.Error(), "b.toml") {
+		t.Errorf("setSecretResolvers() error = %v, want one naming b.toml", err)
+	}
+}
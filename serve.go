@@ -0,0 +1,686 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// defaultServeInterval is how often targets run in serve mode when nothing else is configured
+const defaultServeInterval = time.Minute
+
+// Schedule decides when a target runs next in serve mode
+type Schedule interface {
+	Next(time.Time) time.Time
+}
+
+// intervalSchedule runs a target on fixed ticks a whole number of intervals apart, so samples
+// stay evenly spaced however long each run takes
+type intervalSchedule time.Duration
+
+func (s intervalSchedule) Next(t time.Time) time.Time {
+	return t.Truncate(time.Duration(s)).Add(time.Duration(s))
+}
+
+// parseSchedule returns a target's schedule: its cron `schedule`, else its `interval`, else
+// the global interval, else defaultServeInterval
+func parseSchedule(global GlobalConfig, target TargetConfig) (Schedule, error) {
+	if target.Schedule != "" {
+		schedule, err := cron.ParseStandard(target.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule %q: %s", target.Schedule, err)
+		}
+		return schedule, nil
+	}
+
+	interval := target.Interval
+	if interval == "" {
+		interval = global.Interval
+	}
+	if interval == "" {
+		return intervalSchedule(defaultServeInterval), nil
+	}
+
+	d, err := parseDurationOrSeconds(interval)
+	if err != nil || d <= 0 {
+		return nil, fmt.Errorf("invalid interval %q", interval)
+	}
+	return intervalSchedule(d), nil
+}
+
+// defaultReloadInterval is how often serve mode checks config files for changes
+const defaultReloadInterval = 2 * time.Second
+
+// historyPruneInterval is how often serve mode compacts history past its retention
+const historyPruneInterval = 24 * time.Hour
+
+// targetSpec is everything needed to schedule one target
+type targetSpec struct {
+	configName string
+	targetName string
+	config     Config
+	target     TargetConfig
+	schedule   Schedule
+}
+
+// sameAs reports whether two specs would run identically, so reloads can leave the target alone
+func (s targetSpec) sameAs(other targetSpec) bool {
+	return reflect.DeepEqual(s.config.Global, other.config.Global) &&
+		reflect.DeepEqual(s.config.Discovery, other.config.Discovery) &&
+		reflect.DeepEqual(s.target, other.target)
+}
+
+// scheduledTarget is a running target goroutine
+type scheduledTarget struct {
+	spec   targetSpec
+	cancel context.CancelFunc
+}
+
+// buildTargetSpecs flattens configs into specs keyed by run key, failing on any invalid schedule
+func buildTargetSpecs(configs []ConfigWithSource) (map[string]targetSpec, error) {
+	specs := make(map[string]targetSpec)
+	for _, configWithSource := range configs {
+		for targetName, target := range configWithSource.Config.Targets {
+			schedule, err := parseSchedule(configWithSource.Config.Global, target)
+			if err != nil {
+				return nil, fmt.Errorf("target %s in %s: %s", targetName, configWithSource.Filename, err)
+			}
+			specs[runKey(configWithSource.Filename, targetName)] = targetSpec{
+				configName: configWithSource.Filename,
+				targetName: targetName,
+				config:     configWithSource.Config,
+				target:     target,
+				schedule:   schedule,
+			}
+		}
+	}
+	return specs, nil
+}
+
+// diffTargets returns the sorted keys added, removed, and changed between two sets of specs
+func diffTargets(old, updated map[string]targetSpec) (added, removed, changed []string) {
+	for key, spec := range updated {
+		oldSpec, ok := old[key]
+		switch {
+		case !ok:
+			added = append(added, key)
+		case !oldSpec.sameAs(spec):
+			changed = append(changed, key)
+		}
+	}
+	for key := range old {
+		if _, ok := updated[key]; !ok {
+			removed = append(removed, key)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	sort.Strings(changed)
+	return added, removed, changed
+}
+
+// Daemon runs targets on their schedules and keeps the latest results in memory
+type Daemon struct {
+	configFiles    []string
+	stateFile      string
+	reloadInterval time.Duration
+	opts           runOptions
+	sem            chan struct{}
+
+	mu        sync.Mutex
+	latest    map[string]TargetRun
+	targets   map[string]*scheduledTarget
+	loaded    bool
+	history   HistoryConfig
+	sinks     SinksConfig
+	notifiers NotifiersConfig
+	alerts    *alertEngine
+	incidents bool
+	issues    IssuesConfig
+	wg        sync.WaitGroup
+
+	// digestWindow batches alerts into digest when set
+	digestWindow time.Duration
+	digest       alertDigest
+}
+
+// newDaemon prepares a daemon for the given config files
+func newDaemon(configFiles []string, stateFile string, opts runOptions) *Daemon {
+	d := &Daemon{
+		configFiles:    configFiles,
+		stateFile:      stateFile,
+		reloadInterval: defaultReloadInterval,
+		opts:           opts,
+		latest:         make(map[string]TargetRun),
+		targets:        make(map[string]*scheduledTarget),
+	}
+	if opts.concurrency > 0 {
+		d.sem = make(chan struct{}, opts.concurrency)
+	}
+	return d
+}
+
+// Run schedules every target and reloads changed config files until the context is cancelled
+func (d *Daemon) Run(ctx context.Context) error {
+	modTimes := configModTimes(d.configFiles)
+	configs, err := loadConfigFiles(d.configFiles)
+	if err != nil {
+		return err
+	}
+	if err := d.apply(ctx, configs); err != nil {
+		return err
+	}
+
+	d.wg.Add(2)
+	go func() {
+		defer d.wg.Done()
+		d.scheduleReports(ctx)
+	}()
+	go func() {
+		defer d.wg.Done()
+		d.scheduleDigests(ctx)
+	}()
+
+	ticker := time.NewTicker(d.reloadInterval)
+	defer ticker.Stop()
+	var lastPrune time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			d.wg.Wait()
+			return nil
+		case <-ticker.C:
+		}
+
+		// Keep the history store bounded without a separate cron job
+		if time.Since(lastPrune) >= historyPruneInterval {
+			lastPrune = time.Now()
+			d.prune()
+		}
+
+		current := configModTimes(d.configFiles)
+		if reflect.DeepEqual(current, modTimes) {
+			continue
+		}
+		modTimes = current
+
+		d.reload(ctx)
+	}
+}
+
+// reload re-reads the config files, keeping the running targets if anything is invalid
+func (d *Daemon) reload(ctx context.Context) {
+	configs, err := loadConfigFiles(d.configFiles)
+	if err == nil {
+		// Changed config files may point at rotated secrets or change form logins
+		clearSecretCache()
+		clearFormSessions()
+		err = d.apply(ctx, configs)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s Error reloading config, keeping current targets: %s\n", time.Now().Format(time.RFC3339), err)
+	}
+}
+
+// apply starts, stops, and restarts target goroutines so they match configs
+func (d *Daemon) apply(ctx context.Context, configs []ConfigWithSource) error {
+	specs, err := buildTargetSpecs(configs)
+	if err != nil {
+		return err
+	}
+
+	notifiers := notifiersConfig(configs)
+	if email := notifiers.Email; email != nil && email.ReportSchedule != "" {
+		if _, err := cron.ParseStandard(email.ReportSchedule); err != nil {
+			return fmt.Errorf("invalid report_schedule %q: %s", email.ReportSchedule, err)
+		}
+	}
+	rules, err := compileAlertRules(alertsConfig(configs), notifiers)
+	if err != nil {
+		return err
+	}
+	window, err := digestWindow(alertsConfig(configs))
+	if err != nil {
+		return err
+	}
+	issues, _ := issuesConfig(configs)
+	if _, err := issueAfter(issues); err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// Running targets read the state store through runOptions, so it is only set under d.mu
+	if needsState(configs) && d.opts.state == nil {
+		if d.opts.state, err = loadState(d.stateFile); err != nil {
+			return err
+		}
+	}
+
+	d.history, _ = historyConfig(configs)
+	d.sinks, _ = sinksConfig(configs)
+	d.notifiers = notifiers
+	// Rule state (failure streaks, latencies, firing alerts) survives reloads that keep the rules
+	if d.alerts == nil || !reflect.DeepEqual(d.alerts.rules, rules) {
+		d.alerts = newAlertEngine(rules)
+	}
+	d.digestWindow = window
+	d.incidents = incidentsEnabled(configs)
+	d.issues = issues
+
+	running := make(map[string]targetSpec, len(d.targets))
+	for key, scheduled := range d.targets {
+		running[key] = scheduled.spec
+	}
+	added, removed, changed := diffTargets(running, specs)
+
+	// The first load starts everything quietly; later loads log what changed
+	initial := !d.loaded
+	d.loaded = true
+	now := time.Now().Format(time.RFC3339)
+
+	for _, key := range removed {
+		d.targets[key].cancel()
+		delete(d.targets, key)
+		delete(d.latest, key)
+		fmt.Printf("%s [%s] target removed\n", now, key)
+	}
+	for _, key := range changed {
+		d.targets[key].cancel()
+		d.start(ctx, key, specs[key])
+		fmt.Printf("%s [%s] target changed\n", now, key)
+	}
+	for _, key := range added {
+		d.start(ctx, key, specs[key])
+		if !initial {
+			fmt.Printf("%s [%s] target added\n", now, key)
+		}
+	}
+	return nil
+}
+
+// start launches the goroutine for a target; d.mu must be held
+func (d *Daemon) start(ctx context.Context, key string, spec targetSpec) {
+	targetCtx, cancel := context.WithCancel(ctx)
+	d.targets[key] = &scheduledTarget{spec: spec, cancel: cancel}
+
+	client, ok := d.opts.tlsClient(spec.config, spec.target)
+	if !ok {
+		client = d.opts.httpClient(spec.config, spec.target)
+	}
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		d.scheduleTarget(targetCtx, client, spec)
+	}()
+}
+
+// runOptions returns the daemon's run options; a reload may add the state store, so they are
+// read under d.mu by everything but apply
+func (d *Daemon) runOptions() runOptions {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.opts
+}
+
+// scheduleTarget runs a single target immediately and then whenever its schedule fires. Ticks
+// that pass while a run is still going are skipped rather than run late
+func (d *Daemon) scheduleTarget(ctx context.Context, client *http.Client, spec targetSpec) {
+	for {
+		started := time.Now()
+		opts := d.runOptions()
+		results := runTarget(ctx, client, spec.config, spec.targetName, spec.target, d.sem, opts)
+		run := TargetRun{
+			Key:        runKey(spec.configName, spec.targetName),
+			TargetName: spec.targetName,
+			ConfigName: spec.configName,
+			Ownership:  spec.target.ownership(),
+			Labels:     opts.labels,
+			Notes:      spec.target.Notes,
+			Results:    results,
+
+			ApdexThreshold: spec.target.apdexThreshold(),
+			RunID:          newRunID(),
+		}
+
+		// A target removed or replaced mid-run must not overwrite the new state
+		if ctx.Err() != nil {
+			return
+		}
+		d.record(run)
+
+		now := time.Now()
+		next := spec.schedule.Next(now)
+		if skipped := skippedTicks(spec.schedule, started, now); skipped > 0 {
+			fmt.Fprintf(os.Stderr, "%s [%s] run took %s, skipping %d scheduled run(s)\n",
+				now.Format(time.RFC3339), run.Key, now.Sub(started).Round(time.Millisecond), skipped)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Until(next)):
+		}
+	}
+}
+
+// skippedTicks counts the ticks of a schedule that passed while a run was in progress
+func skippedTicks(schedule Schedule, started, finished time.Time) int {
+	var skipped int
+	for tick := schedule.Next(started); !tick.After(finished); tick = schedule.Next(tick) {
+		skipped++
+	}
+	return skipped
+}
+
+// prune compacts old history when a retention period is configured
+func (d *Daemon) prune() {
+	d.mu.Lock()
+	history := d.history
+	d.mu.Unlock()
+
+	if history.Retention == "" {
+		return
+	}
+	stats, err := pruneHistory(history, "")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s Error pruning history: %s\n", time.Now().Format(time.RFC3339), err)
+		return
+	}
+	if stats.Compacted > 0 {
+		fmt.Printf("%s Compacted %d results into %d daily rollups\n", time.Now().Format(time.RFC3339), stats.Compacted, stats.Rollups)
+	}
+}
+
+// scheduleReports emails the HTML report of the latest results whenever the email
+// notifier's report_schedule fires, checking for a changed schedule at every reload interval
+func (d *Daemon) scheduleReports(ctx context.Context) {
+	ticker := time.NewTicker(d.reloadInterval)
+	defer ticker.Stop()
+
+	var current string
+	var next time.Time
+	for {
+		d.mu.Lock()
+		email := d.notifiers.Email
+		d.mu.Unlock()
+
+		var schedule string
+		if email != nil {
+			schedule = email.ReportSchedule
+		}
+		if schedule != current {
+			current, next = schedule, time.Time{}
+			if parsed, err := cron.ParseStandard(schedule); err == nil {
+				next = parsed.Next(time.Now())
+			}
+		}
+
+		if !next.IsZero() && !time.Now().Before(next) {
+			d.sendReport(*email)
+			parsed, _ := cron.ParseStandard(schedule)
+			next = parsed.Next(time.Now())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// scheduleDigests sends the queued alerts once the oldest has waited for the digest window,
+// checking at every reload interval, and sends what is left when the daemon stops
+func (d *Daemon) scheduleDigests(ctx context.Context) {
+	ticker := time.NewTicker(d.reloadInterval)
+	defer ticker.Stop()
+	for {
+		var stopping bool
+		select {
+		case <-ctx.Done():
+			stopping = true
+		case <-ticker.C:
+		}
+
+		d.mu.Lock()
+		notifiers, window := d.notifiers, d.digestWindow
+		d.mu.Unlock()
+		if stopping {
+			window = 0
+		}
+		if pending := d.digest.take(window, time.Now()); pending != nil {
+			destinations := make([]string, 0, len(pending))
+			for destination := range pending {
+				destinations = append(destinations, destination)
+			}
+			sort.Strings(destinations)
+			fmt.Printf("%s Sending alert digest to %s\n", time.Now().Format(time.RFC3339), strings.Join(destinations, ", "))
+			for _, err := range sendDigest(notifiers, pending) {
+				fmt.Fprintf(os.Stderr, "%s\n", err)
+			}
+		}
+		if stopping {
+			return
+		}
+	}
+}
+
+// sendReport emails the HTML report of the latest run of every target
+func (d *Daemon) sendReport(email EmailConfig) {
+	latest := d.Latest()
+	runs := make([]TargetRun, 0, len(latest))
+	for _, run := range latest {
+		runs = append(runs, run)
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].Key < runs[j].Key })
+
+	if len(email.To) > 0 {
+		d.emailReport(email, runs)
+	}
+
+	// Each team also gets a report of just the targets it owns
+	teams := make([]string, 0, len(email.TeamTo))
+	for team := range email.TeamTo {
+		teams = append(teams, team)
+	}
+	sort.Strings(teams)
+	for _, team := range teams {
+		owned := teamRuns(runs, team)
+		if len(owned) == 0 {
+			continue
+		}
+		teamEmail := email
+		teamEmail.To = email.TeamTo[team]
+		d.emailReport(teamEmail, owned)
+	}
+}
+
+// emailReport renders runs and emails them to email.To, logging the outcome
+func (d *Daemon) emailReport(email EmailConfig, runs []TargetRun) {
+	now := time.Now().Format(time.RFC3339)
+	html, err := renderReport(runs)
+	if err == nil {
+		err = sendEmail(email, reportSubject(email, runs), html)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s Error emailing report: %s\n", now, err)
+		return
+	}
+	fmt.Printf("%s Emailed report to %s\n", now, strings.Join(email.To, ", "))
+}
+
+// configModTimes returns the modification time of every config file, used to detect edits
+func configModTimes(configFiles []string) map[string]time.Time {
+	modTimes := make(map[string]time.Time, len(configFiles))
+	for _, configFile := range configFiles {
+		if info, err := os.Stat(configFile); err == nil {
+			modTimes[configFile] = info.ModTime()
+		}
+	}
+	return modTimes
+}
+
+// targetConfig looks up the config and target of a scheduled target by key
+func (d *Daemon) targetConfig(key string) (Config, TargetConfig, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	scheduled, ok := d.targets[key]
+	if !ok {
+		return Config{}, TargetConfig{}, false
+	}
+	return scheduled.spec.config, scheduled.spec.target, true
+}
+
+// record stores the latest run of a target, persists state, and logs a summary line
+func (d *Daemon) record(run TargetRun) {
+	d.mu.Lock()
+	d.latest[run.Key] = run
+	history, sinks, incidents, issues := d.history, d.sinks, d.incidents, d.issues
+	notifiers, alerts, digesting := d.notifiers, d.alerts, d.digestWindow > 0
+	state := d.opts.state
+	d.mu.Unlock()
+
+	if history.enabled() {
+		if err := appendHistory(history, []TargetRun{run}); err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+		}
+	}
+	if sinks.enabled() {
+		for _, err := range publishToSinks(sinks, []TargetRun{run}, state) {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+		}
+	}
+
+	var opened, resolved, filed []Incident
+	var issueErrs []error
+	if incidents && state != nil {
+		opened, resolved = state.TrackIncidents([]TargetRun{run}, time.Now())
+		if issues.enabled() {
+			filed, issueErrs = fileIssues(issues, []TargetRun{run}, state, resolved, d.targetConfig, time.Now())
+		}
+	}
+
+	if state != nil {
+		if err := state.Save(); err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+		}
+	}
+
+	var passed int
+	for _, result := range run.Results {
+		if result.State().passing() {
+			passed++
+		}
+	}
+	fmt.Printf("%s [%s] %d/%d checks passing\n", time.Now().Format(time.RFC3339), run.Key, passed, len(run.Results))
+	for _, result := range run.Results {
+		if resultFailed(result) {
+			fmt.Printf("  %s %s: %s\n", result.Method, result.label(), describeRequestFailure(result))
+			if result.RunbookURL != "" {
+				fmt.Printf("    runbook: %s\n", result.RunbookURL)
+			}
+		}
+	}
+	for _, incident := range opened {
+		fmt.Printf("  incident %s opened: %s %s\n", incident.ID, incident.Method, incident.URL)
+	}
+	for _, incident := range resolved {
+		fmt.Printf("  incident %s resolved after %s: %s %s\n", incident.ID, incident.Duration(time.Now()).Round(time.Second), incident.Method, incident.URL)
+	}
+	for _, incident := range filed {
+		fmt.Printf("  issue %s filed for incident %s: %s\n", incident.Issue, incident.ID, incident.IssueURL)
+	}
+	for _, err := range issueErrs {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+	}
+
+	for _, alert := range alerts.evaluate(run, time.Now()) {
+		state := "resolved"
+		if alert.Firing {
+			state = "firing"
+		}
+		if digesting {
+			fmt.Printf("  alert %q %s, queued for the digest to %s\n", alert.Rule, state, strings.Join(alert.Notify, ", "))
+			d.digest.add(alert, time.Now())
+			continue
+		}
+		fmt.Printf("  alert %q %s, notifying %s\n", alert.Rule, state, strings.Join(alert.Notify, ", "))
+		for _, err := range sendAlert(notifiers, alert) {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+		}
+	}
+}
+
+// Latest returns a snapshot of the most recent run of every target
+func (d *Daemon) Latest() map[string]TargetRun {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	snapshot := make(map[string]TargetRun, len(d.latest))
+	for k, v := range d.latest {
+		snapshot[k] = v
+	}
+	return snapshot
+}
+
+// runServe implements `vitals serve`, running every target on its own schedule until interrupted
+// and reloading config files when they change
+func runServe(args []string) int {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	var configFiles []string
+	var opts runOptions
+	var stateFile string
+	fs.Var((*stringSlice)(&configFiles), "config", "Path to configuration file(s)")
+	fs.Var((*stringSlice)(&configFiles), "c", "Path to configuration file(s) (shorthand)")
+	fs.IntVar(&opts.timeout, "timeout", 0, "Override the global timeout in seconds")
+	fs.IntVar(&opts.timeout, "t", 0, "Override the global timeout in seconds (shorthand)")
+	fs.BoolVar(&opts.verbose, "verbose", false, "Enable verbose logging")
+	fs.BoolVar(&opts.verbose, "v", false, "Enable verbose logging (shorthand)")
+	fs.IntVar(&opts.concurrency, "concurrency", 0, "Maximum number of concurrent requests (0 means unlimited)")
+	fs.StringVar(&stateFile, "state-file", defaultStateFile, "File used to persist state between runs")
+	fs.Var(&opts.labels, "label", "Attach a key=value label to every result (repeatable)")
+	fs.StringVar(&opts.caFile, "ca-file", "", "Trust the certificate authorities of this PEM bundle on every target")
+	fs.BoolVar(&opts.insecure, "insecure", false, "Accept any server certificate on every target")
+	listen := fs.String("listen", "", "Address to serve the REST API on, e.g. :8080 (disabled when empty)")
+	fs.Parse(args)
+
+	if len(configFiles) == 0 {
+		configFiles = append(configFiles, "vitals.toml")
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ctx, done := serviceContext(ctx, filepath.Dir(configFiles[0]))
+	defer done()
+
+	daemon := newDaemon(configFiles, stateFile, opts)
+
+	if *listen != "" {
+		listener, err := net.Listen("tcp", *listen)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error listening on '%s': %s\n", *listen, err)
+			return 1
+		}
+		server := &http.Server{Handler: newAPIHandler(daemon)}
+		go server.Serve(listener)
+		defer server.Close()
+	}
+
+	if err := daemon.Run(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 1
+	}
+	return 0
+}
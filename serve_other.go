@@ -0,0 +1,10 @@
+//go:build !windows
+
+package main
+
+import "context"
+
+// serviceContext is a no-op where service managers stop serve mode with signals
+func serviceContext(ctx context.Context, workDir string) (context.Context, func()) {
+	return ctx, func() {}
+}
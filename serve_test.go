@@ -0,0 +1,135 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseSchedule(t *testing.T) {
+	base := time.Date(2024, 1, 1, 10, 2, 30, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		global  GlobalConfig
+		target  TargetConfig
+		want    time.Time
+		wantErr bool
+	}{
+		{
+			name: "default interval",
+			want: time.Date(2024, 1, 1, 10, 3, 0, 0, time.UTC),
+		},
+		{
+			name:   "global interval",
+			global: GlobalConfig{Interval: "5m"},
+			want:   time.Date(2024, 1, 1, 10, 5, 0, 0, time.UTC),
+		},
+		{
+			name:   "target interval wins",
+			global: GlobalConfig{Interval: "5m"},
+			target: TargetConfig{Interval: "30s"},
+			want:   time.Date(2024, 1, 1, 10, 3, 0, 0, time.UTC),
+		},
+		{
+			name:   "cron schedule",
+			target: TargetConfig{Schedule: "*/5 * * * *"},
+			want:   time.Date(2024, 1, 1, 10, 5, 0, 0, time.UTC),
+		},
+		{
+			name:    "invalid cron",
+			target:  TargetConfig{Schedule: "every five minutes"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid interval",
+			target:  TargetConfig{Interval: "soon"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := parseSchedule(tt.global, tt.target)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSchedule() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && !schedule.Next(base).Equal(tt.want) {
+				t.Errorf("Next() = %v, want %v", schedule.Next(base), tt.want)
+			}
+		})
+	}
+}
+
+func TestIntervalScheduleAligned(t *testing.T) {
+	schedule := intervalSchedule(time.Minute)
+	tick := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want time.Time
+	}{
+		{"on a tick", tick, tick.Add(time.Minute)},
+		{"after a short run", tick.Add(3 * time.Second), tick.Add(time.Minute)},
+		{"after a run overlapping the next tick", tick.Add(75 * time.Second), tick.Add(2 * time.Minute)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := schedule.Next(tt.now); !got.Equal(tt.want) {
+				t.Errorf("Next() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSkippedTicks(t *testing.T) {
+	schedule := intervalSchedule(time.Minute)
+	tick := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		started  time.Time
+		finished time.Time
+		want     int
+	}{
+		{"short run", tick, tick.Add(3 * time.Second), 0},
+		{"overlaps one tick", tick, tick.Add(75 * time.Second), 1},
+		{"overlaps three ticks", tick.Add(30 * time.Second), tick.Add(200 * time.Second), 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := skippedTicks(schedule, tt.started, tt.finished); got != tt.want {
+				t.Errorf("skippedTicks() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffTargets(t *testing.T) {
+	spec := func(baseURL string) targetSpec {
+		return targetSpec{target: TargetConfig{BaseURLs: []string{baseURL}}}
+	}
+
+	old := map[string]targetSpec{
+		"a.toml::kept":    spec("https://kept.example.com"),
+		"a.toml::changed": spec("https://old.example.com"),
+		"a.toml::removed": spec("https://removed.example.com"),
+	}
+	updated := map[string]targetSpec{
+		"a.toml::kept":    spec("https://kept.example.com"),
+		"a.toml::changed": spec("https://new.example.com"),
+		"b.toml::added":   spec("https://added.example.com"),
+	}
+
+	added, removed, changed := diffTargets(old, updated)
+	if !reflect.DeepEqual(added, []string{"b.toml::added"}) {
+		t.Errorf("added = %v", added)
+	}
+	if !reflect.DeepEqual(removed, []string{"a.toml::removed"}) {
+		t.Errorf("removed = %v", removed)
+	}
+	if !reflect.DeepEqual(changed, []string{"a.toml::changed"}) {
+		t.Errorf("changed = %v", changed)
+	}
+}
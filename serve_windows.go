@@ -0,0 +1,67 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/windows/svc"
+)
+
+// serviceContext hands serve mode to the service control manager when Windows started it as a
+// service: stop and shutdown requests cancel the returned context, and done reports the
+// service stopped once serve mode returns. Services start in System32, so it moves to workDir
+func serviceContext(ctx context.Context, workDir string) (context.Context, func()) {
+	isService, err := svc.IsWindowsService()
+	if err != nil || !isService {
+		return ctx, func() {}
+	}
+	if err := os.Chdir(workDir); err != nil {
+		fmt.Fprintf(os.Stderr, "Error changing to directory '%s': %s\n", workDir, err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	handler := &serviceHandler{cancel: cancel, finished: make(chan struct{})}
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		if err := svc.Run("", handler); err != nil {
+			fmt.Fprintf(os.Stderr, "Error running as a Windows service: %s\n", err)
+			cancel()
+		}
+	}()
+	return ctx, func() {
+		close(handler.finished)
+		<-stopped
+	}
+}
+
+// serviceHandler relays service control requests to serve mode
+type serviceHandler struct {
+	cancel   context.CancelFunc
+	finished chan struct{}
+}
+
+// Execute reports the service running until it is asked to stop or serve mode exits on its own
+func (h *serviceHandler) Execute(args []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+	for {
+		select {
+		case req := <-requests:
+			switch req.Cmd {
+			case svc.Interrogate:
+				status <- req.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				status <- svc.Status{State: svc.StopPending}
+				h.cancel()
+				<-h.finished
+				return false, 0
+			}
+		case <-h.finished:
+			// Serve mode only returns by itself on an error, so the manager may restart it
+			return false, 1
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/xml"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ServiceOptions describe the `vitals serve` service that `vitals service` manages
+type ServiceOptions struct {
+	// Name is the service name: the systemd unit, the launchd label suffix, or the Windows service
+	Name string
+
+	// User installs a per-user systemd unit or launchd agent instead of a system-wide service
+	User bool
+
+	// Command is the full command line the service runs, starting with the vitals executable
+	Command []string
+
+	// WorkDir is where the service runs, the directory of its first config, so relative paths
+	// such as the state file resolve as they do from the command line
+	WorkDir string
+}
+
+// errServiceNotRunning is returned by serviceStatus for an installed service that is stopped,
+// after the service manager has printed its status
+var errServiceNotRunning = errors.New("service is not running")
+
+// runService implements `vitals service install|uninstall|status`, exiting 3 like an LSB
+// init script when the service is stopped
+func runService(args []string) int {
+	usage := "usage: vitals service install|uninstall|status [-c vitals.toml] [--name vitals] [--user] [-- serve options]"
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+	action := args[0]
+
+	fs := flag.NewFlagSet("service "+action, flag.ExitOnError)
+	var configFiles []string
+	fs.Var((*stringSlice)(&configFiles), "config", "Config file the service serves (repeatable)")
+	fs.Var((*stringSlice)(&configFiles), "c", "Config file the service serves (shorthand)")
+	name := fs.String("name", "vitals", "Service name")
+	user := fs.Bool("user", false, "Install a per-user service (systemd --user or a launchd agent)")
+	fs.Parse(args[1:])
+
+	if len(configFiles) == 0 {
+		configFiles = append(configFiles, "vitals.toml")
+	}
+	opts, err := newServiceOptions(*name, *user, configFiles, fs.Args())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 1
+	}
+
+	switch action {
+	case "install":
+		// Catch config mistakes now rather than in a restart loop
+		if _, err := loadConfigFiles(configFiles); err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			return 1
+		}
+		err = installService(opts)
+	case "uninstall":
+		err = uninstallService(opts)
+	case "status":
+		err = serviceStatus(opts)
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+	if errors.Is(err, errServiceNotRunning) {
+		return 3
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error running service %s for '%s': %s\n", action, opts.Name, err)
+		return 1
+	}
+	return 0
+}
+
+// newServiceOptions builds the serve command a service runs, with absolute paths to this
+// executable and the configs since services don't start in the current directory
+func newServiceOptions(name string, user bool, configFiles, serveArgs []string) (ServiceOptions, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return ServiceOptions{}, fmt.Errorf("error finding the vitals executable: %s", err)
+	}
+	command := []string{exe, "serve"}
+	var workDir string
+	for _, configFile := range configFiles {
+		abs, err := filepath.Abs(configFile)
+		if err != nil {
+			return ServiceOptions{}, fmt.Errorf("error resolving config file %s: %s", configFile, err)
+		}
+		if workDir == "" {
+			workDir = filepath.Dir(abs)
+		}
+		command = append(command, "-c", abs)
+	}
+	command = append(command, serveArgs...)
+
+	return ServiceOptions{
+		Name:    name,
+		User:    user,
+		Command: command,
+		WorkDir: workDir,
+	}, nil
+}
+
+// systemdUnit renders a systemd unit that keeps `vitals serve` running
+func systemdUnit(opts ServiceOptions) string {
+	args := make([]string, len(opts.Command))
+	for i, arg := range opts.Command {
+		args[i] = systemdQuote(arg)
+	}
+	wantedBy := "multi-user.target"
+	if opts.User {
+		wantedBy = "default.target"
+	}
+
+	return fmt.Sprintf(`[Unit]
+Description=vitals health checks (%s)
+Wants=network-online.target
+After=network-online.target
+
+[Service]
+ExecStart=%s
+WorkingDirectory=%s
+Restart=on-failure
+RestartSec=5
+
+[Install]
+WantedBy=%s
+`, opts.Name, strings.Join(args, " "), systemdQuote(opts.WorkDir), wantedBy)
+}
+
+// systemdQuote escapes a unit file argument: specifiers and variables are doubled, and
+// arguments with spaces or quotes are double-quoted
+func systemdQuote(arg string) string {
+	arg = strings.NewReplacer("%", "%%", "$", "$$").Replace(arg)
+	if arg != "" && !strings.ContainsAny(arg, " \t\"'\\") {
+		return arg
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(arg) + `"`
+}
+
+// launchdLabel is the launchd job label of a service, e.g. com.github.erietz.vitals
+func launchdLabel(opts ServiceOptions) string {
+	return "com.github.erietz." + opts.Name
+}
+
+// launchdPlist renders a launchd property list that keeps `vitals serve` running
+func launchdPlist(opts ServiceOptions) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+`)
+	fmt.Fprintf(&b, "\t<key>Label</key>\n\t<string>%s</string>\n", xmlEscape(launchdLabel(opts)))
+	b.WriteString("\t<key>ProgramArguments</key>\n\t<array>\n")
+	for _, arg := range opts.Command {
+		fmt.Fprintf(&b, "\t\t<string>%s</string>\n", xmlEscape(arg))
+	}
+	b.WriteString("\t</array>\n")
+	fmt.Fprintf(&b, "\t<key>WorkingDirectory</key>\n\t<string>%s</string>\n", xmlEscape(opts.WorkDir))
+	b.WriteString("\t<key>RunAtLoad</key>\n\t<true/>\n")
+	b.WriteString("\t<key>KeepAlive</key>\n\t<true/>\n")
+	logs := filepath.Join(opts.WorkDir, opts.Name)
+	fmt.Fprintf(&b, "\t<key>StandardOutPath</key>\n\t<string>%s</string>\n", xmlEscape(logs+".out.log"))
+	fmt.Fprintf(&b, "\t<key>StandardErrorPath</key>\n\t<string>%s</string>\n", xmlEscape(logs+".err.log"))
+	b.WriteString("</dict>\n</plist>\n")
+	return b.String()
+}
+
+// xmlEscape escapes text for an XML element
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
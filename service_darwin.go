@@ -0,0 +1,76 @@
+//go:build darwin
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// launchdPlistPath is where the plist of a service is installed: a per-user agent or a
+// system daemon
+func launchdPlistPath(opts ServiceOptions) (string, error) {
+	if !opts.User {
+		return filepath.Join("/Library/LaunchDaemons", launchdLabel(opts)+".plist"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, "Library", "LaunchAgents", launchdLabel(opts)+".plist"), nil
+}
+
+// launchctl runs launchctl, passing its output through
+func launchctl(args ...string) error {
+	cmd := exec.Command("launchctl", args...)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	return cmd.Run()
+}
+
+// installService writes a launchd plist for the service and loads it
+func installService(opts ServiceOptions) error {
+	path, err := launchdPlistPath(opts)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(launchdPlist(opts)), 0o644); err != nil {
+		return err
+	}
+	if err := launchctl("load", "-w", path); err != nil {
+		return err
+	}
+	fmt.Printf("Installed and loaded %s\n", path)
+	return nil
+}
+
+// uninstallService unloads the service and removes its plist
+func uninstallService(opts ServiceOptions) error {
+	path, err := launchdPlistPath(opts)
+	if err != nil {
+		return err
+	}
+	if err := launchctl("unload", "-w", path); err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+	fmt.Printf("Unloaded and removed %s\n", path)
+	return nil
+}
+
+// serviceStatus prints the launchd status of the service
+func serviceStatus(opts ServiceOptions) error {
+	err := launchctl("list", launchdLabel(opts))
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return errServiceNotRunning
+	}
+	return err
+}
@@ -0,0 +1,85 @@
+//go:build linux
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// systemdUnitPath is where the unit of a service is installed
+func systemdUnitPath(opts ServiceOptions) (string, error) {
+	if !opts.User {
+		return filepath.Join("/etc/systemd/system", opts.Name+".service"), nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "systemd", "user", opts.Name+".service"), nil
+}
+
+// systemctl runs systemctl against the system or user manager, passing its output through
+func systemctl(opts ServiceOptions, args ...string) error {
+	if opts.User {
+		args = append([]string{"--user"}, args...)
+	}
+	cmd := exec.Command("systemctl", args...)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	return cmd.Run()
+}
+
+// installService writes a systemd unit for the service, then enables and starts it
+func installService(opts ServiceOptions) error {
+	path, err := systemdUnitPath(opts)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(systemdUnit(opts)), 0o644); err != nil {
+		return err
+	}
+	if err := systemctl(opts, "daemon-reload"); err != nil {
+		return err
+	}
+	if err := systemctl(opts, "enable", "--now", opts.Name+".service"); err != nil {
+		return err
+	}
+	fmt.Printf("Installed %s and started %s.service\n", path, opts.Name)
+	return nil
+}
+
+// uninstallService stops and disables the service and removes its unit
+func uninstallService(opts ServiceOptions) error {
+	path, err := systemdUnitPath(opts)
+	if err != nil {
+		return err
+	}
+	if err := systemctl(opts, "disable", "--now", opts.Name+".service"); err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+	if err := systemctl(opts, "daemon-reload"); err != nil {
+		return err
+	}
+	fmt.Printf("Stopped %s.service and removed %s\n", opts.Name, path)
+	return nil
+}
+
+// serviceStatus prints the systemd status of the service; systemctl exits 3 for a unit that
+// is installed but not running
+func serviceStatus(opts ServiceOptions) error {
+	err := systemctl(opts, "status", "--no-pager", opts.Name+".service")
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 3 {
+		return errServiceNotRunning
+	}
+	return err
+}
@@ -0,0 +1,16 @@
+//go:build !linux && !darwin && !windows
+
+package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// errServicesUnsupported is returned by every service action on platforms without a supported
+// service manager
+var errServicesUnsupported = fmt.Errorf("services are not supported on %s, run vitals serve under your init system", runtime.GOOS)
+
+func installService(opts ServiceOptions) error   { return errServicesUnsupported }
+func uninstallService(opts ServiceOptions) error { return errServicesUnsupported }
+func serviceStatus(opts ServiceOptions) error    { return errServicesUnsupported }
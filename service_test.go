@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestSystemdQuote(t *testing.T) {
+	tests := []struct {
+		arg  string
+		want string
+	}{
+		{"/usr/local/bin/vitals", "/usr/local/bin/vitals"},
+		{"/srv/my configs/vitals.toml", `"/srv/my configs/vitals.toml"`},
+		{`say "hi"`, `"say \"hi\""`},
+		{"100%", "100%%"},
+		{"$HOME", "$$HOME"},
+		{"", `""`},
+	}
+	for _, tt := range tests {
+		if got := systemdQuote(tt.arg); got != tt.want {
+			t.Errorf("systemdQuote(%q) = %s, want %s", tt.arg, got, tt.want)
+		}
+	}
+}
+
+func TestSystemdUnit(t *testing.T) {
+	opts := ServiceOptions{
+		Name:    "vitals",
+		Command: []string{"/usr/local/bin/vitals", "serve", "-c", "/etc/vitals/my vitals.toml", "--listen", ":8080"},
+		WorkDir: "/etc/vitals",
+	}
+
+	unit := systemdUnit(opts)
+	for _, line := range []string{
+		`ExecStart=/usr/local/bin/vitals serve -c "/etc/vitals/my vitals.toml" --listen :8080`,
+		"WorkingDirectory=/etc/vitals",
+		"Restart=on-failure",
+		"WantedBy=multi-user.target",
+	} {
+		if !strings.Contains(unit, line+"\n") {
+			t.Errorf("unit is missing %q:\n%s", line, unit)
+		}
+	}
+
+	opts.User = true
+	if unit := systemdUnit(opts); !strings.Contains(unit, "WantedBy=default.target\n") {
+		t.Errorf("user unit should be wanted by default.target:\n%s", unit)
+	}
+}
+
+func TestLaunchdPlist(t *testing.T) {
+	opts := ServiceOptions{
+		Name:    "vitals",
+		Command: []string{"/usr/local/bin/vitals", "serve", "-c", "/Users/me/R&D/vitals.toml"},
+		WorkDir: "/Users/me/R&D",
+	}
+
+	var plist struct {
+		Dict struct {
+			Keys    []string `xml:"key"`
+			Strings []string `xml:"string"`
+			Args    []string `xml:"array>string"`
+		} `xml:"dict"`
+	}
+	if err := xml.Unmarshal([]byte(launchdPlist(opts)), &plist); err != nil {
+		t.Fatalf("plist is not valid XML: %v", err)
+	}
+	if got := strings.Join(plist.Dict.Args, " "); got != strings.Join(opts.Command, " ") {
+		t.Errorf("ProgramArguments = %q, want %q", got, opts.Command)
+	}
+	want := []string{"com.github.erietz.vitals", "/Users/me/R&D", "/Users/me/R&D/vitals.out.log", "/Users/me/R&D/vitals.err.log"}
+	if strings.Join(plist.Dict.Strings, " ") != strings.Join(want, " ") {
+		t.Errorf("plist strings = %q, want %q", plist.Dict.Strings, want)
+	}
+}
@@ -0,0 +1,97 @@
+//go:build windows
+
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// installService registers the service with the service control manager and starts it
+func installService(opts ServiceOptions) error {
+	if opts.User {
+		return errors.New("--user is not supported for Windows services")
+	}
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("error connecting to the service manager: %s", err)
+	}
+	defer m.Disconnect()
+
+	s, err := m.CreateService(opts.Name, opts.Command[0], mgr.Config{
+		DisplayName: fmt.Sprintf("vitals health checks (%s)", opts.Name),
+		Description: "Runs vitals serve",
+		StartType:   mgr.StartAutomatic,
+	}, opts.Command[1:]...)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	if err := s.Start(); err != nil {
+		return err
+	}
+	fmt.Printf("Installed and started service %s\n", opts.Name)
+	return nil
+}
+
+// uninstallService stops the service and removes it from the service control manager
+func uninstallService(opts ServiceOptions) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("error connecting to the service manager: %s", err)
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(opts.Name)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	if status, err := s.Query(); err == nil && status.State != svc.Stopped {
+		if _, err := s.Control(svc.Stop); err != nil {
+			return err
+		}
+	}
+	if err := s.Delete(); err != nil {
+		return err
+	}
+	fmt.Printf("Stopped and removed service %s\n", opts.Name)
+	return nil
+}
+
+// serviceStatus prints whether the service is running
+func serviceStatus(opts ServiceOptions) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("error connecting to the service manager: %s", err)
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(opts.Name)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	status, err := s.Query()
+	if err != nil {
+		return err
+	}
+
+	states := map[svc.State]string{
+		svc.Stopped:         "stopped",
+		svc.StartPending:    "starting",
+		svc.StopPending:     "stopping",
+		svc.Running:         "running",
+		svc.ContinuePending: "resuming",
+		svc.PausePending:    "pausing",
+		svc.Paused:          "paused",
+	}
+	fmt.Printf("%s: %s\n", opts.Name, states[status.State])
+	if status.State != svc.Running {
+		return errServiceNotRunning
+	}
+	return nil
+}
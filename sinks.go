@@ -0,0 +1,213 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// SinksConfig configures where results and state changes are published after each run
+type SinksConfig struct {
+	NATS  *NATSConfig  `toml:"nats,omitempty"`
+	Kafka *KafkaConfig `toml:"kafka,omitempty"`
+	Redis *RedisConfig `toml:"redis,omitempty"`
+
+	// Grafana only receives state changes, as annotations
+	Grafana *GrafanaConfig `toml:"grafana,omitempty"`
+}
+
+// enabled reports whether any sink is configured
+func (s SinksConfig) enabled() bool {
+	return s.NATS != nil || s.Kafka != nil || s.Redis != nil || s.Grafana != nil
+}
+
+// ResultMessage is one endpoint result as published to a sink
+type ResultMessage struct {
+	Time       time.Time `json:"time"`
+	RunID      string    `json:"run_id,omitempty"`
+	Target     string    `json:"target"`
+	ConfigFile string    `json:"config_file"`
+	Labels     Labels    `json:"labels,omitempty"`
+	Ownership
+	JSONResult
+}
+
+// StateChange is published when an endpoint goes from passing to failing or back
+type StateChange struct {
+	Time       time.Time `json:"time"`
+	RunID      string    `json:"run_id,omitempty"`
+	Target     string    `json:"target"`
+	ConfigFile string    `json:"config_file"`
+	From       string    `json:"from"`
+	To         string    `json:"to"`
+	Labels     Labels    `json:"labels,omitempty"`
+	Ownership
+	Result JSONResult `json:"result"`
+}
+
+// SinkBatch is everything a sink receives from one run
+type SinkBatch struct {
+	Results []ResultMessage
+	Changes []StateChange
+
+	// statuses are the endpoint statuses to record once the batch is published, so changes a
+	// failed publish never delivered are found again on the next run
+	statuses map[string]string
+}
+
+// Sink publishes the results of a run somewhere other tools can consume them
+type Sink interface {
+	Publish(batch SinkBatch) error
+	Close() error
+}
+
+// sinksConfig returns the first [sinks] section among the configs
+func sinksConfig(configs []ConfigWithSource) (SinksConfig, bool) {
+	for _, configWithSource := range configs {
+		if configWithSource.Config.Sinks.enabled() {
+			return configWithSource.Config.Sinks, true
+		}
+	}
+	return SinksConfig{}, false
+}
+
+// openSinks connects to every configured sink, returning the ones that connected and the
+// errors of those that didn't
+func openSinks(config SinksConfig) ([]Sink, []error) {
+	var sinks []Sink
+	var errs []error
+	add := func(sink Sink, err error) {
+		if err != nil {
+			errs = append(errs, err)
+			return
+		}
+		sinks = append(sinks, sink)
+	}
+
+	if config.NATS != nil {
+		add(newNATSSink(*config.NATS))
+	}
+	if config.Kafka != nil {
+		add(newKafkaSink(*config.Kafka))
+	}
+	if config.Redis != nil {
+		add(newRedisSink(*config.Redis))
+	}
+	if config.Grafana != nil {
+		add(newGrafanaSink(*config.Grafana))
+	}
+	return sinks, errs
+}
+
+// closeSinks closes every sink, ignoring errors
+func closeSinks(sinks []Sink) {
+	for _, sink := range sinks {
+		sink.Close()
+	}
+}
+
+// endpointStatus is the up/down wording used in state change events
+func endpointStatus(result EndpointResult) string {
+	if result.Error == nil && result.Success {
+		return "up"
+	}
+	return "down"
+}
+
+// buildSinkBatch converts runs into sink messages, comparing each endpoint with the status
+// stored by the previous published run to find state changes; a nil state reports no changes.
+// The state is left alone until recordBatch
+func buildSinkBatch(runs []TargetRun, state *StateStore, at time.Time) SinkBatch {
+	var batch SinkBatch
+	if state != nil {
+		batch.statuses = make(map[string]string)
+	}
+	for _, run := range runs {
+		for _, result := range run.Results {
+			jsonResult := newJSONResult(result, false)
+			batch.Results = append(batch.Results, ResultMessage{
+				Time:       at.UTC(),
+				RunID:      run.RunID,
+				Target:     run.TargetName,
+				ConfigFile: run.ConfigName,
+				Labels:     run.Labels,
+				Ownership:  run.Ownership,
+				JSONResult: jsonResult,
+			})
+
+			if state == nil {
+				continue
+			}
+			key := run.Key + " " + result.Method + " " + result.URL
+			status := endpointStatus(result)
+			batch.statuses[key] = status
+			previous, known := state.Status(key)
+			if known && previous != status {
+				batch.Changes = append(batch.Changes, StateChange{
+					Time:       at.UTC(),
+					RunID:      run.RunID,
+					Target:     run.TargetName,
+					ConfigFile: run.ConfigName,
+					From:       previous,
+					To:         status,
+					Labels:     run.Labels,
+					Ownership:  run.Ownership,
+					Result:     jsonResult,
+				})
+			}
+		}
+	}
+	return batch
+}
+
+// publishToSinks sends runs to every configured sink, returning the errors of
+// the sinks that failed so one broken sink doesn't stop the others
+func publishToSinks(config SinksConfig, runs []TargetRun, state *StateStore) []error {
+	batch := buildSinkBatch(runs, state, time.Now())
+
+	sinks, errs := openSinks(config)
+	defer closeSinks(sinks)
+	return append(errs, publishBatch(sinks, batch, state)...)
+}
+
+// publishBatch sends a batch to every sink and records its statuses in state once at least
+// one sink took it, so when every sink is down its changes are published on the next run
+func publishBatch(sinks []Sink, batch SinkBatch, state *StateStore) []error {
+	var errs []error
+	published := false
+	for _, sink := range sinks {
+		if err := sink.Publish(batch); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		published = true
+	}
+	if published && state != nil {
+		state.SetStatuses(batch.statuses)
+	}
+	return errs
+}
+
+// subjectUnsafe matches characters not allowed in a subject, topic, or key segment
+var subjectUnsafe = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// unownedTeam stands in for {team} when a target has no team
+const unownedTeam = "none"
+
+// expandSubject replaces {target} and {team} in a subject template with the sanitized
+// target and team names
+func expandSubject(template, target, team string) string {
+	if team == "" {
+		team = unownedTeam
+	}
+	return strings.NewReplacer(
+		"{target}", subjectUnsafe.ReplaceAllString(target, "_"),
+		"{team}", subjectUnsafe.ReplaceAllString(team, "_"),
+	).Replace(template)
+}
+
+// sinkError wraps a publishing error with the sink's name
+func sinkError(sink string, err error) error {
+	return fmt.Errorf("error publishing to %s: %s", sink, err)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBuildSinkBatchStateChanges(t *testing.T) {
+	state, err := loadState(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	run := func(success bool, err error) []TargetRun {
+		return []TargetRun{{Key: "a.toml::api", TargetName: "api", ConfigName: "a.toml", Results: []EndpointResult{
+			{URL: "https://api.example.com/health", Method: "GET", StatusCode: 200, Success: success, Error: err},
+		}}}
+	}
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	steps := []struct {
+		runs     []TargetRun
+		wantFrom string
+		wantTo   string
+	}{
+		{run(true, nil), "", ""},
+		{run(true, nil), "", ""},
+		{run(false, errors.New("refused")), "up", "down"},
+		{run(true, nil), "down", "up"},
+	}
+
+	for i, step := range steps {
+		batch := buildSinkBatch(step.runs, state, at)
+		state.SetStatuses(batch.statuses)
+		if len(batch.Results) != 1 || batch.Results[0].Target != "api" {
+			t.Fatalf("step %d: results = %+v", i, batch.Results)
+		}
+		if step.wantTo == "" {
+			if len(batch.Changes) != 0 {
+				t.Errorf("step %d: unexpected changes %+v", i, batch.Changes)
+			}
+			continue
+		}
+		if len(batch.Changes) != 1 || batch.Changes[0].From != step.wantFrom || batch.Changes[0].To != step.wantTo {
+			t.Errorf("step %d: changes = %+v, want %s -> %s", i, batch.Changes, step.wantFrom, step.wantTo)
+		}
+	}
+}
+
+// fakeSink records published batches, or fails every publish
+type fakeSink struct {
+	err     error
+	batches []SinkBatch
+}
+
+func (s *fakeSink) Publish(batch SinkBatch) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.batches = append(s.batches, batch)
+	return nil
+}
+
+func (s *fakeSink) Close() error { return nil }
+
+func TestPublishBatchKeepsUndeliveredChanges(t *testing.T) {
+	state, err := loadState(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	run := func(success bool) []TargetRun {
+		return []TargetRun{{Key: "a.toml::api", TargetName: "api", Results: []EndpointResult{
+			{URL: "https://api.example.com/health", Method: "GET", Success: success},
+		}}}
+	}
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	sink := &fakeSink{}
+	down := &fakeSink{err: errors.New("connection refused")}
+
+	publishBatch([]Sink{sink}, buildSinkBatch(run(true), state, at), state)
+
+	// Every sink is down for the run the endpoint fails, so the change is kept for the next one
+	if errs := publishBatch([]Sink{down}, buildSinkBatch(run(false), state, at), state); len(errs) != 1 {
+		t.Fatalf("errors = %v, want the failed publish", errs)
+	}
+	publishBatch([]Sink{sink}, buildSinkBatch(run(false), state, at), state)
+	if len(sink.batches) != 2 || len(sink.batches[1].Changes) != 1 || sink.batches[1].Changes[0].To != "down" {
+		t.Errorf("batches = %+v, want the up -> down change published after the outage", sink.batches)
+	}
+}
+
+func TestExpandSubject(t *testing.T) {
+	if got := expandSubject("vitals.results.{target}", "api gateway.v2", ""); got != "vitals.results.api_gateway_v2" {
+		t.Errorf("expandSubject() = %q", got)
+	}
+	if got := expandSubject("vitals.changes.{team}.{target}", "api", "Payments Core"); got != "vitals.changes.Payments_Core.api" {
+		t.Errorf("expandSubject() with team = %q", got)
+	}
+	if got := expandSubject("vitals.changes.{team}", "api", ""); got != "vitals.changes.none" {
+		t.Errorf("expandSubject() without team = %q", got)
+	}
+}
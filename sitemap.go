@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+// maxSitemapDepth bounds how deeply nested sitemap indexes are followed
+const maxSitemapDepth = 3
+
+// sitemapDocument covers both <urlset> sitemaps and <sitemapindex> indexes
+type sitemapDocument struct {
+	URLs []struct {
+		Loc string `xml:"loc"`
+	} `xml:"url"`
+	Sitemaps []struct {
+		Loc string `xml:"loc"`
+	} `xml:"sitemap"`
+}
+
+// importSitemap converts a sitemap.xml (or sitemap index) into targets
+func importSitemap(source string, opts importOptions) (map[string]TargetConfig, error) {
+	urls, err := collectSitemapURLs(source, 0, make(map[string]bool))
+	if err != nil {
+		return nil, err
+	}
+
+	urls = filterURLs(urls, opts)
+	if len(urls) == 0 {
+		return nil, fmt.Errorf("no URLs found in sitemap %s", source)
+	}
+	return targetsFromURLs(urls, opts.name)
+}
+
+// collectSitemapURLs returns every page URL in a sitemap, following index entries
+func collectSitemapURLs(source string, depth int, visited map[string]bool) ([]string, error) {
+	if visited[source] {
+		return nil, nil
+	}
+	visited[source] = true
+
+	data, err := fetchSource(source)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := parseSitemap(data)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing sitemap %s: %s", source, err)
+	}
+
+	urls := make([]string, 0, len(doc.URLs))
+	for _, u := range doc.URLs {
+		urls = append(urls, u.Loc)
+	}
+
+	if len(doc.Sitemaps) > 0 && depth >= maxSitemapDepth {
+		return nil, fmt.Errorf("sitemap index %s nested more than %d levels deep", source, maxSitemapDepth)
+	}
+	for _, child := range doc.Sitemaps {
+		childURLs, err := collectSitemapURLs(child.Loc, depth+1, visited)
+		if err != nil {
+			return nil, err
+		}
+		urls = append(urls, childURLs...)
+	}
+
+	return urls, nil
+}
+
+// parseSitemap decodes sitemap XML, transparently handling gzipped sitemap.xml.gz files
+func parseSitemap(data []byte) (sitemapDocument, error) {
+	if len(data) > 2 && data[0] == 0x1f && data[1] == 0x8b {
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return sitemapDocument{}, err
+		}
+		if data, err = io.ReadAll(gz); err != nil {
+			return sitemapDocument{}, err
+		}
+	}
+
+	var doc sitemapDocument
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return sitemapDocument{}, err
+	}
+	return doc, nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// slackTimeout bounds a single webhook request
+const slackTimeout = 10 * time.Second
+
+// SlackConfig posts messages to a Slack incoming webhook
+type SlackConfig struct {
+	// WebhookURL may reference environment variables, e.g. "${SLACK_WEBHOOK_URL}"
+	WebhookURL string `toml:"webhook_url,omitempty"`
+
+	// Channel overrides the webhook's default channel, e.g. "#ops"
+	Channel  string `toml:"channel,omitempty"`
+	Username string `toml:"username,omitempty"`
+}
+
+// slackMessage is the webhook payload
+type slackMessage struct {
+	Text     string `json:"text"`
+	Channel  string `json:"channel,omitempty"`
+	Username string `json:"username,omitempty"`
+}
+
+// sendSlack posts text to the webhook, to channel when set and the configured channel otherwise
+func sendSlack(config SlackConfig, channel, text string) error {
+	webhookURL, err := expandSecret(config.WebhookURL)
+	if err != nil {
+		return err
+	}
+	if webhookURL == "" {
+		return fmt.Errorf("slack notifier requires webhook_url")
+	}
+	if channel == "" {
+		channel = config.Channel
+	}
+
+	payload, err := json.Marshal(slackMessage{Text: text, Channel: channel, Username: config.Username})
+	if err != nil {
+		return fmt.Errorf("error encoding slack message: %s", err)
+	}
+
+	client := &http.Client{Timeout: slackTimeout}
+	resp, err := client.Post(webhookURL, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("error posting to slack: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("error posting to slack: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSendSlack(t *testing.T) {
+	var got slackMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Content-Type = %q", r.Header.Get("Content-Type"))
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer server.Close()
+
+	t.Setenv("TEST_SLACK_WEBHOOK", server.URL)
+	config := SlackConfig{WebhookURL: "${TEST_SLACK_WEBHOOK}", Channel: "#alerts", Username: "vitals"}
+
+	if err := sendSlack(config, "", "hello"); err != nil {
+		t.Fatal(err)
+	}
+	if got != (slackMessage{Text: "hello", Channel: "#alerts", Username: "vitals"}) {
+		t.Errorf("payload = %+v", got)
+	}
+
+	if err := sendSlack(config, "#ops", "override"); err != nil {
+		t.Fatal(err)
+	}
+	if got.Channel != "#ops" {
+		t.Errorf("Channel = %q, want the per-message override", got.Channel)
+	}
+}
+
+func TestSendSlackError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid_token", http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	if err := sendSlack(SlackConfig{WebhookURL: server.URL}, "", "hello"); err == nil {
+		t.Error("expected an error for a rejected webhook")
+	}
+	if err := sendSlack(SlackConfig{}, "", "hello"); err == nil {
+		t.Error("expected an error without a webhook URL")
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// unstableResultKeys are the result fields that change from run to run without the
+// checked service changing: timings, connection reuse, request IDs, resolved addresses, and
+// the days left on certificates
+var unstableResultKeys = []string{
+	"duration_seconds",
+	"dns_seconds",
+	"connect_seconds",
+	"tls_handshake_seconds",
+	"ttfb_seconds",
+	"callback_seconds",
+	"clock_skew_seconds",
+	"cert_days_remaining",
+	"connection_reused",
+	"request_id",
+	"remote_addrs",
+}
+
+// unstableSummaryKeys are the summary fields derived from timings, connection reuse, or the
+// days left on certificates
+var unstableSummaryKeys = []string{
+	"avg_duration_seconds",
+	"latency",
+	"apdex",
+	"reused_connections",
+	"cert_days_remaining",
+}
+
+// stableJSON renders output for --json-stable: without the run block and the fields listed
+// above, with results sorted by URL and method, object keys sorted, and numbers written
+// without exponents, so two runs against unchanged services print the same bytes
+func stableJSON(output JSONOutput) ([]byte, error) {
+	targets := make(map[string]JSONTargetResults, len(output.Targets))
+	for key, target := range output.Targets {
+		target.Results = slices.Clone(target.Results)
+		slices.SortStableFunc(target.Results, func(a, b JSONResult) int {
+			return cmp.Or(cmp.Compare(a.URL, b.URL), cmp.Compare(a.Method, b.Method), cmp.Compare(a.LinkedFrom, b.LinkedFrom))
+		})
+		targets[key] = target
+	}
+	data, err := json.Marshal(JSONOutput{Targets: targets})
+	if err != nil {
+		return nil, err
+	}
+
+	// Decoding into maps sorts the keys of every object, including embedded struct fields
+	var doc map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+	for _, target := range doc["targets"].(map[string]any) {
+		target := target.(map[string]any)
+		if summary, ok := target["summary"].(map[string]any); ok {
+			deleteKeys(summary, unstableSummaryKeys)
+		}
+		results, _ := target["results"].([]any)
+		for _, result := range results {
+			deleteKeys(result.(map[string]any), unstableResultKeys)
+		}
+	}
+
+	var buf bytes.Buffer
+	writeCanonical(&buf, doc, "")
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+// deleteKeys removes keys from an object
+func deleteKeys(object map[string]any, keys []string) {
+	for _, key := range keys {
+		delete(object, key)
+	}
+}
+
+// writeCanonical writes a decoded JSON value indented by two spaces, with object keys in
+// byte order and numbers in plain decimal notation
+func writeCanonical(buf *bytes.Buffer, v any, indent string) {
+	inner := indent + "  "
+	switch v := v.(type) {
+	case map[string]any:
+		if len(v) == 0 {
+			buf.WriteString("{}")
+			return
+		}
+		buf.WriteString("{\n")
+		for i, key := range slices.Sorted(maps.Keys(v)) {
+			if i > 0 {
+				buf.WriteString(",\n")
+			}
+			buf.WriteString(inner)
+			writeString(buf, key)
+			buf.WriteString(": ")
+			writeCanonical(buf, v[key], inner)
+		}
+		buf.WriteString("\n" + indent + "}")
+	case []any:
+		if len(v) == 0 {
+			buf.WriteString("[]")
+			return
+		}
+		buf.WriteString("[\n")
+		for i, element := range v {
+			if i > 0 {
+				buf.WriteString(",\n")
+			}
+			buf.WriteString(inner)
+			writeCanonical(buf, element, inner)
+		}
+		buf.WriteString("\n" + indent + "]")
+	case json.Number:
+		buf.WriteString(canonicalNumber(v))
+	case string:
+		writeString(buf, v)
+	default:
+		data, _ := json.Marshal(v)
+		buf.Write(data)
+	}
+}
+
+// writeString writes a JSON string without escaping HTML characters
+func writeString(buf *bytes.Buffer, s string) {
+	var quoted bytes.Buffer
+	encoder := json.NewEncoder(&quoted)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(s)
+	buf.Write(bytes.TrimSuffix(quoted.Bytes(), []byte("\n")))
+}
+
+// canonicalNumber writes integers as they are and other numbers in the shortest decimal
+// form without an exponent, e.g. 1e-05 as 0.00001
+func canonicalNumber(n json.Number) string {
+	if !strings.ContainsAny(string(n), ".eE") {
+		return string(n)
+	}
+	f, err := strconv.ParseFloat(string(n), 64)
+	if err != nil {
+		return string(n)
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestStableJSON(t *testing.T) {
+	run := func(first, second time.Duration, reused bool) JSONOutput {
+		results := []EndpointResult{
+			{URL: "https://example.com/b", Method: "GET", StatusCode: 500, Duration: first, RequestID: "id-1", ConnReused: reused, FailureReason: "status 500 <> 200"},
+			{URL: "https://example.com/a", Method: "GET", StatusCode: 200, Success: true, Duration: second, DNSDuration: 3 * time.Microsecond},
+		}
+		target, _ := printJSONResults(results, "api", "vitals.toml", Ownership{}, 100*time.Millisecond, false)
+		return JSONOutput{Run: &RunInfo{ID: "run"}, Targets: map[string]JSONTargetResults{"vitals.toml::api": target}}
+	}
+
+	first, err := stableJSON(run(10*time.Millisecond, 900*time.Millisecond, false))
+	if err != nil {
+		t.Fatalf("stableJSON() error = %v", err)
+	}
+	second, _ := stableJSON(run(300*time.Millisecond, 5*time.Millisecond, true))
+	if string(first) != string(second) {
+		t.Errorf("runs differing only in timing rendered differently:\n%s\n%s", first, second)
+	}
+
+	want := `{
+  "targets": {
+    "vitals.toml::api": {
+      "config_file": "vitals.toml",
+      "results": [
+        {
+          "method": "GET",
+          "state": "passed",
+          "status_code": 200,
+          "success": true,
+          "url": "https://example.com/a"
+        },
+        {
+          "failure_reason": "status 500 <> 200",
+          "method": "GET",
+          "state": "failed",
+          "status_code": 500,
+          "success": false,
+          "url": "https://example.com/b"
+        }
+      ],
+      "summary": {
+        "failed": 1,
+        "successful": 1,
+        "total": 2
+      },
+      "target": "api"
+    }
+  }
+}
+`
+	if string(first) != want {
+		t.Errorf("stableJSON() =\n%s\nwant\n%s", first, want)
+	}
+}
+
+func TestCanonicalNumber(t *testing.T) {
+	tests := []struct {
+		number json.Number
+		want   string
+	}{
+		{"42", "42"},
+		{"1e-05", "0.00001"},
+		{"2.50", "2.5"},
+		{"1.5E+3", "1500"},
+	}
+	for _, tt := range tests {
+		if got := canonicalNumber(tt.number); got != tt.want {
+			t.Errorf("canonicalNumber(%s) = %s, want %s", tt.number, got, tt.want)
+		}
+	}
+}
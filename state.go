@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// defaultStateFile is where run-to-run state is kept when --state-file is not given
+const defaultStateFile = ".vitals-state.json"
+
+// State is the data vitals persists between runs
+type State struct {
+	Validators map[string]Validators `json:"validators,omitempty"`
+
+	// Snapshots are the fingerprints of normalized bodies used for drift detection
+	Snapshots map[string]string `json:"snapshots,omitempty"`
+
+	// Statuses are the last up/down status of each endpoint, used to publish state changes
+	Statuses map[string]string `json:"statuses,omitempty"`
+
+	// Incidents are open incidents and those resolved in the last 30 days
+	Incidents []Incident `json:"incidents,omitempty"`
+}
+
+// Validators are the cache validators last returned for a URL
+type Validators struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+// StateStore guards the persisted State for concurrent checks
+type StateStore struct {
+	mu    sync.Mutex
+	path  string
+	state State
+}
+
+// loadState reads the state file, starting with empty state if it does not exist yet
+func loadState(path string) (*StateStore, error) {
+	store := &StateStore{path: path}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading state file %s: %s", path, err)
+	}
+	if err := json.Unmarshal(data, &store.state); err != nil {
+		return nil, fmt.Errorf("error parsing state file %s: %s", path, err)
+	}
+	return store, nil
+}
+
+// Validators returns the stored validators for a URL
+func (s *StateStore) Validators(url string) (Validators, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.state.Validators[url]
+	return v, ok
+}
+
+// SetValidators records the validators for a URL
+func (s *StateStore) SetValidators(url string, v Validators) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state.Validators == nil {
+		s.state.Validators = make(map[string]Validators)
+	}
+	s.state.Validators[url] = v
+}
+
+// Snapshot returns the stored body fingerprint for a URL
+func (s *StateStore) Snapshot(url string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fingerprint, ok := s.state.Snapshots[url]
+	return fingerprint, ok
+}
+
+// SetSnapshot records the body fingerprint for a URL
+func (s *StateStore) SetSnapshot(url, fingerprint string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state.Snapshots == nil {
+		s.state.Snapshots = make(map[string]string)
+	}
+	s.state.Snapshots[url] = fingerprint
+}
+
+// Status returns an endpoint's recorded status, if it is known
+func (s *StateStore) Status(key string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status, ok := s.state.Statuses[key]
+	return status, ok
+}
+
+// SetStatuses records endpoint statuses by key
+func (s *StateStore) SetStatuses(statuses map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state.Statuses == nil {
+		s.state.Statuses = make(map[string]string, len(statuses))
+	}
+	maps.Copy(s.state.Statuses, statuses)
+}
+
+// Save writes the state file atomically so an interrupted run cannot corrupt it
+func (s *StateStore) Save() error {
+	s.mu.Lock()
+	data, err := json.MarshalIndent(s.state, "", "  ")
+	s.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("error encoding state: %s", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".vitals-state-*")
+	if err != nil {
+		return fmt.Errorf("error writing state file %s: %s", s.path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error writing state file %s: %s", s.path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error writing state file %s: %s", s.path, err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("error writing state file %s: %s", s.path, err)
+	}
+	return nil
+}
+
+// needsState reports whether any target uses a feature that persists state between runs
+func needsState(configs []ConfigWithSource) bool {
+	for _, c := range configs {
+		// Sinks and incidents track state changes, which are found by comparing with the last run
+		if c.Config.Sinks.enabled() || c.Config.Global.Incidents || c.Config.Issues.enabled() {
+			return true
+		}
+		for _, target := range c.Config.Targets {
+			if target.ConditionalRequests || target.DetectDrift {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestStateStoreRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	store, err := loadState(path)
+	if err != nil {
+		t.Fatalf("loadState() on missing file: %v", err)
+	}
+	if _, ok := store.Validators("http://example.com"); ok {
+		t.Fatal("expected no validators in fresh state")
+	}
+
+	want := Validators{ETag: `"abc"`, LastModified: "Wed, 21 Oct 2015 07:28:00 GMT"}
+	store.SetValidators("http://example.com", want)
+	if err := store.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	reloaded, err := loadState(path)
+	if err != nil {
+		t.Fatalf("loadState() error = %v", err)
+	}
+	got, ok := reloaded.Validators("http://example.com")
+	if !ok || got != want {
+		t.Errorf("Validators() = %v, %v, want %v", got, ok, want)
+	}
+}
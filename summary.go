@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Exit reasons reported by --summary-json
+const (
+	exitPassed       = "passed"
+	exitChecksFailed = "checks_failed"
+	exitConfigError  = "config_error"
+	exitPreRunFailed = "pre_run_hook_failed"
+	exitOutputError  = "output_error"
+	exitUploadFailed = "upload_failed"
+)
+
+// RunSummary is the compact, machine-readable outcome of a run written by --summary-json
+type RunSummary struct {
+	RunID      string  `json:"run_id,omitempty"`
+	ExitCode   int     `json:"exit_code"`
+	ExitReason string  `json:"exit_reason"`
+	Duration   float64 `json:"duration_seconds"`
+	Targets    int     `json:"targets"`
+	Total      int     `json:"total"`
+	Successful int     `json:"successful"`
+	Failed     int     `json:"failed"`
+
+	// Errors counts the request errors of every target by class
+	Errors errorCounts `json:"errors,omitempty"`
+
+	// FailedTargets lists the run keys of targets with at least one failed check
+	FailedTargets []string `json:"failed_targets,omitempty"`
+	Labels        Labels   `json:"labels,omitempty"`
+}
+
+// summaryReporter writes the run summary to its destination, if any, as the run exits
+type summaryReporter struct {
+	dest    string
+	started time.Time
+	runID   string
+	labels  Labels
+}
+
+// newRunSummary counts the checks of a run
+func newRunSummary(runs []TargetRun, code int, reason string, duration time.Duration, labels Labels) RunSummary {
+	summary := RunSummary{
+		ExitCode:   code,
+		ExitReason: reason,
+		Duration:   duration.Seconds(),
+		Targets:    len(runs),
+		Labels:     labels,
+	}
+	for _, run := range runs {
+		failed := false
+		for _, result := range run.Results {
+			summary.Total++
+			switch state := result.State(); {
+			case state.failing():
+				summary.Failed++
+				failed = true
+			case state.passing():
+				summary.Successful++
+			}
+		}
+		if failed {
+			summary.FailedTargets = append(summary.FailedTargets, run.Key)
+		}
+		for class, n := range countErrors(run.Results) {
+			if summary.Errors == nil {
+				summary.Errors = make(errorCounts)
+			}
+			summary.Errors[class] += n
+		}
+	}
+	return summary
+}
+
+// openSummaryDest opens an inherited file descriptor given as fdN, e.g. fd3, or creates a file
+func openSummaryDest(dest string) (io.WriteCloser, error) {
+	if n, ok := strings.CutPrefix(dest, "fd"); ok {
+		if fd, err := strconv.Atoi(n); err == nil && fd >= 0 {
+			return os.NewFile(uintptr(fd), dest), nil
+		}
+	}
+	return os.Create(dest)
+}
+
+// write writes the summary as one line of JSON, reporting failures on stderr
+func (r summaryReporter) write(runs []TargetRun, code int, reason string) {
+	if r.dest == "" {
+		return
+	}
+	summary := newRunSummary(runs, code, reason, time.Since(r.started), r.labels)
+	summary.RunID = r.runID
+	data, err := json.Marshal(summary)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error marshaling summary JSON: %s\n", err)
+		return
+	}
+	w, err := openSummaryDest(r.dest)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening summary JSON destination '%s': %s\n", r.dest, err)
+		return
+	}
+	defer w.Close()
+	if _, err := w.Write(append(data, '\n')); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing summary JSON to '%s': %s\n", r.dest, err)
+	}
+}
+
+// exit writes the summary and exits with code
+func (r summaryReporter) exit(runs []TargetRun, code int, reason string) {
+	r.write(runs, code, reason)
+	os.Exit(code)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestNewRunSummary(t *testing.T) {
+	runs := []TargetRun{
+		{Key: "a.toml::api", Results: []EndpointResult{{Success: true}, {Error: fmt.Errorf("dial tcp: %w", syscall.ECONNREFUSED)}}},
+		{Key: "a.toml::web", Results: []EndpointResult{{Success: true}, {Success: true}}},
+	}
+	got := newRunSummary(runs, 1, exitChecksFailed, 1500*time.Millisecond, Labels{"sha": "abc123"})
+	want := RunSummary{
+		ExitCode:      1,
+		ExitReason:    exitChecksFailed,
+		Duration:      1.5,
+		Targets:       2,
+		Total:         4,
+		Successful:    3,
+		Failed:        1,
+		Errors:        errorCounts{ErrorRefused: 1},
+		FailedTargets: []string{"a.toml::api"},
+		Labels:        Labels{"sha": "abc123"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("newRunSummary() = %+v, want %+v", got, want)
+	}
+}
+
+func TestSummaryReporterWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "summary.json")
+	reporter := summaryReporter{dest: path, started: time.Now()}
+	reporter.write([]TargetRun{{Key: "a.toml::api", Results: []EndpointResult{{Success: true}}}}, 0, exitPassed)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var summary RunSummary
+	if err := json.Unmarshal(data, &summary); err != nil {
+		t.Fatal(err)
+	}
+	if summary.ExitReason != exitPassed || summary.Total != 1 || summary.Successful != 1 {
+		t.Errorf("summary = %+v", summary)
+	}
+	if data[len(data)-1] != '\n' {
+		t.Error("summary should be a single line ending in a newline")
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// targetTLSConfig builds the TLS settings of a target's client certificate, CA file, and
+// insecure_skip_verify, nil when the target sets none
+func targetTLSConfig(target TargetConfig) (*tls.Config, error) {
+	if target.ClientCert == "" && target.ClientKey == "" && target.CAFile == "" && !target.InsecureSkipVerify {
+		return nil, nil
+	}
+	config := &tls.Config{InsecureSkipVerify: target.InsecureSkipVerify}
+	if target.ClientCert != "" || target.ClientKey != "" {
+		if target.ClientCert == "" || target.ClientKey == "" {
+			return nil, fmt.Errorf("client_cert and client_key must be set together")
+		}
+		cert, err := tls.LoadX509KeyPair(target.ClientCert, target.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("error loading client certificate %s: %s", target.ClientCert, err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	if target.CAFile != "" {
+		pool, err := loadCAFile(target.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = pool
+	}
+	return config, nil
+}
+
+// loadCAFile returns the system certificate pool with the certificates of a PEM bundle added
+func loadCAFile(filename string) (*x509.CertPool, error) {
+	bundle, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error reading CA file: %s", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(bundle) {
+		return nil, fmt.Errorf("no certificates found in CA file %s", filename)
+	}
+	return pool, nil
+}
+
+// failingTransport fails every request with err, for targets whose transport can't be built
+type failingTransport struct {
+	err error
+}
+
+// RoundTrip returns the error without sending the request
+func (t failingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	return nil, t.err
+}
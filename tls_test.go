@@ -0,0 +1,191 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeClientCert writes a self-signed client certificate and its key to dir
+func writeClientCert(t *testing.T, dir string) (certFile, keyFile string, cert *x509.Certificate) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cert, err = x509.ParseCertificate(der); err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile, keyFile = filepath.Join(dir, "client.pem"), filepath.Join(dir, "client-key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile, cert
+}
+
+func TestTargetTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile, _ := writeClientCert(t, dir)
+
+	tests := []struct {
+		name     string
+		target   TargetConfig
+		wantCert bool
+		wantErr  string
+	}{
+		{"no certificate", TargetConfig{}, false, ""},
+		{"certificate", TargetConfig{ClientCert: certFile, ClientKey: keyFile}, true, ""},
+		{"certificate without key", TargetConfig{ClientCert: certFile}, false, "client_cert and client_key must be set together"},
+		{"missing file", TargetConfig{ClientCert: filepath.Join(dir, "missing.pem"), ClientKey: keyFile}, false, "error loading client certificate " + filepath.Join(dir, "missing.pem")},
+		{"CA file without certificates", TargetConfig{CAFile: keyFile}, false, "no certificates found in CA file " + keyFile},
+		{"missing CA file", TargetConfig{CAFile: filepath.Join(dir, "missing.pem")}, false, "error reading CA file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := targetTLSConfig(tt.target)
+			if tt.wantErr != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+					t.Errorf("error = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("targetTLSConfig() error: %s", err)
+			}
+			if (got != nil && len(got.Certificates) == 1) != tt.wantCert {
+				t.Errorf("targetTLSConfig() = %+v, want a certificate: %v", got, tt.wantCert)
+			}
+		})
+	}
+}
+
+func TestCheckEndpointClientCert(t *testing.T) {
+	certFile, keyFile, cert := writeClientCert(t, t.TempDir())
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(cert)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	server.StartTLS()
+	defer server.Close()
+
+	target := TargetConfig{StatusCodes: []int{200}, ClientCert: certFile, ClientKey: keyFile, InsecureSkipVerify: true}
+	opts := runOptions{}
+	client, ok := opts.tlsClient(Config{}, target)
+	if !ok {
+		t.Fatal("tlsClient() returned no client for a target with a client certificate")
+	}
+	if result := checkEndpoint(client, server.URL, "/", target, buildResponseChecks(target), nil, false); !result.Success || result.Error != nil {
+		t.Errorf("check with a client certificate failed: %+v", result)
+	}
+
+	// Without the certificate the server ends the handshake
+	plain := TargetConfig{StatusCodes: []int{200}}
+	if result := checkEndpoint(server.Client(), server.URL, "/", plain, buildResponseChecks(plain), nil, false); result.Success {
+		t.Error("check without a client certificate succeeded")
+	}
+
+	// A certificate that can't be loaded fails every check of the target
+	broken := TargetConfig{StatusCodes: []int{200}, ClientCert: certFile}
+	client, _ = opts.tlsClient(Config{}, broken)
+	result := checkEndpoint(client, server.URL, "/", broken, buildResponseChecks(broken), nil, false)
+	if result.Error == nil || !strings.Contains(result.Error.Error(), "client_cert and client_key must be set together") {
+		t.Errorf("error = %v, want the certificate error", result.Error)
+	}
+	if _, ok := opts.tlsClient(Config{}, TargetConfig{}); ok {
+		t.Error("tlsClient() returned a client for a target without TLS settings")
+	}
+}
+
+func TestCheckEndpointCAFile(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		target      TargetConfig
+		opts        runOptions
+		wantSuccess bool
+	}{
+		{"untrusted", TargetConfig{}, runOptions{}, false},
+		{"ca_file", TargetConfig{CAFile: caFile}, runOptions{}, true},
+		{"insecure_skip_verify", TargetConfig{InsecureSkipVerify: true}, runOptions{}, true},
+		{"--ca-file", TargetConfig{}, runOptions{caFile: caFile}, true},
+		{"--insecure", TargetConfig{}, runOptions{insecure: true}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.target.StatusCodes = []int{200}
+			client, ok := tt.opts.tlsClient(Config{}, tt.target)
+			if !ok {
+				client = tt.opts.httpClient(Config{}, tt.target)
+			}
+			result := checkEndpoint(client, server.URL, "/", tt.target, buildResponseChecks(tt.target), nil, false)
+			if result.Success != tt.wantSuccess {
+				t.Errorf("success = %v, want %v (error %v)", result.Success, tt.wantSuccess, result.Error)
+			}
+			if !tt.wantSuccess && classifyError(result.Error) != ErrorTLS {
+				t.Errorf("error = %v, want a TLS error", result.Error)
+			}
+		})
+	}
+}
+
+func TestRecordWithCAFile(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cassette, err := newCassette(t.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Recording keeps the TLS settings of the target's client
+	opts := runOptions{cassette: cassette}
+	target := TargetConfig{CAFile: caFile, StatusCodes: []int{200}}
+	client, ok := opts.tlsClient(Config{}, target)
+	if !ok {
+		t.Fatal("tlsClient() returned no client for a target with ca_file")
+	}
+	if _, recording := client.Transport.(*Cassette); !recording {
+		t.Fatalf("transport = %T, want the cassette", client.Transport)
+	}
+	result := checkEndpoint(client, server.URL, "/", target, buildResponseChecks(target), nil, false)
+	if !result.Success {
+		t.Errorf("recorded check failed: %v", result.Error)
+	}
+}
@@ -0,0 +1,425 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fatih/color"
+	"golang.org/x/term"
+)
+
+// sparkBlocks are the bar heights of a latency sparkline, lowest first
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// topTarget is one target's rolling numbers on the `vitals top` dashboard
+type topTarget struct {
+	Key       string
+	Checks    int
+	Successes int
+
+	// Latency is the slowest check of each run in the window, oldest first
+	Latency []float64
+	P95     float64
+
+	// Failing counts the endpoints whose latest check failed
+	Failing   int
+	Endpoints []topEndpoint
+}
+
+// topEndpoint is one endpoint's rolling numbers within a target
+type topEndpoint struct {
+	Method    string
+	URL       string
+	Checks    int
+	Successes int
+	Latency   []float64
+	Latest    HistoryRecord
+}
+
+// topState is everything one frame of the dashboard shows
+type topState struct {
+	Targets   []topTarget
+	Incidents []Incident
+	Window    time.Duration
+	Updated   time.Time
+	Err       error
+
+	// Selected is the highlighted target, and Detail shows its endpoints instead of the list
+	Selected int
+	Detail   bool
+}
+
+// uptimePercent is the share of passing checks, 100 when nothing was checked
+func uptimePercent(successes, checks int) float64 {
+	if checks == 0 {
+		return 100
+	}
+	return 100 * float64(successes) / float64(checks)
+}
+
+// buildTopTargets aggregates history records into per-target and per-endpoint numbers,
+// sorted by target key and URL. Records of one run share a timestamp
+func buildTopTargets(records []HistoryRecord) []topTarget {
+	records = slices.Clone(records)
+	slices.SortStableFunc(records, func(a, b HistoryRecord) int {
+		return a.Time.Compare(b.Time)
+	})
+
+	targets := make(map[string]*topTarget)
+	lastRun := make(map[string]time.Time)
+	for _, record := range records {
+		target, ok := targets[record.Target]
+		if !ok {
+			target = &topTarget{Key: record.Target}
+			targets[record.Target] = target
+		}
+		target.Checks++
+		if record.Success {
+			target.Successes++
+		}
+		if n := len(target.Latency); n > 0 && lastRun[record.Target].Equal(record.Time) {
+			target.Latency[n-1] = max(target.Latency[n-1], record.Duration)
+		} else {
+			target.Latency = append(target.Latency, record.Duration)
+			lastRun[record.Target] = record.Time
+		}
+
+		i := slices.IndexFunc(target.Endpoints, func(e topEndpoint) bool {
+			return e.Method == record.Method && e.URL == record.URL
+		})
+		if i < 0 {
+			target.Endpoints = append(target.Endpoints, topEndpoint{Method: record.Method, URL: record.URL})
+			i = len(target.Endpoints) - 1
+		}
+		endpoint := &target.Endpoints[i]
+		endpoint.Checks++
+		if record.Success {
+			endpoint.Successes++
+		}
+		endpoint.Latency = append(endpoint.Latency, record.Duration)
+		endpoint.Latest = record
+	}
+
+	sorted := make([]topTarget, 0, len(targets))
+	for _, target := range targets {
+		target.P95 = percentile(target.Latency, 95)
+		for _, endpoint := range target.Endpoints {
+			if !endpoint.Latest.Success {
+				target.Failing++
+			}
+		}
+		slices.SortFunc(target.Endpoints, func(a, b topEndpoint) int {
+			return strings.Compare(a.URL+" "+a.Method, b.URL+" "+b.Method)
+		})
+		sorted = append(sorted, *target)
+	}
+	slices.SortFunc(sorted, func(a, b topTarget) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+	return sorted
+}
+
+// sparkline draws the most recent values that fit in width, scaled to the largest of them
+func sparkline(values []float64, width int) string {
+	if width <= 0 || len(values) == 0 {
+		return ""
+	}
+	if len(values) > width {
+		values = values[len(values)-width:]
+	}
+	peak := slices.Max(values)
+	var b strings.Builder
+	for _, v := range values {
+		level := 0
+		if peak > 0 {
+			level = min(int(v/peak*float64(len(sparkBlocks))), len(sparkBlocks)-1)
+		}
+		b.WriteRune(sparkBlocks[level])
+	}
+	return b.String()
+}
+
+// fitText pads or truncates text to exactly width characters
+func fitText(text string, width int) string {
+	runes := []rune(text)
+	if len(runes) > width {
+		if width <= 3 {
+			return string(runes[:width])
+		}
+		return string(runes[:width-3]) + "..."
+	}
+	return text + strings.Repeat(" ", width-len(runes))
+}
+
+// targetIncidents returns the open incidents of a target key, or of every target when key
+// is empty
+func targetIncidents(incidents []Incident, key string) []Incident {
+	var open []Incident
+	for _, incident := range incidents {
+		if incident.Open() && (key == "" || runKey(incident.ConfigFile, incident.Target) == key) {
+			open = append(open, incident)
+		}
+	}
+	return open
+}
+
+// topLines renders one frame of the dashboard for a terminal size, with a zero height
+// rendering everything
+func topLines(state topState, width, height int) []string {
+	green, red, neutral := setupColorOutput()
+	bold := color.New(color.Bold).SprintFunc()
+	status := func(ok bool) string {
+		if ok {
+			return green("●")
+		}
+		return red("●")
+	}
+
+	var lines []string
+	header := fmt.Sprintf("vitals top: %d targets over the last %s, updated %s", len(state.Targets), state.Window, state.Updated.Format("15:04:05"))
+	lines = append(lines, bold(fitText(header, width)))
+	if state.Err != nil {
+		lines = append(lines, red(fitText(state.Err.Error(), width)))
+	}
+	lines = append(lines, "")
+
+	incidentsKey := ""
+	if state.Detail && state.Selected < len(state.Targets) {
+		target := state.Targets[state.Selected]
+		incidentsKey = target.Key
+		summary := fmt.Sprintf("%s: %.2f%% uptime, p95 %.2fs", target.Key, uptimePercent(target.Successes, target.Checks), target.P95)
+		lines = append(lines, bold(fitText(summary, width)), "")
+
+		urlWidth := max(min(width/2, 60), 20)
+		sparkWidth := max(width-urlWidth-28, 0)
+		lines = append(lines, neutral(fitText(fmt.Sprintf("  %s  %-6s  %-8s  %s", fitText("ENDPOINT", urlWidth), "LAST", "UPTIME", "LATENCY"), width)))
+		for _, endpoint := range target.Endpoints {
+			last := "ERROR"
+			if endpoint.Latest.Error == "" {
+				last = fmt.Sprint(endpoint.Latest.StatusCode)
+			}
+			lines = append(lines, fmt.Sprintf("%s %s  %-6s  %7.2f%%  %s", status(endpoint.Latest.Success),
+				fitText(endpoint.Method+" "+endpoint.URL, urlWidth), last, uptimePercent(endpoint.Successes, endpoint.Checks),
+				sparkline(endpoint.Latency, sparkWidth)))
+		}
+	} else {
+		keyWidth := 30
+		for _, target := range state.Targets {
+			keyWidth = max(keyWidth, min(len(target.Key), width/2))
+		}
+		sparkWidth := max(width-keyWidth-26, 0)
+		lines = append(lines, neutral(fitText(fmt.Sprintf("    %s  %-8s  %-6s  %s", fitText("TARGET", keyWidth), "UPTIME", "P95", "LATENCY"), width)))
+
+		// Scroll so the selection stays within the rows left after the incidents pane, or list
+		// every target when there is no screen height to fit
+		rows := len(state.Targets)
+		if height > 0 {
+			rows = max(height-len(lines)-6, 1)
+		}
+		start := max(state.Selected-rows+1, 0)
+		for i := start; i < len(state.Targets) && i < start+rows; i++ {
+			target := state.Targets[i]
+			cursor := " "
+			if i == state.Selected {
+				cursor = ">"
+			}
+			lines = append(lines, fmt.Sprintf("%s %s %s  %7.2f%%  %5.2fs  %s", cursor, status(target.Failing == 0),
+				fitText(target.Key, keyWidth), uptimePercent(target.Successes, target.Checks), target.P95,
+				sparkline(target.Latency, sparkWidth)))
+		}
+		if len(state.Targets) == 0 {
+			lines = append(lines, "  No results recorded in this window yet")
+		}
+	}
+
+	open := targetIncidents(state.Incidents, incidentsKey)
+	lines = append(lines, "", bold(fmt.Sprintf("Active incidents (%d)", len(open))))
+	for _, incident := range open {
+		text := fmt.Sprintf("  %s  %s %s  open %s  %s", incident.Target, incident.Method, incident.URL,
+			incident.Duration(state.Updated).Round(time.Second), incident.Reason)
+		lines = append(lines, red(fitText(text, width)))
+	}
+
+	help := "q quit  ↑/↓ select  enter details  esc back  r refresh"
+	if len(lines) < height {
+		lines = append(lines, make([]string, height-len(lines)-1)...)
+	}
+	return append(lines, neutral(fitText(help, width)))
+}
+
+// parseKeys turns raw terminal input into dashboard actions
+func parseKeys(input []byte) []string {
+	var actions []string
+	for len(input) > 0 {
+		switch {
+		case strings.HasPrefix(string(input), "\x1b[A"):
+			actions, input = append(actions, "up"), input[3:]
+		case strings.HasPrefix(string(input), "\x1b[B"):
+			actions, input = append(actions, "down"), input[3:]
+		case strings.HasPrefix(string(input), "\x1b[C"):
+			actions, input = append(actions, "enter"), input[3:]
+		case strings.HasPrefix(string(input), "\x1b[D"):
+			actions, input = append(actions, "back"), input[3:]
+		default:
+			switch input[0] {
+			case 'k':
+				actions = append(actions, "up")
+			case 'j':
+				actions = append(actions, "down")
+			case '\r', '\n', 'l':
+				actions = append(actions, "enter")
+			case '\x1b', 'h':
+				actions = append(actions, "back")
+			case 'r':
+				actions = append(actions, "refresh")
+			case 'q', '\x03':
+				actions = append(actions, "quit")
+			}
+			input = input[1:]
+		}
+	}
+	return actions
+}
+
+// apply updates the state for an action, reporting whether the dashboard should quit
+func (s *topState) apply(action string) bool {
+	switch action {
+	case "up":
+		s.Selected = max(s.Selected-1, 0)
+	case "down":
+		s.Selected = max(min(s.Selected+1, len(s.Targets)-1), 0)
+	case "enter":
+		s.Detail = len(s.Targets) > 0
+	case "back":
+		s.Detail = false
+	case "quit":
+		return true
+	}
+	return false
+}
+
+// runTop implements `vitals top`, a dashboard of the history and incidents serve mode records
+func runTop(args []string) int {
+	fs := flag.NewFlagSet("top", flag.ExitOnError)
+	var configFiles []string
+	fs.Var((*stringSlice)(&configFiles), "config", "Path to configuration file(s)")
+	fs.Var((*stringSlice)(&configFiles), "c", "Path to configuration file(s) (shorthand)")
+	stateFile := fs.String("state-file", defaultStateFile, "File incidents are read from")
+	window := fs.Duration("window", time.Hour, "How much history the uptime and graphs cover")
+	refresh := fs.Duration("refresh", 5*time.Second, "How often to reload history")
+	once := fs.Bool("once", false, "Print a single frame and exit, the default when stdout is not a terminal")
+	fs.Parse(args)
+
+	if len(configFiles) == 0 {
+		configFiles = append(configFiles, "vitals.toml")
+	}
+	configs, err := loadConfigFiles(configFiles)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 1
+	}
+	config, ok := historyConfig(configs)
+	if !ok {
+		fmt.Fprintln(os.Stderr, "No [history] section in the config; vitals top shows the results recorded by serve mode")
+		return 1
+	}
+	store, err := openHistory(config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 1
+	}
+	defer store.Close()
+
+	state := topState{Window: *window}
+	reload := func() {
+		now := time.Now()
+		state.Updated, state.Err = now, nil
+		records, err := store.Since(now.Add(-*window))
+		if err != nil {
+			state.Err = err
+			return
+		}
+		state.Targets = buildTopTargets(records)
+		state.Selected = max(min(state.Selected, len(state.Targets)-1), 0)
+		if incidents, err := loadState(*stateFile); err != nil {
+			state.Err = err
+		} else {
+			state.Incidents = incidents.Incidents(false)
+		}
+	}
+	reload()
+
+	stdin, stdout := int(os.Stdin.Fd()), int(os.Stdout.Fd())
+	if *once || !term.IsTerminal(stdin) || !term.IsTerminal(stdout) {
+		lines := topLines(state, getTerminalWidth(), 0)
+		fmt.Println(strings.Join(lines[:len(lines)-1], "\n"))
+		return 0
+	}
+
+	oldState, err := term.MakeRaw(stdin)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error setting up the terminal: %s\n", err)
+		return 1
+	}
+	defer term.Restore(stdin, oldState)
+
+	// Draw on the alternate screen so the shell's scrollback is left as it was
+	fmt.Print("\x1b[?1049h\x1b[?25l")
+	defer fmt.Print("\x1b[?25h\x1b[?1049l")
+
+	keys := make(chan []string)
+	go readTopKeys(os.Stdin, keys)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	ticker := time.NewTicker(*refresh)
+	defer ticker.Stop()
+
+	for {
+		width, height, err := term.GetSize(stdout)
+		if err != nil || width <= 0 || height <= 0 {
+			width, height = 80, 24
+		}
+		fmt.Print("\x1b[H\x1b[2J" + strings.Join(topLines(state, width, height), "\r\n"))
+
+		select {
+		case <-ticker.C:
+			reload()
+		case <-signals:
+			return 0
+		case actions, ok := <-keys:
+			if !ok {
+				return 0
+			}
+			for _, action := range actions {
+				if action == "refresh" {
+					reload()
+				}
+				if state.apply(action) {
+					return 0
+				}
+			}
+		}
+	}
+}
+
+// readTopKeys sends the actions of everything typed, closing keys when input ends
+func readTopKeys(r io.Reader, keys chan<- []string) {
+	defer close(keys)
+	buf := make([]byte, 64)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			keys <- parseKeys(buf[:n])
+		}
+		if err != nil {
+			return
+		}
+	}
+}
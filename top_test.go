@@ -0,0 +1,107 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildTopTargets(t *testing.T) {
+	run1 := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	run2 := run1.Add(time.Minute)
+	records := []HistoryRecord{
+		{Time: run2, Target: "a.toml::api", Method: "GET", URL: "https://api/health", StatusCode: 503, Duration: 0.4},
+		{Time: run1, Target: "a.toml::api", Method: "GET", URL: "https://api/health", StatusCode: 200, Success: true, Duration: 0.1},
+		{Time: run1, Target: "a.toml::api", Method: "GET", URL: "https://api/users", StatusCode: 200, Success: true, Duration: 0.3},
+		{Time: run2, Target: "a.toml::api", Method: "GET", URL: "https://api/users", StatusCode: 200, Success: true, Duration: 0.2},
+		{Time: run1, Target: "a.toml::web", Method: "GET", URL: "https://web/", StatusCode: 200, Success: true, Duration: 0.05},
+	}
+
+	targets := buildTopTargets(records)
+	if len(targets) != 2 || targets[0].Key != "a.toml::api" || targets[1].Key != "a.toml::web" {
+		t.Fatalf("targets = %+v", targets)
+	}
+	api := targets[0]
+	if api.Checks != 4 || api.Successes != 3 || api.Failing != 1 {
+		t.Errorf("checks %d, successes %d, failing %d, want 4, 3, 1", api.Checks, api.Successes, api.Failing)
+	}
+	// One latency point per run, the slowest check of it
+	if !slices.Equal(api.Latency, []float64{0.3, 0.4}) {
+		t.Errorf("latency = %v, want [0.3 0.4]", api.Latency)
+	}
+	if health := api.Endpoints[0]; health.URL != "https://api/health" || health.Latest.StatusCode != 503 {
+		t.Errorf("first endpoint = %+v, want /health with its latest status", health)
+	}
+}
+
+func TestSparkline(t *testing.T) {
+	tests := []struct {
+		values []float64
+		width  int
+		want   string
+	}{
+		{nil, 10, ""},
+		{[]float64{0, 0}, 10, "▁▁"},
+		{[]float64{1, 2, 4, 8}, 10, "▂▃▅█"},
+		{[]float64{8, 1, 2, 4, 8}, 4, "▂▃▅█"},
+	}
+	for _, tt := range tests {
+		if got := sparkline(tt.values, tt.width); got != tt.want {
+			t.Errorf("sparkline(%v, %d) = %q, want %q", tt.values, tt.width, got, tt.want)
+		}
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	got := parseKeys([]byte("j\x1b[Ak\r\x1bq"))
+	want := []string{"down", "up", "up", "enter", "back", "quit"}
+	if !slices.Equal(got, want) {
+		t.Errorf("parseKeys() = %v, want %v", got, want)
+	}
+}
+
+func TestTopStateApply(t *testing.T) {
+	state := topState{Targets: make([]topTarget, 3)}
+	for _, action := range []string{"down", "down", "down", "up", "enter"} {
+		state.apply(action)
+	}
+	if state.Selected != 1 || !state.Detail {
+		t.Errorf("selected %d, detail %v, want 1, true", state.Selected, state.Detail)
+	}
+	if !state.apply("quit") {
+		t.Error("quit should stop the dashboard")
+	}
+}
+
+func TestTopLines(t *testing.T) {
+	now := time.Now()
+	state := topState{
+		Targets: buildTopTargets([]HistoryRecord{
+			{Time: now, Target: "a.toml::api", Method: "GET", URL: "https://api/health", StatusCode: 200, Success: true, Duration: 0.1},
+		}),
+		Incidents: []Incident{
+			{Target: "api", ConfigFile: "a.toml", Method: "GET", URL: "https://api/users", OpenedAt: now.Add(-time.Minute), Reason: "status 503"},
+			{Target: "web", ConfigFile: "a.toml", Method: "GET", URL: "https://web/", OpenedAt: now, ResolvedAt: &now},
+		},
+		Window:  time.Hour,
+		Updated: now,
+	}
+
+	list := strings.Join(topLines(state, 100, 0), "\n")
+	for _, want := range []string{"a.toml::api", "100.00%", "Active incidents (1)", "status 503"} {
+		if !strings.Contains(list, want) {
+			t.Errorf("list view missing %q:\n%s", want, list)
+		}
+	}
+
+	state.Detail = true
+	detail := strings.Join(topLines(state, 100, 0), "\n")
+	if !strings.Contains(detail, "GET https://api/health") {
+		t.Errorf("detail view missing the endpoint:\n%s", detail)
+	}
+
+	if lines := topLines(state, 100, 30); len(lines) != 30 {
+		t.Errorf("frame has %d lines, want the terminal height", len(lines))
+	}
+}
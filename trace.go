@@ -0,0 +1,118 @@
+package main
+
+import (
+	"crypto/tls"
+	"net/http/httptrace"
+	"slices"
+	"sync"
+	"time"
+)
+
+// requestTrace records how a request was carried out: the time spent resolving hosts,
+// connecting, and in TLS handshakes, the time to its first response byte, the addresses
+// dialed, and whether it reused a kept-alive connection. Hooks run on the transport's dialing
+// goroutines, so it is safe for concurrent use
+type requestTrace struct {
+	mu           sync.Mutex
+	dnsStart     time.Time
+	dns          time.Duration
+	connectStart time.Time
+	connect      time.Duration
+	tlsStart     time.Time
+	tls          time.Duration
+	requestStart time.Time
+	ttfb         time.Duration
+	reused       bool
+	addrs        []string
+}
+
+// addAddr records an address the request connected to or tried, once. The caller holds mu
+func (t *requestTrace) addAddr(addr string) {
+	if !slices.Contains(t.addrs, addr) {
+		t.addrs = append(t.addrs, addr)
+	}
+}
+
+// hooks returns the client trace that feeds the record
+func (t *requestTrace) hooks() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.mu.Lock()
+			t.dnsStart = time.Now()
+			t.mu.Unlock()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.mu.Lock()
+			t.dns += time.Since(t.dnsStart)
+			t.mu.Unlock()
+		},
+		ConnectStart: func(network, addr string) {
+			t.mu.Lock()
+			t.connectStart = time.Now()
+			t.addAddr(addr)
+			t.mu.Unlock()
+		},
+		ConnectDone: func(network, addr string, err error) {
+			t.mu.Lock()
+			t.connect += time.Since(t.connectStart)
+			t.mu.Unlock()
+		},
+		TLSHandshakeStart: func() {
+			t.mu.Lock()
+			t.tlsStart = time.Now()
+			t.mu.Unlock()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.mu.Lock()
+			t.tls += time.Since(t.tlsStart)
+			t.mu.Unlock()
+		},
+		GetConn: func(string) {
+			t.mu.Lock()
+			t.requestStart = time.Now()
+			t.mu.Unlock()
+		},
+		GotFirstResponseByte: func() {
+			t.mu.Lock()
+			t.ttfb = time.Since(t.requestStart)
+			t.mu.Unlock()
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			t.reused = info.Reused
+			if info.Conn != nil {
+				t.addAddr(info.Conn.RemoteAddr().String())
+			}
+			t.mu.Unlock()
+		},
+	}
+}
+
+// DNSDuration returns the resolution time so far
+func (t *requestTrace) DNSDuration() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.dns
+}
+
+// Phases returns the time spent connecting and in TLS handshakes so far, and the time from
+// the start of the last request (or redirect) to its first response byte
+func (t *requestTrace) Phases() (connect, tls, ttfb time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.connect, t.tls, t.ttfb
+}
+
+// Reused reports whether the last connection the request used came from the idle pool
+func (t *requestTrace) Reused() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.reused
+}
+
+// Addrs returns the addresses dialed or connected to, in the order they were first used
+func (t *requestTrace) Addrs() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return slices.Clone(t.addrs)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// requestIDHeader carries the ID generated for each request when trace headers are enabled
+const requestIDHeader = "X-Request-ID"
+
+// setTraceHeaders gives a request a fresh X-Request-ID and a W3C traceparent whose trace ID
+// is the same value, so either finds the request in backend logs, and returns the ID.
+// Headers the target configures itself are left alone
+func setTraceHeaders(req *http.Request) string {
+	requestID := req.Header.Get(requestIDHeader)
+	if requestID == "" {
+		requestID = newRunID()
+		req.Header.Set(requestIDHeader, requestID)
+	}
+	if req.Header.Get("traceparent") == "" {
+		traceID := strings.ReplaceAll(requestID, "-", "")
+		if !isTraceID(traceID) {
+			traceID = strings.ReplaceAll(newRunID(), "-", "")
+		}
+		var span [8]byte
+		rand.Read(span[:])
+		req.Header.Set("traceparent", "00-"+traceID+"-"+hex.EncodeToString(span[:])+"-01")
+	}
+	return requestID
+}
+
+// isTraceID reports whether id is a valid traceparent trace ID: 32 lowercase hex digits,
+// not all zero
+func isTraceID(id string) bool {
+	if len(id) != 32 || strings.Trim(id, "0") == "" {
+		return false
+	}
+	for _, c := range id {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// describeRequestFailure describes a failed check for notifications and logs, with the ID of
+// its request when one was sent
+func describeRequestFailure(result EndpointResult) string {
+	if result.RequestID == "" {
+		return describeFailure(result)
+	}
+	return describeFailure(result) + " (request ID " + result.RequestID + ")"
+}
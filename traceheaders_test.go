@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var traceparentPattern = regexp.MustCompile(`^00-([0-9a-f]{32})-[0-9a-f]{16}-01$`)
+
+func TestSetTraceHeaders(t *testing.T) {
+	tests := []struct {
+		name          string
+		header        http.Header
+		wantID        string
+		wantTraceID   string
+		wantTraceSame bool
+	}{
+		{"generated", http.Header{}, "", "", true},
+		{"configured request ID", http.Header{"X-Request-Id": {"deploy-check-7"}}, "deploy-check-7", "", false},
+		{"configured traceparent", http.Header{"Traceparent": {"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}}, "", "4bf92f3577b34da6a3ce929d0e0e4736", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "http://example.com", nil)
+			req.Header = tt.header
+			id := setTraceHeaders(req)
+			if id != req.Header.Get(requestIDHeader) || (tt.wantID != "" && id != tt.wantID) {
+				t.Errorf("request ID = %s, header %s, want %s", id, req.Header.Get(requestIDHeader), tt.wantID)
+			}
+			match := traceparentPattern.FindStringSubmatch(req.Header.Get("traceparent"))
+			if match == nil {
+				t.Fatalf("traceparent = %q", req.Header.Get("traceparent"))
+			}
+			if tt.wantTraceID != "" && match[1] != tt.wantTraceID {
+				t.Errorf("trace ID = %s, want %s", match[1], tt.wantTraceID)
+			}
+			if same := match[1] == strings.ReplaceAll(id, "-", ""); same != tt.wantTraceSame {
+				t.Errorf("trace ID %s matches request ID %s = %v, want %v", match[1], id, same, tt.wantTraceSame)
+			}
+		})
+	}
+}
+
+func TestCheckEndpointTraceHeaders(t *testing.T) {
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = append(received, r.Header.Get(requestIDHeader))
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	target := TargetConfig{StatusCodes: []int{200}, TraceHeaders: true}
+	first := checkEndpoint(server.Client(), server.URL, "/", target, buildResponseChecks(target), nil, false)
+	second := checkEndpoint(server.Client(), server.URL, "/", target, buildResponseChecks(target), nil, false)
+	if len(received) != 2 || first.RequestID != received[0] || second.RequestID != received[1] || first.RequestID == second.RequestID {
+		t.Errorf("request IDs = %s and %s, server received %v", first.RequestID, second.RequestID, received)
+	}
+	if got, want := describeRequestFailure(first), "status 500 (request ID "+first.RequestID+")"; got != want {
+		t.Errorf("describeRequestFailure() = %q, want %q", got, want)
+	}
+
+	received = nil
+	target.TraceHeaders = false
+	if result := checkEndpoint(server.Client(), server.URL, "/", target, buildResponseChecks(target), nil, false); result.RequestID != "" || received[0] != "" {
+		t.Errorf("request ID %q sent without trace headers", result.RequestID)
+	}
+	if got := describeRequestFailure(EndpointResult{Error: errors.New("refused")}); got != "error: refused" {
+		t.Errorf("describeRequestFailure() = %q", got)
+	}
+}
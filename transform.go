@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/itchyny/gojq"
+)
+
+// Transform is a compiled jq expression applied to JSON bodies before body assertions and
+// drift fingerprinting, e.g. `del(.generated_at) | .items |= sort_by(.id)`
+type Transform struct {
+	code *gojq.Code
+}
+
+// compileTransform parses and compiles a jq expression
+func compileTransform(source string) (*Transform, error) {
+	query, err := gojq.Parse(source)
+	if err != nil {
+		return nil, err
+	}
+	code, err := gojq.Compile(query)
+	if err != nil {
+		return nil, err
+	}
+	return &Transform{code: code}, nil
+}
+
+// apply runs the expression on a JSON body and returns every output as compact JSON, one per
+// line with object keys sorted. Bodies that aren't JSON are returned unchanged
+func (t *Transform) apply(body string) (string, error) {
+	decoder := json.NewDecoder(strings.NewReader(body))
+	decoder.UseNumber()
+	var input any
+	if err := decoder.Decode(&input); err != nil {
+		return body, nil
+	}
+	input = normalizeJSONNumbers(input)
+
+	var outputs []string
+	iter := t.code.Run(input)
+	for {
+		value, ok := iter.Next()
+		if !ok {
+			break
+		}
+		if err, ok := value.(error); ok {
+			return "", fmt.Errorf("transform error: %s", err)
+		}
+
+		var b bytes.Buffer
+		encoder := json.NewEncoder(&b)
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(value); err != nil {
+			return "", fmt.Errorf("transform error: %s", err)
+		}
+		outputs = append(outputs, strings.TrimSuffix(b.String(), "\n"))
+	}
+	return strings.Join(outputs, "\n"), nil
+}
+
+// normalizeJSONNumbers converts json.Number values into the int and float64 values gojq
+// works with, keeping large integers exact
+func normalizeJSONNumbers(value any) any {
+	switch v := value.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return int(n)
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]any:
+		for key, item := range v {
+			v[key] = normalizeJSONNumbers(item)
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = normalizeJSONNumbers(item)
+		}
+	}
+	return value
+}
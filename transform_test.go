@@ -0,0 +1,76 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTransformApply(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		body    string
+		want    string
+		wantErr bool
+	}{
+		{"drop field", `del(.generated_at)`, `{"b": 1, "generated_at": "2026-01-01", "a": 2}`, `{"a":2,"b":1}`, false},
+		{"select field", `.status`, `{"status": "ok", "time": 5}`, `"ok"`, false},
+		{"sort array", `.items | sort_by(.id) | map(.id)`, `{"items": [{"id": 3}, {"id": 1}, {"id": 2}]}`, `[1,2,3]`, false},
+		{"large integers stay exact", `.id`, `{"id": 9007199254740993}`, `9007199254740993`, false},
+		{"multiple outputs", `.[]`, `[1, "two"]`, "1\n\"two\"", false},
+		{"no html escaping", `.`, `{"html": "<b>"}`, `{"html":"<b>"}`, false},
+		{"not json", `.status`, `plain text`, `plain text`, false},
+		{"runtime error", `.status | keys`, `{"status": "ok"}`, ``, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transform, err := compileTransform(tt.source)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := transform.apply(tt.body)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("apply() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("apply() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileTransformError(t *testing.T) {
+	if _, err := compileTransform(`.items |`); err == nil {
+		t.Error("expected a parse error")
+	}
+}
+
+func TestTransformBeforeAssertions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status": "ok", "error_count": 0, "generated_at": "2026-10-14T12:00:00Z"}`))
+	}))
+	defer server.Close()
+
+	// Without the transform "error" matches the error_count key in the raw body
+	target := TargetConfig{
+		StatusCodes:     []int{200},
+		BodyNotContains: []string{"error"},
+		Transform:       `{status}`,
+	}
+	result := checkEndpoint(server.Client(), server.URL, "/", target, buildResponseChecks(target), nil, false)
+	if !result.Success {
+		t.Fatalf("expected success after transform, got %q", result.FailureReason)
+	}
+	if !strings.Contains(result.ResponseBody, "generated_at") {
+		t.Error("ResponseBody should keep the original body")
+	}
+
+	target.Transform = ""
+	result = checkEndpoint(server.Client(), server.URL, "/", target, buildResponseChecks(target), nil, false)
+	if result.Success {
+		t.Error("expected failure without the transform")
+	}
+}
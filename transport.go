@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// newTransport builds the transport for a config's requests, returning nil to keep
+// http.DefaultTransport when nothing in the global config changes it, along with the DNS
+// cache when dns_cache_ttl enables one
+func newTransport(global GlobalConfig) (*http.Transport, *dnsCache) {
+	var cache *dnsCache
+	if ttl, ok := transportDuration("dns_cache_ttl", global.DNSCacheTTL); ok {
+		cache = newDNSCache(ttl)
+	}
+	idleTimeout, setIdleTimeout := transportDuration("idle_conn_timeout", global.IdleConnTimeout)
+	handshakeTimeout, setHandshakeTimeout := transportDuration("tls_handshake_timeout", global.TLSHandshakeTimeout)
+	continueTimeout, setContinueTimeout := transportDuration("expect_continue_timeout", global.ExpectContinueTimeout)
+
+	if cache == nil && global.MaxIdleConnsPerHost == 0 && !global.DisableKeepAlives && global.MaxIdleConns == 0 &&
+		!setIdleTimeout && !setHandshakeTimeout && !setContinueTimeout {
+		return nil, nil
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if cache != nil {
+		transport.DialContext = cache.dialContext(&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second})
+	}
+	if global.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = global.MaxIdleConnsPerHost
+	}
+	if global.MaxIdleConns > 0 {
+		transport.MaxIdleConns = global.MaxIdleConns
+	}
+	if setIdleTimeout {
+		transport.IdleConnTimeout = idleTimeout
+	}
+	if setHandshakeTimeout {
+		transport.TLSHandshakeTimeout = handshakeTimeout
+	}
+	if setContinueTimeout {
+		transport.ExpectContinueTimeout = continueTimeout
+	}
+	transport.DisableKeepAlives = global.DisableKeepAlives
+	return transport, cache
+}
+
+// transportDuration parses an optional global duration setting, reporting invalid values and
+// leaving them unset
+func transportDuration(name, value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		fmt.Fprintf(os.Stderr, "Error parsing %s '%s': expected a positive duration such as 10s\n", name, value)
+		return 0, false
+	}
+	return d, true
+}
+
+// targetHosts returns the distinct hostnames of the targets' plain HTTP base URLs; discovered
+// base URLs are only known once the targets run
+func targetHosts(targets []TargetConfig) []string {
+	seen := make(map[string]bool)
+	var hosts []string
+	for _, target := range targets {
+		for _, baseURL := range target.BaseURLs {
+			u, err := url.Parse(baseURL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+				continue
+			}
+			if host := u.Hostname(); !seen[host] {
+				seen[host] = true
+				hosts = append(hosts, host)
+			}
+		}
+	}
+	return hosts
+}
+
+// primeDNS resolves the targets' hosts before any check starts, bounded by the client timeout
+func primeDNS(cache *dnsCache, client *http.Client, targets []TargetConfig) {
+	if cache == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), client.Timeout)
+	defer cancel()
+	cache.prime(ctx, targetHosts(targets))
+}
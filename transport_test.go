@@ -0,0 +1,100 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestNewTransport(t *testing.T) {
+	if transport, _ := newTransport(GlobalConfig{}); transport != nil {
+		t.Error("an empty global config should keep the default transport")
+	}
+	if transport, _ := newTransport(GlobalConfig{DNSCacheTTL: "soon", IdleConnTimeout: "-1s"}); transport != nil {
+		t.Error("invalid durations should keep the default transport")
+	}
+
+	transport, cache := newTransport(GlobalConfig{
+		MaxIdleConnsPerHost:   50,
+		DisableKeepAlives:     true,
+		MaxIdleConns:          500,
+		IdleConnTimeout:       "2m",
+		TLSHandshakeTimeout:   "3s",
+		ExpectContinueTimeout: "250ms",
+	})
+	if transport == nil || cache != nil {
+		t.Fatalf("transport = %v, cache = %v, want a transport without a DNS cache", transport, cache)
+	}
+	if transport.MaxIdleConnsPerHost != 50 || !transport.DisableKeepAlives || transport.MaxIdleConns != 500 {
+		t.Errorf("MaxIdleConnsPerHost = %d, DisableKeepAlives = %v, MaxIdleConns = %d",
+			transport.MaxIdleConnsPerHost, transport.DisableKeepAlives, transport.MaxIdleConns)
+	}
+	if transport.IdleConnTimeout != 2*time.Minute || transport.TLSHandshakeTimeout != 3*time.Second ||
+		transport.ExpectContinueTimeout != 250*time.Millisecond {
+		t.Errorf("IdleConnTimeout = %s, TLSHandshakeTimeout = %s, ExpectContinueTimeout = %s",
+			transport.IdleConnTimeout, transport.TLSHandshakeTimeout, transport.ExpectContinueTimeout)
+	}
+
+	// Settings left out keep Go's defaults
+	transport, _ = newTransport(GlobalConfig{MaxIdleConns: 10})
+	if transport.TLSHandshakeTimeout != http.DefaultTransport.(*http.Transport).TLSHandshakeTimeout {
+		t.Errorf("TLSHandshakeTimeout = %s, want the default", transport.TLSHandshakeTimeout)
+	}
+}
+
+func TestConnectionReuse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	tests := []struct {
+		name       string
+		global     GlobalConfig
+		wantReused []bool
+	}{
+		{"keep-alive", GlobalConfig{MaxIdleConnsPerHost: 4}, []bool{false, true, true}},
+		{"keep-alives disabled", GlobalConfig{DisableKeepAlives: true}, []bool{false, false, false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport, _ := newTransport(tt.global)
+			defer transport.CloseIdleConnections()
+			client := &http.Client{Transport: transport, Timeout: time.Second}
+
+			var results []EndpointResult
+			var reused []bool
+			for range tt.wantReused {
+				result := checkEndpoint(client, server.URL, "/", TargetConfig{StatusCodes: []int{200}}, ResponseChecks{}, nil, false)
+				results = append(results, result)
+				reused = append(reused, result.ConnReused)
+			}
+			if !slices.Equal(reused, tt.wantReused) {
+				t.Errorf("reused = %v, want %v", reused, tt.wantReused)
+			}
+
+			summary, _ := printJSONResults(results, "api", "a.toml", Ownership{}, 0, false)
+			want := 0
+			for _, r := range tt.wantReused {
+				if r {
+					want++
+				}
+			}
+			if summary.Summary.ReusedConnections != want {
+				t.Errorf("ReusedConnections = %d, want %d", summary.Summary.ReusedConnections, want)
+			}
+		})
+	}
+}
+
+func TestTargetHosts(t *testing.T) {
+	targets := []TargetConfig{
+		{BaseURLs: []string{"https://api.example.com", "https://api.example.com:8443", "http://10.0.0.1"}},
+		{BaseURLs: []string{"srv+https://_web._tcp.example.com", "https://web.example.com"}},
+	}
+	want := []string{"api.example.com", "10.0.0.1", "web.example.com"}
+	if got := targetHosts(targets); !slices.Equal(got, want) {
+		t.Errorf("targetHosts() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// uploadTimeout bounds each report upload
+const uploadTimeout = 30 * time.Second
+
+// UploadTarget is a parsed --upload destination such as s3://bucket/reports/
+type UploadTarget struct {
+	Scheme string
+	Bucket string
+	Prefix string
+}
+
+// parseUploadURL parses an s3:// or gs:// destination
+func parseUploadURL(s string) (UploadTarget, error) {
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "s3" && u.Scheme != "gs") || u.Host == "" {
+		return UploadTarget{}, fmt.Errorf("invalid upload destination %q, expected s3://bucket/prefix/ or gs://bucket/prefix/", s)
+	}
+
+	prefix := strings.TrimPrefix(u.Path, "/")
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return UploadTarget{Scheme: u.Scheme, Bucket: u.Host, Prefix: prefix}, nil
+}
+
+// uploadCredentials are the HMAC keys used to sign uploads
+type uploadCredentials struct {
+	accessKey    string
+	secretKey    string
+	sessionToken string
+	region       string
+}
+
+// credentials reads the upload keys from the environment: the usual AWS variables for S3,
+// and HMAC interoperability keys for GCS
+func (t UploadTarget) credentials() (uploadCredentials, error) {
+	if t.Scheme == "gs" {
+		creds := uploadCredentials{
+			accessKey: os.Getenv("GCS_HMAC_ACCESS_KEY_ID"),
+			secretKey: os.Getenv("GCS_HMAC_SECRET"),
+			region:    "auto",
+		}
+		if creds.accessKey == "" || creds.secretKey == "" {
+			return uploadCredentials{}, fmt.Errorf("uploading to gs:// requires GCS_HMAC_ACCESS_KEY_ID and GCS_HMAC_SECRET")
+		}
+		return creds, nil
+	}
+
+	creds := uploadCredentials{
+		accessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		sessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+		region:       os.Getenv("AWS_REGION"),
+	}
+	if creds.region == "" {
+		creds.region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if creds.region == "" {
+		creds.region = "us-east-1"
+	}
+	if creds.accessKey == "" || creds.secretKey == "" {
+		return uploadCredentials{}, fmt.Errorf("uploading to s3:// requires AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+	return creds, nil
+}
+
+// objectURL returns the HTTPS URL of an object; AWS_ENDPOINT_URL switches S3 to path-style
+// requests against another endpoint, such as MinIO
+func (t UploadTarget) objectURL(key, region string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	path := strings.Join(segments, "/")
+
+	if t.Scheme == "gs" {
+		return fmt.Sprintf("https://storage.googleapis.com/%s/%s", t.Bucket, path)
+	}
+	if endpoint := os.Getenv("AWS_ENDPOINT_URL"); endpoint != "" {
+		return fmt.Sprintf("%s/%s/%s", strings.TrimSuffix(endpoint, "/"), t.Bucket, path)
+	}
+	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", t.Bucket, region, path)
+}
+
+// reportKeys returns the timestamped key for a report and, when latest is set, its alias
+func reportKeys(target UploadTarget, at time.Time, ext string, latest bool) []string {
+	keys := []string{target.Prefix + "vitals-" + at.UTC().Format("20060102T150405Z") + ext}
+	if latest {
+		keys = append(keys, target.Prefix+"latest"+ext)
+	}
+	return keys
+}
+
+// uploadReport uploads a report under a timestamped key (and the latest alias), returning
+// the keys written
+func uploadReport(destination string, latest bool, report []byte, contentType, ext string) ([]string, error) {
+	target, err := parseUploadURL(destination)
+	if err != nil {
+		return nil, err
+	}
+	creds, err := target.credentials()
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{Timeout: uploadTimeout}
+	keys := reportKeys(target, time.Now(), ext, latest)
+	for _, key := range keys {
+		req, err := http.NewRequest(http.MethodPut, target.objectURL(key, creds.region), bytes.NewReader(report))
+		if err != nil {
+			return nil, fmt.Errorf("error creating upload request: %s", err)
+		}
+		req.Header.Set("Content-Type", contentType)
+		signV4(req, report, creds, time.Now())
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("error uploading %s: %s", key, err)
+		}
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+		if resp.StatusCode/100 != 2 {
+			return nil, fmt.Errorf("error uploading %s: %s: %s", key, resp.Status, strings.TrimSpace(string(body)))
+		}
+	}
+	return keys, nil
+}
+
+// signV4 signs a request with AWS Signature Version 4 for the s3 service, which GCS also
+// accepts with HMAC keys; every header already set is signed
+func signV4(req *http.Request, payload []byte, creds uploadCredentials, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	payloadHash := sha256Hex(payload)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if creds.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.sessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + creds.region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+creds.secretKey), date)
+	key = hmacSHA256(key, creds.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.accessKey, scope, signedHeaders, signature))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
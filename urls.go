@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// importURLList converts a plain list of URLs (one per line) or a CSV of url,status rows into targets
+func importURLList(source string, opts importOptions) (map[string]TargetConfig, error) {
+	data, err := fetchSource(source)
+	if err != nil {
+		return nil, err
+	}
+
+	requests, err := parseURLList(data)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing URL list %s: %s", source, err)
+	}
+
+	var kept []importedRequest
+	for _, req := range requests {
+		if opts.filter != nil && !opts.filter.MatchString(req.URL) {
+			continue
+		}
+		kept = append(kept, req)
+		if opts.limit > 0 && len(kept) == opts.limit {
+			break
+		}
+	}
+	if len(kept) == 0 {
+		return nil, fmt.Errorf("no URLs found in %s", source)
+	}
+	return targetsFromRequests(kept, opts.name)
+}
+
+// parseURLList reads URL rows with an optional expected status column, skipping blank
+// lines, # comments, and a leading header row
+func parseURLList(data []byte) ([]importedRequest, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.Comment = '#'
+	reader.TrimLeadingSpace = true
+
+	var requests []importedRequest
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return requests, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		rawURL := strings.TrimSpace(record[0])
+		if rawURL == "" {
+			continue
+		}
+		if line == 1 && !strings.Contains(rawURL, "://") {
+			continue // header row such as "url,status"
+		}
+
+		req := importedRequest{URL: rawURL}
+		if len(record) > 1 {
+			if statusStr := strings.TrimSpace(record[1]); statusStr != "" {
+				status, err := strconv.Atoi(statusStr)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: invalid status %q", line, statusStr)
+				}
+				if status != 200 {
+					req.StatusCodes = []int{status}
+				}
+			}
+		}
+		requests = append(requests, req)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseURLList(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    []importedRequest
+		wantErr bool
+	}{
+		{
+			name: "plain list",
+			data: "# sweep\nhttps://a.example.com/\n\nhttps://b.example.com/health\n",
+			want: []importedRequest{
+				{URL: "https://a.example.com/"},
+				{URL: "https://b.example.com/health"},
+			},
+		},
+		{
+			name: "csv with header and status",
+			data: "url,status\nhttps://a.example.com/,200\nhttps://a.example.com/gone, 410\nhttps://a.example.com/any\n",
+			want: []importedRequest{
+				{URL: "https://a.example.com/"},
+				{URL: "https://a.example.com/gone", StatusCodes: []int{410}},
+				{URL: "https://a.example.com/any"},
+			},
+		},
+		{
+			name:    "invalid status",
+			data:    "https://a.example.com/,ok\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseURLList([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseURLList() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("parseURLList() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i].URL != tt.want[i].URL || !slices.Equal(got[i].StatusCodes, tt.want[i].StatusCodes) {
+					t.Errorf("parseURLList()[%d] = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// Cassette records HTTP exchanges to a directory or replays them from it instead of the network
+type Cassette struct {
+	dir    string
+	replay bool
+
+	// transport makes the real requests while recording, the wrapped client's own so its TLS
+	// and connection settings still apply
+	transport http.RoundTripper
+}
+
+// CassetteEntry is one recorded exchange as stored on disk
+type CassetteEntry struct {
+	Method     string              `json:"method"`
+	URL        string              `json:"url"`
+	StatusCode int                 `json:"status_code,omitempty"`
+	Header     map[string][]string `json:"header,omitempty"`
+	Body       []byte              `json:"body,omitempty"`
+
+	// Error is a transport error (e.g. connection refused) to reproduce on replay
+	Error string `json:"error,omitempty"`
+}
+
+// newCassette returns a cassette for the --record or --replay directory, nil when neither is set
+func newCassette(recordDir, replayDir string) (*Cassette, error) {
+	switch {
+	case recordDir != "" && replayDir != "":
+		return nil, fmt.Errorf("--record and --replay cannot be used together")
+	case recordDir != "":
+		if err := os.MkdirAll(recordDir, 0o755); err != nil {
+			return nil, fmt.Errorf("error creating cassette directory: %s", err)
+		}
+		return &Cassette{dir: recordDir}, nil
+	case replayDir != "":
+		if _, err := os.Stat(replayDir); err != nil {
+			return nil, fmt.Errorf("error opening cassette directory: %s", err)
+		}
+		return &Cassette{dir: replayDir, replay: true}, nil
+	}
+	return nil, nil
+}
+
+// wrap routes a client's requests through the cassette, recording with the client's transport;
+// a nil cassette leaves it alone
+func (c *Cassette) wrap(client *http.Client) *http.Client {
+	if c == nil {
+		return client
+	}
+	transport := client.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	client.Transport = &Cassette{dir: c.dir, replay: c.replay, transport: transport}
+	return client
+}
+
+// path returns the file an exchange is stored in, named after its method and URL
+func (c *Cassette) path(req *http.Request) string {
+	sum := sha256.Sum256([]byte(req.Method + " " + req.URL.String()))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:8])+".json")
+}
+
+// RoundTrip implements http.RoundTripper
+func (c *Cassette) RoundTrip(req *http.Request) (*http.Response, error) {
+	if c.replay {
+		return c.play(req)
+	}
+	return c.record(req)
+}
+
+// record performs the request and saves the raw response (or transport error) to disk
+func (c *Cassette) record(req *http.Request) (*http.Response, error) {
+	entry := CassetteEntry{Method: req.Method, URL: req.URL.String()}
+
+	resp, err := c.transport.RoundTrip(req)
+	if err != nil {
+		entry.Error = err.Error()
+	} else {
+		body, readErr := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if readErr != nil {
+			return nil, readErr
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+
+		entry.StatusCode = resp.StatusCode
+		entry.Header = resp.Header
+		entry.Body = body
+	}
+
+	data, marshalErr := json.MarshalIndent(entry, "", "  ")
+	if marshalErr == nil {
+		marshalErr = os.WriteFile(c.path(req), data, 0o644)
+	}
+	if marshalErr != nil {
+		fmt.Fprintf(os.Stderr, "Error recording '%s': %s\n", entry.URL, marshalErr)
+	}
+
+	return resp, err
+}
+
+// play answers the request from its recording
+func (c *Cassette) play(req *http.Request) (*http.Response, error) {
+	data, err := os.ReadFile(c.path(req))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("no recorded response for %s %s in %s", req.Method, req.URL, c.dir)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading cassette: %s", err)
+	}
+
+	var entry CassetteEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("error parsing cassette %s: %s", c.path(req), err)
+	}
+	if entry.Error != "" {
+		return nil, errors.New(entry.Error)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", entry.StatusCode, http.StatusText(entry.StatusCode)),
+		StatusCode:    entry.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header(entry.Header),
+		Body:          io.NopCloser(bytes.NewReader(entry.Body)),
+		ContentLength: int64(len(entry.Body)),
+		Request:       req,
+	}, nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCassetteRecordReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Served-By", "origin")
+		w.WriteHeader(http.StatusTeapot)
+		io.WriteString(w, "recorded "+r.URL.Path)
+	}))
+	dir := t.TempDir()
+
+	recorder, err := newCassette(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := recorder.wrap(&http.Client{})
+	for _, path := range []string{"/a", "/b"} {
+		resp, err := client.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	server.Close()
+
+	// A closed server records its transport error too
+	if _, err := client.Get(server.URL + "/down"); err == nil {
+		t.Fatal("expected an error from the closed server")
+	}
+
+	player, err := newCassette("", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client = player.wrap(&http.Client{})
+
+	resp, err := client.Get(server.URL + "/b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTeapot || string(body) != "recorded /b" || resp.Header.Get("X-Served-By") != "origin" {
+		t.Errorf("replayed %d %q %v", resp.StatusCode, body, resp.Header)
+	}
+
+	if _, err := client.Get(server.URL + "/down"); err == nil || !strings.Contains(err.Error(), "connection refused") {
+		t.Errorf("expected the recorded transport error, got %v", err)
+	}
+	if _, err := client.Get(server.URL + "/never"); err == nil || !strings.Contains(err.Error(), "no recorded response") {
+		t.Errorf("expected a missing recording error, got %v", err)
+	}
+}
+
+func TestNewCassette(t *testing.T) {
+	if c, err := newCassette("", ""); c != nil || err != nil {
+		t.Errorf("newCassette() with no directories = %v, %v", c, err)
+	}
+	if _, err := newCassette(t.TempDir(), t.TempDir()); err == nil {
+		t.Error("expected an error when recording and replaying at once")
+	}
+	if _, err := newCassette("", t.TempDir()+"/missing"); err == nil {
+		t.Error("expected an error replaying from a missing directory")
+	}
+}
@@ -2,17 +2,26 @@
 package main
 
 import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/sha256"
 	"embed"
+	"encoding/hex"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"html/template"
 	"io"
+	"mime"
 	"net/http"
+	"net/http/httptrace"
 	"os"
+	"regexp"
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	"slices"
 
@@ -34,6 +43,9 @@ import (
 // ┴ U+2534  Light Up and Horizontal (bottom tee)
 // ┼ U+253C  Light Vertical and Horizontal (center cross)
 
+// version is the vitals release version, overridden at build time via -ldflags
+var version = "dev"
+
 // stringSlice is a custom type that implements flag.Value interface for string slices
 type stringSlice []string
 
@@ -53,23 +65,266 @@ var templateFS embed.FS
 
 // Config represents the top-level configuration structure
 type Config struct {
-	Global  GlobalConfig            `toml:"global"`
-	Targets map[string]TargetConfig `toml:"targets"`
+	Global    GlobalConfig            `toml:"global"`
+	Discovery DiscoveryConfig         `toml:"discovery"`
+	History   HistoryConfig           `toml:"history"`
+	Sinks     SinksConfig             `toml:"sinks"`
+	Notifiers NotifiersConfig         `toml:"notifiers"`
+	Alerts    AlertsConfig            `toml:"alerts"`
+	Issues    IssuesConfig            `toml:"issues"`
+	Hooks     HooksConfig             `toml:"hooks"`
+	Secrets   SecretsConfig           `toml:"secrets"`
+	Targets   map[string]TargetConfig `toml:"targets"`
 }
 
 // GlobalConfig represents global configuration settings
 type GlobalConfig struct {
-	Timeout int `toml:"timeout"`
+	Timeout   int    `toml:"timeout"`
+	UserAgent string `toml:"user_agent,omitempty"`
+
+	// Headers are sent with every request; targets' own headers replace those of the same name
+	Headers map[string]string `toml:"headers,omitempty"`
+
+	// Interval is how often serve mode runs targets without their own schedule, e.g. "1m"
+	Interval string `toml:"interval,omitempty"`
+
+	// Incidents tracks failing endpoints as incidents in the state file
+	Incidents bool `toml:"incidents,omitempty"`
+
+	// DNSCacheTTL enables resolving every host once up front and reusing its addresses for
+	// this long, e.g. "5m"
+	DNSCacheTTL string `toml:"dns_cache_ttl,omitempty"`
+
+	// MaxIdleConnsPerHost is how many kept-alive connections to keep per host (Go's default
+	// is 2), and DisableKeepAlives opens a new connection for every request
+	MaxIdleConnsPerHost int  `toml:"max_idle_conns_per_host,omitempty"`
+	DisableKeepAlives   bool `toml:"disable_keep_alives,omitempty"`
+
+	// MaxIdleConns caps kept-alive connections across all hosts, and the timeouts bound how
+	// long an idle connection is kept, a TLS handshake, and waiting for 100 Continue, e.g. "10s"
+	MaxIdleConns          int    `toml:"max_idle_conns,omitempty"`
+	IdleConnTimeout       string `toml:"idle_conn_timeout,omitempty"`
+	TLSHandshakeTimeout   string `toml:"tls_handshake_timeout,omitempty"`
+	ExpectContinueTimeout string `toml:"expect_continue_timeout,omitempty"`
+
+	// TraceHeaders sends every request with a generated X-Request-ID and W3C traceparent
+	// header, as targets can enable for themselves
+	TraceHeaders bool `toml:"trace_headers,omitempty"`
+
+	// LatencyColors colors table durations green below the first threshold, yellow below the
+	// second, and red above it, whether or not the check passed, e.g. ["300ms", "1s"]
+	LatencyColors []string `toml:"latency_colors,omitempty"`
 }
 
-// TargetConfig represents configuration for a specific API target
+// TargetConfig represents configuration for a specific API target. Fields are omitempty so
+// configs generated by `vitals import` only contain what was set
 type TargetConfig struct {
-	Name         string            `toml:"name"`
-	BaseURLs     []string          `toml:"base_urls"`
-	Endpoints    []string          `toml:"endpoints"`
-	Headers      map[string]string `toml:"headers"`
-	StatusCodes  []int             `toml:"status_codes"`
-	StatusRanges []string          `toml:"status_ranges"`
+	Name      string           `toml:"name,omitempty"`
+	BaseURLs  []string         `toml:"base_urls,omitempty"`
+	Endpoints []EndpointConfig `toml:"endpoints,omitempty"`
+
+	// EndpointBlocks are endpoints written as [[targets.NAME.endpoint]] tables, merged into
+	// Endpoints when the config is loaded
+	EndpointBlocks []EndpointConfig  `toml:"endpoint,omitempty"`
+	Headers        map[string]string `toml:"headers,omitempty"`
+	StatusCodes    []int             `toml:"status_codes,omitempty"`
+	StatusRanges   []string          `toml:"status_ranges,omitempty"`
+	UserAgent      string            `toml:"user_agent,omitempty"`
+
+	// Method is the HTTP method of every endpoint's request, GET when unset
+	Method string `toml:"method,omitempty"`
+
+	// TraceHeaders sends every request with a generated X-Request-ID and W3C traceparent
+	// header, reporting the ID with the result
+	TraceHeaders bool `toml:"trace_headers,omitempty"`
+
+	// UseCookies keeps the cookies responses set for the rest of the target's run, checking its
+	// endpoints one at a time in order so a login endpoint can start a session for the others
+	UseCookies bool `toml:"use_cookies,omitempty"`
+
+	// ExpectFailure inverts the checks of endpoints that must not be reachable, such as an
+	// admin panel from the public internet: they pass when they fail and fail when they pass
+	ExpectFailure bool `toml:"expect_failure,omitempty"`
+
+	// QueryParams are encoded and appended to every endpoint's URL
+	QueryParams map[string]string `toml:"query_params,omitempty"`
+
+	// Body is the payload sent with every endpoint's request, or BodyFile the file it is read
+	// from on every check; JSON bodies are sent as application/json and others as a form
+	Body     string `toml:"body,omitempty"`
+	BodyFile string `toml:"body_file,omitempty"`
+
+	// Body assertions that fail the check when an error marker appears in the response
+	BodyNotContains []string `toml:"body_not_contains,omitempty"`
+	BodyNotRegex    []string `toml:"body_not_regex,omitempty"`
+
+	// ExpectedContentType is the media type every endpoint must return, e.g. "application/json"
+	ExpectedContentType string `toml:"expected_content_type,omitempty"`
+
+	// Transform is a jq expression applied to JSON bodies before the body assertions and drift
+	// detection, e.g. to drop volatile fields or sort arrays
+	Transform string `toml:"transform,omitempty"`
+
+	// Assert is a CEL expression over the response that must evaluate to true, e.g.
+	// `json.status == "ok" && duration_ms < 500 && header("X-Cache") == "HIT"`
+	Assert string `toml:"assert,omitempty"`
+
+	// Script is a Lua file whose check(response) function validates every endpoint, for logic
+	// too complex to express declaratively
+	Script string `toml:"script,omitempty"`
+
+	// Hooks run shell commands before and after this target's checks
+	Hooks *HooksConfig `toml:"hooks,omitempty"`
+
+	// Plugin checks every endpoint with a WebAssembly module instead of an HTTP request
+	Plugin *PluginConfig `toml:"plugin,omitempty"`
+
+	// BodySHA256 is the hex-encoded checksum the response body must match byte-for-byte
+	BodySHA256 string `toml:"body_sha256,omitempty"`
+
+	// Audit lists security header checks to report as warnings, e.g. ["hsts", "csp", "xcto"]
+	Audit []string `toml:"audit,omitempty"`
+
+	// CORS turns every endpoint check into an OPTIONS preflight with these expectations
+	CORS *CORSConfig `toml:"cors,omitempty"`
+
+	// Auth authenticates every request, e.g. with HTTP Digest
+	Auth *AuthConfig `toml:"auth,omitempty"`
+
+	// BasicAuth sends a username and password with every request as HTTP Basic auth
+	BasicAuth *BasicAuth `toml:"basic_auth,omitempty"`
+
+	// ClientCert and ClientKey are the PEM files of a client certificate presented to services
+	// that require mutual TLS
+	ClientCert string `toml:"client_cert,omitempty"`
+	ClientKey  string `toml:"client_key,omitempty"`
+
+	// CAFile is a PEM bundle of certificate authorities trusted in addition to the system ones,
+	// for services with certificates from an internal CA
+	CAFile string `toml:"ca_file,omitempty"`
+
+	// InsecureSkipVerify accepts any server certificate, e.g. self-signed ones in development
+	InsecureSkipVerify bool `toml:"insecure_skip_verify,omitempty"`
+
+	// Callback makes checks pass only when the service calls back a webhook URL they pass it
+	Callback *CallbackConfig `toml:"callback,omitempty"`
+
+	// ConditionalRequests sends stored ETag/Last-Modified validators and accepts 304 responses
+	ConditionalRequests bool `toml:"conditional_requests,omitempty"`
+
+	// ExpectRedirectTo turns off following redirects and checks the Location of the 3xx response
+	// against this URL, which may contain * wildcards, e.g. "https://example.com/*"
+	ExpectRedirectTo string `toml:"expect_redirect_to,omitempty"`
+
+	// FollowRedirects set to false checks the redirect response itself instead of where it leads
+	FollowRedirects *bool `toml:"follow_redirects,omitempty"`
+
+	// MaxRedirects fails checks redirected more than this many times (0 uses Go's limit of 10)
+	MaxRedirects int `toml:"max_redirects,omitzero"`
+
+	// RequireCompression fails responses that are not gzip or brotli compressed
+	RequireCompression bool `toml:"require_compression,omitempty"`
+
+	// RetryAfterLimit lets a rate limited check wait out a Retry-After up to this long and
+	// retry once, e.g. "10s"
+	RetryAfterLimit string `toml:"retry_after_limit,omitempty"`
+
+	// Retries repeats a failed check up to this many times; checks that pass on a retry are
+	// reported as flaky instead of failing the run
+	Retries int `toml:"retries,omitzero"`
+
+	// MaintenanceUntil reports failed checks as maintenance instead of failing the run until
+	// this time, e.g. 2026-10-15T06:00:00Z
+	MaintenanceUntil time.Time `toml:"maintenance_until,omitzero"`
+
+	// CircuitBreaker skips the remaining endpoints of a base URL as "host down" after this
+	// many consecutive connection failures to it, zero to check every endpoint
+	CircuitBreaker int `toml:"circuit_breaker,omitzero"`
+
+	// ConsulService adds the passing instances of a Consul service (optionally filtered by tags)
+	// to the base URLs on every run
+	ConsulService string   `toml:"consul_service,omitempty"`
+	ConsulTags    []string `toml:"consul_tags,omitempty"`
+
+	// Schedule (cron syntax) or Interval (duration) controls how often serve mode runs the target
+	Schedule string `toml:"schedule,omitempty"`
+	Interval string `toml:"interval,omitempty"`
+
+	// DetectDrift flags passing endpoints whose normalized body changed since the last run,
+	// ignoring the DriftIgnore JSON fields and DriftIgnoreRegex patterns; FailOnDrift fails them
+	DetectDrift      bool     `toml:"detect_drift,omitempty"`
+	DriftIgnore      []string `toml:"drift_ignore,omitempty"`
+	DriftIgnoreRegex []string `toml:"drift_ignore_regex,omitempty"`
+	FailOnDrift      bool     `toml:"fail_on_drift,omitempty"`
+
+	// DependsOn names targets (in the same config, or as config::name) this one relies on,
+	// drawn as edges by `vitals graph`
+	DependsOn []string `toml:"depends_on,omitempty"`
+
+	// Mock overrides how `vitals mock` answers this target's endpoints
+	Mock *MockConfig `toml:"mock,omitempty"`
+
+	// Max*MS fail a passing check whose DNS lookup, TCP connect, TLS handshake, or time to
+	// first byte took longer than this many milliseconds
+	MaxDNSMS          int `toml:"max_dns_ms,omitzero"`
+	MaxConnectMS      int `toml:"max_connect_ms,omitzero"`
+	MaxTLSHandshakeMS int `toml:"max_tls_handshake_ms,omitzero"`
+	MaxTTFBMS         int `toml:"max_ttfb_ms,omitzero"`
+
+	// MaxClockSkewMS fails responses whose Date header is more than this many milliseconds off
+	// the local clock, as skewed clocks break token validation
+	MaxClockSkewMS int `toml:"max_clock_skew_ms,omitzero"`
+
+	// CertExpiryWarnDays fails checks whose server certificate expires within this many days
+	CertExpiryWarnDays int `toml:"cert_expiry_warn_days,omitzero"`
+
+	// ApdexThresholdMS scores the target's checks with Apdex: checks answering within this many
+	// milliseconds satisfy, within four times it tolerate, and slower or failed ones frustrate
+	ApdexThresholdMS int `toml:"apdex_threshold_ms,omitzero"`
+
+	// RunbookURL links responders to the target's runbook wherever a failure is reported
+	RunbookURL string `toml:"runbook_url,omitempty"`
+
+	// Owner, Team, and Contact say who is responsible for the target; Team also routes
+	// notifications, e.g. per-team report recipients and sink subjects
+	Owner   string `toml:"owner,omitempty"`
+	Team    string `toml:"team,omitempty"`
+	Contact string `toml:"contact,omitempty"`
+
+	// Notes is operational context shown with the target in HTML reports and `vitals explain`,
+	// e.g. "expected slow during nightly batch 02:00-03:00 UTC"
+	Notes string `toml:"notes,omitempty"`
+
+	// Tags are free-form labels for the target; `vitals lint` holds targets tagged "prod" to
+	// production rules such as https-only base URLs
+	Tags []string `toml:"tags,omitempty"`
+}
+
+// Ownership is who is responsible for a target, as shown in reports
+type Ownership struct {
+	Owner   string `json:"owner,omitempty"`
+	Team    string `json:"team,omitempty"`
+	Contact string `json:"contact,omitempty"`
+}
+
+// ownership returns the target's ownership metadata
+func (t TargetConfig) ownership() Ownership {
+	return Ownership{Owner: t.Owner, Team: t.Team, Contact: t.Contact}
+}
+
+// String describes the ownership on one line, or returns "" when none is set
+func (o Ownership) String() string {
+	var parts []string
+	if o.Owner != "" {
+		parts = append(parts, "Owner: "+o.Owner)
+	}
+	if o.Team != "" {
+		parts = append(parts, "Team: "+o.Team)
+	}
+	if o.Contact != "" {
+		parts = append(parts, "Contact: "+o.Contact)
+	}
+	return strings.Join(parts, ", ")
 }
 
 // StatusRange represents a range of acceptable HTTP status codes
@@ -88,6 +343,198 @@ func parseStatusRange(rangeStr string) (StatusRange, error) {
 	return StatusRange{Min: min, Max: max}, nil
 }
 
+// ResponseChecks holds the parsed assertions applied to every response of a target
+type ResponseChecks struct {
+	StatusRanges []StatusRange
+	BodyNotRegex []*regexp.Regexp
+	Audits       []string
+
+	DriftIgnoreRegex []*regexp.Regexp
+
+	// Assertion is the compiled `assert` expression, nil when there is none
+	Assertion *Assertion
+
+	// Script is the compiled Lua validation script, nil when there is none
+	Script *Script
+
+	// EndpointScripts are the compiled scripts of the target's endpoints, by path
+	EndpointScripts map[string]*Script
+
+	// Plugin is the compiled WebAssembly check module, nil when there is none
+	Plugin *Plugin
+
+	// Transform is the compiled jq `transform` expression, nil when there is none
+	Transform *Transform
+
+	// RetryAfterLimit is the longest Retry-After a rate limited check waits out before its
+	// one retry, zero to never retry
+	RetryAfterLimit time.Duration
+
+	// SkipBody drains responses instead of reading them when nothing consumes the body;
+	// health+json responses and verbose runs are always read
+	SkipBody bool
+}
+
+// buildResponseChecks parses a target's status ranges and body patterns, reporting
+// and skipping any that are invalid
+func buildResponseChecks(target TargetConfig) ResponseChecks {
+	var checks ResponseChecks
+
+	for _, rangeStr := range target.StatusRanges {
+		r, err := parseStatusRange(rangeStr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error parsing status range '%s': %s\n", rangeStr, err)
+			continue
+		}
+		checks.StatusRanges = append(checks.StatusRanges, r)
+	}
+
+	for _, pattern := range target.BodyNotRegex {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error parsing body regex '%s': %s\n", pattern, err)
+			continue
+		}
+		checks.BodyNotRegex = append(checks.BodyNotRegex, re)
+	}
+
+	for _, pattern := range target.DriftIgnoreRegex {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error parsing drift ignore regex '%s': %s\n", pattern, err)
+			continue
+		}
+		checks.DriftIgnoreRegex = append(checks.DriftIgnoreRegex, re)
+	}
+
+	for _, name := range target.Audit {
+		if _, ok := headerAudits[name]; !ok {
+			fmt.Fprintf(os.Stderr, "Unknown header audit '%s', expected one of %s\n", name, strings.Join(allHeaderAudits(), ", "))
+			continue
+		}
+		checks.Audits = append(checks.Audits, name)
+	}
+
+	if target.Assert != "" {
+		assertion, err := compileAssertion(target.Assert)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error parsing assert expression '%s': %s\n", target.Assert, err)
+		} else {
+			checks.Assertion = assertion
+		}
+	}
+
+	if target.Script != "" {
+		checks.Script = loadScript(target.Script)
+	}
+	for _, endpoint := range target.Endpoints {
+		if endpoint.Script == "" || checks.EndpointScripts[endpoint.Script] != nil {
+			continue
+		}
+		if checks.EndpointScripts == nil {
+			checks.EndpointScripts = make(map[string]*Script)
+		}
+		checks.EndpointScripts[endpoint.Script] = loadScript(endpoint.Script)
+	}
+
+	if target.RetryAfterLimit != "" {
+		limit, err := time.ParseDuration(target.RetryAfterLimit)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error parsing retry_after_limit '%s': %s\n", target.RetryAfterLimit, err)
+		} else {
+			checks.RetryAfterLimit = limit
+		}
+	}
+
+	if target.Transform != "" {
+		transform, err := compileTransform(target.Transform)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error parsing transform '%s': %s\n", target.Transform, err)
+		} else {
+			checks.Transform = transform
+		}
+	}
+
+	if target.Plugin != nil {
+		plugin, err := loadPlugin(target.Plugin.Path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading plugin '%s': %s\n", target.Plugin.Path, err)
+		} else {
+			checks.Plugin = plugin
+		}
+	}
+
+	return checks
+}
+
+// bodyUnused reports whether none of a target's assertions look at the response body
+func bodyUnused(target TargetConfig, checks ResponseChecks) bool {
+	return len(target.BodyNotContains) == 0 && len(checks.BodyNotRegex) == 0 && target.BodySHA256 == "" &&
+		checks.Transform == nil && checks.Assertion == nil && checks.Script == nil && !target.DetectDrift
+}
+
+// checkResponse runs all response assertions beyond the status code and returns the
+// reason for the first failure, or an empty string if the response is acceptable
+func checkResponse(resp *http.Response, body string, target TargetConfig, checks ResponseChecks) string {
+	if target.RequireCompression && !isCompressed(resp.Header.Get("Content-Encoding")) {
+		return "response not compressed"
+	}
+	if target.CORS != nil {
+		if reason := checkCORS(resp.Header, *target.CORS); reason != "" {
+			return reason
+		}
+	}
+	if reason := checkRedirect(resp, target.ExpectRedirectTo); reason != "" {
+		return reason
+	}
+	if reason := checkContentType(resp.Header.Get("Content-Type"), target.ExpectedContentType); reason != "" {
+		return reason
+	}
+	return checkBody(body, target, checks)
+}
+
+// checkContentType compares the media type of a Content-Type header against the
+// expected one, ignoring parameters such as charset
+func checkContentType(contentType, expected string) string {
+	if expected == "" {
+		return ""
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return fmt.Sprintf("content type %q, expected %s", contentType, expected)
+	}
+	if !strings.EqualFold(mediaType, expected) {
+		return fmt.Sprintf("content type %s, expected %s", mediaType, expected)
+	}
+	return ""
+}
+
+// checkBody runs the body assertions and returns the reason for the first failure,
+// or an empty string if the body is acceptable
+func checkBody(body string, target TargetConfig, checks ResponseChecks) string {
+	if target.BodySHA256 != "" {
+		sum := sha256.Sum256([]byte(body))
+		if got := hex.EncodeToString(sum[:]); !strings.EqualFold(got, target.BodySHA256) {
+			return fmt.Sprintf("body sha256 %s, expected %s", got, target.BodySHA256)
+		}
+	}
+
+	for _, marker := range target.BodyNotContains {
+		if strings.Contains(body, marker) {
+			return fmt.Sprintf("body contains %q", marker)
+		}
+	}
+
+	for _, re := range checks.BodyNotRegex {
+		if re.MatchString(body) {
+			return fmt.Sprintf("body matches /%s/", re)
+		}
+	}
+
+	return ""
+}
+
 // isStatusAcceptable checks if the status code is in the acceptable list or ranges
 func isStatusAcceptable(status int, codes []int, ranges []StatusRange) bool {
 	// Check if status is in the list of acceptable codes
@@ -112,7 +559,30 @@ type cliFlags struct {
 	verbosity   bool
 	concurrency int
 	jsonOutput  bool
+	jsonStable  bool
 	htmlOutput  bool
+
+	mermaidOutput bool
+
+	upload       string
+	uploadLatest bool
+
+	auditHeaders bool
+	stateFile    string
+	crawlDepth   int
+	compare      bool
+	bell         bool
+	cassette     *Cassette
+	labels       Labels
+	dedupe       bool
+	maxRows      int
+	summaryJSON  string
+
+	saveBodies      string
+	saveBodiesLimit int
+
+	caFile   string
+	insecure bool
 }
 
 // parseFlags parses command line flags
@@ -133,13 +603,74 @@ func parseFlags() cliFlags {
 
 	flag.BoolVar(&flags.jsonOutput, "json", false, "Output results in JSON format instead of table")
 	flag.BoolVar(&flags.jsonOutput, "j", false, "Output results in JSON format instead of table (shorthand)")
+	flag.BoolVar(&flags.jsonStable, "json-stable", false, "Output results as canonical JSON without timings, for diffing two runs")
 
 	flag.BoolVar(&flags.htmlOutput, "html", false, "Output results in HTML format")
 	flag.BoolVar(&flags.htmlOutput, "h", false, "Output results in HTML format (shorthand)")
 
+	flag.StringVar(&flags.upload, "upload", "", "Upload the report to s3://bucket/prefix/ or gs://bucket/prefix/ after the run")
+	flag.BoolVar(&flags.uploadLatest, "upload-latest", false, "Also upload the report as latest.html or latest.json")
+
+	output := flag.String("output", "", "Output format: table, json, html, or mermaid")
+
+	flag.BoolVar(&flags.compare, "compare", false, "Compare endpoints side by side across the base URLs of each target")
+	flag.BoolVar(&flags.bell, "bell", false, "Ring the terminal bell and print a final failure count when any check fails")
+	flag.IntVar(&flags.maxRows, "max-rows-per-target", 0, "Print at most this many rows per target in tables and HTML reports, failures first (0 means all)")
+	flag.BoolVar(&flags.dedupe, "dedupe", false, "Send identical requests (same method, URL, and headers) only once per run, sharing the response between targets")
+	flag.BoolVar(&flags.auditHeaders, "audit-headers", false, "Audit security headers on every target and report missing ones as warnings")
+
+	flag.StringVar(&flags.summaryJSON, "summary-json", "", "Also write a one-line JSON run summary to a file descriptor (fd3) or path")
+
+	flag.Var(&flags.labels, "label", "Attach a key=value label to every result, e.g. --label sha=abc123 (repeatable)")
+
+	flag.StringVar(&flags.saveBodies, "save-bodies", "", "Write every response body to a file in this directory, named by target, endpoint, and time")
+	flag.IntVar(&flags.saveBodiesLimit, "save-bodies-limit", defaultSaveBodiesLimit, "Bytes of each response body --save-bodies writes; checks still read whole bodies")
+
+	flag.StringVar(&flags.caFile, "ca-file", "", "Trust the certificate authorities of this PEM bundle on every target, in addition to the system ones")
+	flag.BoolVar(&flags.insecure, "insecure", false, "Accept any server certificate on every target, e.g. self-signed ones")
+
+	flag.StringVar(&flags.stateFile, "state-file", defaultStateFile, "File used to persist state between runs")
+
+	var crawlSpec string
+	flag.StringVar(&crawlSpec, "crawl-links", "", "Also check same-origin links found on HTML pages, e.g. depth=1")
+
+	var recordDir, replayDir string
+	flag.StringVar(&recordDir, "record", "", "Record every response into this cassette directory")
+	flag.StringVar(&replayDir, "replay", "", "Answer requests from this cassette directory instead of the network")
+
 	// Parse the flags
 	flag.Parse()
 
+	var err error
+	if flags.crawlDepth, err = parseCrawlSpec(crawlSpec); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(2)
+	}
+	if flags.cassette, err = newCassette(recordDir, replayDir); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(2)
+	}
+
+	switch *output {
+	case "", "table":
+	case "json":
+		flags.jsonOutput = true
+	case "html":
+		flags.htmlOutput = true
+	case "mermaid":
+		flags.mermaidOutput = true
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown output format '%s', expected table, json, html, or mermaid\n", *output)
+		os.Exit(2)
+	}
+	if flags.jsonStable {
+		flags.jsonOutput = true
+	}
+	if flags.saveBodiesLimit <= 0 {
+		fmt.Fprintf(os.Stderr, "Invalid --save-bodies-limit %d, expected a positive number of bytes\n", flags.saveBodiesLimit)
+		os.Exit(2)
+	}
+
 	// If no config files specified, use the default
 	if len(flags.configFiles) == 0 {
 		flags.configFiles = append(flags.configFiles, "vitals.toml")
@@ -148,12 +679,18 @@ func parseFlags() cliFlags {
 	return flags
 }
 
+// tableOutput reports whether results are printed as tables rather than another format
+func (f cliFlags) tableOutput() bool {
+	return !f.jsonOutput && !f.htmlOutput && !f.mermaidOutput
+}
+
 // loadConfig loads and validates a single configuration file
 func loadConfig(configFile string) (Config, error) {
 	var config Config
 	if _, err := toml.DecodeFile(configFile, &config); err != nil {
 		return Config{}, fmt.Errorf("error reading config file %s: %s", configFile, err)
 	}
+	config.mergeEndpointBlocks()
 	return config, nil
 }
 
@@ -179,6 +716,9 @@ func loadConfigFiles(configFiles []string) ([]ConfigWithSource, error) {
 		})
 	}
 
+	if err := setSecretResolvers(configsWithSource); err != nil {
+		return nil, err
+	}
 	return configsWithSource, nil
 }
 
@@ -207,6 +747,18 @@ func setupHTTPClient(configTimeout, cliTimeout int) *http.Client {
 	}
 }
 
+// resolveUserAgent returns the User-Agent to send, preferring the target override,
+// then the global setting, then the default vitals/<version>
+func resolveUserAgent(globalUA, targetUA string) string {
+	if targetUA != "" {
+		return targetUA
+	}
+	if globalUA != "" {
+		return globalUA
+	}
+	return "vitals/" + version
+}
+
 // setupColorOutput returns colored output functions
 func setupColorOutput() (func(a ...interface{}) string, func(a ...interface{}) string, func(a ...interface{}) string) {
 	return color.New(color.FgGreen).SprintFunc(),
@@ -216,62 +768,347 @@ func setupColorOutput() (func(a ...interface{}) string, func(a ...interface{}) s
 
 // EndpointResult represents the result of checking a single endpoint
 type EndpointResult struct {
-	URL          string
+	// BaseURL and Endpoint are the parts URL was built from
+	BaseURL  string
+	Endpoint string
+
+	URL    string
+	Method string
+
+	// Name and Description are the endpoint's optional display name and summary
+	Name        string
+	Description string
+
 	StatusCode   int
 	ResponseBody string
 	Error        error
 	Duration     time.Duration
 	Success      bool
+
+	// DNSDuration is the part of Duration spent resolving hostnames
+	DNSDuration time.Duration
+
+	// ConnectDuration and TLSDuration are the parts spent connecting and in TLS handshakes,
+	// and TTFB the time to the first response byte
+	ConnectDuration time.Duration
+	TLSDuration     time.Duration
+	TTFB            time.Duration
+
+	// CallbackDuration is how long a webhook check's callback took to arrive
+	CallbackDuration time.Duration
+
+	// ClockSkew is how far ahead of the local clock the response's Date header is, negative
+	// when it is behind
+	ClockSkew time.Duration
+
+	// CertExpiry is when the server's certificate expires, zero for responses not over TLS
+	CertExpiry time.Time
+
+	// ConnReused is set when the request went over a kept-alive connection
+	ConnReused bool
+
+	// RequestID is the X-Request-ID sent when trace headers are enabled
+	RequestID string
+
+	// Host is the Host header sent, and RemoteAddrs the IP addresses dialed for the request,
+	// which tell which record of a multi-address host a failed connection went to
+	Host        string
+	RemoteAddrs []string
+
+	// RateLimited is set for a failed check the server throttled with a 429 (or a 503 with
+	// Retry-After), and RetryAfter is when it said to come back
+	RateLimited bool
+	RetryAfter  time.Duration
+
+	// Skipped is set for an endpoint left unchecked because the circuit breaker found its
+	// host down
+	Skipped bool
+
+	// Attempts counts the requests a check took with retries, and Maintenance is set for
+	// checks made during the target's maintenance window; both shape State
+	Attempts    int
+	Maintenance bool
+
+	// FailureReason explains why an otherwise completed request failed its assertions
+	FailureReason string
+
+	// Components lists non-passing components reported by an application/health+json response
+	Components []HealthComponent
+
+	// HeaderWarnings lists missing or misconfigured security headers found by the audit
+	HeaderWarnings []string
+
+	// ContentEncoding is the response Content-Encoding, with the body size on the wire and decoded
+	ContentEncoding string
+	CompressedSize  int
+	BodySize        int
+
+	// ContentType is the response Content-Type header
+	ContentType string
+
+	// LinkedFrom is the page a crawled link was found on, empty for configured endpoints
+	LinkedFrom string
+
+	// ContentChanged is set when drift detection saw a different body than the last run
+	ContentChanged bool
+
+	// ExpectedFailure is how an expect_failure check failed, which made it pass
+	ExpectedFailure string
+
+	// RunbookURL is the target's runbook, reported alongside failures
+	RunbookURL string
+}
+
+// maxTargetWorkers bounds the goroutines checking one target's endpoints, so configs with tens
+// of thousands of checks don't start a goroutine (and open a connection) for each at once
+const maxTargetWorkers = 64
+
+// checkJob is one base URL and endpoint waiting for a worker
+type checkJob struct {
+	baseURL  string
+	endpoint EndpointConfig
 }
 
-// processTarget handles checking all endpoints for a single target
-func processTarget(client *http.Client, target TargetConfig, statusRanges []StatusRange, sem chan struct{}, verbose bool) []EndpointResult {
+// processTarget checks every endpoint of a target on a bounded pool of workers fed from a job
+// queue. Cancelling ctx stops handing out jobs, and checks that never started are left out
+func processTarget(ctx context.Context, client *http.Client, target TargetConfig, checks ResponseChecks, state *StateStore, sem chan struct{}, verbose bool) []EndpointResult {
 	resultsCount := len(target.BaseURLs) * len(target.Endpoints)
-	resultsChan := make(chan EndpointResult, resultsCount)
+	workers := min(resultsCount, maxTargetWorkers)
+	if sem != nil {
+		workers = min(workers, cap(sem))
+	}
+
+	// A session's endpoints are checked one at a time, in order, sharing the run's cookies
+	if target.UseCookies {
+		client = withCookieJar(client)
+		workers = min(workers, 1)
+	}
 
-	for _, baseURL := range target.BaseURLs {
-		for _, endpoint := range target.Endpoints {
-			go func(baseURL, endpoint string) {
-				// If semaphore is provided, use it to limit concurrency
+	breaker := newCircuitBreaker(target.CircuitBreaker)
+	jobs := make(chan checkJob)
+	go func() {
+		defer close(jobs)
+		for _, baseURL := range target.BaseURLs {
+			for _, endpoint := range target.Endpoints {
+				select {
+				case jobs <- checkJob{baseURL: baseURL, endpoint: endpoint}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	resultsChan := make(chan EndpointResult, workers)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				// Wait for the host to answer a first check before sending it more, and before
+				// taking a semaphore slot so waiting doesn't hold one
+				release := breaker.probe(job.baseURL)
+
+				// If semaphore is provided, use it to limit concurrency across targets
 				if sem != nil {
-					sem <- struct{}{}        // Acquire
-					defer func() { <-sem }() // Release
+					select {
+					case sem <- struct{}{}: // Acquire
+					case <-ctx.Done():
+						release()
+						continue
+					}
 				}
 
-				resultsChan <- checkEndpoint(client, baseURL, endpoint, target, statusRanges, verbose)
-			}(baseURL, endpoint)
-		}
+				var result EndpointResult
+				endpointTarget, endpointChecks := target.forEndpoint(job.endpoint), checks.forEndpoint(job.endpoint)
+				if breaker.open(job.baseURL) {
+					result = skippedResult(job, endpointTarget)
+				} else {
+					endpointClient := client
+					if job.endpoint.Timeout > 0 {
+						endpointClient = withEndpointTimeout(client, time.Duration(job.endpoint.Timeout)*time.Second)
+					}
+					result = checkWithRetries(func(attempt int) EndpointResult {
+						attemptClient := endpointClient
+						if attempt > 1 {
+							// A retry goes to the server again rather than getting the shared failure
+							attemptClient = withoutDedupe(endpointClient)
+						}
+						checked := checkEndpoint(attemptClient, job.baseURL, job.endpoint.Path, endpointTarget, endpointChecks, state, verbose)
+						if endpointTarget.ExpectFailure {
+							checked = invertResult(checked)
+						}
+						return checked
+					}, target.Retries)
+					breaker.record(result)
+				}
+				release()
+				result.Maintenance = target.inMaintenance(time.Now())
+				result.Name = job.endpoint.Name
+				result.Description = job.endpoint.Description
+
+				if sem != nil {
+					<-sem // Release
+				}
+				resultsChan <- result
+			}
+		}()
 	}
+	go func() {
+		wg.Wait()
+		close(resultsChan)
+	}()
 
-	// Collect results from the channel until it's closed
+	// Collect results until every worker has finished
 	results := make([]EndpointResult, 0, resultsCount)
-
-	for range resultsCount {
-		results = append(results, <-resultsChan)
+	for result := range resultsChan {
+		results = append(results, result)
 	}
 
 	return results
 }
 
+// requestMethod is the method every endpoint check of a target is sent with
+func requestMethod(target TargetConfig) string {
+	if target.CORS != nil {
+		return "OPTIONS"
+	}
+	if target.Method != "" {
+		return strings.ToUpper(target.Method)
+	}
+	return "GET"
+}
+
+// requestBody returns the payload a target's requests send, nil for none
+func requestBody(target TargetConfig) ([]byte, error) {
+	switch {
+	case target.Body != "" && target.BodyFile != "":
+		return nil, fmt.Errorf("body and body_file can't both be set")
+	case target.BodyFile != "":
+		body, err := os.ReadFile(target.BodyFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading body file %s: %s", target.BodyFile, err)
+		}
+		return body, nil
+	case target.Body != "":
+		return []byte(target.Body), nil
+	}
+	return nil, nil
+}
+
+// bodyContentType is the Content-Type sent with a body unless a header sets one
+func bodyContentType(body []byte) string {
+	if json.Valid(body) {
+		return "application/json"
+	}
+	return "application/x-www-form-urlencoded"
+}
+
+// bodyDrainLimit is how much of an unread response body is drained before closing it
+const bodyDrainLimit = 64 << 10
+
 // checkEndpoint performs the HTTP request and checks the response
-func checkEndpoint(client *http.Client, baseURL, endpoint string, target TargetConfig, statusRanges []StatusRange, verbose bool) EndpointResult {
-	url := constructURL(baseURL, endpoint)
+func checkEndpoint(client *http.Client, baseURL, endpoint string, target TargetConfig, checks ResponseChecks, state *StateStore, verbose bool) EndpointResult {
+	if target.Plugin != nil {
+		return checkPlugin(client, baseURL, endpoint, target, checks, verbose)
+	}
+
+	url := withQueryParams(constructURL(baseURL, endpoint), target.QueryParams)
+	method := requestMethod(target)
 
 	result := EndpointResult{
-		URL: url,
+		BaseURL:    baseURL,
+		Endpoint:   endpoint,
+		URL:        url,
+		Method:     method,
+		RunbookURL: target.RunbookURL,
 	}
 
-	startTime := time.Now()
+	body, err := requestBody(target)
+	if err != nil {
+		result.Error = err
+		return result
+	}
 
-	req, err := http.NewRequest("GET", url, nil)
+	// Webhook checks hand the service a callback URL in place of {callback_url}
+	requestURL := url
+	var callback *pendingCallback
+	var callbackWait time.Duration
+	if target.Callback != nil {
+		if callbackWait, err = callbackTimeout(*target.Callback); err != nil {
+			result.Error = err
+			return result
+		}
+		if callback, err = awaitCallback(*target.Callback); err != nil {
+			result.Error = err
+			return result
+		}
+		defer callback.release()
+		requestURL = callback.substitute(url)
+		if body != nil {
+			body = []byte(callback.substitute(string(body)))
+		}
+	}
+	var reqBody io.Reader
+	if body != nil {
+		reqBody = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequest(method, requestURL, reqBody)
 	if err != nil {
-		result.Error = fmt.Errorf("error creating request: %s", err)
+		result.Error = fmt.Errorf("error creating request: %w", err)
 		return result
 	}
 
-	// Add headers
+	// Add headers, letting explicit headers win over the configured User-Agent, encodings,
+	// and body type
+	req.Header.Set("User-Agent", target.UserAgent)
+	req.Header.Set("Accept-Encoding", acceptEncoding)
+	if body != nil {
+		req.Header.Set("Content-Type", bodyContentType(body))
+	}
 	for key, value := range target.Headers {
-		req.Header.Add(key, value)
+		if isSecretReference(value) {
+			if value, err = expandSecret(value); err != nil {
+				result.Error = err
+				return result
+			}
+		}
+		if callback != nil {
+			value = callback.substitute(value)
+		}
+		// The transport ignores a Host header, so it overrides the request's host instead
+		if strings.EqualFold(key, "Host") {
+			req.Host = value
+			continue
+		}
+		req.Header.Set(key, value)
+	}
+	if target.BasicAuth != nil {
+		if err := setBasicAuth(req, *target.BasicAuth, target.Auth); err != nil {
+			result.Error = err
+			return result
+		}
+	}
+	if target.CORS != nil {
+		setPreflightHeaders(req, *target.CORS)
+	}
+	if target.TraceHeaders {
+		result.RequestID = setTraceHeaders(req)
+	}
+
+	// Revalidate against the validators stored by a previous passing run
+	var validators Validators
+	var revalidating bool
+	if target.ConditionalRequests && state != nil {
+		validators, revalidating = state.Validators(url)
+		if validators.ETag != "" {
+			req.Header.Set("If-None-Match", validators.ETag)
+		}
+		if validators.LastModified != "" {
+			req.Header.Set("If-Modified-Since", validators.LastModified)
+		}
 	}
 
 	// Send request
@@ -279,7 +1116,28 @@ func checkEndpoint(client *http.Client, baseURL, endpoint string, target TargetC
 		fmt.Printf("Sending request to %s\n", url)
 	}
 
-	resp, err := client.Do(req)
+	switch {
+	case !followsRedirects(target):
+		client = withoutRedirects(client)
+	case target.MaxRedirects > 0:
+		client = withMaxRedirects(client, target.MaxRedirects)
+	}
+	if target.Auth != nil {
+		if client, err = withAuth(client, *target.Auth); err != nil {
+			result.Error = err
+			return result
+		}
+	}
+
+	var trace requestTrace
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace.hooks()))
+
+	resp, startTime, err := sendRespectingRetryAfter(client, req, checks.RetryAfterLimit)
+	result.DNSDuration = trace.DNSDuration()
+	result.ConnectDuration, result.TLSDuration, result.TTFB = trace.Phases()
+	result.ConnReused = trace.Reused()
+	result.Host = cmp.Or(req.Host, req.URL.Host)
+	result.RemoteAddrs = trace.Addrs()
 	if err != nil {
 		result.Error = err
 		result.Duration = time.Since(startTime)
@@ -289,21 +1147,136 @@ func checkEndpoint(client *http.Client, baseURL, endpoint string, target TargetC
 
 	result.StatusCode = resp.StatusCode
 	result.Duration = time.Since(startTime)
+	var dated bool
+	result.ClockSkew, dated = clockSkew(resp.Header.Get("Date"), startTime, startTime.Add(result.Duration))
+	result.CertExpiry = certExpiry(resp.TLS)
+	result.ContentType = resp.Header.Get("Content-Type")
+	result.ContentEncoding = resp.Header.Get("Content-Encoding")
+
+	if checks.SkipBody && !verbose && !isHealthJSON(result.ContentType) {
+		// Drain a small body so the connection can be reused; larger ones are cut off on close
+		io.Copy(io.Discard, io.LimitReader(resp.Body, bodyDrainLimit))
+	} else {
+		raw, err := io.ReadAll(resp.Body)
+		if err != nil {
+			result.Error = fmt.Errorf("error reading response body: %w", err)
+			return result
+		}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		result.Error = fmt.Errorf("error reading response body: %s", err)
+		// Setting Accept-Encoding ourselves disables transparent decompression, so decode here
+		result.CompressedSize = len(raw)
+		body, err := decodeBody(result.ContentEncoding, raw)
+		if err != nil {
+			result.Error = err
+			return result
+		}
+		result.BodySize = len(body)
+		result.ResponseBody = string(body)
+	}
+
+	result.HeaderWarnings = auditHeaders(resp.Header, resp.TLS != nil, checks.Audits)
+
+	// An unchanged resource is still the one that passed every assertion last time
+	if revalidating && resp.StatusCode == http.StatusNotModified {
+		result.Success = true
+		return result
+	}
+
+	result.Success = isStatusAcceptable(resp.StatusCode, target.StatusCodes, checks.StatusRanges)
+	if !result.Success {
+		result.FailureReason = unexpectedRedirect(resp)
+	}
+
+	// Throttling is reported apart from genuine failures
+	if !result.Success && isRateLimited(resp) {
+		result.RateLimited = true
+		result.FailureReason, result.RetryAfter = rateLimitReason(resp)
 		return result
 	}
 
-	result.ResponseBody = string(body)
-	result.Success = isStatusAcceptable(resp.StatusCode, target.StatusCodes, statusRanges)
+	// Health+JSON responses carry their own verdict and component details
+	var healthReason string
+	if isHealthJSON(resp.Header.Get("Content-Type")) {
+		healthReason, result.Components = evaluateHealth(result.ResponseBody)
+	}
+
+	// Body assertions and drift detection see the transformed body; output keeps the original
+	checkedBody := result.ResponseBody
+	var transformReason string
+	if checks.Transform != nil {
+		if checkedBody, err = checks.Transform.apply(result.ResponseBody); err != nil {
+			transformReason = err.Error()
+		}
+	}
+
+	if result.Success {
+		reason := transformReason
+		if reason == "" {
+			reason = checkResponse(resp, checkedBody, target, checks)
+		}
+		if reason == "" {
+			reason = checkPhases(result, target)
+		}
+		if reason == "" {
+			reason = checkClockSkew(result.ClockSkew, dated, target.MaxClockSkewMS)
+		}
+		if reason == "" {
+			reason = checkCertExpiry(result.CertExpiry, time.Now(), target.CertExpiryWarnDays)
+		}
+		if reason == "" && checks.Assertion != nil {
+			reason = checks.Assertion.check(resp, result.ResponseBody, result.Duration)
+		}
+		if reason == "" && checks.Script != nil {
+			reason = checks.Script.check(resp, result.ResponseBody, result)
+		}
+		if reason == "" {
+			reason = healthReason
+		}
+		if reason != "" {
+			result.Success = false
+			result.FailureReason = reason
+		}
+	}
+
+	// An accepted trigger still has to be called back
+	if callback != nil && result.Success {
+		var reason string
+		if result.CallbackDuration, reason = callback.wait(*target.Callback, callbackWait); reason != "" {
+			result.Success = false
+			result.FailureReason = reason
+		}
+	}
+
+	// Compare passing bodies with the last run's fingerprint, then remember the new one
+	if target.DetectDrift && state != nil && result.Success {
+		fingerprint := contentFingerprint(checkedBody, target, checks)
+		if previous, ok := state.Snapshot(url); ok && previous != fingerprint {
+			result.ContentChanged = true
+			if target.FailOnDrift {
+				result.Success = false
+				result.FailureReason = "content changed since last run"
+			}
+		}
+		state.SetSnapshot(url, fingerprint)
+	}
+
+	// Only remember validators for responses that passed, so a 304 never masks a failure
+	if target.ConditionalRequests && state != nil && result.Success {
+		fresh := Validators{
+			ETag:         resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+		}
+		if fresh != (Validators{}) && fresh != validators {
+			state.SetValidators(url, fresh)
+		}
+	}
 
 	return result
 }
 
 // constructURL builds the full URL from base URL and endpoint
 func constructURL(baseURL, endpoint string) string {
+	baseURL = normalizeBaseURL(baseURL)
 	if endpoint == "" {
 		return baseURL
 	}
@@ -332,7 +1305,7 @@ func printDivider(widths map[string]int, neutral func(a ...interface{}) string,
 }
 
 // printRow prints a single row of the table with proper padding
-func printRow(method, url string, status interface{}, duration, result string, widths map[string]int, rowColor, neutral func(a ...interface{}) string) {
+func printRow(method, url string, status interface{}, duration, result string, widths map[string]int, rowColor, durationColor, neutral func(a ...interface{}) string) {
 	// Split the row into parts for proper coloring
 	parts := []string{
 		fmt.Sprintf(" %-*s ", widths["METHOD"], method),
@@ -347,7 +1320,11 @@ func printRow(method, url string, status interface{}, duration, result string, w
 	coloredRow += neutral("│")
 
 	for i, part := range parts {
-		coloredRow += rowColor(part)
+		if i == 3 {
+			coloredRow += durationColor(part)
+		} else {
+			coloredRow += rowColor(part)
+		}
 		if i < len(parts)-1 {
 			coloredRow += neutral("│")
 		}
@@ -357,11 +1334,48 @@ func printRow(method, url string, status interface{}, duration, result string, w
 	fmt.Println(coloredRow)
 }
 
+// printDetailLine prints a line of free text spanning the full table width, truncated to fit
+func printDetailLine(text string, totalWidth int, neutral func(a ...interface{}) string) {
+	width := totalWidth - 4 // Account for borders and spacing
+	if len(text) > width {
+		text = text[:width-3] + "..."
+	}
+	fmt.Print(neutral("│ "))
+	fmt.Printf("%-*s", width, text)
+	fmt.Println(neutral(" │"))
+}
+
+// printRunbookLine prints a runbook link under a failing row, letting it overflow the table
+// rather than truncating it so it stays clickable
+func printRunbookLine(url string, totalWidth int, neutral func(a ...interface{}) string) {
+	text := "Runbook: " + url
+	if len(text) <= totalWidth-4 {
+		printDetailLine(text, totalWidth, neutral)
+		return
+	}
+	fmt.Println(neutral("│ ") + text)
+}
+
 // printResults formats and prints the collected endpoint results in a table
-func printResults(results []EndpointResult, targetName string, configName string, green, red func(a ...interface{}) string, verbose bool) {
+func printResults(results []EndpointResult, targetName string, configName string, ownership Ownership, labels Labels, green, red func(a ...interface{}) string, latency latencyBands, apdexThreshold time.Duration, verbose bool, maxRows int) {
 	var successful, failed int
+	var states stateCounts
 	var totalDuration time.Duration
 
+	// The summary counts every result, but only the rows that fit are printed
+	for _, result := range results {
+		state := result.State()
+		switch {
+		case state.failing():
+			failed++
+		case state.passing():
+			successful++
+		}
+		states.add(state)
+		totalDuration += result.Duration
+	}
+	rows, hiddenFailed, hiddenPassed := limitRows(results, maxRows, resultFailed)
+
 	// Get neutral color for borders
 	_, _, neutral := setupColorOutput()
 
@@ -377,26 +1391,55 @@ func printResults(results []EndpointResult, targetName string, configName string
 	columnNames := []string{"METHOD", "URL", "STATUS", "DURATION", "RESULT"}
 
 	// Pre-process results to determine column widths
-	tableData := make([][]string, 0, len(results))
-	for _, result := range results {
-		method := "GET"
-		urlStr := result.URL
+	tableData := make([][]string, 0, len(rows))
+	for _, result := range rows {
+		method := result.Method
+		urlStr := displayURL(result.URL)
+		if result.Name != "" {
+			urlStr = result.Name
+		}
 		var status interface{}
 		duration := fmt.Sprintf("%.2fs", result.Duration.Seconds())
 		var resultStr string
 
-		if result.Error != nil {
-			status = "ERROR"
+		if result.Skipped {
+			status = "-"
+			resultStr = "SKIPPED (" + result.FailureReason + ")"
+		} else if result.State() == StateMaintenance {
+			status = result.StatusCode
+			if result.Error != nil {
+				status = errorClassLabels[result.ErrorClass()]
+			}
+			resultStr = "MAINTENANCE (" + describeFailure(result) + ")"
+		} else if result.Error != nil {
+			status = errorClassLabels[result.ErrorClass()]
 			resultStr = fmt.Sprintf("Error: %v", result.Error)
-			failed++
 		} else {
 			status = result.StatusCode
 			if result.Success {
 				resultStr = "Success"
-				successful++
+				if result.Attempts > 1 {
+					resultStr = fmt.Sprintf("Flaky (passed on attempt %d)", result.Attempts)
+				}
+				if result.ExpectedFailure != "" {
+					if result.StatusCode == 0 {
+						status = "-"
+					}
+					resultStr += " (failed as expected: " + result.ExpectedFailure + ")"
+				}
+				if result.ContentChanged {
+					resultStr += " (content changed)"
+				}
+			} else if result.RateLimited {
+				resultStr = "RATE LIMITED"
+				if result.RetryAfter > 0 {
+					resultStr += fmt.Sprintf(" (retry after %s)", result.RetryAfter.Round(time.Second))
+				}
 			} else {
 				resultStr = "Failed"
-				failed++
+				if result.FailureReason != "" {
+					resultStr += ": " + result.FailureReason
+				}
 			}
 		}
 
@@ -404,8 +1447,8 @@ func printResults(results []EndpointResult, targetName string, configName string
 		if len(method) > widths["METHOD"] {
 			widths["METHOD"] = len(method)
 		}
-		if len(urlStr) > widths["URL"] {
-			widths["URL"] = len(urlStr)
+		if n := utf8.RuneCountInString(urlStr); n > widths["URL"] {
+			widths["URL"] = n
 		}
 		statusLen := len(fmt.Sprintf("%v", status))
 		if statusLen > widths["STATUS"] {
@@ -419,7 +1462,6 @@ func printResults(results []EndpointResult, targetName string, configName string
 		}
 
 		tableData = append(tableData, []string{method, urlStr, fmt.Sprintf("%v", status), duration, resultStr})
-		totalDuration += result.Duration
 	}
 
 	// Get terminal width
@@ -458,6 +1500,31 @@ func printResults(results []EndpointResult, targetName string, configName string
 		totalWidth += widths[col] + 3 // width + 2 for padding + 1 for border
 	}
 
+	// Summarize every result, widening the RESULT column when the summary would not fit
+	total := len(results)
+	var summaryStr string
+	if total > 0 {
+		avgDuration := totalDuration / time.Duration(total)
+		summaryStr = fmt.Sprintf("Total: %d, Success: %d, Failed: %d%s, Avg: %.2fs",
+			total, successful, failed, states, avgDuration.Seconds())
+		if latency := newLatencyPercentiles(results); latency != nil {
+			summaryStr += ", " + latency.String()
+		}
+		if apdex := newApdex(results, apdexThreshold); apdex != nil {
+			summaryStr += ", Apdex: " + apdex.String()
+		}
+		if errs := countErrors(results); errs != nil {
+			summaryStr += ", Errors: " + errs.String()
+		}
+		if days := minCertDaysLeft(results, time.Now()); days != nil {
+			summaryStr += ", Cert: " + days.String()
+		}
+	}
+	if extra := len(summaryStr) + 4 - totalWidth; extra > 0 {
+		widths["RESULT"] += extra
+		totalWidth += extra
+	}
+
 	// Construct the title with target and config file names
 	title := fmt.Sprintf("[%s] from %s", targetName, configName)
 
@@ -471,9 +1538,15 @@ func printResults(results []EndpointResult, targetName string, configName string
 	titleRow := "│" + strings.Repeat(" ", padding) + title
 	titleRow += strings.Repeat(" ", totalWidth-2-padding-len(title)) + "│"
 	fmt.Println(neutral(titleRow))
+	if owners := ownership.String(); owners != "" {
+		printDetailLine(owners, totalWidth, neutral)
+	}
+	if len(labels) > 0 {
+		printDetailLine("Labels: "+labels.String(), totalWidth, neutral)
+	}
 
 	printDivider(widths, neutral, "┬")
-	printRow("METHOD", "URL", "STATUS", "DURATION", "RESULT", widths, neutral, neutral)
+	printRow("METHOD", "URL", "STATUS", "DURATION", "RESULT", widths, neutral, neutral, neutral)
 	printDivider(widths, neutral, "┼")
 
 	// Print table rows
@@ -481,30 +1554,61 @@ func printResults(results []EndpointResult, targetName string, configName string
 		method := row[0]
 		url := row[1]
 		// Truncate URL if it's too long for the column
-		if len(url) > widths["URL"] {
-			url = url[:widths["URL"]-3] + "..."
+		if runes := []rune(url); len(runes) > widths["URL"] {
+			url = string(runes[:widths["URL"]-3]) + "..."
 		}
 		status := row[2]
 		duration := row[3]
 		resultStr := row[4]
 
-		if strings.HasPrefix(resultStr, "Error:") || resultStr == "Failed" {
+		// Durations of answered requests take their latency band's color when one is configured
+		rowColor := green
+		switch state := rows[i].State(); {
+		case state == StateError:
+			rowColor = errorColor(rows[i].ErrorClass(), red)
+		case state.failing():
+			rowColor = red
+		case state != StatePassed:
+			rowColor = color.New(color.FgYellow).SprintFunc()
+		}
+		durationColor := rowColor
+		if rows[i].Error == nil && !rows[i].Skipped {
+			if bandColor := latency.color(rows[i].Duration, green, red); bandColor != nil {
+				durationColor = bandColor
+			}
+		}
+
+		if resultFailed(rows[i]) {
 			// Color the row content red for failures, but borders neutral
-			printRow(method, url, status, duration, resultStr, widths, red, neutral)
+			printRow(method, url, status, duration, resultStr, widths, rowColor, durationColor, neutral)
+			if rows[i].Name != "" && !verbose {
+				printDetailLine("URL: "+rows[i].URL, totalWidth, neutral)
+			}
+			if rows[i].RunbookURL != "" {
+				printRunbookLine(rows[i].RunbookURL, totalWidth, neutral)
+			}
 		} else {
 			// Color the row content green for successes, but borders neutral
-			printRow(method, url, status, duration, resultStr, widths, green, neutral)
+			printRow(method, url, status, duration, resultStr, widths, rowColor, durationColor, neutral)
+		}
+
+		// Named endpoints keep their URL in view when verbose, along with the description
+		if verbose && rows[i].Name != "" {
+			printDetailLine("URL: "+rows[i].URL, totalWidth, neutral)
+		}
+		if verbose && rows[i].Description != "" {
+			printDetailLine("Description: "+rows[i].Description, totalWidth, neutral)
 		}
 
 		// If verbose and there's response body, print it under the row
-		if verbose && len(results[i].ResponseBody) > 0 && results[i].Error == nil {
+		if verbose && len(rows[i].ResponseBody) > 0 && rows[i].Error == nil {
 			responseWidth := totalWidth - 4 // Account for borders and spacing
 			fmt.Print(neutral("│ "))
 			fmt.Print(strings.Repeat(" ", responseWidth))
 			fmt.Println(neutral(" │"))
 
 			// Truncate response body if too long
-			responseBody := results[i].ResponseBody
+			responseBody := rows[i].ResponseBody
 			maxBodyLen := responseWidth - 11 // Account for "Response: "
 			if len(responseBody) > maxBodyLen {
 				responseBody = responseBody[:maxBodyLen-3] + "..."
@@ -514,17 +1618,64 @@ func printResults(results []EndpointResult, targetName string, configName string
 			fmt.Printf("Response: %-*s", maxBodyLen, responseBody)
 			fmt.Println(neutral(" │"))
 		}
+
+		if rows[i].RequestID != "" && (verbose || resultFailed(rows[i])) {
+			printDetailLine("Request ID: "+rows[i].RequestID, totalWidth, neutral)
+		}
+		if verbose && rows[i].Error != nil && rows[i].Host != "" {
+			line := "Host: " + rows[i].Host
+			if len(rows[i].RemoteAddrs) > 0 {
+				line += ", tried " + strings.Join(rows[i].RemoteAddrs, ", ")
+			}
+			printDetailLine(line, totalWidth, neutral)
+		}
+		if verbose && rows[i].DNSDuration > 0 {
+			printDetailLine(fmt.Sprintf("DNS: %.3fs", rows[i].DNSDuration.Seconds()), totalWidth, neutral)
+		}
+		if verbose && rows[i].TTFB > 0 {
+			printDetailLine(phasesLine(rows[i]), totalWidth, neutral)
+		}
+		if verbose && rows[i].ClockSkew != 0 {
+			printDetailLine("Clock skew: server "+describeSkew(rows[i].ClockSkew), totalWidth, neutral)
+		}
+		if verbose && rows[i].CallbackDuration > 0 {
+			printDetailLine(fmt.Sprintf("Callback: %.3fs", rows[i].CallbackDuration.Seconds()), totalWidth, neutral)
+		}
+		if verbose && rows[i].Error == nil && !rows[i].Skipped && rows[i].Method != "PLUGIN" {
+			connection := "new"
+			if rows[i].ConnReused {
+				connection = "reused"
+			}
+			printDetailLine("Connection: "+connection, totalWidth, neutral)
+		}
+
+		// If verbose, show the transfer encoding and list health+json components that are not passing
+		if verbose && rows[i].Error == nil {
+			if isCompressed(rows[i].ContentEncoding) {
+				printDetailLine(fmt.Sprintf("Encoding: %s (%d bytes compressed, %d bytes decompressed)",
+					rows[i].ContentEncoding, rows[i].CompressedSize, rows[i].BodySize), totalWidth, neutral)
+			} else {
+				printDetailLine(fmt.Sprintf("Encoding: none (%d bytes)", rows[i].BodySize), totalWidth, neutral)
+			}
+
+			for _, component := range rows[i].Components {
+				line := fmt.Sprintf("Component %s: %s", component.Name, component.Status)
+				if component.Output != "" {
+					line += " - " + component.Output
+				}
+				printDetailLine(line, totalWidth, neutral)
+			}
+		}
+	}
+
+	if line := hiddenRowsLine(hiddenFailed, hiddenPassed); line != "" {
+		printDetailLine(line, totalWidth, neutral)
 	}
 
 	// Print summary statistics row
-	total := successful + failed
 	if total > 0 {
 		printDivider(widths, neutral, "┴")
 
-		avgDuration := totalDuration / time.Duration(total)
-		summaryStr := fmt.Sprintf("Total: %d, Success: %d, Failed: %d, Avg: %.2fs",
-			total, successful, failed, avgDuration.Seconds())
-
 		// Create a single row for the summary that spans all columns
 		fmt.Print(neutral("│ "))
 		if failed > 0 {
@@ -542,13 +1693,47 @@ func printResults(results []EndpointResult, targetName string, configName string
 
 // JSONResult represents a JSON-serializable version of EndpointResult
 type JSONResult struct {
-	URL          string  `json:"url"`
-	Method       string  `json:"method"`
-	StatusCode   int     `json:"status_code,omitempty"`
-	Duration     float64 `json:"duration_seconds"`
-	Success      bool    `json:"success"`
-	Error        string  `json:"error,omitempty"`
-	ResponseBody string  `json:"response_body,omitempty"`
+	URL          string      `json:"url"`
+	Name         string      `json:"name,omitempty"`
+	Description  string      `json:"description,omitempty"`
+	Method       string      `json:"method"`
+	StatusCode   int         `json:"status_code,omitempty"`
+	Duration     float64     `json:"duration_seconds"`
+	DNSDuration  float64     `json:"dns_seconds,omitempty"`
+	Connect      float64     `json:"connect_seconds,omitempty"`
+	TLSHandshake float64     `json:"tls_handshake_seconds,omitempty"`
+	TTFB         float64     `json:"ttfb_seconds,omitempty"`
+	Callback     float64     `json:"callback_seconds,omitempty"`
+	ClockSkew    float64     `json:"clock_skew_seconds,omitempty"`
+	CertExpiry   string      `json:"cert_expiry,omitempty"`
+	CertDaysLeft *int        `json:"cert_days_remaining,omitempty"`
+	ConnReused   bool        `json:"connection_reused"`
+	RequestID    string      `json:"request_id,omitempty"`
+	Host         string      `json:"host,omitempty"`
+	RemoteAddrs  []string    `json:"remote_addrs,omitempty"`
+	Success      bool        `json:"success"`
+	State        ResultState `json:"state"`
+	Attempts     int         `json:"attempts,omitempty"`
+	RateLimited  bool        `json:"rate_limited,omitempty"`
+	Skipped      bool        `json:"skipped,omitempty"`
+	Error        string      `json:"error,omitempty"`
+	ErrorClass   ErrorClass  `json:"error_class,omitempty"`
+	ResponseBody string      `json:"response_body,omitempty"`
+
+	FailureReason  string            `json:"failure_reason,omitempty"`
+	Components     []HealthComponent `json:"components,omitempty"`
+	HeaderWarnings []string          `json:"header_warnings,omitempty"`
+
+	LinkedFrom string `json:"linked_from,omitempty"`
+
+	// DisplayURL is URL with its host in Unicode, for the HTML report
+	DisplayURL      string `json:"-"`
+	ContentEncoding string `json:"content_encoding,omitempty"`
+	CompressedBytes int    `json:"compressed_bytes,omitempty"`
+	BodyBytes       int    `json:"body_bytes,omitempty"`
+	ContentChanged  bool   `json:"content_changed,omitempty"`
+	ExpectedFailure string `json:"expected_failure,omitempty"`
+	RunbookURL      string `json:"runbook_url,omitempty"`
 }
 
 // JSONTargetResults represents results for a single target in JSON format
@@ -557,6 +1742,12 @@ type JSONTargetResults struct {
 	ConfigFile string       `json:"config_file"` // Added config file name
 	Results    []JSONResult `json:"results"`
 	Summary    JSONSummary  `json:"summary"`
+	Ownership
+	Labels Labels `json:"labels,omitempty"`
+	Notes  string `json:"notes,omitempty"`
+
+	// HiddenRows describes the rows an HTML report left out under --max-rows-per-target
+	HiddenRows string `json:"-"`
 }
 
 // JSONSummary contains summary statistics for a target
@@ -565,49 +1756,135 @@ type JSONSummary struct {
 	Successful  int     `json:"successful"`
 	Failed      int     `json:"failed"`
 	AvgDuration float64 `json:"avg_duration_seconds"`
+
+	// Latency gives the spread of durations when more than one check was answered
+	Latency *LatencyPercentiles `json:"latency,omitempty"`
+
+	// Errors counts the request errors by class, e.g. {"timeout": 3}
+	Errors errorCounts `json:"errors,omitempty"`
+
+	// ReusedConnections counts the requests sent over a kept-alive connection
+	ReusedConnections int `json:"reused_connections"`
+
+	// stateCounts counts the skipped, maintenance, flaky, and rate limited checks, which are
+	// neither successful nor failed
+	stateCounts
+
+	// Apdex scores the checks when the target sets apdex_threshold_ms
+	Apdex *Apdex `json:"apdex,omitempty"`
+
+	// CertDaysLeft is the fewest days left on a server certificate of the target's checks
+	CertDaysLeft *CertDays `json:"cert_days_remaining,omitempty"`
 }
 
 // JSONOutput represents the complete JSON output format
 type JSONOutput struct {
+	// Run identifies the run; the serve API leaves it out as its results span runs
+	Run     *RunInfo                     `json:"run,omitempty"`
 	Targets map[string]JSONTargetResults `json:"targets"`
 }
 
 // HTMLTemplateData represents the data passed to the HTML template
 type HTMLTemplateData struct {
-	Targets map[string]JSONTargetResults
-	Verbose bool
+	Run           *RunInfo
+	Targets       map[string]JSONTargetResults
+	Verbose       bool
+	AuditFindings []AuditFinding
+}
+
+// newJSONResult converts an endpoint result to its JSON form, including the response body
+// only in verbose mode
+func newJSONResult(result EndpointResult, verbose bool) JSONResult {
+	jsonResult := JSONResult{
+		URL:          result.URL,
+		Name:         result.Name,
+		Description:  result.Description,
+		Method:       result.Method,
+		Duration:     result.Duration.Seconds(),
+		DNSDuration:  result.DNSDuration.Seconds(),
+		Connect:      result.ConnectDuration.Seconds(),
+		TLSHandshake: result.TLSDuration.Seconds(),
+		TTFB:         result.TTFB.Seconds(),
+		Callback:     result.CallbackDuration.Seconds(),
+		ClockSkew:    result.ClockSkew.Seconds(),
+		ConnReused:   result.ConnReused,
+		RequestID:    result.RequestID,
+		Success:      result.Success,
+		State:        result.State(),
+		RateLimited:  result.RateLimited,
+		Skipped:      result.Skipped,
+
+		FailureReason: result.FailureReason,
+		Components:    result.Components,
+
+		HeaderWarnings:  result.HeaderWarnings,
+		LinkedFrom:      result.LinkedFrom,
+		DisplayURL:      displayURL(result.URL),
+		ContentChanged:  result.ContentChanged,
+		ExpectedFailure: result.ExpectedFailure,
+	}
+
+	if !result.CertExpiry.IsZero() {
+		days := certDaysLeft(result.CertExpiry, time.Now())
+		jsonResult.CertExpiry = result.CertExpiry.UTC().Format(time.RFC3339)
+		jsonResult.CertDaysLeft = &days
+	}
+
+	// Only report wire sizes separately when the body was actually compressed
+	if result.Error == nil {
+		jsonResult.BodyBytes = result.BodySize
+		if isCompressed(result.ContentEncoding) {
+			jsonResult.ContentEncoding = result.ContentEncoding
+			jsonResult.CompressedBytes = result.CompressedSize
+		}
+	}
+
+	if result.Error != nil {
+		jsonResult.Error = result.Error.Error()
+		jsonResult.ErrorClass = result.ErrorClass()
+		jsonResult.Host = result.Host
+		jsonResult.RemoteAddrs = result.RemoteAddrs
+	} else {
+		jsonResult.StatusCode = result.StatusCode
+	}
+
+	// Retried checks say how many requests they took
+	if result.Attempts > 1 {
+		jsonResult.Attempts = result.Attempts
+	}
+
+	// Only failures point at the runbook
+	if result.Error != nil || !result.Success {
+		jsonResult.RunbookURL = result.RunbookURL
+	}
+
+	// Include response body only in verbose mode
+	if verbose && len(result.ResponseBody) > 0 && result.Error == nil {
+		jsonResult.ResponseBody = result.ResponseBody
+	}
+
+	return jsonResult
 }
 
 // printJSONResults formats and prints the collected endpoint results as JSON
-func printJSONResults(results []EndpointResult, targetName string, configName string, verbose bool) (JSONTargetResults, error) {
-	var successful, failed int
+func printJSONResults(results []EndpointResult, targetName string, configName string, ownership Ownership, apdexThreshold time.Duration, verbose bool) (JSONTargetResults, error) {
+	var successful, failed, reused int
+	var states stateCounts
 	var totalDuration time.Duration
 
 	// Convert to JSON-friendly format
 	jsonResults := make([]JSONResult, 0, len(results))
 	for _, result := range results {
-		jsonResult := JSONResult{
-			URL:      result.URL,
-			Method:   "GET",
-			Duration: result.Duration.Seconds(),
-			Success:  result.Success,
-		}
-
-		if result.Error != nil {
-			jsonResult.Error = result.Error.Error()
+		jsonResult := newJSONResult(result, verbose)
+		switch {
+		case jsonResult.State.failing():
 			failed++
-		} else {
-			jsonResult.StatusCode = result.StatusCode
-			if result.Success {
-				successful++
-			} else {
-				failed++
-			}
+		case jsonResult.State.passing():
+			successful++
 		}
-
-		// Include response body only in verbose mode
-		if verbose && len(result.ResponseBody) > 0 && result.Error == nil {
-			jsonResult.ResponseBody = result.ResponseBody
+		states.add(jsonResult.State)
+		if result.ConnReused {
+			reused++
 		}
 
 		jsonResults = append(jsonResults, jsonResult)
@@ -615,7 +1892,7 @@ func printJSONResults(results []EndpointResult, targetName string, configName st
 	}
 
 	// Create summary
-	total := successful + failed
+	total := len(results)
 	var avgDuration float64
 	if total > 0 {
 		avgDuration = totalDuration.Seconds() / float64(total)
@@ -626,7 +1903,14 @@ func printJSONResults(results []EndpointResult, targetName string, configName st
 		Successful:  successful,
 		Failed:      failed,
 		AvgDuration: avgDuration,
+		Latency:     newLatencyPercentiles(results),
+		Errors:      countErrors(results),
+
+		ReusedConnections: reused,
+		Apdex:             newApdex(results, apdexThreshold),
+		CertDaysLeft:      minCertDaysLeft(results, time.Now()),
 	}
+	summary.stateCounts = states
 
 	// Create target results
 	targetResults := JSONTargetResults{
@@ -634,19 +1918,39 @@ func printJSONResults(results []EndpointResult, targetName string, configName st
 		ConfigFile: configName, // Include config file name
 		Results:    jsonResults,
 		Summary:    summary,
+		Ownership:  ownership,
 	}
 
 	return targetResults, nil
 }
 
 // generateHTMLResults formats the endpoint results into HTML using the embedded template
-func generateHTMLResults(allTargets map[string]JSONTargetResults, verbose bool) (string, error) {
+func generateHTMLResults(run *RunInfo, allTargets map[string]JSONTargetResults, verbose bool) (string, error) {
 	// Create template data
 	data := HTMLTemplateData{
+		Run:     run,
 		Targets: allTargets,
 		Verbose: verbose,
 	}
 
+	// Collect header audit warnings for the dedicated report section, in template order
+	keys := make([]string, 0, len(allTargets))
+	for k := range allTargets {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		for _, result := range allTargets[key].Results {
+			if len(result.HeaderWarnings) > 0 {
+				data.AuditFindings = append(data.AuditFindings, AuditFinding{
+					Target:   allTargets[key].Target,
+					URL:      result.URL,
+					Warnings: result.HeaderWarnings,
+				})
+			}
+		}
+	}
+
 	// Parse the template from embedded file
 	tmpl, err := template.ParseFS(templateFS, "templates/report.html")
 	if err != nil {
@@ -671,157 +1975,168 @@ func getTerminalWidth() int {
 	return width
 }
 
+// subcommands maps the first CLI argument to an alternative entry point returning the exit code
+var subcommands = map[string]func(args []string) int{
+	"import":    runImport,
+	"check-one": runCheckOne,
+	"wait":      runWait,
+	"serve":     runServe,
+	"mock":      runMock,
+	"graph":     runGraph,
+	"history":   runHistory,
+	"incidents": runIncidents,
+	"compare":   runCompare,
+	"top":       runTop,
+	"secret":    runSecret,
+	"service":   runService,
+	"explain":   runExplain,
+	"lint":      runLint,
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		if run, ok := subcommands[os.Args[1]]; ok {
+			os.Exit(run(os.Args[2:]))
+		}
+	}
+
 	flags := parseFlags()
+	runInfo := newRunInfo(time.Now())
+	summary := summaryReporter{dest: flags.summaryJSON, started: runInfo.Started, runID: runInfo.ID, labels: flags.labels}
 	configs, err := loadConfigFiles(flags.configFiles)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "%s\n", err)
-		os.Exit(1)
+		summary.exit(nil, 1, exitConfigError)
 	}
 
-	// Only print a newline in table mode
-	if !flags.jsonOutput && !flags.htmlOutput {
-		fmt.Println()
+	// Load persisted state only when a target needs it
+	var state *StateStore
+	if needsState(configs) {
+		state, err = loadState(flags.stateFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			summary.exit(nil, 1, exitConfigError)
+		}
 	}
 
-	// Always collect results for all targets in case of JSON or HTML output
-	collectResults := flags.jsonOutput || flags.htmlOutput
-	jsonOutput := JSONOutput{Targets: make(map[string]JSONTargetResults)}
-
-	// Use mutex to safely access the shared jsonOutput map from multiple goroutines
-	var outputMutex sync.Mutex
-	var wg sync.WaitGroup
-
-	// Track overall success/failure status
-	var overallSuccess = true
-	var successMutex sync.Mutex
+	// Pre-run hooks set up what the checks need, e.g. a tunnel; post-run hooks tear it down
+	if err := runPreRunHooks(configs); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		runPostRunHooks(configs, 0)
+		summary.exit(nil, 1, exitPreRunFailed)
+	}
 
-	// Create a semaphore if concurrency is limited
-	var sem chan struct{}
-	if flags.concurrency > 0 {
-		sem = make(chan struct{}, flags.concurrency)
+	// Only print a newline in table mode
+	if flags.tableOutput() {
+		fmt.Println()
 	}
 
-	// Create a map to store results for table printing
-	tableResults := make(map[string]struct {
-		results    []EndpointResult
-		targetName string
-		configName string
+	dedupe := newDedupeCache(flags.dedupe)
+	runs := runChecks(configs, runOptions{
+		timeout:      flags.timeout,
+		verbose:      flags.verbosity,
+		concurrency:  flags.concurrency,
+		auditHeaders: flags.auditHeaders,
+		crawlDepth:   flags.crawlDepth,
+		state:        state,
+		cassette:     flags.cassette,
+		labels:       flags.labels,
+		dedupe:       dedupe,
+		runID:        runInfo.ID,
+		saveBodies:   flags.saveBodies != "",
+		caFile:       flags.caFile,
+		insecure:     flags.insecure,
 	})
-	var tableResultsMutex sync.Mutex
-
-	// Process all configs concurrently
-	for _, configWithSource := range configs {
-		wg.Add(1)
-
-		go func(configWithSource ConfigWithSource) {
-			defer wg.Done()
-
-			config := configWithSource.Config
-			configName := configWithSource.Filename
-
-			// Set up HTTP client with timeout from this config
-			client := setupHTTPClient(config.Global.Timeout, flags.timeout)
-
-			// Create a wait group for targets within this config
-			var targetWg sync.WaitGroup
-
-			// Process each target from this config file concurrently
-			for targetName, target := range config.Targets {
-				targetWg.Add(1)
-
-				// Launch a goroutine for each target
-				go func(targetName string, target TargetConfig, configName string, client *http.Client) {
-					defer targetWg.Done()
-
-					// Create a unique key for this target in this config file
-					uniqueTargetKey := fmt.Sprintf("%s::%s", configName, targetName)
-
-					// Parse status ranges
-					var statusRanges []StatusRange
-					for _, rangeStr := range target.StatusRanges {
-						r, err := parseStatusRange(rangeStr)
-						if err != nil {
-							fmt.Fprintf(os.Stderr, "Error parsing status range '%s': %s\n", rangeStr, err)
-							continue
-						}
-						statusRanges = append(statusRanges, r)
-					}
-
-					// Default to 200 if no status codes or ranges specified
-					if len(target.StatusCodes) == 0 && len(statusRanges) == 0 {
-						target.StatusCodes = []int{200}
-					}
-
-					results := processTarget(client, target, statusRanges, sem, flags.verbosity)
-
-					// Check if any requests failed and update overall success status
-					for _, result := range results {
-						if result.Error != nil || !result.Success {
-							successMutex.Lock()
-							overallSuccess = false
-							successMutex.Unlock()
-							break
-						}
-					}
-
-					if collectResults {
-						jsonTargetResults, err := printJSONResults(results, targetName, configName, flags.verbosity)
-						if err != nil {
-							fmt.Fprintf(os.Stderr, "Error processing results: %s\n", err)
-						}
-
-						// Safely update the shared map
-						outputMutex.Lock()
-						jsonOutput.Targets[uniqueTargetKey] = jsonTargetResults
-						outputMutex.Unlock()
-					}
-
-					// Store results for table output
-					if !flags.jsonOutput && !flags.htmlOutput {
-						tableResultsMutex.Lock()
-						tableResults[uniqueTargetKey] = struct {
-							results    []EndpointResult
-							targetName string
-							configName string
-						}{
-							results:    results,
-							targetName: targetName,
-							configName: configName,
-						}
-						tableResultsMutex.Unlock()
-					}
-				}(targetName, target, configName, client)
+	runInfo.finish(time.Now())
+	if flags.saveBodies != "" {
+		if err := saveBodies(flags.saveBodies, runs, runInfo.Started, flags.saveBodiesLimit); err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+		}
+	}
+	if shared := dedupe.Shared(); shared > 0 {
+		fmt.Fprintf(os.Stderr, "Deduplicated %d identical requests\n", shared)
+	}
+	runPostRunHooks(configs, failedChecks(runs))
+
+	if state != nil && incidentsEnabled(configs) {
+		_, resolved := state.TrackIncidents(runs, time.Now())
+		if issues, ok := issuesConfig(configs); ok {
+			_, errs := fileIssues(issues, runs, state, resolved, configTargets(configs), time.Now())
+			for _, err := range errs {
+				fmt.Fprintf(os.Stderr, "%s\n", err)
 			}
-
-			// Wait for all targets in this config to complete
-			targetWg.Wait()
-		}(configWithSource)
+		}
+	}
+	if err := recordHistory(configs, runs); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+	}
+	if sinks, ok := sinksConfig(configs); ok {
+		for _, err := range publishToSinks(sinks, runs, state) {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+		}
+	}
+	if state != nil {
+		if err := state.Save(); err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+		}
 	}
 
-	// Wait for all config processing to complete
-	wg.Wait()
+	// Convert results for JSON or HTML output
+	jsonOutput := JSONOutput{Run: &runInfo, Targets: make(map[string]JSONTargetResults)}
+	if flags.jsonOutput || flags.htmlOutput || flags.upload != "" {
+		for _, run := range runs {
+			jsonTargetResults, err := printJSONResults(run.Results, run.TargetName, run.ConfigName, run.Ownership, run.ApdexThreshold, flags.verbosity)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error processing results: %s\n", err)
+			}
+			jsonTargetResults.Labels = run.Labels
+			jsonTargetResults.Notes = run.Notes
+			jsonOutput.Targets[run.Key] = jsonTargetResults
+		}
+	}
 
 	// Print table results after all processing is complete
-	if !flags.jsonOutput && !flags.htmlOutput {
+	if flags.tableOutput() {
 		green, red, _ := setupColorOutput()
+		latency := latencyBandsByConfig(configs)
 
-		// Sort keys for consistent output order
-		keys := make([]string, 0, len(tableResults))
-		for k := range tableResults {
-			keys = append(keys, k)
-		}
-		slices.Sort(keys)
+		var auditFindings []AuditFinding
 
-		for _, key := range keys {
-			result := tableResults[key]
-			printResults(result.results, result.targetName, result.configName, green, red, flags.verbosity)
+		// Runs are sorted by key for consistent output order
+		for _, run := range runs {
+			printResults(run.Results, run.TargetName, run.ConfigName, run.Ownership, run.Labels, green, red, latency[run.ConfigName], run.ApdexThreshold, flags.verbosity, flags.maxRows)
 			fmt.Println()
+
+			if flags.compare {
+				if comparison := buildComparison(run.Results); len(comparison.BaseURLs) > 1 {
+					printComparison(comparison, run.TargetName, green, red)
+					fmt.Println()
+				}
+			}
+
+			for _, r := range run.Results {
+				if len(r.HeaderWarnings) > 0 {
+					auditFindings = append(auditFindings, AuditFinding{
+						Target:   run.TargetName,
+						URL:      r.URL,
+						Warnings: r.HeaderWarnings,
+					})
+				}
+			}
 		}
+
+		printAuditFindings(auditFindings)
 	}
 
 	// Output the final result in the requested format
-	if flags.jsonOutput {
+	if flags.jsonStable {
+		jsonData, err := stableJSON(jsonOutput)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error marshaling JSON output: %s\n", err)
+		} else {
+			os.Stdout.Write(jsonData)
+		}
+	} else if flags.jsonOutput {
 		jsonData, err := json.MarshalIndent(jsonOutput, "", "  ")
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error marshaling JSON output: %s\n", err)
@@ -829,16 +2144,72 @@ func main() {
 			fmt.Println(string(jsonData))
 		}
 	} else if flags.htmlOutput {
-		htmlOutput, err := generateHTMLResults(jsonOutput.Targets, flags.verbosity)
+		htmlOutput, err := generateHTMLResults(jsonOutput.Run, limitReportRows(jsonOutput.Targets, flags.maxRows), flags.verbosity)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error generating HTML output: %s\n", err)
-			os.Exit(1)
+			summary.exit(runs, 1, exitOutputError)
 		}
 		fmt.Println(htmlOutput)
+	} else if flags.mermaidOutput {
+		if err := renderMermaid(os.Stdout, buildGraph(configs, runs)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing Mermaid output: %s\n", err)
+			summary.exit(runs, 1, exitOutputError)
+		}
+	}
+
+	if flags.upload != "" {
+		if err := uploadResults(flags, jsonOutput); err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			summary.exit(runs, 1, exitUploadFailed)
+		}
+	}
+
+	// Make failures hard to miss once the tables have scrolled past
+	if failed := failedChecks(runs); failed > 0 && flags.bell {
+		printFailureTrailer(os.Stderr, failed)
 	}
 
 	// Exit with non-zero status if any requests failed
-	if !overallSuccess {
-		os.Exit(1)
+	if !runsSucceeded(runs) {
+		summary.exit(runs, 1, exitChecksFailed)
 	}
+	summary.write(runs, 0, exitPassed)
+}
+
+// printFailureTrailer rings the terminal bell and prints a bold count of failed checks
+func printFailureTrailer(w io.Writer, failed int) {
+	noun := "CHECKS"
+	if failed == 1 {
+		noun = "CHECK"
+	}
+	fmt.Fprint(w, "\a")
+	fmt.Fprintln(w, color.New(color.Bold, color.FgRed).Sprintf("%d %s FAILED", failed, noun))
+}
+
+// uploadResults uploads the JSON report with --json and the HTML report otherwise
+func uploadResults(flags cliFlags, jsonOutput JSONOutput) error {
+	var report []byte
+	contentType, ext := "text/html; charset=utf-8", ".html"
+	if flags.jsonOutput {
+		data, err := json.MarshalIndent(jsonOutput, "", "  ")
+		if err != nil {
+			return fmt.Errorf("error marshaling JSON output: %s", err)
+		}
+		report, contentType, ext = data, "application/json", ".json"
+	} else {
+		html, err := generateHTMLResults(jsonOutput.Run, limitReportRows(jsonOutput.Targets, flags.maxRows), flags.verbosity)
+		if err != nil {
+			return fmt.Errorf("error generating HTML output: %s", err)
+		}
+		report = []byte(html)
+	}
+
+	keys, err := uploadReport(flags.upload, flags.uploadLatest, report, contentType, ext)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		fmt.Fprintf(os.Stderr, "Uploaded %s\n", key)
+	}
+	return nil
 }
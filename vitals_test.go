@@ -1,7 +1,14 @@
 package main
 
 import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -206,6 +213,18 @@ func TestConstructURL(t *testing.T) {
 			endpoint: "api",
 			want:     "http://example.com/api",
 		},
+		{
+			name:     "IPv6 literal with port",
+			baseURL:  "http://[2001:db8::1]:8080",
+			endpoint: "/health",
+			want:     "http://[2001:db8::1]:8080/health",
+		},
+		{
+			name:     "internationalized domain name",
+			baseURL:  "https://münchen.example",
+			endpoint: "/health",
+			want:     "https://xn--mnchen-3ya.example/health",
+		},
 	}
 
 	for _, tt := range tests {
@@ -255,3 +274,311 @@ func TestSetupHTTPClient(t *testing.T) {
 		})
 	}
 }
+
+func TestResolveUserAgent(t *testing.T) {
+	tests := []struct {
+		name     string
+		globalUA string
+		targetUA string
+		want     string
+	}{
+		{
+			name: "default user agent",
+			want: "vitals/" + version,
+		},
+		{
+			name:     "global override",
+			globalUA: "probe/1.0",
+			want:     "probe/1.0",
+		},
+		{
+			name:     "target override wins",
+			globalUA: "probe/1.0",
+			targetUA: "payments-probe",
+			want:     "payments-probe",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := resolveUserAgent(tt.globalUA, tt.targetUA)
+			if got != tt.want {
+				t.Errorf("resolveUserAgent() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckBody(t *testing.T) {
+	target := TargetConfig{
+		BodyNotContains: []string{"stack trace"},
+		BodyNotRegex:    []string{`(?i)maintenance\s+mode`},
+	}
+	checks := buildResponseChecks(target)
+
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{
+			name: "clean body",
+			body: `{"status":"ok"}`,
+			want: "",
+		},
+		{
+			name: "contains error marker",
+			body: "Internal error\nstack trace: ...",
+			want: `body contains "stack trace"`,
+		},
+		{
+			name: "matches error pattern",
+			body: "<h1>Maintenance Mode</h1>",
+			want: `body matches /(?i)maintenance\s+mode/`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := checkBody(tt.body, target, checks)
+			if got != tt.want {
+				t.Errorf("checkBody() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckBodySHA256(t *testing.T) {
+	// sha256("hello")
+	target := TargetConfig{BodySHA256: "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"}
+
+	if got := checkBody("hello", target, ResponseChecks{}); got != "" {
+		t.Errorf("checkBody() with matching checksum = %q, want no failure", got)
+	}
+
+	got := checkBody("hello!", target, ResponseChecks{})
+	if !strings.Contains(got, "expected "+target.BodySHA256) {
+		t.Errorf("checkBody() with mismatched checksum = %q, want mismatch reason", got)
+	}
+}
+
+func TestCheckContentType(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		expected    string
+		wantFail    bool
+	}{
+		{name: "no expectation", contentType: "text/html", expected: "", wantFail: false},
+		{name: "exact match", contentType: "application/json", expected: "application/json", wantFail: false},
+		{name: "parameters ignored", contentType: "application/json; charset=utf-8", expected: "application/json", wantFail: false},
+		{name: "case insensitive", contentType: "Application/JSON", expected: "application/json", wantFail: false},
+		{name: "html error page", contentType: "text/html; charset=utf-8", expected: "application/json", wantFail: true},
+		{name: "missing header", contentType: "", expected: "application/json", wantFail: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := checkContentType(tt.contentType, tt.expected)
+			if (got != "") != tt.wantFail {
+				t.Errorf("checkContentType() = %q, wantFail %v", got, tt.wantFail)
+			}
+		})
+	}
+}
+
+func TestNewJSONResultRunbookURL(t *testing.T) {
+	tests := []struct {
+		name   string
+		result EndpointResult
+		want   string
+	}{
+		{"passing result", EndpointResult{Success: true, RunbookURL: "https://wiki/api"}, ""},
+		{"failed assertion", EndpointResult{StatusCode: 503, RunbookURL: "https://wiki/api"}, "https://wiki/api"},
+		{"request error", EndpointResult{Error: os.ErrDeadlineExceeded, RunbookURL: "https://wiki/api"}, "https://wiki/api"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newJSONResult(tt.result, false).RunbookURL; got != tt.want {
+				t.Errorf("RunbookURL = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOwnershipString(t *testing.T) {
+	tests := []struct {
+		target TargetConfig
+		want   string
+	}{
+		{TargetConfig{}, ""},
+		{TargetConfig{Team: "payments"}, "Team: payments"},
+		{TargetConfig{Owner: "alice", Team: "payments", Contact: "#payments-oncall"}, "Owner: alice, Team: payments, Contact: #payments-oncall"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.target.ownership().String(); got != tt.want {
+			t.Errorf("ownership().String() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestPrintFailureTrailer(t *testing.T) {
+	tests := []struct {
+		failed int
+		want   string
+	}{
+		{1, "\a1 CHECK FAILED\n"},
+		{3, "\a3 CHECKS FAILED\n"},
+	}
+
+	for _, tt := range tests {
+		var b strings.Builder
+		printFailureTrailer(&b, tt.failed)
+		if got := b.String(); got != tt.want {
+			t.Errorf("printFailureTrailer(%d) = %q, want %q", tt.failed, got, tt.want)
+		}
+	}
+}
+
+func TestCheckEndpointSkipsUnusedBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			w.Header().Set("Content-Type", "application/health+json")
+			io.WriteString(w, `{"status": "fail"}`)
+			return
+		}
+		io.WriteString(w, strings.Repeat("x", 1<<20))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		endpoint string
+		target   TargetConfig
+		verbose  bool
+		wantBody bool
+		wantOK   bool
+	}{
+		{"no body assertions", "/", TargetConfig{}, false, false, true},
+		{"verbose", "/", TargetConfig{}, true, true, true},
+		{"body assertion", "/", TargetConfig{BodyNotContains: []string{"error"}}, false, true, true},
+		{"drift detection", "/", TargetConfig{DetectDrift: true}, false, true, true},
+		{"health+json", "/health", TargetConfig{}, false, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.target.StatusCodes = []int{200}
+			checks := buildResponseChecks(tt.target)
+			checks.SkipBody = bodyUnused(tt.target, checks)
+
+			result := checkEndpoint(server.Client(), server.URL, tt.endpoint, tt.target, checks, nil, tt.verbose)
+			if result.Error != nil || result.Success != tt.wantOK {
+				t.Fatalf("Success = %v, Error = %v, want success %v", result.Success, result.Error, tt.wantOK)
+			}
+			if gotBody := result.BodySize > 0; gotBody != tt.wantBody {
+				t.Errorf("read body = %v, want %v", gotBody, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestProcessTargetWorkerPool(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+	}))
+	defer server.Close()
+
+	paths := make([]string, 500)
+	for i := range paths {
+		paths[i] = fmt.Sprintf("/item/%d", i)
+	}
+	target := TargetConfig{BaseURLs: []string{server.URL}, Endpoints: pathEndpoints(paths...), StatusCodes: []int{200}}
+
+	tests := []struct {
+		name     string
+		sem      chan struct{}
+		wantPeak int32
+	}{
+		{"default pool", nil, maxTargetWorkers},
+		{"concurrency limit", make(chan struct{}, 4), 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			peak.Store(0)
+			results := processTarget(context.Background(), server.Client(), target, ResponseChecks{}, nil, tt.sem, false)
+			if len(results) != len(paths) {
+				t.Fatalf("got %d results, want %d", len(results), len(paths))
+			}
+			for _, result := range results {
+				if result.Error != nil || !result.Success {
+					t.Fatalf("%s failed: %v", result.URL, result.Error)
+				}
+			}
+			if peak.Load() > tt.wantPeak {
+				t.Errorf("peak concurrency = %d, want at most %d", peak.Load(), tt.wantPeak)
+			}
+		})
+	}
+
+	// A cancelled context stops handing out jobs
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if results := processTarget(ctx, server.Client(), target, ResponseChecks{}, nil, nil, false); len(results) == len(paths) {
+		t.Errorf("got all %d results after cancellation", len(results))
+	}
+}
+
+func TestCheckEndpointRecordsHostAndAddrs(t *testing.T) {
+	var gotHost string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHost = r.Host
+	}))
+	defer server.Close()
+
+	// A Host header is sent as the request's host
+	target := TargetConfig{StatusCodes: []int{200}, Headers: map[string]string{"Host": "api.example.com"}}
+	result := checkEndpoint(server.Client(), server.URL, "/", target, buildResponseChecks(target), nil, false)
+	if gotHost != "api.example.com" || result.Host != "api.example.com" {
+		t.Errorf("server saw host %q, result has %q, want api.example.com", gotHost, result.Host)
+	}
+
+	// A failed connection reports the address it tried, in JSON too
+	addr := strings.TrimPrefix(server.URL, "http://")
+	server.Close()
+	target.Headers = nil
+	result = checkEndpoint(&http.Client{}, "http://"+addr, "/", target, buildResponseChecks(target), nil, false)
+	jsonResult := newJSONResult(result, false)
+	if result.Error == nil || jsonResult.Host != addr || len(jsonResult.RemoteAddrs) != 1 || jsonResult.RemoteAddrs[0] != addr {
+		t.Errorf("error %v, host %q, remote addrs %v, want %s", result.Error, jsonResult.Host, jsonResult.RemoteAddrs, addr)
+	}
+}
+
+func TestHTMLReportNotes(t *testing.T) {
+	targets := map[string]JSONTargetResults{
+		"vitals.toml::batch": {Target: "batch", Notes: "Expected slow during nightly batch 02:00-03:00 UTC <ops>"},
+		"vitals.toml::web":   {Target: "web"},
+	}
+	html, err := generateHTMLResults(nil, targets, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `<div class="notes">Expected slow during nightly batch 02:00-03:00 UTC &lt;ops&gt;</div>`; !strings.Contains(html, want) {
+		t.Errorf("report is missing %s:\n%s", want, html)
+	}
+	if n := strings.Count(html, `<div class="notes">`); n != 1 {
+		t.Errorf("report has %d notes, want 1", n)
+	}
+}
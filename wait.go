@@ -0,0 +1,97 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+// runWait implements `vitals wait`, polling the selected targets until every endpoint passes
+// (exit 0) or the deadline expires (exit 1)
+func runWait(args []string) int {
+	fs := flag.NewFlagSet("wait", flag.ExitOnError)
+	var configFiles, only []string
+	fs.Var((*stringSlice)(&configFiles), "config", "Path to configuration file(s)")
+	fs.Var((*stringSlice)(&configFiles), "c", "Path to configuration file(s) (shorthand)")
+	fs.Var((*stringSlice)(&only), "for", "Target to wait for, by name or config::name (repeatable, default all)")
+	timeout := fs.Duration("timeout", 5*time.Minute, "Give up after this long")
+	interval := fs.Duration("interval", 5*time.Second, "Delay between attempts")
+	verbose := fs.Bool("verbose", false, "Print every failing check after each attempt")
+	fs.BoolVar(verbose, "v", false, "Print every failing check after each attempt (shorthand)")
+	fs.Parse(args)
+
+	if len(configFiles) == 0 {
+		configFiles = append(configFiles, "vitals.toml")
+	}
+	configs, err := loadConfigFiles(configFiles)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 2
+	}
+
+	opts := runOptions{only: only}
+	if missing := missingTargets(configs, only); len(missing) > 0 {
+		fmt.Fprintf(os.Stderr, "Unknown target(s): %v\n", missing)
+		return 2
+	}
+
+	deadline := time.Now().Add(*timeout)
+	for attempt := 1; ; attempt++ {
+		runs := runChecks(configs, opts)
+
+		var passed, total int
+		for _, run := range runs {
+			for _, result := range run.Results {
+				total++
+				if result.Error == nil && result.Success {
+					passed++
+				} else if *verbose {
+					fmt.Fprintf(os.Stderr, "  [%s] %s: %s\n", run.TargetName, result.URL, describeRequestFailure(result))
+				}
+			}
+		}
+		fmt.Fprintf(os.Stderr, "Attempt %d: %d/%d checks passing\n", attempt, passed, total)
+
+		if runsSucceeded(runs) {
+			return 0
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			fmt.Fprintf(os.Stderr, "Timed out after %s waiting for checks to pass\n", *timeout)
+			return 1
+		}
+		time.Sleep(min(*interval, remaining))
+	}
+}
+
+// missingTargets returns the --for names that match no target in the configs
+func missingTargets(configs []ConfigWithSource, only []string) []string {
+	var missing []string
+	for _, name := range only {
+		found := false
+		for _, c := range configs {
+			for targetName := range c.Config.Targets {
+				if name == targetName || name == runKey(c.Filename, targetName) {
+					found = true
+				}
+			}
+		}
+		if !found {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+// describeFailure summarizes why a check did not pass
+func describeFailure(result EndpointResult) string {
+	if result.Error != nil {
+		return "error: " + result.Error.Error()
+	}
+	if result.FailureReason != "" {
+		return result.FailureReason
+	}
+	return fmt.Sprintf("status %d", result.StatusCode)
+}
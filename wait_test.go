@@ -0,0 +1,50 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRunWait(t *testing.T) {
+	// The service becomes healthy on the third request
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "vitals.toml")
+	config := "[targets.api]\nbase_urls = [\"" + server.URL + "\"]\nendpoints = [\"health\"]\n"
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if code := runWait([]string{"-c", path, "--for", "api", "--interval", "10ms", "--timeout", "5s"}); code != 0 {
+		t.Errorf("wait exit code = %d, want 0", code)
+	}
+	if got := requests.Load(); got != 3 {
+		t.Errorf("expected 3 attempts, got %d", got)
+	}
+
+	if code := runWait([]string{"-c", path, "--for", "nope"}); code != 2 {
+		t.Errorf("wait with unknown target exit code = %d, want 2", code)
+	}
+}
+
+func TestMissingTargets(t *testing.T) {
+	configs := []ConfigWithSource{{
+		Filename: "a.toml",
+		Config:   Config{Targets: map[string]TargetConfig{"api": {}, "web": {}}},
+	}}
+	got := missingTargets(configs, []string{"api", "a.toml::web", "db"})
+	if !slices.Equal(got, []string{"db"}) {
+		t.Errorf("missingTargets() = %v, want [db]", got)
+	}
+}